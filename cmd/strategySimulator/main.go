@@ -0,0 +1,7 @@
+package main
+
+import "github.com/airenas/listgo/internal/app/simulator"
+
+func main() {
+	simulator.Execute()
+}
@@ -6,29 +6,44 @@ import (
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/airenas/listgo/internal/pkg/mongo"
+	"github.com/airenas/listgo/internal/pkg/storage"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// RefCounter returns the number of the transcriptions reusing the data of the ID
+type RefCounter interface {
+	Refs(ID string) (int64, error)
+}
+
 type cleanerImpl struct {
 	jobs        []Cleaner
 	fileStorage string
+	// storage is set if the files are kept in the object storage
+	storage storage.Storage
+	// refs counts the transcriptions reusing the audio and results of the ID
+	refs RefCounter
 
 	counter prometheus.Counter
 }
 
-func newCleanerImpl(mng *mongo.SessionProvider, fileStorage string, patterns string, counter prometheus.Counter) (*cleanerImpl, error) {
+func newCleanerImpl(mng *mongo.SessionProvider, fileStorage string, patterns string, logStorage string,
+	logPatterns string, counter prometheus.Counter) (*cleanerImpl, error) {
 	c := cleanerImpl{}
 	c.jobs = make([]Cleaner, 0)
 	c.fileStorage = fileStorage
 
-	fcs, err := newFileCleaners(fileStorage, patterns)
+	fcs, st, err := newPatternCleaners(fileStorage, patterns)
 	if err != nil {
 		return nil, err
 	}
-	for _, fc := range fcs {
-		c.jobs = append(c.jobs, fc)
+	c.storage = st
+	c.jobs = append(c.jobs, fcs...)
+	lcs, err := newLogCleaners(logStorage, logPatterns)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't init logs cleaner")
 	}
+	c.jobs = append(c.jobs, lcs...)
 
 	mcs, err := mongo.NewCleanRecords(mng)
 	if err != nil {
@@ -37,6 +52,15 @@ func newCleanerImpl(mng *mongo.SessionProvider, fileStorage string, patterns str
 	for _, mc := range mcs {
 		c.jobs = append(c.jobs, mc)
 	}
+	rfc, err := mongo.NewCleanResultFiles(mng)
+	if err != nil {
+		return nil, err
+	}
+	c.jobs = append(c.jobs, rfc)
+	c.refs, err = mongo.NewDedup(mng)
+	if err != nil {
+		return nil, err
+	}
 	if counter == nil {
 		return nil, errors.New("No metrics counter")
 	}
@@ -44,7 +68,19 @@ func newCleanerImpl(mng *mongo.SessionProvider, fileStorage string, patterns str
 	return &c, nil
 }
 
+// Clean deletes the data by ID. The data shared with other transcriptions is kept
+// until all the referencing transcriptions are cleaned
 func (c *cleanerImpl) Clean(ID string) error {
+	if c.refs != nil {
+		n, err := c.refs.Refs(ID)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			cmdapp.Log.Infof("Keeping %s, it is referenced by %d transcriptions", ID, n)
+			return nil
+		}
+	}
 	c.counter.Inc()
 	failed := 0
 	for _, job := range c.jobs {
@@ -60,6 +96,43 @@ func (c *cleanerImpl) Clean(ID string) error {
 	return nil
 }
 
+// newPatternCleaners creates the cleaners of the patterns in the local dir or in the object storage,
+// the object storage is returned if it is used
+func newPatternCleaners(path string, patterns string) ([]Cleaner, storage.Storage, error) {
+	if storage.IsS3(path) {
+		st, err := storage.New(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		scs, err := newStorageCleaners(st, patterns)
+		if err != nil {
+			return nil, nil, err
+		}
+		return scs, st, nil
+	}
+	fcs, err := newFileCleaners(path, patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make([]Cleaner, 0, len(fcs))
+	for _, fc := range fcs {
+		result = append(result, fc)
+	}
+	return result, nil, nil
+}
+
+// newLogCleaners creates the cleaners of the step logs shipped by the workers, none if the logs are not kept
+func newLogCleaners(path string, patterns string) ([]Cleaner, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if strings.TrimSpace(patterns) == "" {
+		return nil, errors.New("No logs patterns")
+	}
+	result, _, err := newPatternCleaners(path, patterns)
+	return result, err
+}
+
 func newFileCleaners(fs string, patterns string) ([]*localFile, error) {
 	ps := strings.Split(patterns, "\n")
 	result := make([]*localFile, 0)
@@ -76,8 +149,36 @@ func newFileCleaners(fs string, patterns string) ([]*localFile, error) {
 	return result, nil
 }
 
+// newStorageCleaners creates cleaners for the object storage, absolute patterns are cleaned on the local disk
+func newStorageCleaners(st storage.Storage, patterns string) ([]Cleaner, error) {
+	result := make([]Cleaner, 0)
+	for _, p := range strings.Split(patterns, "\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "/") {
+			fc, err := newLocalFile("", p)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, fc)
+			continue
+		}
+		sc, err := newStorageFile(st, p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sc)
+	}
+	return result, nil
+}
+
 // HealthyFunc returns func for health check
 func (c *cleanerImpl) HealthyFunc() func() error {
+	if c.storage != nil {
+		return storage.HealthyFunc(c.storage, 0)
+	}
 	return func() error {
 		var info syscall.Statfs_t
 		err := syscall.Statfs(c.fileStorage, &info)
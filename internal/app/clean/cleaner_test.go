@@ -3,6 +3,11 @@ package clean
 import (
 	"testing"
 
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +32,64 @@ func TestNewFileCleanersPath(t *testing.T) {
 	assert.Equal(t, "/path", f[0].StoragePath)
 	assert.Equal(t, "path1{ID}", f[0].pattern)
 }
+
+func TestNewLogCleaners(t *testing.T) {
+	f, err := newLogCleaners("/logs", "{ID}")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(f))
+	assert.Equal(t, "/logs", f[0].(*localFile).StoragePath)
+	assert.Equal(t, "{ID}", f[0].(*localFile).pattern)
+}
+
+func TestNewLogCleaners_NotKept(t *testing.T) {
+	f, err := newLogCleaners("", "{ID}")
+	assert.Nil(t, err)
+	assert.Empty(t, f)
+}
+
+func TestNewLogCleaners_Fails(t *testing.T) {
+	_, err := newLogCleaners("/logs", " ")
+	assert.NotNil(t, err)
+	_, err = newLogCleaners("/logs", "logs")
+	assert.NotNil(t, err)
+}
+
+type testJob struct {
+	ids []string
+}
+
+func (j *testJob) Clean(ID string) error {
+	j.ids = append(j.ids, ID)
+	return nil
+}
+
+func newTestCleaner(refs RefCounter) (*cleanerImpl, *testJob) {
+	j := &testJob{}
+	return &cleanerImpl{jobs: []Cleaner{j}, refs: refs,
+		counter: prometheus.NewCounter(prometheus.CounterOpts{Name: "test"})}, j
+}
+
+func initRefsMock(t *testing.T, n int64, err error) *mocks.MockRefCounter {
+	mocks.AttachMockToTest(t)
+	res := mocks.NewMockRefCounter()
+	pegomock.When(res.Refs(pegomock.AnyString())).ThenReturn(n, err)
+	return res
+}
+
+func TestClean_KeepsReferenced(t *testing.T) {
+	c, j := newTestCleaner(initRefsMock(t, 2, nil))
+	assert.Nil(t, c.Clean("1"))
+	assert.Empty(t, j.ids)
+}
+
+func TestClean_NotReferenced(t *testing.T) {
+	c, j := newTestCleaner(initRefsMock(t, 0, nil))
+	assert.Nil(t, c.Clean("1"))
+	assert.Equal(t, []string{"1"}, j.ids)
+}
+
+func TestClean_RefsFails(t *testing.T) {
+	c, j := newTestCleaner(initRefsMock(t, 0, errors.New("olia")))
+	assert.NotNil(t, c.Clean("1"))
+	assert.Empty(t, j.ids)
+}
@@ -29,6 +29,7 @@ func init() {
 	rootCmd.PersistentFlags().Int32P("port", "", 8000, "Default service port")
 	cmdapp.Config.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
 	cmdapp.Config.SetDefault("port", 8080)
+	cmdapp.Config.SetDefault("fileStorage.logPatterns", "{ID}")
 }
 
 // Execute starts the server
@@ -51,7 +52,8 @@ func run(cmd *cobra.Command, args []string) {
 	cm, err := newCleanMetric()
 	cmdapp.CheckOrPanic(err, "Can't init clean metrics")
 	cln, err := newCleanerImpl(mongoSessionProvider, cmdapp.Config.GetString("fileStorage.path"),
-		cmdapp.Config.GetString("fileStorage.patterns"), cm)
+		cmdapp.Config.GetString("fileStorage.patterns"), cmdapp.Config.GetString("fileStorage.logs"),
+		cmdapp.Config.GetString("fileStorage.logPatterns"), cm)
 	cmdapp.CheckOrPanic(err, "Can't init cleaner")
 	data.cleaner = cln
 
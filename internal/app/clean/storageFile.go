@@ -0,0 +1,74 @@
+package clean
+
+import (
+	"path"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// storageFile deletes the objects matching the pattern from the storage.
+// The pattern is a glob relative to the storage root, an object is deleted
+// if its name or any of its parent dirs matches the pattern
+type storageFile struct {
+	storage storage.Storage
+	pattern string
+}
+
+func newStorageFile(st storage.Storage, pattern string) (*storageFile, error) {
+	cmdapp.Log.Infof("Init Storage Clean for: %s", pattern)
+	if st == nil {
+		return nil, errors.New("No storage provided")
+	}
+	if pattern == "" {
+		return nil, errors.New("No pattern provided")
+	}
+	if !strings.Contains(pattern, "{ID}") {
+		return nil, errors.New("Pattern does not contain {ID}")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		return nil, errors.New("Pattern must be relative to the storage")
+	}
+	return &storageFile{storage: st, pattern: pattern}, nil
+}
+
+func (fs *storageFile) Clean(ID string) error {
+	p := strings.ReplaceAll(fs.pattern, "{ID}", ID)
+	if _, err := path.Match(p, ""); err != nil {
+		return errors.Wrapf(err, "wrong pattern %s", p)
+	}
+	cmdapp.Log.Infof("Removing %s", p)
+	objs, err := fs.storage.List(listPrefix(p))
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		if !matches(p, o.Name) {
+			continue
+		}
+		if err := fs.storage.Delete(o.Name); err != nil {
+			return err
+		}
+		cmdapp.Log.Infof("Removed %s", o.Name)
+	}
+	return nil
+}
+
+// listPrefix returns the pattern part before the first glob symbol
+func listPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?[\\"); i > -1 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+func matches(pattern, name string) bool {
+	for n := name; n != "." && n != "/"; n = path.Dir(n) {
+		if ok, _ := path.Match(pattern, n); ok {
+			return true
+		}
+	}
+	return false
+}
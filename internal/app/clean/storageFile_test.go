@@ -0,0 +1,69 @@
+package clean
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStorage(t *testing.T, files ...string) (storage.Storage, func()) {
+	dir, err := ioutil.TempDir("", "clean")
+	assert.Nil(t, err)
+	st, err := storage.NewLocal(dir)
+	assert.Nil(t, err)
+	for _, f := range files {
+		assert.Nil(t, st.Put(f, strings.NewReader("olia")))
+	}
+	return st, func() { os.RemoveAll(dir) }
+}
+
+func TestStorageFile_FailsInit(t *testing.T) {
+	st, cf := newTestStorage(t)
+	defer cf()
+	_, err := newStorageFile(nil, "a/{ID}")
+	assert.NotNil(t, err)
+	_, err = newStorageFile(st, "")
+	assert.NotNil(t, err)
+	_, err = newStorageFile(st, "a/olia")
+	assert.NotNil(t, err)
+	_, err = newStorageFile(st, "/a/{ID}")
+	assert.NotNil(t, err)
+}
+
+func TestStorageFile_Clean(t *testing.T) {
+	st, cf := newTestStorage(t, "audio/1.wav", "audio/12.wav", "results/1/a.txt", "results/1/b/c.txt",
+		"results/1/edits/2-1.json", "results/12/a.txt")
+	defer cf()
+	for _, p := range []string{"audio/{ID}.*", "results/{ID}"} {
+		f, err := newStorageFile(st, p)
+		assert.Nil(t, err)
+		assert.Nil(t, f.Clean("1"))
+	}
+	objs, err := st.List("")
+	assert.Nil(t, err)
+	var names []string
+	for _, o := range objs {
+		names = append(names, o.Name)
+	}
+	assert.ElementsMatch(t, []string{"audio/12.wav", "results/12/a.txt"}, names)
+}
+
+func TestListPrefix(t *testing.T) {
+	assert.Equal(t, "audio/1.", listPrefix("audio/1.*"))
+	assert.Equal(t, "results/1", listPrefix("results/1"))
+	assert.Equal(t, "a/", listPrefix("a/[12]"))
+}
+
+func TestNewStorageCleaners(t *testing.T) {
+	st, cf := newTestStorage(t)
+	defer cf()
+	cs, err := newStorageCleaners(st, "audio/{ID}.*\n/tmp/{ID}\n  \n")
+	assert.Nil(t, err)
+	assert.Len(t, cs, 2)
+	_, ok := cs[1].(*localFile)
+	assert.True(t, ok)
+}
@@ -0,0 +1,118 @@
+package cmdworker
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/cmdtemplate"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// ChunkJoin joins the outputs of the chunked transcription.
+// Files are the templates of the step output files. They must contain {TAG:chunk_index|...},
+// so every chunk writes its own files, e.g. /data/trans/{ID}/lat{TAG:chunk_index|}.gz.
+// The dispatcher sends the chunk_join message after all chunks are done, then the chunk files are
+// concatenated in the chunk order into the file expanded without the chunk tag and removed.
+// The files are joined as bytes, nothing is shifted or merged, so the step must process only the audio
+// from {TAG:chunk_from} to {TAG:chunk_to} and write the timestamps of the whole audio, not of the chunk
+type ChunkJoin struct {
+	Files []string
+}
+
+func validateChunkJoin(cj *ChunkJoin) error {
+	if len(cj.Files) == 0 {
+		return errors.New("No chunk files")
+	}
+	for _, f := range cj.Files {
+		target, err := expandPath(f, &cmdtemplate.Params{ID: "id"})
+		if err != nil {
+			return errors.Wrapf(err, "Wrong chunk file '%s'", f)
+		}
+		chunk, err := expandPath(f, chunkParams(&cmdtemplate.Params{ID: "id"}, 0))
+		if err != nil {
+			return errors.Wrapf(err, "Wrong chunk file '%s'", f)
+		}
+		if chunk == target {
+			return errors.Errorf("Chunk file '%s' does not depend on {TAG:%s}", f, messages.TagChunkIndex)
+		}
+	}
+	return nil
+}
+
+// chunksToJoin returns the number of chunks if the message asks to join them
+func chunksToJoin(msg *messages.QueueMessage) (int, bool, error) {
+	v, ok := messages.GetTag(msg.Tags, messages.TagChunkJoin)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, true, errors.Errorf("Wrong %s tag '%s'", messages.TagChunkJoin, v)
+	}
+	return n, true, nil
+}
+
+func chunkParams(tp *cmdtemplate.Params, i int) *cmdtemplate.Params {
+	tags := make([]messages.Tag, 0, len(tp.Tags)+1)
+	tags = append(tags, messages.NewTag(messages.TagChunkIndex, strconv.Itoa(i)))
+	tags = append(tags, tp.Tags...)
+	return &cmdtemplate.Params{ID: tp.ID, Tags: tags, Settings: tp.Settings}
+}
+
+// joinChunks concatenates the chunk files, the chunk files are removed after all the files are joined
+func joinChunks(cj *ChunkJoin, tp *cmdtemplate.Params, n int) error {
+	var done []string
+	for _, f := range cj.Files {
+		target, err := expandPath(f, tp)
+		if err != nil {
+			return errors.Wrap(err, "Can't prepare file name")
+		}
+		sources := make([]string, n)
+		for i := range sources {
+			if sources[i], err = expandPath(f, chunkParams(tp, i)); err != nil {
+				return errors.Wrap(err, "Can't prepare chunk file name")
+			}
+		}
+		cmdapp.Log.Infof("Joining %d chunks into %s", n, target)
+		if err := concatFiles(target, sources); err != nil {
+			return err
+		}
+		done = append(done, sources...)
+	}
+	for _, f := range done {
+		cmdapp.LogIf(os.Remove(f))
+	}
+	return nil
+}
+
+func concatFiles(target string, sources []string) error {
+	tmp := target + ".join"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrapf(err, "Can't create %s", tmp)
+	}
+	defer os.Remove(tmp)
+	for _, s := range sources {
+		if err := appendFile(out, s); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return errors.Wrapf(err, "Can't write %s", tmp)
+	}
+	return errors.Wrapf(os.Rename(tmp, target), "Can't rename %s", tmp)
+}
+
+func appendFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.Wrapf(err, "Can't open chunk file %s", file)
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return errors.Wrapf(err, "Can't copy chunk file %s", file)
+}
@@ -0,0 +1,92 @@
+package cmdworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/cmdtemplate"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunks_TranscribedAndJoined(t *testing.T) {
+	initTest(t)
+	dir := t.TempDir()
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.Command = fmt.Sprintf(`sh -c "printf 'c{TAG:chunk_index|}:{TAG:chunk_from|}\n' > %s/lat{TAG:chunk_index|}.txt"`, dir)
+	data.ChunkJoin = &ChunkJoin{Files: []string{filepath.Join(dir, "lat{TAG:chunk_index|}.txt")}}
+	assert.Nil(t, StartWorkerService(&data))
+
+	send := func(tags ...messages.Tag) {
+		msgdata, _ := json.Marshal(messages.NewQueueMessage("1", "rec", tags))
+		d := message
+		d.Body, d.ReplyTo = msgdata, "rt"
+		wc <- d
+	}
+	for i := 0; i < 3; i++ {
+		send(messages.NewTag(messages.TagChunkIndex, strconv.Itoa(i)), messages.NewTag(messages.TagChunkCount, "3"),
+			messages.NewTag(messages.TagChunkFrom, strconv.Itoa(i*100)))
+	}
+	send(messages.NewTag(messages.TagChunkJoin, "3"))
+	close(wc)
+	<-data.quitChannel.C
+
+	msgs, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Times(4)).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetAllCapturedArguments()
+	for _, m := range msgs {
+		assert.Empty(t, m.(*messages.QueueMessage).Error)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "lat.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "c0:0\nc1:100\nc2:200\n", string(b))
+	_, err = os.Stat(filepath.Join(dir, "lat0.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestChunks_JoinFailsOnMissingChunk(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "lat0.txt"), []byte("c0"), 0644)
+	os.WriteFile(filepath.Join(dir, "lat.txt"), []byte("old"), 0644)
+
+	err := joinChunks(&ChunkJoin{Files: []string{filepath.Join(dir, "lat{TAG:chunk_index|}.txt")}},
+		&cmdtemplate.Params{ID: "1"}, 2)
+
+	assert.NotNil(t, err)
+	b, _ := os.ReadFile(filepath.Join(dir, "lat.txt"))
+	assert.Equal(t, "old", string(b))
+	_, err = os.Stat(filepath.Join(dir, "lat0.txt"))
+	assert.Nil(t, err)
+}
+
+func TestChunks_JoinNotConfigured(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	StartWorkerService(&data)
+
+	msgdata, _ := json.Marshal(messages.NewQueueMessage("1", "rec", []messages.Tag{messages.NewTag(messages.TagChunkJoin, "2")}))
+	message.Body, message.ReplyTo = msgdata, "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C
+
+	cMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Contains(t, cMsg.(*messages.QueueMessage).Error, "not configured")
+}
+
+func TestValidateChunkJoin(t *testing.T) {
+	assert.Nil(t, validateChunkJoin(&ChunkJoin{Files: []string{"/data/{ID}/lat{TAG:chunk_index|}.gz"}}))
+	assert.NotNil(t, validateChunkJoin(&ChunkJoin{}))
+	assert.NotNil(t, validateChunkJoin(&ChunkJoin{Files: []string{"/data/{ID}/lat.gz"}}))
+	assert.NotNil(t, validateChunkJoin(&ChunkJoin{Files: []string{"/data/{ID}/lat{TAG:chunk_index}.gz"}}))
+	assert.NotNil(t, validateChunkJoin(&ChunkJoin{Files: []string{"/data/{ID"}}))
+}
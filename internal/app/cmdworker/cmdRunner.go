@@ -2,27 +2,63 @@ package cmdworker
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/cmdtemplate"
+	errc "github.com/airenas/listgo/internal/pkg/err"
 	"github.com/pkg/errors"
 )
 
+// Limits keeps resource constraints for the command
+type Limits struct {
+	// Timeout kills the process group of the command after the time, 0 - no timeout
+	Timeout time.Duration
+	// MemoryMB limits virtual memory of the command, 0 - no limit
+	MemoryMB int64
+	// CPU limits cpu time of the command, 0 - no limit
+	CPU time.Duration
+	// Cgroup is a path of existing cgroup dir to put the command into
+	Cgroup string
+}
+
 // RunCommand executes system comman end return error if any
 func RunCommand(command string, workingDir string, id string, envs []string, outWriter io.Writer) error {
+	tmpl, err := cmdtemplate.ParseCommand(command)
+	if err != nil {
+		return err
+	}
+	args, err := tmpl.Args(&cmdtemplate.Params{ID: id})
+	if err != nil {
+		return err
+	}
+	return RunCommandWithLimits(args, workingDir, envs, outWriter, Limits{})
+}
+
+// RunCommandWithLimits executes system command with the resource limits and return error if any.
+// The error contains TIMEOUT error code if the command is killed because of timeout
+func RunCommandWithLimits(args []string, workingDir string, envs []string, outWriter io.Writer,
+	limits Limits) error {
 	logger := log.New(outWriter, "cmd: ", log.LstdFlags)
-	realCommand := strings.Replace(command, "{ID}", id, -1)
+	realCommand := strings.Join(args, " ")
 	cmdapp.Log.Infof("Running command: %s", realCommand)
 	logger.Printf("===== Running command: %s", realCommand)
 	cmdapp.Log.Debugf("Working Dir: %s", workingDir)
-	cmdArr := strings.Split(realCommand, " ")
-	if len(cmdArr) < 2 {
+	if len(args) < 2 {
 		return errors.New("Wrong command. No parameter " + realCommand)
 	}
+	cmdArr := limits.wrap(args)
 
 	cmd := exec.Command(cmdArr[0], cmdArr[1:]...)
 	cmd.Dir = workingDir
@@ -31,13 +67,30 @@ func RunCommand(command string, workingDir string, id string, envs []string, out
 		cmdapp.Log.Debug("Append env: " + env)
 		cmd.Env = append(cmd.Env, env)
 	}
+	// own process group to be able to kill all children on timeout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	var outputBuffer bytes.Buffer
 	outCopyWriter := io.MultiWriter(&outputBuffer, outWriter)
 	cmd.Stdout = outCopyWriter
 	cmd.Stderr = outCopyWriter
 
-	err := cmd.Run()
+	err := cmd.Start()
+	if err != nil {
+		logger.Printf("===== ERROR ============")
+		return errors.Wrap(err, "Can't start command")
+	}
+	if limits.Cgroup != "" {
+		if err := addToCgroup(limits.Cgroup, cmd.Process.Pid); err != nil {
+			cmdapp.Log.Warn(err)
+		}
+	}
+	timeout, err := wait(cmd, limits.Timeout)
+	if timeout {
+		logger.Printf("===== TIMEOUT ============")
+		return errors.Errorf("Command timeout after %v. %s\nOutput: %s", limits.Timeout,
+			errc.Mark(errc.TimeoutCode), string(outputBuffer.Bytes()))
+	}
 	if err != nil {
 		logger.Printf("===== ERROR ============")
 		return errors.Wrap(err, "Output: "+string(outputBuffer.Bytes()))
@@ -45,3 +98,44 @@ func RunCommand(command string, workingDir string, id string, envs []string, out
 	logger.Printf("===== Finished ============")
 	return nil
 }
+
+// wait waits for the started command, kills the process group on timeout
+func wait(cmd *exec.Cmd, timeout time.Duration) (bool, error) {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	if timeout <= 0 {
+		return false, <-done
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return false, err
+	case <-timer.C:
+		cmdapp.Log.Warnf("Timeout. Killing process group %d", cmd.Process.Pid)
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+			cmdapp.Log.Error(errors.Wrap(err, "Can't kill process group"))
+		}
+		return true, <-done
+	}
+}
+
+// wrap sets rlimits with the shell ulimit before executing the command, the limits are inherited by children
+func (l Limits) wrap(cmdArr []string) []string {
+	var ul []string
+	if l.MemoryMB > 0 {
+		ul = append(ul, fmt.Sprintf("ulimit -v %d", l.MemoryMB*1024))
+	}
+	if l.CPU > 0 {
+		ul = append(ul, "ulimit -t "+strconv.FormatInt(int64(math.Ceil(l.CPU.Seconds())), 10))
+	}
+	if len(ul) == 0 {
+		return cmdArr
+	}
+	return append([]string{"/bin/sh", "-c", strings.Join(ul, " && ") + ` && exec "$@"`, "sh"}, cmdArr...)
+}
+
+func addToCgroup(dir string, pid int) error {
+	err := ioutil.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+	return errors.Wrapf(err, "Can't add %d to cgroup %s", pid, dir)
+}
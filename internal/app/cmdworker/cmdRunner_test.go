@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	errc "github.com/airenas/listgo/internal/pkg/err"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,3 +56,44 @@ func TestRun_WritesOnError(t *testing.T) {
 	assert.Contains(t, s, "ERROR")
 	assert.Contains(t, err.Error(), "ech")
 }
+
+func TestRun_Quoted(t *testing.T) {
+	cmd := "echo 'olia  {ID}'"
+	var b bytes.Buffer
+	err := RunCommand(cmd, "/", "id", nil, &b)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b.Bytes()), "\nolia  id\n")
+}
+
+func TestRun_WrongTemplate_Fail(t *testing.T) {
+	err := RunCommand("echo 'olia", "/", "id", nil, ioutil.Discard)
+	assert.NotNil(t, err)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	var b bytes.Buffer
+	st := time.Now()
+	err := RunCommandWithLimits([]string{"sleep", "10"}, "/", nil, &b, Limits{Timeout: 100 * time.Millisecond})
+	assert.NotNil(t, err, "Error expected")
+	assert.Less(t, time.Since(st).Seconds(), 5.0)
+	assert.Equal(t, errc.TimeoutCode, errc.CodeExtractor{}.Get(err.Error()))
+	assert.Contains(t, string(b.Bytes()), "TIMEOUT")
+}
+
+func TestRun_NoTimeout(t *testing.T) {
+	err := RunCommandWithLimits([]string{"echo", "olia"}, "/", nil, ioutil.Discard, Limits{Timeout: 10 * time.Second})
+	assert.Nil(t, err)
+}
+
+func TestRun_Limits(t *testing.T) {
+	var b bytes.Buffer
+	err := RunCommandWithLimits([]string{"echo", "olia"}, "/", nil, &b, Limits{MemoryMB: 1024, CPU: time.Minute})
+	assert.Nil(t, err)
+	assert.Contains(t, string(b.Bytes()), "\nolia\n")
+}
+
+func TestLimitsWrap(t *testing.T) {
+	assert.Equal(t, []string{"ls", "-la"}, Limits{Timeout: time.Second}.wrap([]string{"ls", "-la"}))
+	assert.Equal(t, []string{"/bin/sh", "-c", `ulimit -v 2048 && ulimit -t 2 && exec "$@"`, "sh", "ls", "-la"},
+		Limits{MemoryMB: 2, CPU: 1500 * time.Millisecond}.wrap([]string{"ls", "-la"}))
+}
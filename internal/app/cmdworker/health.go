@@ -0,0 +1,61 @@
+package cmdworker
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	tapi "github.com/airenas/listgo/internal/pkg/tasks/api"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// healthResult is the response of the health endpoint
+type healthResult struct {
+	Name     string            `json:"name"`
+	Ready    bool              `json:"ready"`
+	Working  bool              `json:"working"`
+	Draining bool              `json:"draining"`
+	Preload  []tapi.TaskHealth `json:"preload,omitempty"`
+}
+
+func startHealthServer(data *ServiceData, port int) error {
+	portStr := strconv.Itoa(port)
+	cmdapp.Log.Infof("Starting health HTTP service at %s", portStr)
+	err := http.ListenAndServe(":"+portStr, newHealthRouter(data))
+	return errors.Wrap(err, "Can't start health HTTP listener at port "+portStr)
+}
+
+func newHealthRouter(data *ServiceData) *mux.Router {
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/live").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Methods("GET").Path("/health").Handler(healthHandler{data: data})
+	return router
+}
+
+type healthHandler struct {
+	data *ServiceData
+}
+
+// ServeHTTP returns 503 if the worker is draining or any loaded preload task is not ready
+func (h healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res := healthResult{Name: h.data.Name, Working: atomic.LoadInt32(&h.data.working) == 1,
+		Draining: atomic.LoadInt32(&h.data.draining) == 1}
+	res.Preload = h.data.PreloadManager.Health()
+	res.Ready = !res.Draining
+	for _, p := range res.Preload {
+		res.Ready = res.Ready && p.Ready
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !res.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(&res)
+	if err != nil {
+		cmdapp.Log.Error(errors.Wrap(err, "Can't write health response"))
+	}
+}
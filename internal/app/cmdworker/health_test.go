@@ -0,0 +1,61 @@
+package cmdworker
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	tapi "github.com/airenas/listgo/internal/pkg/tasks/api"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHealth(t *testing.T, data *ServiceData, path string) (int, *healthResult) {
+	req := httptest.NewRequest("GET", path, nil)
+	resp := httptest.NewRecorder()
+	newHealthRouter(data).ServeHTTP(resp, req)
+	if path != "/health" {
+		return resp.Code, nil
+	}
+	var res healthResult
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &res))
+	return resp.Code, &res
+}
+
+func TestHealth_Live(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	code, _ := testHealth(t, &data, "/live")
+	assert.Equal(t, 200, code)
+}
+
+func TestHealth(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	pegomock.When(preloadTaskManagerMock.Health()).ThenReturn([]tapi.TaskHealth{{Key: "m1", Running: true, Ready: true}})
+	code, res := testHealth(t, &data, "/health")
+	assert.Equal(t, 200, code)
+	assert.True(t, res.Ready)
+	assert.Equal(t, "olia", res.Name)
+	assert.Equal(t, []tapi.TaskHealth{{Key: "m1", Running: true, Ready: true}}, res.Preload)
+}
+
+func TestHealth_NotReady(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	pegomock.When(preloadTaskManagerMock.Health()).ThenReturn([]tapi.TaskHealth{{Key: "m1", Running: true, Ready: true},
+		{Key: "m2", Running: true, Error: "olia"}})
+	code, res := testHealth(t, &data, "/health")
+	assert.Equal(t, 503, code)
+	assert.False(t, res.Ready)
+	assert.Equal(t, "olia", res.Preload[1].Error)
+}
+
+func TestHealth_Draining(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.draining = 1
+	code, res := testHealth(t, &data, "/health")
+	assert.Equal(t, 503, code)
+	assert.True(t, res.Draining)
+}
@@ -0,0 +1,78 @@
+package cmdworker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// LogSaver saves the log file to the shared storage
+type LogSaver interface {
+	Save(name string, reader io.Reader) error
+}
+
+// LogShipping keeps settings for shipping the command logs to the shared storage
+type LogShipping struct {
+	Saver LogSaver
+	// Step is the name of the step, the log is saved as {ID}/{Step}.log
+	Step string
+	// MaxSize of the saved log in bytes, only the tail of the log is kept
+	MaxSize int
+}
+
+// tailBuffer keeps the last max bytes written into it
+type tailBuffer struct {
+	max     int
+	buf     []byte
+	dropped int64
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if l := len(b.buf); l > b.max {
+		b.dropped += int64(l - b.max)
+		b.buf = append(b.buf[:0], b.buf[l-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the kept tail with the note about the truncated head
+func (b *tailBuffer) Bytes() []byte {
+	if b.dropped == 0 {
+		return b.buf
+	}
+	var res bytes.Buffer
+	fmt.Fprintf(&res, "===== Log truncated, skipped first %d bytes =====\n", b.dropped)
+	res.Write(b.buf)
+	return res.Bytes()
+}
+
+// logName returns the name of the log in the storage, the chunk index is added to the step for chunk tasks
+func logName(step string, msg *messages.QueueMessage) string {
+	if ci, ok := messages.GetTag(msg.Tags, messages.TagChunkIndex); ok {
+		step = fmt.Sprintf("%s_chunk_%s", step, ci)
+	}
+	return msg.ID + "/" + step + ".log"
+}
+
+// shipLog saves the log, the failure is only logged as it must not fail the task
+func shipLog(cfg *LogShipping, msg *messages.QueueMessage, b *tailBuffer) {
+	name := logName(cfg.Step, msg)
+	if strings.Contains(name, "..") {
+		cmdapp.Log.Warnf("Wrong log name %s", name)
+		return
+	}
+	err := cfg.Saver.Save(name, bytes.NewReader(b.Bytes()))
+	if err != nil {
+		cmdapp.Log.Warn(errors.Wrapf(err, "Can't ship log %s", name))
+	}
+}
@@ -0,0 +1,88 @@
+package cmdworker
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailBuffer(t *testing.T) {
+	b := newTailBuffer(5)
+	b.Write([]byte("olia"))
+	assert.Equal(t, "olia", string(b.Bytes()))
+	b.Write([]byte("12"))
+	b.Write([]byte("345"))
+	assert.Equal(t, "===== Log truncated, skipped first 4 bytes =====\n12345", string(b.Bytes()))
+}
+
+func TestLogName(t *testing.T) {
+	assert.Equal(t, "1/Diarization.log", logName("Diarization", messages.NewQueueMessage("1", "", nil)))
+	assert.Equal(t, "1/Transcription_chunk_2.log", logName("Transcription",
+		messages.NewQueueMessage("1", "", []messages.Tag{messages.NewTag(messages.TagChunkIndex, "2")})))
+}
+
+func newTestLogSaver(t *testing.T) (*mocks.MockFileSaver, *string) {
+	mocks.AttachMockToTest(t)
+	res := mocks.NewMockFileSaver()
+	var saved string
+	pegomock.When(res.Save(pegomock.AnyString(), matchers.AnyIoReader())).Then(
+		func(params []pegomock.Param) pegomock.ReturnValues {
+			b, _ := ioutil.ReadAll(params[1].(io.Reader))
+			saved = string(b)
+			return []pegomock.ReturnValue{nil}
+		})
+	return res, &saved
+}
+
+func TestWork_ShipsLog(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	saver, saved := newTestLogSaver(t)
+	data.LogShipping = &LogShipping{Saver: saver, Step: "Diarization", MaxSize: 1000}
+	data.Command = "sh -c 'echo olia'"
+	_, err := work(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.Nil(t, err)
+	name, _ := saver.VerifyWasCalledOnce().Save(pegomock.AnyString(), matchers.AnyIoReader()).GetCapturedArguments()
+	assert.Equal(t, "1/Diarization.log", name)
+	assert.Contains(t, *saved, "olia")
+}
+
+func TestWork_ShipsLogOnFailure(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	saver, saved := newTestLogSaver(t)
+	data.LogShipping = &LogShipping{Saver: saver, Step: "Diarization", MaxSize: 1000}
+	data.Command = "sh -c 'echo olia; exit 1'"
+	_, err := work(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.NotNil(t, err)
+	saver.VerifyWasCalledOnce().Save(pegomock.AnyString(), matchers.AnyIoReader())
+	assert.Contains(t, *saved, "olia")
+}
+
+func TestWork_ShipLogFails(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	saver := mocks.NewMockFileSaver()
+	pegomock.When(saver.Save(pegomock.AnyString(), matchers.AnyIoReader())).ThenReturn(errors.New("olia"))
+	data.LogShipping = &LogShipping{Saver: saver, Step: "Diarization", MaxSize: 1000}
+	_, err := work(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.Nil(t, err)
+}
+
+func TestStartWorkerService_WrongLogShipping(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.LogShipping = &LogShipping{Step: "Diarization", MaxSize: 1000}
+	assert.NotNil(t, StartWorkerService(&data))
+	data.LogShipping = &LogShipping{Saver: mocks.NewMockFileSaver(), MaxSize: 1000}
+	assert.NotNil(t, StartWorkerService(&data))
+	data.LogShipping = &LogShipping{Saver: mocks.NewMockFileSaver(), Step: "Diarization"}
+	assert.NotNil(t, StartWorkerService(&data))
+}
@@ -2,13 +2,21 @@ package cmdworker
 
 import (
 	"io"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/airenas/listgo/internal/pkg/config"
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/rabbit"
+	"github.com/airenas/listgo/internal/pkg/status"
+	"github.com/airenas/listgo/internal/pkg/step"
+	"github.com/airenas/listgo/internal/pkg/storage"
 	"github.com/airenas/listgo/internal/pkg/tasks"
+	tapi "github.com/airenas/listgo/internal/pkg/tasks/api"
 	"github.com/airenas/listgo/internal/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -40,7 +48,9 @@ func run(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 	data := ServiceData{}
-	data.quitChannel = utils.NewSignalChannel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	data.quitChannel = utils.NewMultiCloseChannel()
 
 	data.RecInfoLoader, err = config.NewFileRecognizerInfoLoader(cmdapp.Config.GetString("recognizerConfig.path"))
 	cmdapp.CheckOrPanic(err, "Can't init recognizer info loader config (Did you provide correct setting 'recognizerConfig.path'?)")
@@ -52,36 +62,93 @@ func run(cmd *cobra.Command, args []string) {
 	rabbitSender := rabbit.NewSender(msgChannelProvider)
 	data.MessageSender = rabbitSender
 	queueName := ""
-	data.WorkCh, queueName, err = initWorkQueue(msgChannelProvider)
+	data.WorkCh, queueName, data.stopConsume, err = initWorkQueue(msgChannelProvider)
 	cmdapp.CheckOrPanic(err, "Can't connect/prepare work queue")
 
 	data.Name = cmdapp.Config.GetString("worker.name")
+	if sn := cmdapp.Config.GetString("worker.step"); sn != "" {
+		cmdapp.Log.Infof("In-process step: %s", sn)
+		data.Step, err = step.New(sn)
+		cmdapp.CheckOrPanic(err, "Can't init step")
+	}
 	data.Command = cmdapp.Config.GetString("worker.command")
 	data.WorkingDir = cmdapp.Config.GetString("worker.workingDir")
 	data.ResultFile = cmdapp.Config.GetString("worker.resultFile")
 	data.LogFile = cmdapp.Config.GetString("worker.logFile")
 	data.ReadFunc = ReadFile
+	data.ResultRefRoot = cmdapp.Config.GetString("worker.resultRef.root")
+	if data.ResultRefRoot != "" {
+		cmdapp.Log.Infof("Passing results by reference to: %s", data.ResultRefRoot)
+	}
+	data.Limits = Limits{MemoryMB: cmdapp.Config.GetInt64("worker.limits.memoryMB"),
+		CPU: cmdapp.Config.GetDuration("worker.limits.cpu"), Cgroup: cmdapp.Config.GetString("worker.limits.cgroup")}
+	data.timeouts, err = newTimeoutCalc(cmdapp.Config.GetDuration("worker.timeout.base"),
+		cmdapp.Config.GetFloat64("worker.timeout.rtFactor"), cmdapp.Config.GetDuration("worker.timeout.max"))
+	cmdapp.CheckOrPanic(err, "Can't init timeout calculator")
+	data.Progress, err = initProgress(rabbitSender)
+	cmdapp.CheckOrPanic(err, "Can't init progress reporting")
+	data.LogShipping, err = initLogShipping()
+	cmdapp.CheckOrPanic(err, "Can't init log shipping")
+	if files := cmdapp.Config.GetStringSlice("worker.chunk.files"); len(files) > 0 {
+		cmdapp.Log.Infof("Chunk join files: %v", files)
+		data.ChunkJoin = &ChunkJoin{Files: files}
+	} else if cmdapp.Config.GetBool("chunk.enabled") {
+		cmdapp.CheckOrPanic(errors.New("No worker.chunk.files"), "Chunking is enabled, but the chunks can't be joined")
+	}
+
+	// init zombies reaper
+	data.reapLock = &sync.RWMutex{}
+	go reapChildren(data.reapLock)
 
-	data.PreloadManager, err = initPreloadManager()
+	data.PreloadManager, err = initPreloadManager(data.reapLock)
 	cmdapp.CheckOrPanic(err, "Can't init preload task manager")
 	defer data.PreloadManager.Close()
 
-	registrator, err := initRegistrator(rabbitSender, queueName, data.quitChannel)
+	registrator, err := initRegistrator(rabbitSender, queueName, data.quitChannel, data.PreloadManager.Warm)
 	cmdapp.CheckOrPanic(err, "Can't start registrator")
 	defer registrator.Close()
 	data.skipAck = isRegistrator()
 
-	// init zombies reaper
-	data.reapLock = &sync.RWMutex{}
-	go reapChildren(data.reapLock)
-
 	err = StartWorkerService(&data)
 	cmdapp.CheckOrPanic(err, "Can't start service")
 
-	<-data.quitChannel.C
+	if port := cmdapp.Config.GetInt("worker.health.port"); port > 0 {
+		go func() {
+			cmdapp.LogIf(startHealthServer(&data, port))
+		}()
+	}
+
+	waitForExit(&data, registrator, sigCh)
 	cmdapp.Log.Infof("Exiting service")
 }
 
+// waitForExit waits for the service to stop. On SIGTERM it drains the worker:
+// informs the dispatcher and waits for the current task to finish.
+// SIGINT exits at once without the drain, as it did before the drain was added
+func waitForExit(data *ServiceData, reg workerRegistrator, sigCh <-chan os.Signal) {
+	select {
+	case <-data.quitChannel.C:
+		return
+	case s := <-sigCh:
+		if s != syscall.SIGTERM {
+			return
+		}
+	}
+	cmdapp.Log.Info("Got SIGTERM. Draining worker")
+	cmdapp.LogIf(reg.Drain())
+	go func() {
+		if !drain(data, cmdapp.Config.GetDuration("worker.drainTimeout")) {
+			cmdapp.Log.Warn("Drain timeout. Exiting with unfinished task")
+		}
+		data.quitChannel.Close()
+	}()
+	select {
+	case <-data.quitChannel.C:
+	case <-sigCh:
+		cmdapp.Log.Warn("Got second signal. Exiting without waiting for the task")
+	}
+}
+
 // /////////////////////////////////////////////////////////////////////////////////
 func validateConfig() error {
 	if cmdapp.Config.GetString("worker.name") == "" {
@@ -93,21 +160,82 @@ func validateConfig() error {
 	if cmdapp.Config.GetString("worker.queue") != "" && cmdapp.Config.GetString("registry.queue") != "" {
 		cmdapp.Log.Warn("worker.queue config will be ignored because of registry.queue")
 	}
-	if cmdapp.Config.GetString("worker.command") == "" {
-		return errors.New("No worker.command configured")
+	if cmdapp.Config.GetString("worker.command") == "" && cmdapp.Config.GetString("worker.step") == "" {
+		return errors.New("No worker.command or worker.step configured")
+	}
+	if cmdapp.Config.GetString("worker.command") != "" && cmdapp.Config.GetString("worker.step") != "" {
+		cmdapp.Log.Warn("worker.command config will be ignored because of worker.step")
 	}
 	return nil
 }
 
+// /////////////////////////////////////////////////////////////////////////////////
+func initProgress(sender messages.Sender) (*ProgressConfig, error) {
+	if !cmdapp.Config.GetBool("worker.progress.enabled") {
+		return nil, nil
+	}
+	res := &ProgressConfig{Sender: sender, Step: cmdapp.Config.GetString("worker.progress.step"),
+		Interval: cmdapp.Config.GetDuration("worker.progress.interval"),
+		File:     cmdapp.Config.GetString("worker.progress.file")}
+	if res.Step == "" {
+		res.Step = cmdapp.Config.GetString("worker.queue")
+	}
+	if status.From(res.Step) == 0 {
+		return nil, errors.Errorf("Wrong worker.progress.step '%s'", res.Step)
+	}
+	if res.Interval <= 0 {
+		res.Interval = 5 * time.Second
+	}
+	cmdapp.Log.Infof("Progress reporting: step %s, interval %v, file '%s'", res.Step, res.Interval, res.File)
+	return res, nil
+}
+
+func initLogShipping() (*LogShipping, error) {
+	dir := cmdapp.Config.GetString("worker.logs.storage")
+	if dir == "" {
+		return nil, nil
+	}
+	st, err := storage.New(dir)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := storage.NewSaver(st)
+	if err != nil {
+		return nil, err
+	}
+	res := &LogShipping{Saver: fs, Step: cmdapp.Config.GetString("worker.logs.step"),
+		MaxSize: cmdapp.Config.GetInt("worker.logs.maxSizeKB") * 1024}
+	if res.Step == "" {
+		res.Step = cmdapp.Config.GetString("worker.name")
+	}
+	if res.MaxSize <= 0 {
+		res.MaxSize = 1024 * 1024
+	}
+	cmdapp.Log.Infof("Log shipping: step %s, max size %d b", res.Step, res.MaxSize)
+	return res, nil
+}
+
 // /////////////////////////////////////////////////////////////////////////////////
 // init prepload task manager
 // /////////////////////////////////////////////////////////////////////////////////
-func initPreloadManager() (PreloadTaskManager, error) {
+func initPreloadManager(reapLock *sync.RWMutex) (PreloadTaskManager, error) {
 	kp := cmdapp.Config.GetString("worker.preloadKeyPrefix")
 	if kp == "" {
 		return &fakePreloadManager{}, nil
 	}
-	return tasks.NewManager(kp, cmdapp.Config.GetString("worker.workingDir"))
+	max := cmdapp.Config.GetInt("worker.preload.max")
+	if max < 1 {
+		max = 1
+	}
+	res, err := tasks.NewManagerWithLimits(kp, cmdapp.Config.GetString("worker.workingDir"), max,
+		cmdapp.Config.GetInt64("worker.preload.memoryMB"), reapLock)
+	if err != nil {
+		return nil, err
+	}
+	if ci := cmdapp.Config.GetDuration("worker.preload.checkInterval"); ci > 0 {
+		go res.StartChecks(ci)
+	}
+	return res, nil
 }
 
 type fakePreloadManager struct{}
@@ -116,6 +244,14 @@ func (pm *fakePreloadManager) EnsureRunning(map[string]string) error {
 	return nil
 }
 
+func (pm *fakePreloadManager) Warm() []string {
+	return nil
+}
+
+func (pm *fakePreloadManager) Health() []tapi.TaskHealth {
+	return nil
+}
+
 func (pm *fakePreloadManager) Close() error {
 	return nil
 }
@@ -145,30 +281,34 @@ func getPrivateQueue(ch *amqp.Channel) (<-chan amqp.Delivery, string, error) {
 }
 
 // /////////////////////////////////////////////////////////////////////////
-func initWorkQueue(msgChannelProvider *rabbit.ChannelProvider) (<-chan amqp.Delivery, string, error) {
+const workConsumer = "worker"
+
+// initWorkQueue returns the work channel, the queue name and the func to stop consuming the static queue
+func initWorkQueue(msgChannelProvider *rabbit.ChannelProvider) (<-chan amqp.Delivery, string, func() error, error) {
 	ch, err := msgChannelProvider.Channel()
 	if err != nil {
-		return nil, "", errors.Wrap(err, "Can't open channel")
+		return nil, "", nil, errors.Wrap(err, "Can't open channel")
 	}
 	err = ch.Qos(1, 0, false)
 	if err != nil {
-		return nil, "", errors.Wrap(err, "Can't set Qos")
+		return nil, "", nil, errors.Wrap(err, "Can't set Qos")
 	}
 	if !isRegistrator() {
 		queue := cmdapp.Config.GetString("worker.queue")
 		cmdapp.Log.Infof("Try listen static queue %s", queue)
 		if queue == "" {
-			return nil, "", errors.Errorf("No worker.queue configured!")
+			return nil, "", nil, errors.Errorf("No worker.queue configured!")
 		}
-		res, err := rabbit.NewChannel(ch, queue)
+		res, err := rabbit.NewConsumerChannel(ch, queue, workConsumer)
 		if err != nil {
-			return nil, "", errors.Wrap(err, "Can't listen "+queue+" channel")
+			return nil, "", nil, errors.Wrap(err, "Can't listen "+queue+" channel")
 		}
-		return res, queue, nil
+		return res, queue, func() error { return ch.Cancel(workConsumer, false) }, nil
 	}
 
 	cmdapp.Log.Infof("Creating private worker queue")
-	return getPrivateQueue(ch)
+	res, queue, err := getPrivateQueue(ch)
+	return res, queue, nil, err
 }
 
 func isRegistrator() bool {
@@ -176,12 +316,19 @@ func isRegistrator() bool {
 }
 
 // /////////////////////////////////////////////////////////////////////////
-func initRegistrator(sender messages.Sender, qName string, closeChan *utils.MultiCloseChannel) (io.Closer, error) {
+type workerRegistrator interface {
+	io.Closer
+	Drain() error
+}
+
+func initRegistrator(sender messages.Sender, qName string, closeChan *utils.MultiCloseChannel,
+	warm func() []string) (workerRegistrator, error) {
 	if isRegistrator() {
 		reg, err := newQueueRegistrator(sender, qName, closeChan)
 		if err != nil {
 			return nil, errors.Wrap(err, "Can't init registrator")
 		}
+		reg.warm = warm
 		go reg.live()
 		return reg, nil
 	}
@@ -194,4 +341,8 @@ func (fc *fakeCloser) Close() error {
 	return nil
 }
 
+func (fc *fakeCloser) Drain() error {
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,146 @@
+package cmdworker
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// ProgressConfig keeps settings for the live progress reporting
+type ProgressConfig struct {
+	Sender messages.Sender
+	// Step is the status name of the step, it is sent to the manager
+	Step string
+	// Interval is a min time between two progress messages
+	Interval time.Duration
+	// File if not empty is a template of the file the command writes progress into
+	File string
+}
+
+var progressRegexp = regexp.MustCompile(`^\s*PROGRESS:\s*(\d{1,3})\s*%?\s*$`)
+
+// parseProgress extracts the value from the line 'PROGRESS: 42'
+func parseProgress(line string) (int32, bool) {
+	m := progressRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.Atoi(m[1])
+	if err != nil || v > 100 {
+		return 0, false
+	}
+	return int32(v), true
+}
+
+// lastProgress returns the last progress value in the data
+func lastProgress(data []byte) (int32, bool) {
+	var res int32
+	found := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if v, ok := parseProgress(sc.Text()); ok {
+			res, found = v, true
+		}
+	}
+	return res, found
+}
+
+// progressReporter sends throttled progress messages for one task
+type progressReporter struct {
+	cfg  *ProgressConfig
+	msg  *messages.QueueMessage
+	lock sync.Mutex
+	last int32
+	at   time.Time
+	now  func() time.Time
+}
+
+func newProgressReporter(cfg *ProgressConfig, msg *messages.QueueMessage) *progressReporter {
+	return &progressReporter{cfg: cfg, msg: msg, last: -1, now: time.Now}
+}
+
+// reset sends 0 at the start of the task, so the progress saved by the previous attempt of the retried,
+// requeued or restarted step is not shown
+func (r *progressReporter) reset() {
+	r.report(0)
+}
+
+// report sends the value if it differs from the last one and the interval has passed.
+// Final value 100 is sent without waiting for the interval
+func (r *progressReporter) report(p int32) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if p <= r.last {
+		return
+	}
+	now := r.now()
+	if p < 100 && now.Sub(r.at) < r.cfg.Interval {
+		return
+	}
+	r.last, r.at = p, now
+	cmdapp.Log.Debugf("Progress %s: %d%%", r.msg.ID, p)
+	err := r.cfg.Sender.Send(&messages.ProgressMessage{QueueMessage: *messages.NewQueueMessageFromM(r.msg),
+		Step: r.cfg.Step, Progress: p}, messages.Progress, "")
+	if err != nil {
+		cmdapp.Log.Warn(errors.Wrap(err, "Can't send progress"))
+	}
+}
+
+// progressWriter scans the command output for progress lines
+type progressWriter struct {
+	r   *progressReporter
+	buf []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if v, ok := parseProgress(string(w.buf[:i])); ok {
+			w.r.report(v)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > 1024 { // no progress in such long lines
+		w.buf = w.buf[:0]
+	}
+	return len(p), nil
+}
+
+// watchProgressFile polls the file until the stop channel is closed
+func watchProgressFile(file string, r *progressReporter, interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					cmdapp.Log.Warn(errors.Wrapf(err, "Can't read progress file %s", file))
+				}
+				continue
+			}
+			if v, ok := lastProgress(data); ok {
+				r.report(v)
+			}
+		}
+	}
+}
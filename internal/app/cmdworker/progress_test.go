@@ -0,0 +1,161 @@
+package cmdworker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/step"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want int32
+		ok   bool
+	}{
+		{line: "PROGRESS: 42", want: 42, ok: true},
+		{line: "  PROGRESS:7 ", want: 7, ok: true},
+		{line: "PROGRESS: 100%", want: 100, ok: true},
+		{line: "PROGRESS: 0", want: 0, ok: true},
+		{line: "PROGRESS: 101", ok: false},
+		{line: "PROGRESS: -1", ok: false},
+		{line: "PROGRESS:", ok: false},
+		{line: "olia PROGRESS: 10", ok: false},
+		{line: "progress: 10", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseProgress(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLastProgress(t *testing.T) {
+	v, ok := lastProgress([]byte("PROGRESS: 10\nolia\nPROGRESS: 20\nPROGRESS: x\n"))
+	assert.True(t, ok)
+	assert.Equal(t, int32(20), v)
+	_, ok = lastProgress([]byte("olia\n"))
+	assert.False(t, ok)
+}
+
+func newTestReporter(t *testing.T, interval time.Duration) (*progressReporter, *mocks.MockSender) {
+	mocks.AttachMockToTest(t)
+	sender := mocks.NewMockSender()
+	r := newProgressReporter(&ProgressConfig{Sender: sender, Step: "Transcription", Interval: interval},
+		messages.NewQueueMessage("1", "rec", []messages.Tag{messages.NewTag("k", "v")}))
+	return r, sender
+}
+
+func capturedProgress(sender *mocks.MockSender, times int) []int32 {
+	msgs, _, _ := sender.VerifyWasCalled(pegomock.Times(times)).Send(matchers.AnyMessagesMessage(),
+		pegomock.EqString(messages.Progress), pegomock.AnyString()).GetAllCapturedArguments()
+	res := make([]int32, len(msgs))
+	for i, m := range msgs {
+		res[i] = m.(*messages.ProgressMessage).Progress
+	}
+	return res
+}
+
+func TestProgressReporter(t *testing.T) {
+	r, sender := newTestReporter(t, 0)
+	r.report(10)
+	r.report(10)
+	r.report(5)
+	r.report(20)
+	assert.Equal(t, []int32{10, 20}, capturedProgress(sender, 2))
+	msgs, _, _ := sender.VerifyWasCalled(pegomock.Times(2)).Send(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString()).GetAllCapturedArguments()
+	pm := msgs[0].(*messages.ProgressMessage)
+	assert.Equal(t, "1", pm.ID)
+	assert.Equal(t, "Transcription", pm.Step)
+	assert.Equal(t, []messages.Tag{messages.NewTag("k", "v")}, pm.Tags)
+}
+
+func TestProgressReporter_Throttles(t *testing.T) {
+	r, sender := newTestReporter(t, time.Minute)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.report(10)
+	r.report(20)
+	now = now.Add(30 * time.Second)
+	r.report(30)
+	r.report(100)
+	now = now.Add(time.Minute)
+	r.report(100)
+	assert.Equal(t, []int32{10, 100}, capturedProgress(sender, 2))
+}
+
+func TestProgressWriter(t *testing.T) {
+	r, sender := newTestReporter(t, 0)
+	w := &progressWriter{r: r}
+	w.Write([]byte("olia\nPROGR"))
+	w.Write([]byte("ESS: 10\nPROGRESS: 20"))
+	assert.Equal(t, []int32{10}, capturedProgress(sender, 1))
+	w.Write([]byte("\n"))
+	assert.Equal(t, []int32{10, 20}, capturedProgress(sender, 2))
+}
+
+func TestWatchProgressFile(t *testing.T) {
+	r, sender := newTestReporter(t, 0)
+	dir, err := ioutil.TempDir("", "progress")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "p.txt")
+	assert.Nil(t, ioutil.WriteFile(f, []byte("PROGRESS: 10\nPROGRESS: 30\n"), 0644))
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watchProgressFile(f, r, 5*time.Millisecond, stop)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	<-done
+	assert.Equal(t, []int32{30}, capturedProgress(sender, 1))
+}
+
+func TestWork_SendsProgress(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	sender := mocks.NewMockSender()
+	data.Progress = &ProgressConfig{Sender: sender, Step: "Diarization"}
+	data.Command = "sh -c 'echo PROGRESS: 42'"
+	_, err := work(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{0, 42}, capturedProgress(sender, 2))
+}
+
+type progressStep struct{}
+
+func (s *progressStep) Process(ctx context.Context, msg *messages.QueueMessage) (*messages.ResultMessage, error) {
+	step.ReportProgress(ctx, 50)
+	return nil, nil
+}
+
+func TestStep_SendsProgress(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	sender := mocks.NewMockSender()
+	data.Progress = &ProgressConfig{Sender: sender, Step: "Diarization"}
+	data.Step = &progressStep{}
+	processStep(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.Equal(t, []int32{0, 50}, capturedProgress(sender, 2))
+}
+
+func TestStartWorkerService_NoProgressSender(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.Progress = &ProgressConfig{}
+	assert.NotNil(t, StartWorkerService(&data))
+}
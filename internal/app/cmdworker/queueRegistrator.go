@@ -17,7 +17,10 @@ type queueRegistrator struct {
 	count         int
 	failureCount  int
 	close         bool
+	draining      bool
 	closeChan     *utils.MultiCloseChannel
+	// warm returns the loaded model types, reported to the dispatcher
+	warm func() []string
 }
 
 func newQueueRegistrator(sender messages.Sender, qName string, closeChan *utils.MultiCloseChannel) (*queueRegistrator, error) {
@@ -81,6 +84,10 @@ func (qr *queueRegistrator) sendMsg(mt string) error {
 	msg.Queue = qr.ownQueue
 	msg.Type = mt
 	msg.Timestamp = time.Now().Unix()
+	if qr.warm != nil {
+		msg.ModelTypes = qr.warm()
+	}
+	msg.Draining = qr.draining
 	return qr.sender.Send(msg, qr.registryQueue, "")
 }
 
@@ -88,3 +95,9 @@ func (qr *queueRegistrator) Close() error {
 	qr.close = true
 	return qr.sendMsg(messages.RgrTypeExit)
 }
+
+// Drain informs the dispatcher not to send new tasks to the worker
+func (qr *queueRegistrator) Drain() error {
+	qr.draining = true
+	return qr.sendMsg(messages.RgrTypeDrain)
+}
@@ -0,0 +1,52 @@
+package cmdworker
+
+import (
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrator_SendsWarmModels(t *testing.T) {
+	mocks.AttachMockToTest(t)
+	sender := mocks.NewMockSender()
+	qr := &queueRegistrator{sender: sender, registryQueue: "reg", ownQueue: "own",
+		warm: func() []string { return []string{"m1", "m2"} }}
+	assert.Nil(t, qr.heartbeat())
+	msg, q, _ := sender.VerifyWasCalledOnce().Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, "reg", q)
+	rm := msg.(messages.RegistrationMessage)
+	assert.Equal(t, messages.RgrTypeRegister, rm.Type)
+	assert.Equal(t, "own", rm.Queue)
+	assert.Equal(t, []string{"m1", "m2"}, rm.ModelTypes)
+}
+
+func TestRegistrator_NoWarm(t *testing.T) {
+	mocks.AttachMockToTest(t)
+	sender := mocks.NewMockSender()
+	qr := &queueRegistrator{sender: sender, registryQueue: "reg", ownQueue: "own"}
+	assert.Nil(t, qr.Drain())
+	msg, _, _ := sender.VerifyWasCalledOnce().Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetCapturedArguments()
+	assert.Nil(t, msg.(messages.RegistrationMessage).ModelTypes)
+}
+
+func TestRegistrator_BeatsAfterDrain(t *testing.T) {
+	mocks.AttachMockToTest(t)
+	sender := mocks.NewMockSender()
+	qr := &queueRegistrator{sender: sender, registryQueue: "reg", ownQueue: "own", count: 1}
+	assert.Nil(t, qr.heartbeat())
+	assert.Nil(t, qr.Drain())
+	assert.Nil(t, qr.heartbeat())
+	msgs, _, _ := sender.VerifyWasCalled(pegomock.Times(3)).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetAllCapturedArguments()
+	assert.False(t, msgs[0].(messages.RegistrationMessage).Draining)
+	assert.Equal(t, messages.RgrTypeDrain, msgs[1].(messages.RegistrationMessage).Type)
+	assert.True(t, msgs[1].(messages.RegistrationMessage).Draining)
+	assert.Equal(t, messages.RgrTypeBeat, msgs[2].(messages.RegistrationMessage).Type)
+	assert.True(t, msgs[2].(messages.RegistrationMessage).Draining)
+}
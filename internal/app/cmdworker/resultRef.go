@@ -0,0 +1,43 @@
+package cmdworker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// newResultRef prepares the reference to the result file in the shared storage.
+// The file must be inside the root dir, the path in the reference is relative to the root
+func newResultRef(root, file string) (*messages.ResultRef, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't resolve %s", file)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't resolve %s", root)
+	}
+	rel, err := filepath.Rel(absRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.Errorf("Result file %s is not in the shared storage %s", file, root)
+	}
+	cmdapp.Log.Infof("Preparing result reference: %s", abs)
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't open file "+abs)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't read file "+abs)
+	}
+	return &messages.ResultRef{Path: filepath.ToSlash(rel), Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
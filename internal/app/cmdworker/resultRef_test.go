@@ -0,0 +1,76 @@
+package cmdworker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResultRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resultRef")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "res", "1"), 0755))
+	f := filepath.Join(dir, "res", "1", "r.txt")
+	assert.Nil(t, ioutil.WriteFile(f, []byte("olia"), 0644))
+
+	ref, err := newResultRef(dir, f)
+	assert.Nil(t, err)
+	assert.Equal(t, "res/1/r.txt", ref.Path)
+	assert.Equal(t, int64(4), ref.Size)
+	assert.Equal(t, "d57329cf35760377655bf8417b666cd1b4028878276d8684b9f571746e908996", ref.SHA256)
+}
+
+func TestNewResultRef_Fails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resultRef")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	_, err = newResultRef(filepath.Join(dir, "a"), filepath.Join(dir, "b.txt"))
+	assert.NotNil(t, err)
+	_, err = newResultRef(dir, filepath.Join(dir, "a", "..", "..", "b.txt"))
+	assert.NotNil(t, err)
+	_, err = newResultRef(dir, filepath.Join(dir, "missing.txt"))
+	assert.NotNil(t, err)
+}
+
+func TestHandlesResultRef(t *testing.T) {
+	initTest(t)
+	dir, err := ioutil.TempDir("", "resultRef")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "1.txt"), []byte("olia"), 0644))
+
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.ReadFunc = func(file string) (string, error) {
+		t.Error("must not read the result")
+		return "", nil
+	}
+	data.ResultFile = filepath.Join(dir, "{ID}.txt")
+	data.ResultRefRoot = dir
+	StartWorkerService(&data)
+	message.ReplyTo = "rt"
+
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	cMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	rm := cMsg.(*messages.ResultMessage)
+	assert.Empty(t, rm.Error)
+	assert.Empty(t, rm.Result)
+	if assert.NotNil(t, rm.Ref) {
+		assert.Equal(t, "1.txt", rm.Ref.Path)
+		assert.Equal(t, int64(4), rm.Ref.Size)
+	}
+	b, _ := json.Marshal(rm)
+	assert.Contains(t, string(b), `"ref":{"path":"1.txt"`)
+}
@@ -1,22 +1,30 @@
 package cmdworker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/cmdtemplate"
+	errc "github.com/airenas/listgo/internal/pkg/err"
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/recognizer"
+	"github.com/airenas/listgo/internal/pkg/step"
+	tapi "github.com/airenas/listgo/internal/pkg/tasks/api"
 	"github.com/airenas/listgo/internal/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
 )
 
-type readFunc func(file string, id string) (string, error)
+type readFunc func(file string) (string, error)
 
 // RecInfoLoader loads recognizer information
 type RecInfoLoader interface {
@@ -26,29 +34,53 @@ type RecInfoLoader interface {
 // PreloadTaskManager manages long running process, loaded by key before processing task
 type PreloadTaskManager interface {
 	EnsureRunning(map[string]string) error
+	// Warm returns keys of the loaded tasks
+	Warm() []string
+	// Health returns the state of the loaded tasks
+	Health() []tapi.TaskHealth
 	Close() error
 }
 
 // ServiceData keeps data required for service work
 type ServiceData struct {
-	Name       string
+	Name string
+	//Step if set then the message is processed in-process by the step, Command is not used
+	Step       step.Step
 	Command    string
 	WorkingDir string
 	//ResultFile if non empty then tries to pass result to reply message from the file
-	// changes {ID} in the file with message id
+	// it is a template, see cmdtemplate.Template for the placeholders.
+	// For the Step it only marks that the reply carries the step result
 	ResultFile string
-	//File to log into the cmd output
+	//ResultRefRoot if set then the result file is passed as a reference to the shared storage at this dir,
+	// the result is not inlined into the reply message
+	ResultRefRoot string
+	//File to log into the cmd output, it is a template
 	LogFile        string
 	ReadFunc       readFunc
 	RecInfoLoader  RecInfoLoader
 	PreloadManager PreloadTaskManager
+	//Limits for the command, Timeout is calculated for each task
+	Limits   Limits
+	timeouts *timeoutCalc
+	//Progress if set then the live progress of the task is sent to the manager
+	Progress *ProgressConfig
+	//LogShipping if set then the command log of each task is saved to the shared storage
+	LogShipping *LogShipping
+	//ChunkJoin if set then the chunk_join messages join the outputs of the chunked transcription
+	ChunkJoin *ChunkJoin
 
 	MessageSender messages.SenderWithCorr
 	WorkCh        <-chan amqp.Delivery
 	reapLock      *sync.RWMutex
+	// stopConsume cancels the consumer of the shared work queue, so the drained worker gets no new messages
+	stopConsume func() error
 
 	skipAck     bool
 	quitChannel *utils.MultiCloseChannel
+
+	working  int32
+	draining int32
 }
 
 // StartWorkerService starts the event queue listener service to listen for configured events
@@ -63,10 +95,10 @@ func StartWorkerService(data *ServiceData) error {
 	if data.Name == "" {
 		return errors.New("No Name")
 	}
-	if data.Command == "" {
+	if data.Command == "" && data.Step == nil {
 		return errors.New("No command")
 	}
-	if data.ResultFile != "" && data.ReadFunc == nil {
+	if data.ResultFile != "" && data.ReadFunc == nil && data.Step == nil {
 		return errors.New("No command")
 	}
 	if data.RecInfoLoader == nil {
@@ -75,70 +107,208 @@ func StartWorkerService(data *ServiceData) error {
 	if data.PreloadManager == nil {
 		return errors.New("No Preload manager set")
 	}
+	if data.Progress != nil && data.Progress.Sender == nil {
+		return errors.New("No progress sender")
+	}
+	if data.LogShipping != nil && (data.LogShipping.Saver == nil || data.LogShipping.Step == "" ||
+		data.LogShipping.MaxSize <= 0) {
+		return errors.New("Wrong log shipping settings")
+	}
+	if data.Step == nil {
+		err := validateTemplates(data)
+		if err != nil {
+			return err
+		}
+	}
+	if data.ChunkJoin != nil {
+		if err := validateChunkJoin(data.ChunkJoin); err != nil {
+			return err
+		}
+	}
 
 	go listenQueue(data)
 	return nil
 }
 
+func validateTemplates(data *ServiceData) error {
+	_, err := cmdtemplate.ParseCommand(data.Command)
+	if err != nil {
+		return err
+	}
+	_, err = cmdtemplate.ParsePath(data.ResultFile)
+	if err != nil {
+		return errors.Wrap(err, "Wrong result file")
+	}
+	_, err = cmdtemplate.ParsePath(data.LogFile)
+	if err != nil {
+		return errors.Wrap(err, "Wrong log file")
+	}
+	if data.Progress != nil {
+		_, err = cmdtemplate.ParsePath(data.Progress.File)
+		return errors.Wrap(err, "Wrong progress file")
+	}
+	return nil
+}
+
 // work is main method to process of the worker
-func work(data *ServiceData, msg *messages.QueueMessage) error {
+// returns template params for the result file
+func work(data *ServiceData, msg *messages.QueueMessage) (*cmdtemplate.Params, error) {
 	data.reapLock.Lock()
 	defer data.reapLock.Unlock()
 
 	cmdapp.Log.Infof("Got task %s for ID: %s, rec: %s", data.Name, msg.ID, msg.Recognizer)
 	rp, err := data.RecInfoLoader.Get(msg.Recognizer)
 	if err != nil {
-		return errors.Wrap(err, "Can't load description")
+		return nil, errors.Wrap(err, "Can't load description")
+	}
+	tp := &cmdtemplate.Params{ID: msg.ID, Tags: msg.Tags, Settings: rp.Settings}
+	if n, join, err := chunksToJoin(msg); join {
+		if err != nil {
+			return tp, err
+		}
+		if data.ChunkJoin == nil {
+			return tp, errors.New("Chunk join is not configured")
+		}
+		return tp, joinChunks(data.ChunkJoin, tp, n)
 	}
 	envs, err := collectEnvParams(rp, msg)
 	if err != nil {
-		return err
+		return tp, err
 	}
 	err = data.PreloadManager.EnsureRunning(rp.Settings)
 	if err != nil {
-		return errors.Wrap(err, "Can't init preload task")
+		return tp, errors.Wrap(err, "Can't init preload task")
+	}
+	args, err := expandCommand(data.Command, tp)
+	if err != nil {
+		return tp, err
 	}
 	logOutput := ioutil.Discard
 	if data.LogFile != "" {
-		lf := strings.Replace(data.LogFile, "{ID}", msg.ID, -1)
-		f, err := os.OpenFile(lf, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		lf, err := expandPath(data.LogFile, tp)
 		if err != nil {
+			cmdapp.Log.Warn(errors.Wrap(err, "Can't prepare log file name"))
+		} else if f, err := os.OpenFile(lf, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
 			cmdapp.Log.Warn(errors.Wrapf(err, "Can't open file %s", lf))
 		} else {
 			defer f.Close()
 			logOutput = f
 		}
 	}
-	return RunCommand(data.Command, data.WorkingDir, msg.ID, envs, logOutput)
+	limits := data.Limits
+	if data.timeouts != nil {
+		limits.Timeout = data.timeouts.get(rp.Settings, messages.GetTagDuration(msg.Tags, messages.TagAudioDuration))
+		cmdapp.Log.Infof("Task timeout: %v", limits.Timeout)
+	}
+	if data.Progress != nil {
+		stop, w := startProgress(data.Progress, msg, tp)
+		defer close(stop)
+		logOutput = io.MultiWriter(logOutput, w)
+	}
+	if data.LogShipping != nil {
+		b := newTailBuffer(data.LogShipping.MaxSize)
+		defer shipLog(data.LogShipping, msg, b)
+		logOutput = io.MultiWriter(logOutput, b)
+	}
+	return tp, RunCommandWithLimits(args, data.WorkingDir, envs, logOutput, limits)
+}
+
+// startProgress prepares the output writer and the file watcher for the progress lines,
+// close the returned channel to stop watching
+func startProgress(cfg *ProgressConfig, msg *messages.QueueMessage, tp *cmdtemplate.Params) (chan struct{}, io.Writer) {
+	r := newProgressReporter(cfg, msg)
+	r.reset()
+	stop := make(chan struct{})
+	if cfg.File != "" {
+		f, err := expandPath(cfg.File, tp)
+		if err != nil {
+			cmdapp.Log.Warn(errors.Wrap(err, "Can't prepare progress file name"))
+		} else {
+			os.Remove(f) // drop the old progress of the retried task
+			go watchProgressFile(f, r, cfg.Interval, stop)
+		}
+	}
+	return stop, &progressWriter{r: r}
+}
+
+func expandCommand(command string, tp *cmdtemplate.Params) ([]string, error) {
+	tmpl, err := cmdtemplate.ParseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tmpl.Args(tp)
+	return res, errors.Wrap(err, "Can't prepare command")
+}
+
+func expandPath(path string, tp *cmdtemplate.Params) (string, error) {
+	tmpl, err := cmdtemplate.ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.String(tp)
 }
 
 func listenQueue(data *ServiceData) {
 	for d := range data.WorkCh {
-		msg, err := processMsg(&d, data)
+		if atomic.LoadInt32(&data.draining) == 1 && !data.skipAck {
+			// the consumer is canceled, only the message prefetched before the drain may arrive
+			cmdapp.Log.Info("Worker is draining. Return message to the queue")
+			d.Nack(false, true)
+			continue
+		}
+		atomic.StoreInt32(&data.working, 1)
+		handleDelivery(&d, data)
+		atomic.StoreInt32(&data.working, 0)
+	}
+	cmdapp.Log.Infof("Stopped listening queue")
+	data.quitChannel.Close()
+}
+
+func handleDelivery(d *amqp.Delivery, data *ServiceData) {
+	msg, err := processMsg(d, data)
+	if err != nil {
+		cmdapp.Log.Error("Message error", err)
+		if !data.skipAck {
+			d.Nack(false, false)
+		}
+		return
+	}
+	if d.ReplyTo != "" {
+		err = data.MessageSender.SendWithCorr(msg, d.ReplyTo, "", d.CorrelationId)
 		if err != nil {
-			cmdapp.Log.Error("Message error", err)
+			cmdapp.Log.Error("Can't reply result", err)
 			if !data.skipAck {
-				d.Nack(false, false)
+				d.Nack(false, !d.Redelivered) // try redeliver for first time
 			}
-			continue
+			return
 		}
-		if d.ReplyTo != "" {
-			err = data.MessageSender.SendWithCorr(msg, d.ReplyTo, "", d.CorrelationId)
-			if err != nil {
-				cmdapp.Log.Error("Can't reply result", err)
-				if !data.skipAck {
-					d.Nack(false, !d.Redelivered) // try redeliver for first time
-				}
-				continue
-			}
-			cmdapp.Log.Infof("Sent reply message to %s, corrID: %s", d.ReplyTo, d.CorrelationId)
+		cmdapp.Log.Infof("Sent reply message to %s, corrID: %s", d.ReplyTo, d.CorrelationId)
+	}
+	if !data.skipAck {
+		d.Ack(false)
+	}
+}
+
+var drainCheckInterval = time.Second
+
+// drain stops taking new tasks and waits for the current task to finish.
+// Returns false if the task is still running after the timeout, zero timeout means wait forever
+func drain(data *ServiceData, timeout time.Duration) bool {
+	atomic.StoreInt32(&data.draining, 1)
+	if data.stopConsume != nil {
+		cmdapp.LogIf(errors.Wrap(data.stopConsume(), "Can't cancel the consumer"))
+	}
+	end := time.Now().Add(timeout)
+	for {
+		// give time for the task sent before the drain to arrive
+		time.Sleep(drainCheckInterval)
+		if atomic.LoadInt32(&data.working) == 0 {
+			return true
 		}
-		if !data.skipAck {
-			d.Ack(false)
+		if timeout > 0 && time.Now().After(end) {
+			return false
 		}
 	}
-	cmdapp.Log.Infof("Stopped listening queue")
-	data.quitChannel.Close()
 }
 
 func processMsg(d *amqp.Delivery, data *ServiceData) (messages.Message, error) {
@@ -146,16 +316,24 @@ func processMsg(d *amqp.Delivery, data *ServiceData) (messages.Message, error) {
 	if err := json.Unmarshal(d.Body, &message); err != nil {
 		return nil, errors.Wrap(err, "Can't unmarshal message "+string(d.Body))
 	}
-	err := work(data, &message)
+	if data.Step != nil {
+		return processStep(data, &message), nil
+	}
+	tp, err := work(data, &message)
 	cmdapp.Log.Infof("Msg processed")
 	result := messages.NewQueueMessageFromM(&message)
 	var res string
+	var ref *messages.ResultRef
 	if err != nil {
 		cmdapp.Log.Error(err)
 		result.Error = err.Error()
 	} else {
 		if data.ResultFile != "" && d.ReplyTo != "" {
-			res, err = data.ReadFunc(data.ResultFile, message.ID)
+			if data.ResultRefRoot != "" {
+				ref, err = makeResultRef(data, tp)
+			} else {
+				res, err = readResult(data, tp)
+			}
 			if err != nil {
 				cmdapp.Log.Error(err)
 				result.Error = err.Error()
@@ -163,18 +341,81 @@ func processMsg(d *amqp.Delivery, data *ServiceData) (messages.Message, error) {
 		}
 	}
 	if data.ResultFile != "" {
-		return &messages.ResultMessage{QueueMessage: *result, Result: res}, nil
+		return &messages.ResultMessage{QueueMessage: *result, Result: res, Ref: ref}, nil
 	}
 	return result, nil
 }
 
+func makeResultRef(data *ServiceData, tp *cmdtemplate.Params) (*messages.ResultRef, error) {
+	file, err := expandPath(data.ResultFile, tp)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't prepare result file name")
+	}
+	return newResultRef(data.ResultRefRoot, file)
+}
+
+func readResult(data *ServiceData, tp *cmdtemplate.Params) (string, error) {
+	file, err := expandPath(data.ResultFile, tp)
+	if err != nil {
+		return "", errors.Wrap(err, "Can't prepare result file name")
+	}
+	return data.ReadFunc(file)
+}
+
+// processStep runs the in-process step, returns the reply message.
+// As for the command, the result message is sent only if the ResultFile is configured
+func processStep(data *ServiceData, msg *messages.QueueMessage) messages.Message {
+	cmdapp.Log.Infof("Got task %s for ID: %s, rec: %s", data.Name, msg.ID, msg.Recognizer)
+	res, err := runStep(data, msg)
+	cmdapp.Log.Infof("Msg processed")
+	if err != nil {
+		cmdapp.Log.Error(err)
+		result := messages.NewQueueMessageFromM(msg)
+		result.Error = err.Error()
+		res = &messages.ResultMessage{QueueMessage: *result}
+	}
+	if res == nil {
+		res = &messages.ResultMessage{QueueMessage: *messages.NewQueueMessageFromM(msg)}
+	}
+	if data.ResultFile == "" {
+		return &res.QueueMessage
+	}
+	return res
+}
+
+func runStep(data *ServiceData, msg *messages.QueueMessage) (*messages.ResultMessage, error) {
+	ctx := context.Background()
+	if data.timeouts != nil {
+		rp, err := data.RecInfoLoader.Get(msg.Recognizer)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't load description")
+		}
+		timeout := data.timeouts.get(rp.Settings, messages.GetTagDuration(msg.Tags, messages.TagAudioDuration))
+		if timeout > 0 {
+			cmdapp.Log.Infof("Task timeout: %v", timeout)
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	if data.Progress != nil {
+		r := newProgressReporter(data.Progress, msg)
+		r.reset()
+		ctx = step.WithProgress(ctx, r.report)
+	}
+	res, err := data.Step.Process(ctx, msg)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.Errorf("Step timeout. %s\n%s", errc.Mark(errc.TimeoutCode), err.Error())
+	}
+	return res, err
+}
+
 // ReadFile reads content as string
-func ReadFile(file string, id string) (string, error) {
-	realFile := strings.Replace(file, "{ID}", id, -1)
-	cmdapp.Log.Infof("Reading file: %s", realFile)
-	bytes, err := ioutil.ReadFile(realFile)
+func ReadFile(file string) (string, error) {
+	cmdapp.Log.Infof("Reading file: %s", file)
+	bytes, err := ioutil.ReadFile(file)
 	if err != nil {
-		return "", errors.Wrap(err, "Can't read file "+realFile)
+		return "", errors.Wrap(err, "Can't read file "+file)
 	}
 	return string(bytes), nil
 }
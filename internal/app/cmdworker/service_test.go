@@ -1,9 +1,11 @@
 package cmdworker
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/recognizer"
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
@@ -107,6 +109,26 @@ func TestHandlesWhenTaskFails(t *testing.T) {
 	ackMock.VerifyWasCalledOnce().Ack(pegomock.AnyUint64(), pegomock.AnyBool())
 }
 
+func TestHandlesTimeout(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.Command = "sleep 10"
+	data.timeouts, _ = newTimeoutCalc(0, 0.1, 0)
+	StartWorkerService(&data)
+
+	msgdata, _ := json.Marshal(messages.NewQueueMessage("1", "rec",
+		[]messages.Tag{messages.NewTag(messages.TagAudioDuration, "1")}))
+	message.Body = msgdata
+	message.ReplyTo = "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	cMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Contains(t, cMsg.(*messages.QueueMessage).Error, "[[[ErrorCode:TIMEOUT]]]")
+}
+
 func TestHandlesWhenPreloadFails(t *testing.T) {
 	initTest(t)
 	wc := make(chan amqp.Delivery)
@@ -161,7 +183,7 @@ func TestHandlesResultRequired(t *testing.T) {
 	data := initData(t, wc)
 	StartWorkerService(&data)
 
-	data.ReadFunc = func(file string, id string) (string, error) {
+	data.ReadFunc = func(file string) (string, error) {
 		return "olia", nil
 	}
 	data.ResultFile = "rFile"
@@ -182,7 +204,7 @@ func TestHandlesWithResultFailing(t *testing.T) {
 	data := initData(t, wc)
 	StartWorkerService(&data)
 
-	data.ReadFunc = func(file string, id string) (string, error) {
+	data.ReadFunc = func(file string) (string, error) {
 		return "", errors.New("error")
 	}
 	data.ResultFile = "rFile"
@@ -210,6 +232,61 @@ func TestCheckInputParametersNoFunction(t *testing.T) {
 	assert.NotNil(t, error)
 }
 
+func TestCheckInputParameters_WrongTemplates(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.Command = "ls '-la"
+	assert.NotNil(t, StartWorkerService(&data))
+
+	data = initData(t, nil)
+	data.ReadFunc = ReadFile
+	data.ResultFile = "/data/{ID"
+	assert.NotNil(t, StartWorkerService(&data))
+
+	data = initData(t, nil)
+	data.LogFile = "/data/{OLIA}.log"
+	assert.NotNil(t, StartWorkerService(&data))
+}
+
+func TestHandlesResultFileTemplate(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	file := ""
+	data.ReadFunc = func(f string) (string, error) {
+		file = f
+		return "olia", nil
+	}
+	data.ResultFile = "/data/{TAG:path}/{SETTING:model}/{ID}.txt"
+	pegomock.When(recInfoLoaderMock.Get(pegomock.AnyString())).
+		ThenReturn(&recognizer.Info{Settings: map[string]string{"model": "m1"}}, nil)
+	StartWorkerService(&data)
+
+	msgdata, _ := json.Marshal(messages.NewQueueMessage("1", "rec", []messages.Tag{messages.NewTag("path", "a b")}))
+	message.Body = msgdata
+	message.ReplyTo = "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	assert.Equal(t, "/data/a b/m1/1.txt", file)
+}
+
+func TestHandlesCommandTemplateFails(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.Command = "ls {TAG:olia}"
+	StartWorkerService(&data)
+
+	message.ReplyTo = "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	cMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Contains(t, cMsg.(*messages.QueueMessage).Error, "olia")
+}
+
 func TestCheckInputParametersWithFunction(t *testing.T) {
 	initTest(t)
 
@@ -270,3 +347,145 @@ func TestHandlesOKWithNoAck(t *testing.T) {
 	msgSenderMock.VerifyWasCalled(pegomock.Never()).SendWithCorr(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString())
 	ackMock.VerifyWasCalled(pegomock.Never()).Ack(pegomock.AnyUint64(), pegomock.AnyBool())
 }
+
+func TestDraining_ReturnsMessage(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.draining = 1
+	StartWorkerService(&data)
+
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	recInfoLoaderMock.VerifyWasCalled(pegomock.Never()).Get(pegomock.AnyString())
+	_, _, requeue := ackMock.VerifyWasCalledOnce().Nack(pegomock.AnyUint64(), pegomock.AnyBool(), pegomock.AnyBool()).
+		GetCapturedArguments()
+	assert.True(t, requeue)
+}
+
+func TestDraining_ProcessesWithNoAck(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.draining = 1
+	data.skipAck = true
+	StartWorkerService(&data)
+
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	recInfoLoaderMock.VerifyWasCalledOnce().Get(pegomock.AnyString())
+}
+
+func TestDrain(t *testing.T) {
+	drainCheckInterval = time.Millisecond
+	data := initData(t, nil)
+	assert.True(t, drain(&data, 0))
+	assert.Equal(t, int32(1), data.draining)
+}
+
+func TestDrain_StopsConsuming(t *testing.T) {
+	drainCheckInterval = time.Millisecond
+	data := initData(t, nil)
+	stopped := 0
+	data.stopConsume = func() error {
+		stopped++
+		return nil
+	}
+	assert.True(t, drain(&data, 0))
+	assert.Equal(t, 1, stopped)
+}
+
+func TestDrain_Timeout(t *testing.T) {
+	drainCheckInterval = time.Millisecond
+	data := initData(t, nil)
+	data.working = 1
+	assert.False(t, drain(&data, 10*time.Millisecond))
+}
+
+func TestStep(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	data.Command = ""
+	data.ResultFile = "rFile"
+	stepMock := mocks.NewMockStep()
+	data.Step = stepMock
+	pegomock.When(stepMock.Process(matchers.AnyContextContext(), matchers.AnyPtrToMessagesQueueMessage())).
+		ThenReturn(&messages.ResultMessage{QueueMessage: *messages.NewQueueMessage("1", "rec", nil), Result: "olia"}, nil)
+	err := StartWorkerService(&data)
+	assert.Nil(t, err)
+
+	message.ReplyTo = "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	_, cMsg := stepMock.VerifyWasCalledOnce().Process(matchers.AnyContextContext(),
+		matchers.AnyPtrToMessagesQueueMessage()).GetCapturedArguments()
+	assert.Equal(t, "1", cMsg.ID)
+	rMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, "olia", rMsg.(*messages.ResultMessage).Result)
+	ackMock.VerifyWasCalledOnce().Ack(pegomock.AnyUint64(), pegomock.AnyBool())
+	preloadTaskManagerMock.VerifyWasCalled(pegomock.Never()).EnsureRunning(matchers.AnyMapOfStringToString())
+}
+
+func TestStep_Fails(t *testing.T) {
+	initTest(t)
+	wc := make(chan amqp.Delivery)
+	data := initData(t, wc)
+	stepMock := mocks.NewMockStep()
+	data.Step = stepMock
+	pegomock.When(stepMock.Process(matchers.AnyContextContext(), matchers.AnyPtrToMessagesQueueMessage())).
+		ThenReturn(nil, errors.New("olia"))
+	StartWorkerService(&data)
+
+	message.ReplyTo = "rt"
+	wc <- message
+	close(wc)
+	<-data.quitChannel.C // wait for complete
+	rMsg, _, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).SendWithCorr(matchers.AnyMessagesMessage(),
+		pegomock.AnyString(), pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, "olia", rMsg.(*messages.QueueMessage).Error)
+	assert.Equal(t, "1", rMsg.(*messages.QueueMessage).ID)
+	ackMock.VerifyWasCalledOnce().Ack(pegomock.AnyUint64(), pegomock.AnyBool())
+}
+
+func TestStep_NoResultFile(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	stepMock := mocks.NewMockStep()
+	data.Step = stepMock
+	pegomock.When(stepMock.Process(matchers.AnyContextContext(), matchers.AnyPtrToMessagesQueueMessage())).
+		ThenReturn(&messages.ResultMessage{QueueMessage: *messages.NewQueueMessage("1", "rec", nil), Result: "olia"}, nil)
+
+	res := processStep(&data, messages.NewQueueMessage("1", "rec", nil))
+
+	assert.Equal(t, messages.NewQueueMessage("1", "rec", nil), res)
+}
+
+type sleepStep struct{}
+
+func (s *sleepStep) Process(ctx context.Context, msg *messages.QueueMessage) (*messages.ResultMessage, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStep_Timeout(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.Step = &sleepStep{}
+	data.timeouts, _ = newTimeoutCalc(10*time.Millisecond, 0, 0)
+	res := processStep(&data, messages.NewQueueMessage("1", "rec", nil))
+	assert.Contains(t, res.(*messages.QueueMessage).Error, "[[[ErrorCode:TIMEOUT]]]")
+}
+
+func TestStep_NoCommand(t *testing.T) {
+	initTest(t)
+	data := initData(t, nil)
+	data.Command = ""
+	assert.NotNil(t, StartWorkerService(&data))
+	data.Step = &sleepStep{}
+	assert.Nil(t, StartWorkerService(&data))
+}
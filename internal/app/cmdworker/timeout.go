@@ -0,0 +1,79 @@
+package cmdworker
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+)
+
+const (
+	timeoutBaseKey     = "timeout_base"
+	timeoutRTFactorKey = "timeout_rt_factor"
+	timeoutMaxKey      = "timeout_max"
+)
+
+// timeoutCalc calculates the task timeout from audio duration: base + rtFactor * duration, but not more than max.
+// The values can be overridden by recognizer settings
+type timeoutCalc struct {
+	base     time.Duration
+	rtFactor float64
+	max      time.Duration
+}
+
+func newTimeoutCalc(base time.Duration, rtFactor float64, max time.Duration) (*timeoutCalc, error) {
+	if base < 0 || rtFactor < 0 || max < 0 {
+		return nil, errors.New("Negative timeout params")
+	}
+	return &timeoutCalc{base: base, rtFactor: rtFactor, max: max}, nil
+}
+
+// get returns 0 if no timeout is configured
+func (tc *timeoutCalc) get(settings map[string]string, dur time.Duration) time.Duration {
+	c := tc.override(settings)
+	if dur <= 0 {
+		if c.max > 0 {
+			return c.max
+		}
+		return c.base
+	}
+	res := c.base + durTimes(dur, c.rtFactor)
+	if c.max > 0 && res > c.max {
+		return c.max
+	}
+	return res
+}
+
+func (tc *timeoutCalc) override(settings map[string]string) timeoutCalc {
+	res := *tc
+	if v, ok := settings[timeoutBaseKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			cmdapp.Log.Warnf("Wrong %s setting '%s'", timeoutBaseKey, v)
+		} else {
+			res.base = d
+		}
+	}
+	if v, ok := settings[timeoutRTFactorKey]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			cmdapp.Log.Warnf("Wrong %s setting '%s'", timeoutRTFactorKey, v)
+		} else {
+			res.rtFactor = f
+		}
+	}
+	if v, ok := settings[timeoutMaxKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			cmdapp.Log.Warnf("Wrong %s setting '%s'", timeoutMaxKey, v)
+		} else {
+			res.max = d
+		}
+	}
+	return res
+}
+
+func durTimes(d time.Duration, times float64) time.Duration {
+	return time.Duration(float64(d) * times)
+}
@@ -0,0 +1,50 @@
+package cmdworker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutCalc_Init(t *testing.T) {
+	tc, err := newTimeoutCalc(time.Minute, 2, time.Hour)
+	assert.Nil(t, err)
+	assert.NotNil(t, tc)
+	_, err = newTimeoutCalc(-time.Minute, 2, time.Hour)
+	assert.NotNil(t, err)
+	_, err = newTimeoutCalc(time.Minute, -2, time.Hour)
+	assert.NotNil(t, err)
+}
+
+func TestTimeoutCalc(t *testing.T) {
+	tc, _ := newTimeoutCalc(time.Minute, 2, time.Hour)
+	assert.Equal(t, 21*time.Minute, tc.get(nil, 10*time.Minute))
+	assert.Equal(t, time.Hour, tc.get(nil, 100*time.Minute))
+	assert.Equal(t, time.Hour, tc.get(nil, 0))
+}
+
+func TestTimeoutCalc_Disabled(t *testing.T) {
+	tc, _ := newTimeoutCalc(0, 0, 0)
+	assert.Equal(t, time.Duration(0), tc.get(nil, 10*time.Minute))
+	assert.Equal(t, time.Duration(0), tc.get(nil, 0))
+}
+
+func TestTimeoutCalc_NoMax(t *testing.T) {
+	tc, _ := newTimeoutCalc(time.Minute, 2, 0)
+	assert.Equal(t, 201*time.Minute, tc.get(nil, 100*time.Minute))
+	assert.Equal(t, time.Minute, tc.get(nil, 0))
+}
+
+func TestTimeoutCalc_Override(t *testing.T) {
+	tc, _ := newTimeoutCalc(time.Minute, 2, time.Hour)
+	st := map[string]string{timeoutBaseKey: "2m", timeoutRTFactorKey: "0.5", timeoutMaxKey: "3h"}
+	assert.Equal(t, 52*time.Minute, tc.get(st, 100*time.Minute))
+	assert.Equal(t, 3*time.Hour, tc.get(st, 0))
+}
+
+func TestTimeoutCalc_WrongOverride(t *testing.T) {
+	tc, _ := newTimeoutCalc(time.Minute, 2, time.Hour)
+	st := map[string]string{timeoutBaseKey: "2", timeoutRTFactorKey: "olia"}
+	assert.Equal(t, 21*time.Minute, tc.get(st, 10*time.Minute))
+}
@@ -0,0 +1,73 @@
+package dispatcher
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type segmentsGetter interface {
+	segments(id string) ([]segment, error)
+}
+
+type chunk struct {
+	from time.Duration
+	to   time.Duration
+}
+
+// chunker splits long transcription into chunks on the diarization segment boundaries
+type chunker struct {
+	segmentsGetter segmentsGetter
+	minDuration    time.Duration
+	duration       time.Duration
+}
+
+func newChunker(sg segmentsGetter, minDuration, duration time.Duration) (*chunker, error) {
+	if sg == nil {
+		return nil, errors.New("No segments getter")
+	}
+	if duration < time.Minute {
+		return nil, errors.Errorf("Chunk duration too small: %v", duration)
+	}
+	if minDuration < duration {
+		return nil, errors.Errorf("Min duration %v < chunk duration %v", minDuration, duration)
+	}
+	return &chunker{segmentsGetter: sg, minDuration: minDuration, duration: duration}, nil
+}
+
+// split returns chunks for the transcription or nil if the transcription is too short
+func (c *chunker) split(id string) ([]chunk, error) {
+	sgs, err := c.segmentsGetter.segments(id)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sgs, func(i, j int) bool { return sgs[i].from < sgs[j].from })
+	res := make([]chunk, 0)
+	var from, to time.Duration
+	for _, sg := range sgs {
+		if sg.from >= to && to-from >= c.duration {
+			res = append(res, chunk{from: from, to: to})
+			from = to
+		}
+		if sg.end() > to {
+			to = sg.end()
+		}
+	}
+	if to < c.minDuration {
+		return nil, nil
+	}
+	if to-from < c.duration/4 && len(res) > 0 {
+		res[len(res)-1].to = to
+	} else if to > from {
+		res = append(res, chunk{from: from, to: to})
+	}
+	if len(res) < 2 {
+		return nil, nil
+	}
+	return res, nil
+}
+
+func toFrames(d time.Duration) int64 {
+	return int64(d / (10 * time.Millisecond))
+}
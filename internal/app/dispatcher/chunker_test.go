@@ -0,0 +1,79 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChunker(t *testing.T, segments string) *chunker {
+	initTestDuration(t)
+	pegomock.When(loaderMock.Read(pegomock.AnyString())).ThenReturn([]byte(segments), nil)
+	l, _ := newDurationLoaderInt("/aaa/{ID}/aa", loaderMock)
+	c, _ := newChunker(l, 2*time.Minute, time.Minute)
+	return c
+}
+
+func TestInitChunker(t *testing.T) {
+	l, _ := newDurationLoader("/aaa/{ID}/aa")
+	c, err := newChunker(l, time.Hour, time.Minute*10)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+}
+
+func TestInitChunker_Fail(t *testing.T) {
+	l, _ := newDurationLoader("/aaa/{ID}/aa")
+	_, err := newChunker(nil, time.Hour, time.Minute*10)
+	assert.NotNil(t, err)
+	_, err = newChunker(l, time.Hour, time.Second)
+	assert.NotNil(t, err)
+	_, err = newChunker(l, time.Minute, time.Minute*10)
+	assert.NotNil(t, err)
+}
+
+func TestChunkerSplit(t *testing.T) {
+	c := newTestChunker(t, "a 1 0 3000 a\na 1 3000 4000 a\na 1 7000 3000 a\na 1 10000 6000 a")
+	r, err := c.split("id")
+	assert.Nil(t, err)
+	assert.Equal(t, []chunk{{from: 0, to: 70 * time.Second}, {from: 70 * time.Second, to: 160 * time.Second}}, r)
+}
+
+func TestChunkerSplit_SortsSegments(t *testing.T) {
+	c := newTestChunker(t, "a 1 7000 6000 a\na 1 0 3000 a\na 1 3000 4000 a")
+	r, err := c.split("id")
+	assert.Nil(t, err)
+	assert.Equal(t, []chunk{{from: 0, to: 70 * time.Second}, {from: 70 * time.Second, to: 130 * time.Second}}, r)
+}
+
+func TestChunkerSplit_JoinsShortTail(t *testing.T) {
+	c := newTestChunker(t, "a 1 0 7000 a\na 1 7000 6000 a\na 1 13000 500 a")
+	r, err := c.split("id")
+	assert.Nil(t, err)
+	assert.Equal(t, []chunk{{from: 0, to: 70 * time.Second}, {from: 70 * time.Second, to: 135 * time.Second}}, r)
+}
+
+func TestChunkerSplit_DoesNotCutOverlapping(t *testing.T) {
+	c := newTestChunker(t, "a 1 0 10000 a\na 1 5000 1000 a\na 1 10000 3000 a")
+	r, err := c.split("id")
+	assert.Nil(t, err)
+	assert.Equal(t, []chunk{{from: 0, to: 100 * time.Second}, {from: 100 * time.Second, to: 130 * time.Second}}, r)
+}
+
+func TestChunkerSplit_Short(t *testing.T) {
+	c := newTestChunker(t, "a 1 0 3000 a\na 1 3000 4000 a")
+	r, err := c.split("id")
+	assert.Nil(t, err)
+	assert.Nil(t, r)
+}
+
+func TestChunkerSplit_Fail(t *testing.T) {
+	initTestDuration(t)
+	pegomock.When(loaderMock.Read(pegomock.AnyString())).ThenReturn(nil, errors.New("err"))
+	l, _ := newDurationLoaderInt("/aaa/{ID}/aa", loaderMock)
+	c, _ := newChunker(l, 2*time.Minute, time.Minute)
+	_, err := c.split("id")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,165 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/metrics"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defDurationSource = "default"
+
+// RequestGetter provides request info for transcription ID
+type RequestGetter interface {
+	Get(id string) (*persistence.Request, error)
+}
+
+type durationSource struct {
+	name   string
+	getter DurationGetter
+}
+
+// requestDurationGetter gets the duration from the request info, so the chain loads the request once
+type requestDurationGetter interface {
+	requestGetter() RequestGetter
+	fromRequest(id string, r *persistence.Request) (time.Duration, error)
+}
+
+// durationChain asks duration sources one by one and returns the first found duration
+type durationChain struct {
+	sources []durationSource
+	used    *prometheus.CounterVec
+}
+
+func newDurationChain(sources ...durationSource) (*durationChain, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("No duration sources")
+	}
+	for _, s := range sources {
+		if s.getter == nil {
+			return nil, errors.Errorf("No duration getter for '%s'", s.name)
+		}
+	}
+	res := &durationChain{sources: sources}
+	res.used = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "dispatcher",
+			Name:      "duration_source_total",
+			Help:      "Count of task durations by the source used",
+		}, []string{"source"})
+	err := metrics.Register(res.used)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't register metric")
+	}
+	return res, nil
+}
+
+func (c *durationChain) Get(id string) (time.Duration, error) {
+	requests := make(map[RequestGetter]*loadedRequest)
+	for _, s := range c.sources {
+		d, err := c.get(s.getter, id, requests)
+		if err != nil {
+			cmdapp.Log.Debugf("No duration from %s: %v", s.name, err)
+			continue
+		}
+		if d > 0 {
+			cmdapp.Log.Infof("Duration for %s from %s: %v", id, s.name, d)
+			c.used.WithLabelValues(s.name).Inc()
+			return d, nil
+		}
+	}
+	cmdapp.Log.Warnf("No duration for %s, using default %v", id, defDuration)
+	c.used.WithLabelValues(defDurationSource).Inc()
+	return defDuration, errors.Errorf("No duration for %s", id)
+}
+
+type loadedRequest struct {
+	r   *persistence.Request
+	err error
+}
+
+func (c *durationChain) get(g DurationGetter, id string, requests map[RequestGetter]*loadedRequest) (time.Duration, error) {
+	rg, ok := g.(requestDurationGetter)
+	if !ok {
+		return g.Get(id)
+	}
+	lr, ok := requests[rg.requestGetter()]
+	if !ok {
+		lr = &loadedRequest{}
+		lr.r, lr.err = rg.requestGetter().Get(id)
+		requests[rg.requestGetter()] = lr
+	}
+	if lr.err != nil {
+		return 0, errors.Wrapf(lr.err, "Can't get request %s", id)
+	}
+	return rg.fromRequest(id, lr.r)
+}
+
+// metaDurationGetter returns audio duration saved in request info
+type metaDurationGetter struct {
+	requests RequestGetter
+}
+
+func newMetaDurationGetter(requests RequestGetter) (*metaDurationGetter, error) {
+	if requests == nil {
+		return nil, errors.New("No request getter")
+	}
+	return &metaDurationGetter{requests: requests}, nil
+}
+
+func (g *metaDurationGetter) Get(id string) (time.Duration, error) {
+	r, err := g.requests.Get(id)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't get request %s", id)
+	}
+	return g.fromRequest(id, r)
+}
+
+func (g *metaDurationGetter) requestGetter() RequestGetter {
+	return g.requests
+}
+
+func (g *metaDurationGetter) fromRequest(id string, r *persistence.Request) (time.Duration, error) {
+	if r == nil || r.Duration <= 0 {
+		return 0, errors.Errorf("No duration saved for %s", id)
+	}
+	return time.Duration(r.Duration * float64(time.Second)), nil
+}
+
+// sizeDurationGetter estimates audio duration by file size
+type sizeDurationGetter struct {
+	requests       RequestGetter
+	bytesPerSecond float64
+}
+
+func newSizeDurationGetter(requests RequestGetter, bytesPerSecond float64) (*sizeDurationGetter, error) {
+	if requests == nil {
+		return nil, errors.New("No request getter")
+	}
+	if bytesPerSecond <= 0 {
+		return nil, errors.Errorf("Wrong bytes per second %f", bytesPerSecond)
+	}
+	return &sizeDurationGetter{requests: requests, bytesPerSecond: bytesPerSecond}, nil
+}
+
+func (g *sizeDurationGetter) Get(id string) (time.Duration, error) {
+	r, err := g.requests.Get(id)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't get request %s", id)
+	}
+	return g.fromRequest(id, r)
+}
+
+func (g *sizeDurationGetter) requestGetter() RequestGetter {
+	return g.requests
+}
+
+func (g *sizeDurationGetter) fromRequest(id string, r *persistence.Request) (time.Duration, error) {
+	if r == nil || r.FileSize <= 0 {
+		return 0, errors.Errorf("No file size saved for %s", id)
+	}
+	return time.Duration(float64(r.FileSize) / g.bytesPerSecond * float64(time.Second)), nil
+}
@@ -0,0 +1,140 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var requestGetterMock *mocks.MockRequestGetter
+
+func initTestDurationChain(t *testing.T) {
+	mocks.AttachMockToTest(t)
+	requestGetterMock = mocks.NewMockRequestGetter()
+	durGetterMock = mocks.NewMockDurationGetter()
+}
+
+func TestInitDurationChain(t *testing.T) {
+	initTestDurationChain(t)
+	c, err := newDurationChain(durationSource{name: "a", getter: durGetterMock})
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+}
+
+func TestInitDurationChain_Fails(t *testing.T) {
+	initTestDurationChain(t)
+	_, err := newDurationChain()
+	assert.NotNil(t, err)
+	_, err = newDurationChain(durationSource{name: "a"})
+	assert.NotNil(t, err)
+}
+
+func TestDurationChain_First(t *testing.T) {
+	initTestDurationChain(t)
+	dg := mocks.NewMockDurationGetter()
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Second*10, nil)
+	c, _ := newDurationChain(durationSource{name: "a", getter: durGetterMock}, durationSource{name: "b", getter: dg})
+	d, err := c.Get("id")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*10, d)
+	dg.VerifyWasCalled(pegomock.Never()).Get(pegomock.AnyString())
+}
+
+func TestDurationChain_Fallback(t *testing.T) {
+	initTestDurationChain(t)
+	dg := mocks.NewMockDurationGetter()
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Duration(0), errors.New("olia"))
+	pegomock.When(dg.Get(pegomock.AnyString())).ThenReturn(time.Second*20, nil)
+	c, _ := newDurationChain(durationSource{name: "a", getter: durGetterMock}, durationSource{name: "b", getter: dg})
+	d, err := c.Get("id")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*20, d)
+}
+
+func TestDurationChain_SkipsZero(t *testing.T) {
+	initTestDurationChain(t)
+	dg := mocks.NewMockDurationGetter()
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Duration(0), nil)
+	pegomock.When(dg.Get(pegomock.AnyString())).ThenReturn(time.Second*20, nil)
+	c, _ := newDurationChain(durationSource{name: "a", getter: durGetterMock}, durationSource{name: "b", getter: dg})
+	d, _ := c.Get("id")
+	assert.Equal(t, time.Second*20, d)
+}
+
+func TestDurationChain_Default(t *testing.T) {
+	initTestDurationChain(t)
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Duration(0), errors.New("olia"))
+	c, _ := newDurationChain(durationSource{name: "a", getter: durGetterMock})
+	d, err := c.Get("id")
+	assert.NotNil(t, err)
+	assert.Equal(t, defDuration, d)
+}
+
+func TestMetaDuration(t *testing.T) {
+	initTestDurationChain(t)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(&persistence.Request{Duration: 12.5}, nil)
+	g, err := newMetaDurationGetter(requestGetterMock)
+	assert.Nil(t, err)
+	d, err := g.Get("id")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Millisecond*12500, d)
+}
+
+func TestMetaDuration_Fails(t *testing.T) {
+	initTestDurationChain(t)
+	_, err := newMetaDurationGetter(nil)
+	assert.NotNil(t, err)
+	g, _ := newMetaDurationGetter(requestGetterMock)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(nil, nil)
+	_, err = g.Get("id")
+	assert.NotNil(t, err)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(&persistence.Request{}, nil)
+	_, err = g.Get("id")
+	assert.NotNil(t, err)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	_, err = g.Get("id")
+	assert.NotNil(t, err)
+}
+
+func TestSizeDuration(t *testing.T) {
+	initTestDurationChain(t)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(&persistence.Request{FileSize: 32000}, nil)
+	g, err := newSizeDurationGetter(requestGetterMock, 16000)
+	assert.Nil(t, err)
+	d, err := g.Get("id")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*2, d)
+}
+
+func TestSizeDuration_Fails(t *testing.T) {
+	initTestDurationChain(t)
+	_, err := newSizeDurationGetter(nil, 100)
+	assert.NotNil(t, err)
+	_, err = newSizeDurationGetter(requestGetterMock, 0)
+	assert.NotNil(t, err)
+	g, _ := newSizeDurationGetter(requestGetterMock, 100)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(&persistence.Request{}, nil)
+	_, err = g.Get("id")
+	assert.NotNil(t, err)
+}
+
+func TestDurationChain_LoadsRequestOnce(t *testing.T) {
+	initTestDurationChain(t)
+	pegomock.When(requestGetterMock.Get(pegomock.AnyString())).ThenReturn(&persistence.Request{FileSize: 32000}, nil)
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Duration(0), errors.New("olia"))
+	mg, _ := newMetaDurationGetter(requestGetterMock)
+	sg, _ := newSizeDurationGetter(requestGetterMock, 16000)
+	c, _ := newDurationChain(durationSource{name: "metadata", getter: mg}, durationSource{name: "segments", getter: durGetterMock},
+		durationSource{name: "size", getter: sg})
+
+	d, err := c.Get("id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*2, d)
+	requestGetterMock.VerifyWasCalledOnce().Get("id")
+}
@@ -41,28 +41,52 @@ func newDurationLoaderInt(pathPattern string, fl Loader) (*durationLoader, error
 var defDuration = time.Second * 60
 
 func (g *durationLoader) Get(id string) (time.Duration, error) {
+	sgs, err := g.segments(id)
+	if err != nil {
+		return defDuration, err
+	}
+	res := time.Second * 0
+	for _, sg := range sgs {
+		if sg.end() > res {
+			res = sg.end()
+		}
+	}
+	return res, nil
+}
+
+func (g *durationLoader) segments(id string) ([]segment, error) {
 	file := strings.Replace(g.pathPattern, "{ID}", id, -1)
 	cmdapp.Log.Infof("Loading file: %s", file)
 	fData, err := g.loader.Read(file)
 	if err != nil {
-		return defDuration, errors.Wrap(err, "Can't load: "+file)
+		return nil, errors.Wrap(err, "Can't load: "+file)
 	}
-	scanner := bufio.NewScanner(strings.NewReader(string(fData)))
-	res := time.Second * 0
+	return parseSegments(fData), nil
+}
+
+type segment struct {
+	from     time.Duration
+	duration time.Duration
+}
+
+func (s segment) end() time.Duration {
+	return s.from + s.duration
+}
+
+func parseSegments(data []byte) []segment {
+	res := make([]segment, 0)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line != "" {
 			//8689651a-0f62-4d3b-b12a-a87c917a9525 1 2412 226 M S U S0
 			strs := strings.Split(line, " ")
 			if len(strs) > 3 {
-				d := toDuration(strs[2]) + toDuration(strs[3])
-				if d > res {
-					res = d
-				}
+				res = append(res, segment{from: toDuration(strs[2]), duration: toDuration(strs[3])})
 			}
 		}
 	}
-	return res, nil
+	return res
 }
 
 func toDuration(s string) time.Duration {
@@ -0,0 +1,291 @@
+package dispatcher
+
+import (
+	"sort"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/pkg/errors"
+)
+
+// minETAChange is the ETA shift worth notifying the status listeners
+const minETAChange = 10 * time.Second
+
+// estimate of the task in the queue
+type estimate struct {
+	// position is 1-based order of the start among the waiting tasks, 0 - the task is running
+	position int
+	// eta is the expected end of the task, zero if unknown
+	eta time.Time
+}
+
+// queueSnapshot is a copy of the dispatcher queue, it is read without the workers lock
+type queueSnapshot struct {
+	wrks []*worker
+	tsks map[string]*task
+	sel  api.TaskSelector
+}
+
+// Snapshot copies the workers, the tasks and the strategy state. It must be called under the workers lock.
+// A stateful strategy is cloned, so the estimation does not change the real selection
+func (sw *strategyWrapper) Snapshot(wrks []*worker, tsks map[string]*task) *queueSnapshot {
+	res := &queueSnapshot{sel: sw.realStrategy}
+	if c, ok := res.sel.(api.Cloner); ok {
+		res.sel = c.Clone()
+	}
+	res.wrks, res.tsks = copyQueue(wrks, tsks)
+	return res
+}
+
+// copyQueue copies the workers and the tasks keeping the links between them
+func copyQueue(wrks []*worker, tsks map[string]*task) ([]*worker, map[string]*task) {
+	wm := make(map[*worker]*worker, len(wrks))
+	rw := make([]*worker, len(wrks))
+	for i, w := range wrks {
+		nw := *w
+		wm[w], rw[i] = &nw, &nw
+	}
+	tm := make(map[*task]*task, len(tsks))
+	for _, t := range tsks {
+		nt := *t
+		tm[t] = &nt
+	}
+	rt := make(map[string]*task, len(tsks))
+	for k, t := range tsks {
+		nt := tm[t]
+		nt.worker, nt.parent = wm[t.worker], tm[t.parent]
+		if nt.worker == nil && t.worker != nil {
+			w := *t.worker
+			nt.worker = &w
+		}
+		nt.children = make([]*task, 0, len(t.children))
+		for _, c := range t.children {
+			if nc := tm[c]; nc != nil {
+				nt.children = append(nt.children, nc)
+			}
+		}
+		rt[k] = nt
+	}
+	for i, w := range wrks {
+		rw[i].task = tm[w.task]
+	}
+	return rw, rt
+}
+
+// Estimate replays the queue snapshot through the strategy to find when each task starts and ends.
+// The result is keyed by task corrID
+func (sw *strategyWrapper) Estimate(q *queueSnapshot, now time.Time) (map[string]*estimate, error) {
+	res := make(map[string]*estimate)
+	for k, t := range q.tsks {
+		if t.started && t.worker != nil {
+			res[k] = &estimate{eta: t.worker.endAt}
+			if res[k].eta.Before(now) {
+				res[k].eta = now
+			}
+		}
+	}
+	sel := q.sel
+	ws := mapWorkers(q.wrks)
+	pending := mapTasks(q.tsks)
+	pos := 0
+	simNow := now
+	for len(pending) > 0 {
+		for _, w := range ws {
+			if w.Working && !w.EndAt.After(simNow) {
+				w.Working = false
+			}
+		}
+		for i, w := range ws {
+			if w.Working || w.Draining || len(pending) == 0 {
+				continue
+			}
+			realNow := time.Now()
+			rt, err := sel.FindBest(shiftWorkers(ws, simNow, realNow), shiftTasks(pending, simNow, realNow), i)
+			if err != nil {
+				return nil, errors.Wrap(err, "Can't select best task")
+			}
+			if rt == nil {
+				continue
+			}
+			t, ok := rt.RealObject.(*task)
+			if !ok {
+				return nil, errors.New("No wrapped task object")
+			}
+			pending = removeTask(pending, t)
+			w.Working = true
+			w.Tenant = t.tenant
+			w.EndAt = simNow.Add(durTimes(t.expDuration, t.rtFactor))
+			if !w.HasTaskType(t.requiredModelType) {
+				w.EndAt = w.EndAt.Add(t.expModelLoadDuration)
+			}
+			w.TaskType = t.requiredModelType
+			pos++
+			res[t.corrID()] = &estimate{position: pos, eta: w.EndAt}
+		}
+		// every step moves to the next end of a running task, so the loop finishes
+		next, ok := nextEnd(ws, simNow)
+		if !ok {
+			break
+		}
+		simNow = next
+	}
+	// no worker can take the rest, keep them in the arrival order
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].ArrivedAt.Before(pending[j].ArrivedAt) })
+	for _, t := range pending {
+		pos++
+		res[t.RealObject.(*task).corrID()] = &estimate{position: pos}
+	}
+	return res, nil
+}
+
+func nextEnd(ws []*api.Worker, now time.Time) (time.Time, bool) {
+	var res time.Time
+	ok := false
+	for _, w := range ws {
+		if w.Working && !w.EndAt.Before(now) && (!ok || w.EndAt.Before(res)) {
+			res, ok = w.EndAt, true
+		}
+	}
+	return res, ok
+}
+
+// strategies work with the wall clock, so all simulation times are shifted relative to it
+func shiftWorkers(ws []*api.Worker, now, realNow time.Time) []*api.Worker {
+	res := make([]*api.Worker, len(ws))
+	for i, w := range ws {
+		nw := *w
+		if w.Working {
+			nw.EndAt = realNow.Add(w.EndAt.Sub(now))
+		} else {
+			nw.EndAt = realNow
+		}
+		res[i] = &nw
+	}
+	return res
+}
+
+func shiftTasks(ts []*api.Task, now, realNow time.Time) []*api.Task {
+	res := make([]*api.Task, len(ts))
+	for i, t := range ts {
+		nt := *t
+		nt.ArrivedAt = realNow.Add(t.ArrivedAt.Sub(now))
+		res[i] = &nt
+	}
+	return res
+}
+
+func removeTask(ts []*api.Task, t *task) []*api.Task {
+	for i, v := range ts {
+		if v.RealObject == t {
+			return append(ts[:i], ts[i+1:]...)
+		}
+	}
+	return ts
+}
+
+// toQueueInfo converts the estimates to the job records: a chunked job is waiting until the last chunk ends
+func toQueueInfo(tsks map[string]*task, est map[string]*estimate, now time.Time) []*persistence.QueueInfo {
+	res := make([]*persistence.QueueInfo, 0)
+	for k, t := range tsks {
+		if t.parent != nil {
+			continue
+		}
+		e := est[k]
+		if t.waitsForChunks() {
+			e = chunksEstimate(t, est)
+		}
+		if e == nil {
+			continue
+		}
+		qi := &persistence.QueueInfo{ID: t.msg.ID, Position: e.position, Updated: now}
+		if !e.eta.IsZero() {
+			eta := e.eta
+			qi.ETA = &eta
+		}
+		res = append(res, qi)
+	}
+	return res
+}
+
+func chunksEstimate(p *task, est map[string]*estimate) *estimate {
+	var res *estimate
+	for _, c := range p.children {
+		e := est[c.corrID()]
+		if e == nil {
+			continue
+		}
+		if res == nil {
+			res = &estimate{position: e.position, eta: e.eta}
+			continue
+		}
+		if e.position < res.position {
+			res.position = e.position
+		}
+		if e.eta.IsZero() || (!res.eta.IsZero() && e.eta.After(res.eta)) {
+			res.eta = e.eta
+		}
+	}
+	return res
+}
+
+// publishEstimates saves the queue estimates periodically
+func publishEstimates(data *ServiceData, interval time.Duration) {
+	for {
+		select {
+		case <-data.fc.C:
+			return
+		case <-time.After(interval):
+			cmdapp.LogIf(saveEstimates(data))
+		}
+	}
+}
+
+// saveEstimates takes the queue snapshot under the lock, the estimation itself does not block the dispatching
+func saveEstimates(data *ServiceData) error {
+	data.wrkrs.lock.Lock()
+	wrks := make([]*worker, 0)
+	for _, k := range data.wrkrs.workers {
+		wrks = append(wrks, k)
+	}
+	q := data.estimator.Snapshot(wrks, data.tsks.tsks)
+	data.wrkrs.lock.Unlock()
+	now := time.Now()
+	est, err := data.estimator.Estimate(q, now)
+	if err != nil {
+		return errors.Wrap(err, "Can't estimate queue")
+	}
+	items := toQueueInfo(q.tsks, est, now)
+	if err := data.queueSaver.Save(items); err != nil {
+		return errors.Wrap(err, "Can't save queue estimates")
+	}
+	publishChanged(data, items)
+	return nil
+}
+
+// publishChanged sends the status change event for the jobs with the changed estimate,
+// so the websocket clients get the new position and ETA
+func publishChanged(data *ServiceData, items []*persistence.QueueInfo) {
+	last := make(map[string]*persistence.QueueInfo, len(items))
+	for _, it := range items {
+		last[it.ID] = it
+		if data.publisher != nil && estimateChanged(data.lastEstimates[it.ID], it) {
+			cmdapp.LogIf(errors.Wrapf(data.publisher.Publish(it.ID, messages.TopicStatusChange),
+				"Can't publish estimate change for %s", it.ID))
+		}
+	}
+	data.lastEstimates = last
+}
+
+func estimateChanged(old, qi *persistence.QueueInfo) bool {
+	if old == nil || old.Position != qi.Position || (old.ETA == nil) != (qi.ETA == nil) {
+		return true
+	}
+	if qi.ETA == nil {
+		return false
+	}
+	d := qi.ETA.Sub(*old.ETA)
+	return d >= minETAChange || d <= -minETAChange
+}
@@ -0,0 +1,242 @@
+package dispatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/strategy"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/airenas/listgo/internal/pkg/utils"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFIFOWrapper(t *testing.T) *strategyWrapper {
+	s, err := strategy.NewFIFO()
+	assert.Nil(t, err)
+	res, err := newStrategyWrapper(s)
+	assert.Nil(t, err)
+	return res
+}
+
+func TestEstimate(t *testing.T) {
+	now := time.Now()
+	sw := newFIFOWrapper(t)
+	tsks := testEstTasks(now, "1", "2", "3")
+	wrks := []*worker{{mType: "mt", endAt: now}}
+
+	est, err := sw.Estimate(sw.Snapshot(wrks, tsks), now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &estimate{position: 1, eta: now.Add(10 * time.Second)}, est["1"])
+	assert.Equal(t, &estimate{position: 2, eta: now.Add(20 * time.Second)}, est["2"])
+	assert.Equal(t, &estimate{position: 3, eta: now.Add(30 * time.Second)}, est["3"])
+	assert.False(t, wrks[0].working)
+}
+
+func TestEstimate_Running(t *testing.T) {
+	now := time.Now()
+	sw := newFIFOWrapper(t)
+	tsks := testEstTasks(now, "1", "2", "3")
+	w := &worker{mType: "mt", working: true, endAt: now.Add(5 * time.Second)}
+	tsks["1"].started, tsks["1"].worker = true, w
+	w2 := &worker{mType: "other", endAt: now}
+
+	est, err := sw.Estimate(sw.Snapshot([]*worker{w, w2}, tsks), now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &estimate{eta: now.Add(5 * time.Second)}, est["1"])
+	assert.Equal(t, &estimate{position: 1, eta: now.Add(10*time.Second + time.Minute)}, est["2"])
+	assert.Equal(t, &estimate{position: 2, eta: now.Add(15 * time.Second)}, est["3"])
+}
+
+func TestEstimate_SkipsDraining(t *testing.T) {
+	now := time.Now()
+	sw := newFIFOWrapper(t)
+	tsks := testEstTasks(now, "1")
+	wrks := []*worker{{mType: "mt", draining: true, working: true, endAt: now.Add(5 * time.Second)}}
+
+	est, err := sw.Estimate(sw.Snapshot(wrks, tsks), now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &estimate{position: 1}, est["1"])
+}
+
+func TestEstimate_NoWorkers(t *testing.T) {
+	now := time.Now()
+	sw := newFIFOWrapper(t)
+	tsks := testEstTasks(now, "1", "2")
+
+	est, err := sw.Estimate(sw.Snapshot(nil, tsks), now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &estimate{position: 1}, est["1"])
+	assert.Equal(t, &estimate{position: 2}, est["2"])
+}
+
+func TestEstimate_KeepsFairShareState(t *testing.T) {
+	now := time.Now()
+	fifo, _ := strategy.NewFIFO()
+	fs, err := strategy.NewFairShare(fifo)
+	assert.Nil(t, err)
+	sw, _ := newStrategyWrapper(fs)
+	tsks := testEstTasks(now, "a1", "a2", "a3")
+	for _, t := range tsks {
+		t.tenant = "a"
+	}
+	wrks := []*worker{{mType: "mt", endAt: now}}
+
+	_, err = sw.Estimate(sw.Snapshot(wrks, tsks), now)
+	assert.Nil(t, err)
+
+	bTsks := testEstTasks(now, "b1")
+	tsks["b1"] = bTsks["b1"]
+	tsks["b1"].tenant = "b"
+	tsks["b1"].addedAt = now
+	bt, err := sw.FindBest(wrks, tsks, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, tsks["a1"], bt)
+}
+
+func TestEstimate_StrategyFails(t *testing.T) {
+	initTestStrategy(t)
+	now := time.Now()
+	sw, _ := newStrategyWrapper(taskSelectorMock)
+	pegomock.When(taskSelectorMock.FindBest(matchers.AnySliceOfPtrToApiWorker(), matchers.AnySliceOfPtrToApiTask(),
+		pegomock.AnyInt())).ThenReturn(nil, errors.New("olia"))
+
+	_, err := sw.Estimate(sw.Snapshot([]*worker{{}}, testEstTasks(now, "1")), now)
+
+	assert.NotNil(t, err)
+}
+
+func TestSnapshot(t *testing.T) {
+	now := time.Now()
+	sw := newFIFOWrapper(t)
+	tsks := testEstTasks(now, "1", "2")
+	p := tsks["2"]
+	c := &task{msg: p.msg, parent: p, chunkIndex: 0}
+	p.children = []*task{c}
+	tsks[c.corrID()] = c
+	w := &worker{mType: "mt", working: true, task: tsks["1"]}
+	tsks["1"].started, tsks["1"].worker = true, w
+
+	q := sw.Snapshot([]*worker{w}, tsks)
+	w.working, tsks["1"].started = false, false
+	delete(tsks, "2")
+
+	assert.Equal(t, 3, len(q.tsks))
+	assert.NotSame(t, w, q.wrks[0])
+	assert.True(t, q.wrks[0].working)
+	assert.Same(t, q.tsks["1"], q.wrks[0].task)
+	assert.Same(t, q.wrks[0], q.tsks["1"].worker)
+	assert.True(t, q.tsks["1"].started)
+	assert.NotSame(t, p, q.tsks["2"])
+	assert.Same(t, q.tsks["2"], q.tsks[c.corrID()].parent)
+	assert.Equal(t, []*task{q.tsks[c.corrID()]}, q.tsks["2"].children)
+}
+
+func TestToQueueInfo(t *testing.T) {
+	now := time.Now()
+	tsks := testEstTasks(now, "1", "2", "3")
+	p := tsks["3"]
+	c1, c2 := &task{msg: p.msg, parent: p, chunkIndex: 0}, &task{msg: p.msg, parent: p, chunkIndex: 1}
+	p.children = []*task{c1, c2}
+	p.pendingChunks = 2
+	tsks[c1.corrID()], tsks[c2.corrID()] = c1, c2
+	est := map[string]*estimate{"1": {eta: now}, "2": {position: 3},
+		c1.corrID(): {position: 2, eta: now.Add(time.Minute)}, c2.corrID(): {position: 1, eta: now.Add(time.Second)}}
+
+	res := toQueueInfo(tsks, est, now)
+
+	assert.ElementsMatch(t, []*persistence.QueueInfo{{ID: "1", ETA: &now, Updated: now},
+		{ID: "2", Position: 3, Updated: now}, {ID: "3", Position: 1, ETA: timePtr(now.Add(time.Minute)), Updated: now}}, res)
+}
+
+func TestSaveEstimates(t *testing.T) {
+	now := time.Now()
+	data := &ServiceData{wrkrs: newWorkers(), tsks: newTasks(), fc: utils.NewMultiCloseChannel()}
+	data.estimator = newFIFOWrapper(t)
+	saver := &testQueueSaver{}
+	data.queueSaver = saver
+	data.wrkrs.workers["w"] = &worker{mType: "mt"}
+	data.tsks.tsks = testEstTasks(now, "1")
+
+	err := saveEstimates(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(saver.items))
+	assert.Equal(t, 1, saver.items[0].Position)
+	assert.NotNil(t, saver.items[0].ETA)
+}
+
+func TestSaveEstimates_PublishesChanged(t *testing.T) {
+	initTestStrategy(t)
+	publisherMock := mocks.NewMockPublisher()
+	now := time.Now()
+	data := &ServiceData{wrkrs: newWorkers(), tsks: newTasks(), fc: utils.NewMultiCloseChannel()}
+	data.estimator = newFIFOWrapper(t)
+	data.queueSaver = &testQueueSaver{}
+	data.publisher = publisherMock
+	data.wrkrs.workers["w"] = &worker{mType: "mt", endAt: now}
+	data.tsks.tsks = testEstTasks(now, "1", "2")
+
+	assert.Nil(t, saveEstimates(data))
+	publisherMock.VerifyWasCalled(pegomock.Times(2)).Publish(pegomock.AnyString(),
+		pegomock.EqString(messages.TopicStatusChange))
+
+	assert.Nil(t, saveEstimates(data))
+	publisherMock.VerifyWasCalled(pegomock.Times(2)).Publish(pegomock.AnyString(), pegomock.AnyString())
+
+	delete(data.tsks.tsks, "1")
+	assert.Nil(t, saveEstimates(data))
+	publisherMock.VerifyWasCalled(pegomock.Times(2)).Publish(pegomock.EqString("2"), pegomock.AnyString())
+}
+
+func TestEstimateChanged(t *testing.T) {
+	now := time.Now()
+	qi := func(pos int, eta *time.Time) *persistence.QueueInfo {
+		return &persistence.QueueInfo{Position: pos, ETA: eta}
+	}
+	assert.True(t, estimateChanged(nil, qi(1, nil)))
+	assert.True(t, estimateChanged(qi(2, nil), qi(1, nil)))
+	assert.True(t, estimateChanged(qi(1, nil), qi(1, &now)))
+	assert.True(t, estimateChanged(qi(1, &now), qi(1, timePtr(now.Add(-minETAChange)))))
+	assert.False(t, estimateChanged(qi(1, nil), qi(1, nil)))
+	assert.False(t, estimateChanged(qi(1, &now), qi(1, timePtr(now.Add(time.Second)))))
+}
+
+func TestSaveEstimates_Fails(t *testing.T) {
+	data := &ServiceData{wrkrs: newWorkers(), tsks: newTasks(), fc: utils.NewMultiCloseChannel()}
+	data.estimator = newFIFOWrapper(t)
+	data.queueSaver = &testQueueSaver{err: errors.New("olia")}
+
+	assert.NotNil(t, saveEstimates(data))
+}
+
+func testEstTasks(now time.Time, ids ...string) map[string]*task {
+	res := map[string]*task{}
+	for i, id := range ids {
+		res[id] = &task{msg: &messages.QueueMessage{ID: id}, addedAt: now.Add(time.Duration(i-10) * time.Second),
+			expDuration: 10 * time.Second, rtFactor: 1, requiredModelType: "mt", expModelLoadDuration: time.Minute}
+	}
+	return res
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+type testQueueSaver struct {
+	items []*persistence.QueueInfo
+	err   error
+}
+
+func (s *testQueueSaver) Save(items []*persistence.QueueInfo) error {
+	s.items = items
+	return s.err
+}
@@ -1,10 +1,15 @@
 package dispatcher
 
 import (
+	"os"
+
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/airenas/listgo/internal/pkg/config"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/mongo"
 	"github.com/airenas/listgo/internal/pkg/rabbit"
 	"github.com/airenas/listgo/internal/pkg/strategy"
+	"github.com/airenas/listgo/internal/pkg/strategy/sim"
 	"github.com/airenas/listgo/internal/pkg/utils"
 
 	"github.com/pkg/errors"
@@ -23,6 +28,13 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	cmdapp.InitApplication(rootCmd)
+	rootCmd.PersistentFlags().Int32P("port", "", 0, "HTTP port for metrics, 0 - disabled")
+	cmdapp.Config.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
+	cmdapp.Config.SetDefault("duration.bytesPerSecond", 16000)
+	cmdapp.Config.SetDefault("strategy.fairShare.tenantTag", messages.TagTenant)
+	cmdapp.Config.SetDefault("chunk.duration", "20m")
+	cmdapp.Config.SetDefault("chunk.minDuration", "1h")
+	cmdapp.Config.SetDefault("queue.estimateInterval", "10s")
 }
 
 // Execute starts the server
@@ -77,21 +89,66 @@ func run(cmd *cobra.Command, args []string) {
 	data.modelLoadDuration = cmdapp.Config.GetDuration("strategy.modelLoadDuration")
 	data.rtFactor = cmdapp.Config.GetFloat64("strategy.realTimeFactor")
 	cmdapp.Log.Infof("Dispatch params: modelLoadTime=%v, rt=%f", data.modelLoadDuration, data.rtFactor)
-	strg, err := strategy.NewCost()
+	strategyName := cmdapp.Config.GetString("strategy.name")
+	cmdapp.Log.Infof("Strategy: '%s'", strategyName)
+	strg, err := strategy.New(strategyName)
 	cmdapp.CheckOrPanic(err, "Can't init strategy")
-	data.selectionStrategy, err = newStrategyWrapper(strg)
+	if cmdapp.Config.GetBool("strategy.fairShare.enabled") {
+		cmdapp.Log.Info("Fair share mode enabled")
+		strg, err = strategy.NewFairShare(strg)
+		cmdapp.CheckOrPanic(err, "Can't init fair share strategy")
+	}
+	sw, err := newStrategyWrapper(strg)
 	cmdapp.CheckOrPanic(err, "Can't init strategy wrapper")
+	data.selectionStrategy = sw
 
 	recProvider, err := config.NewFileRecognizerInfoLoader(cmdapp.Config.GetString("recognizerConfig.path"))
 	cmdapp.CheckOrPanic(err, "Can't init recognizer config (Did you provide correct setting 'recognizerConfig.path'?)")
 	data.modelTypeGetter, err = newTypeGetter(recProvider, cmdapp.Config.GetString("recognizerConfig.key"))
 	cmdapp.CheckOrPanic(err, "Can't init model type getter. recognizerConfig.key config missing?")
-	data.durationGetter, err = newDurationLoader(cmdapp.Config.GetString("duration.pathPattern"))
-	cmdapp.CheckOrPanic(err, "Can't init duration loader. duration.pathPattern config missing?")
+	var mongoSessionProvider *mongo.SessionProvider
+	if cmdapp.Config.GetString("mongo.url") != "" {
+		mongoSessionProvider, err = mongo.NewSessionProvider()
+		cmdapp.CheckOrPanic(err, "Can't init mongo")
+		defer mongoSessionProvider.Close()
+	}
+	data.durationGetter, err = initDurationGetter(mongoSessionProvider)
+	cmdapp.CheckOrPanic(err, "Can't init duration getter")
+	data.estimateInterval = cmdapp.Config.GetDuration("queue.estimateInterval")
+	if mongoSessionProvider != nil && data.estimateInterval > 0 {
+		data.estimator = sw
+		data.queueSaver, err = mongo.NewQueueSaver(mongoSessionProvider)
+		cmdapp.CheckOrPanic(err, "Can't init queue saver")
+		err = msgChannelProvider.RunOnChannelWithRetry(func(ch *amqp.Channel) error {
+			return rabbit.DeclareExchange(ch, msgChannelProvider.QueueName(messages.TopicStatusChange))
+		})
+		cmdapp.CheckOrPanic(err, "Can't init event exchange")
+		data.publisher = rabbit.NewPublisher(msgChannelProvider)
+	}
 	data.startTimeGetter = newTimeGetter()
+	tenantTag, tenantSep := cmdapp.Config.GetString("strategy.fairShare.tenantTag"),
+		cmdapp.Config.GetString("strategy.fairShare.tenantPrefixSeparator")
+	if tenantTag == messages.TagExternalID && tenantSep == "" {
+		cmdapp.Log.Warn("No strategy.fairShare.tenantPrefixSeparator for external ID tenants, every job is a tenant")
+	}
+	data.tenantGetter, err = newTenantGetter(tenantTag, tenantSep)
+	cmdapp.CheckOrPanic(err, "Can't init tenant getter")
+	if cmdapp.Config.GetBool("chunk.enabled") {
+		data.chunker, err = initChunker()
+		cmdapp.CheckOrPanic(err, "Can't init chunker")
+	}
+	if tf := cmdapp.Config.GetString("dispatcher.traceFile"); tf != "" {
+		cmdapp.Log.Infof("Recording trace to %s", tf)
+		f, err := os.OpenFile(tf, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		cmdapp.CheckOrPanic(err, "Can't open trace file")
+		defer f.Close()
+		data.traceWriter, err = sim.NewTraceWriter(f)
+		cmdapp.CheckOrPanic(err, "Can't init trace writer")
+	}
 
 	err = StartWorkerService(&data)
 	cmdapp.CheckOrPanic(err, "Can't start service")
+	startWebServer(cmdapp.Config.GetInt("port"))
 
 	<-data.fc.C
 	cmdapp.Log.Infof("Bye")
@@ -108,6 +165,57 @@ func validateConfig() error {
 	return nil
 }
 
+// /////////////////////////////////////////////////////////////////////////
+// initDurationGetter prepares duration sources: metadata -> segments file -> size based estimate
+func initDurationGetter(sp *mongo.SessionProvider) (*durationChain, error) {
+	sources := make([]durationSource, 0)
+	var requests RequestGetter
+	if sp != nil {
+		rp, err := mongo.NewRequestProvider(sp)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't init request provider")
+		}
+		requests = rp
+		mg, err := newMetaDurationGetter(requests)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, durationSource{name: "metadata", getter: mg})
+	} else {
+		cmdapp.Log.Warn("No mongo.url configured, skip duration from metadata")
+	}
+	if pp := cmdapp.Config.GetString("duration.pathPattern"); pp != "" {
+		dl, err := newDurationLoader(pp)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't init duration loader")
+		}
+		sources = append(sources, durationSource{name: "segments", getter: dl})
+	}
+	if requests != nil {
+		sg, err := newSizeDurationGetter(requests, cmdapp.Config.GetFloat64("duration.bytesPerSecond"))
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't init size based duration estimator")
+		}
+		sources = append(sources, durationSource{name: "size", getter: sg})
+	}
+	for _, s := range sources {
+		cmdapp.Log.Infof("Duration source: %s", s.name)
+	}
+	return newDurationChain(sources...)
+}
+
+// /////////////////////////////////////////////////////////////////////////
+func initChunker() (*chunker, error) {
+	dl, err := newDurationLoader(cmdapp.Config.GetString("duration.pathPattern"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't init segments loader. duration.pathPattern config missing?")
+	}
+	minDuration := cmdapp.Config.GetDuration("chunk.minDuration")
+	duration := cmdapp.Config.GetDuration("chunk.duration")
+	cmdapp.Log.Infof("Chunked transcription: minDuration=%v, chunk=%v", minDuration, duration)
+	return newChunker(dl, minDuration, duration)
+}
+
 // /////////////////////////////////////////////////////////////////////////
 func initRegistrationQueue(prv *rabbit.ChannelProvider, qName string) error {
 	return prv.RunOnChannelWithRetry(func(ch *amqp.Channel) error {
@@ -7,6 +7,8 @@ import (
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/strategy/sim"
 	"github.com/airenas/listgo/internal/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
@@ -32,6 +34,27 @@ type StartTimeGetter interface {
 	Get(tags []messages.Tag) (time.Time, error)
 }
 
+// TenantGetter type provides tenant key for the transcription
+type TenantGetter interface {
+	Get(tags []messages.Tag) string
+}
+
+// TraceWriter records dispatcher events for the strategy simulation
+type TraceWriter interface {
+	Write(e *sim.Event) error
+}
+
+// QueueEstimator calculates queue positions and completion times of the tasks
+type QueueEstimator interface {
+	Snapshot(wrks []*worker, tsks map[string]*task) *queueSnapshot
+	Estimate(q *queueSnapshot, now time.Time) (map[string]*estimate, error)
+}
+
+// QueueSaver publishes the queue estimates
+type QueueSaver interface {
+	Save(items []*persistence.QueueInfo) error
+}
+
 // ServiceData keeps data required for service work
 type ServiceData struct {
 	fc    *utils.MultiCloseChannel
@@ -45,6 +68,16 @@ type ServiceData struct {
 	startTimeGetter StartTimeGetter
 	modelTypeGetter ModelTypeGetter
 	durationGetter  DurationGetter
+	tenantGetter    TenantGetter
+	traceWriter     TraceWriter
+	chunker         *chunker
+
+	estimator        QueueEstimator
+	queueSaver       QueueSaver
+	estimateInterval time.Duration
+	// publisher notifies the status service about the changed estimates, optional
+	publisher     messages.Publisher
+	lastEstimates map[string]*persistence.QueueInfo
 
 	replySender messages.Sender
 	workSender  messages.Sender
@@ -71,6 +104,10 @@ func StartWorkerService(data *ServiceData) error {
 	go listenWorkQueue(data)
 	go listenResponseQueue(data)
 	go cleanFailingTasks(data)
+	if data.queueSaver != nil {
+		cmdapp.Log.Infof("Publishing queue estimates every %v", data.estimateInterval)
+		go publishEstimates(data, data.estimateInterval)
+	}
 
 	return nil
 }
@@ -103,6 +140,9 @@ func validate(data *ServiceData) error {
 	if data.startTimeGetter == nil {
 		return errors.New("No start time getter")
 	}
+	if data.tenantGetter == nil {
+		return errors.New("No tenant getter")
+	}
 	if data.fc == nil {
 		return errors.New("No quit channel")
 	}
@@ -112,6 +152,12 @@ func validate(data *ServiceData) error {
 	if data.wrkrs == nil {
 		return errors.New("No workers channel")
 	}
+	if data.queueSaver != nil && data.estimator == nil {
+		return errors.New("No queue estimator")
+	}
+	if data.queueSaver != nil && data.estimateInterval <= 0 {
+		return errors.New("Wrong queue estimate interval")
+	}
 	return nil
 }
 
@@ -168,9 +214,26 @@ func processRegistrationMsg(data *ServiceData, d *amqp.Delivery) error {
 	if err := json.Unmarshal(d.Body, &message); err != nil {
 		return errors.Wrap(err, "Can't unmarshal message "+string(d.Body))
 	}
+	traceWorker(data, &message)
 	return processWorker(data.wrkrs, &message)
 }
 
+var workerEvents = map[string]string{messages.RgrTypeRegister: sim.EventWorker,
+	messages.RgrTypeDrain: sim.EventWorkerDrain, messages.RgrTypeExit: sim.EventWorkerExit}
+
+// traceWorker records the worker event with the worker's most recently used model type
+func traceWorker(data *ServiceData, msg *messages.RegistrationMessage) {
+	et, ok := workerEvents[msg.Type]
+	if !ok {
+		return
+	}
+	e := &sim.Event{Type: et, At: time.Now(), ID: msg.Queue}
+	if len(msg.ModelTypes) > 0 {
+		e.TaskType = msg.ModelTypes[0]
+	}
+	trace(data, e)
+}
+
 func processWorkMsg(data *ServiceData, d amqp.Delivery) error {
 	var msg messages.QueueMessage
 	if err := json.Unmarshal(d.Body, &msg); err != nil {
@@ -199,9 +262,28 @@ func addTask(data *ServiceData, d *amqp.Delivery, msg *messages.QueueMessage) er
 	if err != nil {
 		cmdapp.Log.Error("Can't get model type. ", err)
 	}
+	t.tenant = data.tenantGetter.Get(msg.Tags)
+	trace(data, &sim.Event{Type: sim.EventTask, At: t.addedAt, ID: msg.ID, TaskType: t.requiredModelType,
+		Duration: t.expDuration.Seconds(), Tenant: t.tenant})
+	if data.chunker != nil {
+		chunks, err := data.chunker.split(msg.ID)
+		if err != nil {
+			cmdapp.Log.Error("Can't split into chunks. ", err)
+		} else if len(chunks) > 0 {
+			cmdapp.Log.Infof("Split %s into %d chunks", msg.ID, len(chunks))
+			t.children = newChunkTasks(t, chunks)
+			t.expDuration = 0 // only joining of the results remains for the parent
+		}
+	}
 	return data.tsks.addTask(t)
 }
 
+func trace(data *ServiceData, e *sim.Event) {
+	if data.traceWriter != nil {
+		cmdapp.LogIf(data.traceWriter.Write(e))
+	}
+}
+
 var changedStartup sync.Once
 
 // the main task deliver procedure
@@ -216,13 +298,14 @@ func changed(data *ServiceData) {
 
 	data.wrkrs.log()
 
+	// draining workers are passed to account their running tasks
 	wrks := make([]*worker, 0)
 	for _, k := range data.wrkrs.workers {
 		wrks = append(wrks, k)
 	}
 	cmdapp.Log.Infof("Workers: %d, tasks: %d", len(wrks), len(data.tsks.tsks))
 	for i, w := range wrks {
-		if w.working == false {
+		if w.working == false && !w.draining {
 			t, err := data.selectionStrategy.FindBest(wrks, data.tsks.tsks, i)
 			if err != nil {
 				cmdapp.Log.Error("Can't get task", err)
@@ -1,11 +1,14 @@
 package dispatcher
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/strategy/sim"
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
 	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
 	"github.com/airenas/listgo/internal/pkg/utils"
@@ -40,6 +43,7 @@ func initTestData(t *testing.T) *ServiceData {
 	data.startTimeGetter = startTimeGetterMock
 	data.durationGetter = durGetterMock
 	data.modelTypeGetter = modelTypeGetterMock
+	data.tenantGetter, _ = newTenantGetter(messages.TagExternalID, "")
 	data.selectionStrategy, _ = newStrategyWrapper(taskSelectorMock)
 
 	data.replySender = msgSenderMock
@@ -94,6 +98,10 @@ func TestServiceInit_Fails(t *testing.T) {
 	data = initTestData(t)
 	data.tsks = nil
 	assert.NotNil(t, StartWorkerService(data))
+
+	data = initTestData(t)
+	data.tenantGetter = nil
+	assert.NotNil(t, StartWorkerService(data))
 }
 
 func TestServiceAddTask(t *testing.T) {
@@ -101,7 +109,7 @@ func TestServiceAddTask(t *testing.T) {
 	data := initTestData(t)
 	err := StartWorkerService(data)
 	assert.Nil(t, err)
-	msg := messages.NewQueueMessage("ID", "model", nil)
+	msg := messages.NewQueueMessage("ID", "model", []messages.Tag{messages.NewTag(messages.TagExternalID, "cl")})
 	d := newTestDelivery(msg)
 	now := time.Now()
 	pegomock.When(startTimeGetterMock.Get(matchers.AnySliceOfMessagesTag())).ThenReturn(now, nil)
@@ -119,6 +127,7 @@ func TestServiceAddTask(t *testing.T) {
 	assert.Equal(t, now, tsk.addedAt)
 	assert.Equal(t, "mmm", tsk.requiredModelType)
 	assert.Equal(t, time.Second, tsk.expDuration)
+	assert.Equal(t, "cl", tsk.tenant)
 }
 
 func TestServiceAddOnFailure(t *testing.T) {
@@ -145,3 +154,86 @@ func TestServiceAddOnFailure(t *testing.T) {
 	assert.Equal(t, "", tsk.requiredModelType)
 	assert.Equal(t, time.Second, tsk.expDuration)
 }
+
+func TestServiceAddTask_Chunks(t *testing.T) {
+	initTest(t)
+	data := initTestData(t)
+	data.chunker = newTestChunker(t, "a 1 0 7000 a\na 1 7000 6000 a")
+	msg := messages.NewQueueMessage("ID", "model", nil)
+	d := newTestDelivery(msg)
+	pegomock.When(startTimeGetterMock.Get(matchers.AnySliceOfMessagesTag())).ThenReturn(time.Now(), nil)
+	pegomock.When(modelTypeGetterMock.Get(pegomock.AnyString())).ThenReturn("mmm", nil)
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Second*130, nil)
+
+	err := addTask(data, d, msg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(data.tsks.tsks))
+	tsk := data.tsks.tsks["ID"]
+	assert.Equal(t, 2, tsk.pendingChunks)
+	assert.Equal(t, time.Duration(0), tsk.expDuration)
+	assert.Equal(t, "mmm", data.tsks.tsks["ID_chunk_1"].requiredModelType)
+	assert.Equal(t, time.Second*60, data.tsks.tsks["ID_chunk_1"].expDuration)
+}
+
+func TestServiceAddTask_Trace(t *testing.T) {
+	initTest(t)
+	data := initTestData(t)
+	b := &bytes.Buffer{}
+	data.traceWriter, _ = sim.NewTraceWriter(b)
+	msg := messages.NewQueueMessage("ID", "model", nil)
+	d := newTestDelivery(msg)
+	pegomock.When(startTimeGetterMock.Get(matchers.AnySliceOfMessagesTag())).ThenReturn(time.Now(), nil)
+	pegomock.When(modelTypeGetterMock.Get(pegomock.AnyString())).ThenReturn("mmm", nil)
+	pegomock.When(durGetterMock.Get(pegomock.AnyString())).ThenReturn(time.Second*10, nil)
+
+	err := addTask(data, d, msg)
+
+	assert.Nil(t, err)
+	evs, err := sim.ReadTrace(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(evs))
+	assert.Equal(t, sim.EventTask, evs[0].Type)
+	assert.Equal(t, "ID", evs[0].ID)
+	assert.Equal(t, "mmm", evs[0].TaskType)
+	assert.Equal(t, 10.0, evs[0].Duration)
+}
+
+func TestChanged_SkipsDrainingWorkers(t *testing.T) {
+	initTest(t)
+	data := initTestData(t)
+	changedStartup.Do(func() {})
+	data.wrkrs.workers["1"] = &worker{queue: "1"}
+	data.wrkrs.workers["2"] = &worker{queue: "2", draining: true}
+
+	changed(data)
+
+	wrks, _, wi := taskSelectorMock.VerifyWasCalledOnce().FindBest(matchers.AnySliceOfPtrToApiWorker(),
+		matchers.AnySliceOfPtrToApiTask(), pegomock.AnyInt()).GetCapturedArguments()
+	assert.Equal(t, 2, len(wrks))
+	assert.False(t, wrks[wi].Draining)
+	assert.True(t, wrks[1-wi].Draining)
+}
+
+func TestProcessRegistrationMsg_Trace(t *testing.T) {
+	initTest(t)
+	data := initTestData(t)
+	b := &bytes.Buffer{}
+	data.traceWriter, _ = sim.NewTraceWriter(b)
+
+	for _, mt := range []string{messages.RgrTypeRegister, messages.RgrTypeBeat, messages.RgrTypeDrain,
+		messages.RgrTypeExit} {
+		body, _ := json.Marshal(messages.RegistrationMessage{Queue: "w", Type: mt, Timestamp: time.Now().Unix(),
+			ModelTypes: []string{"mmm", "ooo"}})
+		assert.Nil(t, processRegistrationMsg(data, &amqp.Delivery{Body: body}))
+	}
+
+	evs, err := sim.ReadTrace(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(evs))
+	for i, et := range []string{sim.EventWorker, sim.EventWorkerDrain, sim.EventWorkerExit} {
+		assert.Equal(t, et, evs[i].Type)
+		assert.Equal(t, "w", evs[i].ID)
+		assert.Equal(t, "mmm", evs[i].TaskType)
+	}
+}
@@ -45,6 +45,12 @@ func mapWorkers(wrks []*worker) []*api.Worker {
 		nw := &api.Worker{}
 		nw.EndAt = w.endAt
 		nw.TaskType = w.mType
+		nw.Loaded = w.warm
+		nw.Working = w.working
+		nw.Draining = w.draining
+		if w.task != nil {
+			nw.Tenant = w.task.tenant
+		}
 		res[i] = nw
 	}
 	return res
@@ -53,11 +59,12 @@ func mapWorkers(wrks []*worker) []*api.Worker {
 func mapTasks(tsks map[string]*task) []*api.Task {
 	res := make([]*api.Task, 0)
 	for _, v := range tsks {
-		if !v.started && v.failCount < maxTaskFailCount {
+		if !v.started && v.failCount < maxTaskFailCount && !v.waitsForChunks() && v.chunkErr == "" {
 			nt := &api.Task{}
 			nt.TaskType = v.requiredModelType
 			nt.Duration = v.expDuration
 			nt.ArrivedAt = v.addedAt
+			nt.Tenant = v.tenant
 			nt.RealObject = v
 			res = append(res, nt)
 		}
@@ -39,13 +39,22 @@ func TestStrategy_MapsWorker(t *testing.T) {
 	assert.Equal(t, 1, len(res))
 	assert.Equal(t, "olia", res[0].TaskType)
 	assert.Equal(t, now, res[0].EndAt)
+	assert.False(t, res[0].Working)
+}
+
+func TestStrategy_MapsWorkerTenant(t *testing.T) {
+	res := mapWorkers([]*worker{{working: true, task: &task{tenant: "cl"}}})
+	assert.Equal(t, 1, len(res))
+	assert.True(t, res[0].Working)
+	assert.Equal(t, "cl", res[0].Tenant)
 }
 
 func TestStrategy_MapsTask(t *testing.T) {
 	now := time.Now()
-	tsk := &task{addedAt: now, expDuration: time.Second, requiredModelType: "olia", started: false}
+	tsk := &task{addedAt: now, expDuration: time.Second, requiredModelType: "olia", started: false, tenant: "cl"}
 	res := mapTasks(map[string]*task{"1": tsk})
 	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "cl", res[0].Tenant)
 	assert.Equal(t, "olia", res[0].TaskType)
 	assert.Equal(t, time.Second, res[0].Duration)
 	assert.Equal(t, now, res[0].ArrivedAt)
@@ -67,6 +76,16 @@ func TestStrategy_MapsTask_SkipsFailed(t *testing.T) {
 	assert.Equal(t, 0, len(res))
 }
 
+func TestStrategy_MapsTask_SkipsWaitingForChunks(t *testing.T) {
+	tsk := &task{addedAt: time.Now(), expDuration: time.Second, requiredModelType: "olia", pendingChunks: 1}
+	res := mapTasks(map[string]*task{"1": tsk})
+	assert.Equal(t, 0, len(res))
+	tsk.pendingChunks = 0
+	tsk.chunkErr = "err"
+	res = mapTasks(map[string]*task{"1": tsk})
+	assert.Equal(t, 0, len(res))
+}
+
 func TestStrategy_MapsFailedTask(t *testing.T) {
 	now := time.Now()
 	tsk := &task{addedAt: now, expDuration: time.Second, requiredModelType: "olia", started: false}
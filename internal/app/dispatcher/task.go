@@ -1,8 +1,12 @@
 package dispatcher
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
@@ -13,6 +17,9 @@ import (
 
 const maxTaskFailCount = 10
 
+// attemptSeq numbers the deliveries of chunk tasks, so replies of an old attempt are not taken for the new one
+var attemptSeq uint64
+
 type task struct {
 	d   *amqp.Delivery
 	msg *messages.QueueMessage
@@ -22,11 +29,20 @@ type task struct {
 	expModelLoadDuration time.Duration
 	addedAt              time.Time
 	rtFactor             float64
+	tenant               string
 
 	worker    *worker
 	started   bool
 	failCount int32
 	startedAt time.Time
+	attempt   uint64
+
+	// chunked transcription: parent waits for children, then it is scheduled to join the results
+	parent        *task
+	chunkIndex    int
+	children      []*task
+	pendingChunks int
+	chunkErr      string
 }
 
 type tasks struct {
@@ -49,10 +65,85 @@ func newTasks() *tasks {
 	return res
 }
 
+func newChunkTasks(p *task, chunks []chunk) []*task {
+	res := make([]*task, len(chunks))
+	for i, c := range chunks {
+		ct := newTask()
+		ct.parent = p
+		ct.chunkIndex = i
+		ct.msg = messages.NewQueueMessageFromM(p.msg)
+		ct.msg.Tags = append(chunkTags(p.msg.Tags),
+			messages.Tag{Key: messages.TagChunkIndex, Value: strconv.Itoa(i)},
+			messages.Tag{Key: messages.TagChunkCount, Value: strconv.Itoa(len(chunks))},
+			messages.Tag{Key: messages.TagChunkFrom, Value: strconv.FormatInt(toFrames(c.from), 10)},
+			messages.Tag{Key: messages.TagChunkTo, Value: strconv.FormatInt(toFrames(c.to), 10)},
+			messages.NewTag(messages.TagAudioDuration, messages.DurationValue(c.to-c.from)))
+		ct.requiredModelType = p.requiredModelType
+		ct.expDuration = c.to - c.from
+		ct.expModelLoadDuration = p.expModelLoadDuration
+		ct.addedAt = p.addedAt
+		ct.rtFactor = p.rtFactor
+		ct.tenant = p.tenant
+		res[i] = ct
+	}
+	return res
+}
+
+// chunkTags copies the parent tags without the ones describing the whole audio,
+// the chunk gets its own audio_duration
+func chunkTags(tags []messages.Tag) []messages.Tag {
+	res := make([]messages.Tag, 0, len(tags))
+	for _, t := range tags {
+		if t.Key != messages.TagAudioDuration {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// corrID returns the key of the task in the task list, it is used as correlation ID for the worker
+func (t *task) corrID() string {
+	if t.parent != nil {
+		return fmt.Sprintf("%s_chunk_%d", t.msg.ID, t.chunkIndex)
+	}
+	return t.msg.ID
+}
+
+// sendCorrID returns the correlation ID of the current delivery,
+// chunk tasks get the attempt appended as they may be redelivered under the same key
+func (t *task) sendCorrID() string {
+	if t.parent != nil {
+		return fmt.Sprintf("%s#%d", t.corrID(), t.attempt)
+	}
+	return t.corrID()
+}
+
+// splitCorrID returns the task key and the attempt of the delivery
+func splitCorrID(id string) (string, uint64) {
+	i := strings.LastIndex(id, "#")
+	if i < 0 {
+		return id, 0
+	}
+	a, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if err != nil {
+		return id, 0
+	}
+	return id[:i], a
+}
+
+func (t *task) waitsForChunks() bool {
+	return t.pendingChunks > 0
+}
+
 func failRequeueTask(t *task) {
+	requeueTask(t)
+	t.failCount++
+}
+
+// requeueTask returns the task to the queue without counting it as a failure
+func requeueTask(t *task) {
 	t.worker = nil
 	t.started = false
-	t.failCount++
 }
 
 func (ts *tasks) addTask(t *task) error {
@@ -74,8 +165,14 @@ func (ts *tasks) addTask(t *task) error {
 			cmdapp.Log.Warnf("Hmm. What to do with old task worker. Marking as free %s", ot.worker.queue)
 			ot.worker.completeTask()
 		}
+		ts.dropChunks(ot, true)
 	}
 	ts.tsks[t.msg.ID] = t
+	for _, c := range t.children {
+		c.parent = t
+		ts.tsks[c.corrID()] = c
+	}
+	t.pendingChunks = len(t.children)
 	go ts.changedFunc()
 	return nil
 }
@@ -86,6 +183,14 @@ func (ts *tasks) cleanFailing(sender messages.Sender) error {
 	var lastErr error
 	for k, v := range ts.tsks {
 		if !v.started && v.failCount >= maxTaskFailCount {
+			if v.parent != nil {
+				cmdapp.Log.Warnf("Drop failing chunk %s", k)
+				ts.failChunk(v, fmt.Sprintf("Chunk processing failed %d times", v.failCount))
+				continue
+			}
+			if v.waitsForChunks() {
+				continue
+			}
 			err := sendFailureResponse(v, sender)
 			if err != nil {
 				lastErr = err
@@ -95,16 +200,29 @@ func (ts *tasks) cleanFailing(sender messages.Sender) error {
 			}
 		}
 	}
+	for k, v := range ts.tsks {
+		if v.chunkErr != "" && !v.waitsForChunks() {
+			err := sendFailure(v, v.chunkErr, sender)
+			if err != nil {
+				lastErr = err
+			} else {
+				delete(ts.tsks, k)
+			}
+		}
+	}
 	return lastErr
 }
 
 func sendFailureResponse(t *task, sender messages.Sender) error {
+	cmdapp.Log.Infof("Sending failure for the task %s as it faile for %d times", t.msg.ID, t.failCount)
+	return sendFailure(t, fmt.Sprintf("Message processing failed %d times", t.failCount), sender)
+}
+
+func sendFailure(t *task, errStr string, sender messages.Sender) error {
 	id := t.msg.ID
-	cmdapp.Log.Infof("Sending failure for the task %s as it faile for %d times", id, t.failCount)
 	acked := false
 	if t.d.ReplyTo != "" {
-		err := sender.Send(messages.NewQueueMsgWithError(t.msg.ID, fmt.Sprintf("Message processing failed %d times",
-			t.failCount)), t.d.ReplyTo, "")
+		err := sender.Send(messages.NewQueueMsgWithError(t.msg.ID, errStr), t.d.ReplyTo, "")
 		if err != nil {
 			cmdapp.Log.Error("Can't reply result", err)
 			err := t.d.Nack(false, !t.d.Redelivered) // try redeliver for first time
@@ -127,11 +245,17 @@ func sendFailureResponse(t *task, sender messages.Sender) error {
 func (ts *tasks) processResponse(d *amqp.Delivery, sender messages.Sender) error {
 	ts.lock.Lock()
 	defer ts.lock.Unlock()
-	id := d.CorrelationId
-	cmdapp.Log.Infof("Process response message %s", id)
+	cmdapp.Log.Infof("Process response message %s", d.CorrelationId)
+	id, attempt := splitCorrID(d.CorrelationId)
 	t, f := ts.tsks[id]
 	if !f {
-		return errors.Errorf("Hmm, correlation ID '%s' not found in task list, old task arrived?", id)
+		return errors.Errorf("Hmm, correlation ID '%s' not found in task list, old task arrived?", d.CorrelationId)
+	}
+	if t.parent != nil {
+		if !t.started || t.attempt != attempt {
+			return errors.Errorf("Correlation ID '%s' is of an old chunk attempt", d.CorrelationId)
+		}
+		return ts.processChunkResponse(t, d, sender)
 	}
 
 	acked := false
@@ -171,8 +295,9 @@ func (ts *tasks) processResponse(d *amqp.Delivery, sender messages.Sender) error
 }
 
 func (t *task) startOn(w *worker, sender messages.Sender) error {
-	cmdapp.Log.Infof("Delivering task(%s) %s to %s", t.requiredModelType, t.msg.ID, w.queue)
-	err := sender.Send(t.msg, w.queue, t.msg.ID)
+	t.attempt = atomic.AddUint64(&attemptSeq, 1)
+	cmdapp.Log.Infof("Delivering task(%s) %s to %s", t.requiredModelType, t.sendCorrID(), w.queue)
+	err := sender.Send(t.msg, w.queue, t.sendCorrID())
 	if err != nil {
 		t.failCount++
 		return errors.Wrap(err, "Can't send msg")
@@ -187,3 +312,71 @@ func (t *task) startOn(w *worker, sender messages.Sender) error {
 	t.startedAt = time.Now()
 	return nil
 }
+
+func (ts *tasks) processChunkResponse(t *task, d *amqp.Delivery, sender messages.Sender) error {
+	id := t.corrID()
+	if t.worker != nil {
+		err := t.worker.completeTask()
+		if err != nil {
+			cmdapp.Log.Error("Can'not mark worker as completed", err)
+		}
+	} else {
+		cmdapp.Log.Error("Task has no worker")
+	}
+	delete(ts.tsks, id)
+	var msg messages.QueueMessage
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		msg.Error = "Can't unmarshal chunk response"
+		cmdapp.Log.Error(msg.Error, err)
+	}
+	p := t.parent
+	p.pendingChunks--
+	if msg.Error != "" {
+		cmdapp.Log.Warnf("Chunk %s failed: %s", id, msg.Error)
+		ts.failChunk(t, msg.Error)
+	}
+	if !p.waitsForChunks() {
+		if p.chunkErr != "" {
+			err := sendFailure(p, p.chunkErr, sender)
+			if err != nil {
+				cmdapp.Log.Error("Can't send failure", err)
+			}
+			delete(ts.tsks, p.msg.ID)
+		} else {
+			cmdapp.Log.Infof("All chunks done for %s, scheduling join", p.msg.ID)
+			p.msg.Tags = append(p.msg.Tags, messages.Tag{Key: messages.TagChunkJoin, Value: strconv.Itoa(len(p.children))})
+		}
+	}
+	go ts.changedFunc()
+	return nil
+}
+
+// failChunk marks the parent as failed and drops the chunks that are not started yet
+func (ts *tasks) failChunk(t *task, errStr string) {
+	p := t.parent
+	if p.chunkErr == "" {
+		p.chunkErr = errStr
+	}
+	if _, f := ts.tsks[t.corrID()]; f && !t.started {
+		delete(ts.tsks, t.corrID())
+		p.pendingChunks--
+	}
+	p.pendingChunks -= ts.dropChunks(p, false)
+}
+
+// dropChunks removes children from the task list, returns removed count
+func (ts *tasks) dropChunks(p *task, all bool) int {
+	res := 0
+	for _, c := range p.children {
+		ct, f := ts.tsks[c.corrID()]
+		if !f || ct != c || (c.started && !all) {
+			continue
+		}
+		if c.worker != nil {
+			c.worker.completeTask()
+		}
+		delete(ts.tsks, c.corrID())
+		res++
+	}
+	return res
+}
@@ -2,7 +2,9 @@ package dispatcher
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
@@ -206,6 +208,193 @@ func TestCleanFailing(t *testing.T) {
 		Send(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString())
 }
 
+func newTestChunkedTask(n int) *task {
+	tsk := newTask()
+	tsk.msg = messages.NewQueueMessage("cID", "res", []messages.Tag{messages.NewTag("a", "b"),
+		messages.NewTag(messages.TagAudioDuration, "120.00")})
+	tsk.d = newTestDelivery(tsk.msg)
+	tsk.d.ReplyTo = "rQ"
+	chunks := make([]chunk, n)
+	for i := range chunks {
+		chunks[i] = chunk{from: time.Duration(i) * time.Minute, to: time.Duration(i+1) * time.Minute}
+	}
+	tsk.children = newChunkTasks(tsk, chunks)
+	return tsk
+}
+
+func newTestChunkResponse(ct *task, errStr string) *amqp.Delivery {
+	msg := messages.NewQueueMessage("cID", "res", nil)
+	msg.Error = errStr
+	res := newTestDelivery(msg)
+	res.CorrelationId = ct.sendCorrID()
+	return res
+}
+
+func TestAddTask_Chunks(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsk := newTestChunkedTask(2)
+
+	err := tsks.addTask(tsk)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(tsks.tsks))
+	assert.Equal(t, 2, tsk.pendingChunks)
+	ct := tsks.tsks["cID_chunk_1"]
+	assert.NotNil(t, ct)
+	assert.Equal(t, tsk, ct.parent)
+	assert.Equal(t, "cID", ct.msg.ID)
+	assert.Equal(t, []messages.Tag{messages.NewTag("a", "b"), messages.NewTag(messages.TagChunkIndex, "1"),
+		messages.NewTag(messages.TagChunkCount, "2"), messages.NewTag(messages.TagChunkFrom, "6000"),
+		messages.NewTag(messages.TagChunkTo, "12000"), messages.NewTag(messages.TagAudioDuration, "60.00")},
+		ct.msg.Tags)
+	assert.Equal(t, time.Minute, ct.expDuration)
+	assert.Equal(t, 2, len(tsk.msg.Tags))
+}
+
+func TestAddTask_OnExisting_DropsChunks(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsks.addTask(newTestChunkedTask(2))
+	tsk := newTask()
+	tsk.msg = messages.NewQueueMessage("cID", "res", nil)
+	tsk.d = newTestDelivery(tsk.msg)
+
+	err := tsks.addTask(tsk)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tsks.tsks))
+}
+
+func TestStartOn_Chunk(t *testing.T) {
+	initTestTask(t)
+	tsk := newTestChunkedTask(2).children[1]
+	w := newWorker()
+	err := tsk.startOn(w, msgSenderMock)
+	assert.Nil(t, err)
+	_, _, cID := msgSenderMock.VerifyWasCalledOnce().Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, tsk.sendCorrID(), cID)
+	assert.True(t, strings.HasPrefix(cID, "cID_chunk_1#"))
+}
+
+func TestProcessResponse_ChunkOldAttempt(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsks.addTask(newTestChunkedTask(2))
+	old := tsks.tsks["cID_chunk_0"]
+	old.startOn(newWorker(), msgSenderMock)
+	oldResp := newTestChunkResponse(old, "")
+	tsk := newTestChunkedTask(2)
+	tsks.addTask(tsk)
+
+	err := tsks.processResponse(oldResp, msgSenderMock)
+	assert.NotNil(t, err)
+	tsk.children[0].startOn(newWorker(), msgSenderMock)
+	assert.NotEqual(t, oldResp.CorrelationId, tsk.children[0].sendCorrID())
+	err = tsks.processResponse(oldResp, msgSenderMock)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, tsk.pendingChunks)
+
+	err = tsks.processResponse(newTestChunkResponse(tsk.children[0], ""), msgSenderMock)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, tsk.pendingChunks)
+}
+
+func TestSplitCorrID(t *testing.T) {
+	for _, tc := range []struct {
+		in, id  string
+		attempt uint64
+	}{
+		{"a", "a", 0},
+		{"a_chunk_1#5", "a_chunk_1", 5},
+		{"a#b", "a#b", 0},
+		{"a#b#12", "a#b", 12},
+	} {
+		id, a := splitCorrID(tc.in)
+		assert.Equal(t, tc.id, id, tc.in)
+		assert.Equal(t, tc.attempt, a, tc.in)
+	}
+}
+
+func TestProcessResponse_Chunks(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsk := newTestChunkedTask(2)
+	tsks.addTask(tsk)
+	for _, ct := range tsk.children {
+		ct.startOn(newWorker(), msgSenderMock)
+	}
+
+	err := tsks.processResponse(newTestChunkResponse(tsk.children[0], ""), msgSenderMock)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tsks.tsks))
+	assert.Equal(t, 1, tsk.pendingChunks)
+	err = tsks.processResponse(newTestChunkResponse(tsk.children[1], ""), msgSenderMock)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tsks.tsks))
+	assert.False(t, tsk.waitsForChunks())
+	assert.Equal(t, messages.NewTag(messages.TagChunkJoin, "2"), tsk.msg.Tags[len(tsk.msg.Tags)-1])
+	msgSenderMock.VerifyWasCalled(pegomock.Times(2)).
+		Send(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString())
+	ackMock.VerifyWasCalled(pegomock.Never()).Ack(pegomock.AnyUint64(), pegomock.AnyBool())
+}
+
+func TestProcessResponse_ChunkFails(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsk := newTestChunkedTask(3)
+	tsks.addTask(tsk)
+	tsk.children[0].startOn(newWorker(), msgSenderMock)
+	tsk.children[1].startOn(newWorker(), msgSenderMock)
+
+	err := tsks.processResponse(newTestChunkResponse(tsk.children[0], "olia"), msgSenderMock)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tsks.tsks))
+	assert.Equal(t, "olia", tsk.chunkErr)
+	assert.Equal(t, 1, tsk.pendingChunks)
+
+	err = tsks.processResponse(newTestChunkResponse(tsk.children[1], ""), msgSenderMock)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tsks.tsks))
+	cMsg, cQ, _ := msgSenderMock.VerifyWasCalled(pegomock.Times(3)).
+		Send(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString()).GetAllCapturedArguments()
+	assert.Equal(t, "rQ", cQ[2])
+	assert.Equal(t, "olia", cMsg[2].(*messages.QueueMessage).Error)
+	ackMock.VerifyWasCalledOnce().Ack(pegomock.AnyUint64(), pegomock.AnyBool())
+}
+
+func TestCleanFailing_Chunk(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsk := newTestChunkedTask(2)
+	tsks.addTask(tsk)
+	tsk.children[1].failCount = maxTaskFailCount
+
+	err := tsks.cleanFailing(msgSenderMock)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tsks.tsks))
+	msgSenderMock.VerifyWasCalledOnce().
+		Send(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString())
+}
+
+func TestCleanFailing_ChunkWaitsForStarted(t *testing.T) {
+	initTestTask(t)
+	tsks := newTasks()
+	tsk := newTestChunkedTask(3)
+	tsks.addTask(tsk)
+	tsk.children[0].startOn(newWorker(), msgSenderMock)
+	tsk.children[1].failCount = maxTaskFailCount
+
+	err := tsks.cleanFailing(msgSenderMock)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tsks.tsks))
+	assert.Equal(t, 1, tsk.pendingChunks)
+	assert.NotEqual(t, "", tsk.chunkErr)
+}
+
 func newTestDelivery(msg *messages.QueueMessage) *amqp.Delivery {
 	msgdata, _ := json.Marshal(msg)
 	res := amqp.Delivery{Body: msgdata, CorrelationId: msg.ID}
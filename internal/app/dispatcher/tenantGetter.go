@@ -0,0 +1,31 @@
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+type tenantGetter struct {
+	tag       string
+	separator string
+}
+
+func newTenantGetter(tag string, separator string) (*tenantGetter, error) {
+	if tag == "" {
+		return nil, errors.New("No tenant tag")
+	}
+	return &tenantGetter{tag: tag, separator: separator}, nil
+}
+
+// Get returns tag value or its prefix till the separator
+func (g *tenantGetter) Get(tags []messages.Tag) string {
+	v, _ := messages.GetTag(tags, g.tag)
+	if g.separator != "" {
+		if i := strings.Index(v, g.separator); i > -1 {
+			return v[:i]
+		}
+	}
+	return v
+}
@@ -0,0 +1,28 @@
+package dispatcher
+
+import (
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantGetter_Init(t *testing.T) {
+	g, err := newTenantGetter("tag", "")
+	assert.Nil(t, err)
+	assert.NotNil(t, g)
+	_, err = newTenantGetter("", "")
+	assert.NotNil(t, err)
+}
+
+func TestTenantGetter(t *testing.T) {
+	g, _ := newTenantGetter("tag", "")
+	assert.Equal(t, "", g.Get(nil))
+	assert.Equal(t, "client-1", g.Get([]messages.Tag{messages.NewTag("tag", "client-1")}))
+}
+
+func TestTenantGetter_Prefix(t *testing.T) {
+	g, _ := newTenantGetter("tag", "-")
+	assert.Equal(t, "client", g.Get([]messages.Tag{messages.NewTag("tag", "client-1-2")}))
+	assert.Equal(t, "client", g.Get([]messages.Tag{messages.NewTag("tag", "client")}))
+}
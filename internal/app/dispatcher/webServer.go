@@ -0,0 +1,33 @@
+package dispatcher
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// startWebServer starts the HTTP service for metrics, port <= 0 disables it
+func startWebServer(port int) {
+	if port <= 0 {
+		cmdapp.Log.Info("No HTTP port configured, skip metrics endpoint")
+		return
+	}
+	cmdapp.Log.Infof("Starting HTTP service at %d", port)
+	go func() {
+		portStr := strconv.Itoa(port)
+		err := http.ListenAndServe(":"+portStr, newRouter())
+		cmdapp.Log.Error("Can't start HTTP listener at port "+portStr, err)
+	}()
+}
+
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/metrics").Handler(promhttp.Handler())
+	router.Methods("GET").Path("/live").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	return router
+}
@@ -23,11 +23,14 @@ type worker struct {
 	queue    string
 	beatTime time.Time
 	working  bool
+	draining bool
 
 	task    *task
 	started time.Time
 	mType   string
 	endAt   time.Time
+	// warm are the preloaded model types reported by the worker
+	warm []string
 }
 
 type changedFunc func()
@@ -66,13 +69,16 @@ func processWorker(wrks *workers, msg *messages.RegistrationMessage) error {
 	if msg.Type == messages.RgrTypeBeat {
 		return beatWorker(wrks, msg)
 	}
+	if msg.Type == messages.RgrTypeDrain {
+		return drainWorker(wrks, msg)
+	}
 	return errors.Errorf("Unknown msg type: '%s'", msg.Type)
 }
 
 func (wrks *workers) log() {
 	for _, k := range wrks.workers {
-		cmdapp.Log.Debugf("Worker: %s, mt: %s, working: %v, started: %s, endsAt: %s",
-			k.queue, k.mType, k.working, k.started.Format(timeFormat), k.endAt.Format(timeFormat))
+		cmdapp.Log.Debugf("Worker: %s, mt: %s, warm: %v, working: %v, draining: %v, started: %s, endsAt: %s",
+			k.queue, k.mType, k.warm, k.working, k.draining, k.started.Format(timeFormat), k.endAt.Format(timeFormat))
 	}
 }
 
@@ -89,7 +95,12 @@ func exitWorker(wrks *workers, msg *messages.RegistrationMessage) error {
 		cmdapp.Log.Infof("Exit worker %s", w.queue)
 		delete(wrks.workers, msg.Queue)
 		if w.task != nil {
-			failRequeueTask(w.task)
+			if w.draining {
+				cmdapp.Log.Warnf("Draining worker %s exited with unfinished task, requeue", w.queue)
+				requeueTask(w.task)
+			} else {
+				failRequeueTask(w.task)
+			}
 		}
 		go wrks.changedFunc()
 	}
@@ -110,10 +121,35 @@ func registerWorker(wrks *workers, msg *messages.RegistrationMessage) error {
 		go wrks.changedFunc()
 	}
 	w.beatTime = time.Unix(msg.Timestamp, 0)
+	if !equalStrings(w.warm, msg.ModelTypes) {
+		cmdapp.Log.Infof("Worker %s warm models: %v", w.queue, msg.ModelTypes)
+		w.warm = msg.ModelTypes
+		go wrks.changedFunc()
+	}
+	// beats keep the draining state for the restarted dispatcher, the restarted worker registers without it
+	if msg.Draining != w.draining && (msg.Draining || msg.Type == messages.RgrTypeRegister) {
+		cmdapp.Log.Infof("Worker %s draining: %v", w.queue, msg.Draining)
+		w.draining = msg.Draining
+		go wrks.changedFunc()
+	}
 	cmdapp.Log.Debugf("Worker count: %d", len(wrks.workers))
 	return nil
 }
 
+func drainWorker(wrks *workers, msg *messages.RegistrationMessage) error {
+	wrks.lock.Lock()
+	defer wrks.lock.Unlock()
+
+	w, f := wrks.workers[msg.Queue]
+	if f {
+		cmdapp.Log.Infof("Draining worker %s", w.queue)
+		w.draining = true
+		w.beatTime = time.Unix(msg.Timestamp, 0)
+		go wrks.changedFunc()
+	}
+	return nil
+}
+
 func dropWorker(wrks *workers, w *worker) {
 	cmdapp.Log.Infof("Drop worker %s", w.queue)
 	delete(wrks.workers, w.queue)
@@ -180,15 +216,41 @@ func (w *worker) startTaskAt(t *task, now time.Time) error {
 	w.started = now
 	w.endAt = w.started.Add(durTimes(t.expDuration, t.rtFactor))
 	if w.mType != t.requiredModelType {
+		if !w.isWarm(t.requiredModelType) {
+			w.endAt = w.endAt.Add(t.expModelLoadDuration)
+			cmdapp.Log.Debugf("Add ml dur: %v", t.expModelLoadDuration)
+		}
 		if t.requiredModelType != "" {
 			w.mType = t.requiredModelType
 		} else {
 			w.mType = noneWorkerModelType
 		}
-		w.endAt = w.endAt.Add(t.expModelLoadDuration)
-		cmdapp.Log.Debugf("Add ml dur: %v", t.expModelLoadDuration)
 	}
 	cmdapp.Log.Debugf("Task dur: %v, RT: %f", t.expDuration, t.rtFactor)
 	cmdapp.Log.Infof("Estimated complete time at %s", w.endAt.Format(timeFormat))
 	return nil
 }
+
+func (w *worker) isWarm(mt string) bool {
+	if mt == "" {
+		return false
+	}
+	for _, s := range w.warm {
+		if s == mt {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
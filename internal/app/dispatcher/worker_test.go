@@ -65,6 +65,64 @@ func TestRemoveWorker(t *testing.T) {
 	assert.Equal(t, 0, len(wrks.workers))
 }
 
+func TestDrainWorker(t *testing.T) {
+	wrks := newWorkers()
+	processWorker(wrks, newMsg("1", messages.RgrTypeRegister, time.Now()))
+	err := processWorker(wrks, newMsg("1", messages.RgrTypeDrain, time.Now()))
+	assert.Nil(t, err)
+	assert.True(t, wrks.workers["1"].draining)
+	processWorker(wrks, newMsg("1", messages.RgrTypeBeat, time.Now()))
+	assert.True(t, wrks.workers["1"].draining)
+}
+
+func TestBeat_RestoresDraining(t *testing.T) {
+	wrks := newWorkers()
+	msg := newMsg("1", messages.RgrTypeBeat, time.Now())
+	msg.Draining = true
+	err := processWorker(wrks, msg)
+	assert.Nil(t, err)
+	assert.True(t, wrks.workers["1"].draining)
+	processWorker(wrks, newMsg("1", messages.RgrTypeBeat, time.Now()))
+	assert.True(t, wrks.workers["1"].draining)
+	processWorker(wrks, newMsg("1", messages.RgrTypeRegister, time.Now()))
+	assert.False(t, wrks.workers["1"].draining)
+}
+
+func TestDrainWorker_Unknown(t *testing.T) {
+	wrks := newWorkers()
+	err := processWorker(wrks, newMsg("1", messages.RgrTypeDrain, time.Now()))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(wrks.workers))
+}
+
+func TestRemoveWorker_FailsTask(t *testing.T) {
+	wrks := newWorkers()
+	processWorker(wrks, newMsg("1", messages.RgrTypeRegister, time.Now()))
+	tsk := newTask()
+	wrks.workers["1"].startTask(tsk)
+	tsk.worker = wrks.workers["1"]
+	tsk.started = true
+	processWorker(wrks, newMsg("1", messages.RgrTypeExit, time.Now()))
+	assert.False(t, tsk.started)
+	assert.Nil(t, tsk.worker)
+	assert.Equal(t, int32(1), tsk.failCount)
+}
+
+func TestRemoveDrainingWorker_RequeuesTask(t *testing.T) {
+	wrks := newWorkers()
+	processWorker(wrks, newMsg("1", messages.RgrTypeRegister, time.Now()))
+	tsk := newTask()
+	wrks.workers["1"].startTask(tsk)
+	tsk.worker = wrks.workers["1"]
+	tsk.started = true
+	processWorker(wrks, newMsg("1", messages.RgrTypeDrain, time.Now()))
+	processWorker(wrks, newMsg("1", messages.RgrTypeExit, time.Now()))
+	assert.Equal(t, 0, len(wrks.workers))
+	assert.False(t, tsk.started)
+	assert.Nil(t, tsk.worker)
+	assert.Equal(t, int32(0), tsk.failCount)
+}
+
 func TestRemoveOnExpire(t *testing.T) {
 	wrks := newWorkers()
 	processWorker(wrks, newMsg("1", messages.RgrTypeRegister, time.Now()))
@@ -150,6 +208,37 @@ func TestWorkerStartTask_NoModelLoad(t *testing.T) {
 	assert.Equal(t, now.Add(time.Second*2), wrk.endAt)
 }
 
+func TestWorkerStartTask_WarmModel(t *testing.T) {
+	wrk := newWorker()
+	tsk := newTask()
+	tsk.expDuration = time.Second
+	tsk.expModelLoadDuration = time.Minute
+	tsk.rtFactor = 2
+	tsk.requiredModelType = "M2"
+	now := time.Now()
+	wrk.mType = "M1"
+	wrk.warm = []string{"M1", "M2"}
+	err := wrk.startTaskAt(tsk, now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "M2", wrk.mType)
+	assert.Equal(t, now.Add(time.Second*2), wrk.endAt)
+}
+
+func TestWarmModels(t *testing.T) {
+	wrks := newWorkers()
+	msg := newMsg("1", messages.RgrTypeRegister, time.Now())
+	msg.ModelTypes = []string{"M1", "M2"}
+	processWorker(wrks, msg)
+	assert.Equal(t, []string{"M1", "M2"}, wrks.workers["1"].warm)
+	msg = newMsg("1", messages.RgrTypeBeat, time.Now())
+	msg.ModelTypes = []string{"M2"}
+	processWorker(wrks, msg)
+	assert.Equal(t, []string{"M2"}, wrks.workers["1"].warm)
+	mw := mapWorkers([]*worker{wrks.workers["1"]})
+	assert.Equal(t, []string{"M2"}, mw[0].Loaded)
+}
+
 func TestWorkerStartTask_Fails(t *testing.T) {
 	wrk := newWorker()
 	tsk := newTask()
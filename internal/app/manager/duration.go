@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/wav"
+	"github.com/pkg/errors"
+)
+
+// DurationSaver saves the audio duration in seconds to the request info
+type DurationSaver interface {
+	SaveDuration(ID string, duration float64) error
+}
+
+// DurationReader reads the duration of the converted audio
+type DurationReader interface {
+	Get(ID string) (time.Duration, error)
+}
+
+// WavDurationReader reads the duration from the header of the converted wav
+type WavDurationReader struct {
+	pathPattern string
+}
+
+// NewWavDurationReader creates the reader, the pattern must contain {ID}
+func NewWavDurationReader(pathPattern string) (*WavDurationReader, error) {
+	if !strings.Contains(pathPattern, "{ID}") {
+		return nil, errors.Errorf("Path pattern '%s' does not contain '{ID}'", pathPattern)
+	}
+	return &WavDurationReader{pathPattern: pathPattern}, nil
+}
+
+// Get returns the audio duration
+func (r *WavDurationReader) Get(ID string) (time.Duration, error) {
+	fn := strings.ReplaceAll(r.pathPattern, "{ID}", ID)
+	f, err := os.Open(fn)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't open %s", fn)
+	}
+	defer f.Close()
+	h, err := wav.ReadHeader(f)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't read %s", fn)
+	}
+	return h.Duration(), nil
+}
+
+// saveDuration stores the duration known after the conversion, so the dispatcher does not need to guess it.
+// Failures are only logged
+func saveDuration(ID string, data *ServiceData) {
+	if data.durationReader == nil || data.durationSaver == nil {
+		return
+	}
+	d, err := data.durationReader.Get(ID)
+	if err != nil {
+		cmdapp.Log.Warn(errors.Wrapf(err, "Can't get duration for %s", ID))
+		return
+	}
+	cmdapp.Log.Infof("Audio duration for %s: %v", ID, d)
+	cmdapp.LogIf(errors.Wrapf(data.durationSaver.SaveDuration(ID, d.Seconds()), "Can't save duration for %s", ID))
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/wav"
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWavDurationReader(t *testing.T) {
+	dir := t.TempDir()
+	var b bytes.Buffer
+	assert.Nil(t, wav.WriteHeader(&b, &wav.Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16}, 16000))
+	b.Write(make([]byte, 16000))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "id.wav"), b.Bytes(), 0644))
+
+	r, err := NewWavDurationReader(filepath.Join(dir, "{ID}.wav"))
+	assert.Nil(t, err)
+	d, err := r.Get("id")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, d)
+	_, err = r.Get("other")
+	assert.NotNil(t, err)
+}
+
+func TestWavDurationReader_Fails(t *testing.T) {
+	_, err := NewWavDurationReader("/data/a.wav")
+	assert.NotNil(t, err)
+}
+
+func TestHandlesMessagesAudioConvertMsg_SavesDuration(t *testing.T) {
+	td := initTestData(t)
+	dr, ds := mocks.NewMockDurationReader(), mocks.NewMockDurationSaver()
+	td.data.durationReader, td.data.durationSaver = dr, ds
+	pegomock.When(dr.Get(pegomock.AnyString())).ThenReturn(90*time.Second, nil)
+
+	msgdata, _ := json.Marshal(newTestMsg())
+	td.ac <- amqp.Delivery{Body: msgdata}
+	close(td.ac)
+	<-td.fc
+
+	id, dur := ds.VerifyWasCalledOnce().SaveDuration(pegomock.AnyString(), pegomock.AnyFloat64()).GetCapturedArguments()
+	assert.Equal(t, newTestMsg().ID, id)
+	assert.Equal(t, 90.0, dur)
+	verifySendMessageOnce(t, messages.Diarization)
+}
+
+func TestHandlesMessagesAudioConvertMsg_DurationFails(t *testing.T) {
+	td := initTestData(t)
+	dr, ds := mocks.NewMockDurationReader(), mocks.NewMockDurationSaver()
+	td.data.durationReader, td.data.durationSaver = dr, ds
+	pegomock.When(dr.Get(pegomock.AnyString())).ThenReturn(time.Duration(0), errors.New("olia"))
+
+	msgdata, _ := json.Marshal(newTestMsg())
+	td.ac <- amqp.Delivery{Body: msgdata}
+	close(td.ac)
+	<-td.fc
+
+	ds.VerifyWasCalled(pegomock.Never()).SaveDuration(pegomock.AnyString(), pegomock.AnyFloat64())
+	verifySendMessageOnce(t, messages.Diarization)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/mongo"
 	"github.com/airenas/listgo/internal/pkg/rabbit"
+	"github.com/airenas/listgo/internal/pkg/storage"
 	"github.com/airenas/listgo/internal/pkg/utils"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
@@ -70,13 +71,27 @@ func run(cmd *cobra.Command, args []string) {
 	data.TranscriptionCh = makeQChannel(ch, msgChannelProvider.QueueName(messages.ResultQueueFor(messages.Transcription)))
 	data.RescoreCh = makeQChannel(ch, msgChannelProvider.QueueName(messages.ResultQueueFor(messages.Rescore)))
 	data.ResultMakeCh = makeQChannel(ch, msgChannelProvider.QueueName(messages.ResultQueueFor(messages.ResultMake)))
+	data.ProgressCh = makeQChannel(ch, msgChannelProvider.QueueName(messages.Progress))
 
 	data.StatusSaver, err = mongo.NewStatusSaver(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "Can't init status saver")
 	data.ResultSaver, err = mongo.NewResultSaver(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "Can't init result saver")
+	if root := cmdapp.Config.GetString("resultRef.root"); root != "" {
+		cmdapp.Log.Infof("Reading result references from: %s", root)
+		st, err := storage.New(root)
+		cmdapp.CheckOrPanic(err, "Can't init result reference storage")
+		data.ResultRefReader, err = NewStorageResultRefReader(st)
+		cmdapp.CheckOrPanic(err, "Can't init result reference reader")
+	}
 	data.speechIndicator, err = loader.NewNonEmptyFileTester(cmdapp.Config.GetString("speechIndicator.pathPattern"))
 	cmdapp.CheckOrPanic(err, "Can't init result saver")
+	if pp := cmdapp.Config.GetString("audioDuration.pathPattern"); pp != "" {
+		data.durationReader, err = NewWavDurationReader(pp)
+		cmdapp.CheckOrPanic(err, "Can't init duration reader")
+		data.durationSaver, err = mongo.NewRequestSaver(mongoSessionProvider)
+		cmdapp.CheckOrPanic(err, "Can't init duration saver")
+	}
 
 	err = StartWorkerService(&data)
 	cmdapp.CheckOrPanic(err, "Can't start worker service")
@@ -100,7 +115,8 @@ func initQueues(prv *rabbit.ChannelProvider) error {
 			messages.Diarization, messages.ResultQueueFor(messages.Diarization),
 			messages.Transcription, messages.ResultQueueFor(messages.Transcription),
 			messages.Rescore, messages.ResultQueueFor(messages.Rescore),
-			messages.ResultMake, messages.ResultQueueFor(messages.ResultMake)}
+			messages.ResultMake, messages.ResultQueueFor(messages.ResultMake),
+			messages.Progress}
 		for _, queue := range queues {
 			_, err := rabbit.DeclareQueue(ch, prv.QueueName(queue))
 			if err != nil {
@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// StorageResultRefReader reads the referenced results from the shared result storage
+type StorageResultRefReader struct {
+	storage storage.Storage
+}
+
+// NewStorageResultRefReader creates StorageResultRefReader instance
+func NewStorageResultRefReader(st storage.Storage) (*StorageResultRefReader, error) {
+	if st == nil {
+		return nil, errors.New("No result storage")
+	}
+	return &StorageResultRefReader{storage: st}, nil
+}
+
+// Open opens the referenced file, the reader fails at the end if the size or checksum does not match
+func (rr *StorageResultRefReader) Open(ref *messages.ResultRef) (io.ReadCloser, error) {
+	f, err := rr.storage.Get(ref.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't open result file '%s'", ref.Path)
+	}
+	return &checkedReader{rc: f, ref: ref, h: sha256.New()}, nil
+}
+
+// checkedReader verifies the content against the reference on EOF
+type checkedReader struct {
+	rc   io.ReadCloser
+	ref  *messages.ResultRef
+	h    hash.Hash
+	size int64
+}
+
+func (r *checkedReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.h.Write(p[:n])
+	r.size += int64(n)
+	if err == io.EOF {
+		if r.size != r.ref.Size {
+			return n, errors.Errorf("Result size mismatch for %s: %d, expected %d", r.ref.Path, r.size, r.ref.Size)
+		}
+		if cs := hex.EncodeToString(r.h.Sum(nil)); cs != r.ref.SHA256 {
+			return n, errors.Errorf("Result checksum mismatch for %s", r.ref.Path)
+		}
+	}
+	return n, err
+}
+
+func (r *checkedReader) Close() error {
+	return r.rc.Close()
+}
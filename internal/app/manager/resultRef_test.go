@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/status"
+	"github.com/airenas/listgo/internal/pkg/storage"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSHA = "d57329cf35760377655bf8417b666cd1b4028878276d8684b9f571746e908996" // sha256 of "olia"
+
+func initRefTest(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "resultRef")
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "r"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "r", "1.txt"), []byte("olia"), 0644))
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func newTestRefReader(t *testing.T, root string) *StorageResultRefReader {
+	st, err := storage.NewLocal(root)
+	assert.Nil(t, err)
+	fr, err := NewStorageResultRefReader(st)
+	assert.Nil(t, err)
+	return fr
+}
+
+func readRef(t *testing.T, root string, ref *messages.ResultRef) (string, error) {
+	fr := newTestRefReader(t, root)
+	r, err := fr.Open(ref)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	return string(b), err
+}
+
+func TestStorageResultRefReader(t *testing.T) {
+	dir, cf := initRefTest(t)
+	defer cf()
+	res, err := readRef(t, dir, &messages.ResultRef{Path: "r/1.txt", Size: 4, SHA256: testSHA})
+	assert.Nil(t, err)
+	assert.Equal(t, "olia", res)
+}
+
+func TestStorageResultRefReader_Fails(t *testing.T) {
+	dir, cf := initRefTest(t)
+	defer cf()
+	tests := []struct {
+		name string
+		ref  messages.ResultRef
+	}{
+		{name: "checksum", ref: messages.ResultRef{Path: "r/1.txt", Size: 4, SHA256: "aa"}},
+		{name: "size", ref: messages.ResultRef{Path: "r/1.txt", Size: 5, SHA256: testSHA}},
+		{name: "missing", ref: messages.ResultRef{Path: "r/2.txt", Size: 4, SHA256: testSHA}},
+		{name: "parent", ref: messages.ResultRef{Path: "../r/1.txt", Size: 4, SHA256: testSHA}},
+		{name: "abs", ref: messages.ResultRef{Path: filepath.Join(dir, "r/1.txt"), Size: 4, SHA256: testSHA}},
+		{name: "empty", ref: messages.ResultRef{Size: 4, SHA256: testSHA}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readRef(t, dir, &tt.ref)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNewStorageResultRefReader_Fails(t *testing.T) {
+	_, err := NewStorageResultRefReader(nil)
+	assert.NotNil(t, err)
+}
+
+type testResultSaver struct {
+	saved string
+	err   error
+}
+
+func (s *testResultSaver) Save(ID string, result string) error {
+	s.saved = result
+	return nil
+}
+
+func (s *testResultSaver) SaveStream(ID string, reader io.Reader) error {
+	b, err := ioutil.ReadAll(reader)
+	s.saved, s.err = string(b), err
+	return err
+}
+
+func TestSaveResult(t *testing.T) {
+	dir, cf := initRefTest(t)
+	defer cf()
+	fr := newTestRefReader(t, dir)
+	s := &testResultSaver{}
+	err := SaveResult(s, fr, &messages.ResultMessage{Result: "inline"})
+	assert.Nil(t, err)
+	assert.Equal(t, "inline", s.saved)
+
+	err = SaveResult(s, fr, &messages.ResultMessage{Ref: &messages.ResultRef{Path: "r/1.txt", Size: 4, SHA256: testSHA}})
+	assert.Nil(t, err)
+	assert.Equal(t, "olia", s.saved)
+
+	err = SaveResult(s, fr, &messages.ResultMessage{Ref: &messages.ResultRef{Path: "r/1.txt", Size: 4, SHA256: "aa"}})
+	assert.NotNil(t, err)
+	assert.NotNil(t, s.err)
+}
+
+func TestSaveResult_NoReader(t *testing.T) {
+	err := SaveResult(&testResultSaver{}, nil, &messages.ResultMessage{Ref: &messages.ResultRef{Path: "r/1.txt"}})
+	assert.NotNil(t, err)
+}
+
+func TestHandlesMessagesResultMakeRef(t *testing.T) {
+	dir, cf := initRefTest(t)
+	defer cf()
+	td := initTestData(t)
+	td.data.ResultRefReader = newTestRefReader(t, dir)
+
+	msg := messages.ResultMessage{QueueMessage: *newTestMsg(),
+		Ref: &messages.ResultRef{Path: "r/1.txt", Size: 4, SHA256: testSHA}}
+	msgdata, _ := json.Marshal(msg)
+	td.rc <- amqp.Delivery{Body: msgdata}
+	close(td.rc)
+	<-td.fc
+	resultSaverMock.VerifyWasCalled(pegomock.Never()).Save(pegomock.AnyString(), pegomock.AnyString())
+	resultSaverMock.VerifyWasCalled(pegomock.Once()).SaveStream(pegomock.EqString(msg.ID), matchers.AnyIoReader())
+	statusSaverMock.VerifyWasCalled(pegomock.Times(1)).Save(pegomock.AnyString(), matchers.EqStatusStatus(status.Completed))
+}
@@ -1,6 +1,36 @@
 package manager
 
+import (
+	"io"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
 // ResultSaver saves the transcription result into db
 type ResultSaver interface {
 	Save(ID string, result string) error
+	// SaveStream saves the result read from the reader, the result is stored in chunks
+	SaveStream(ID string, reader io.Reader) error
+}
+
+// ResultRefReader opens the result passed by the reference to the shared storage
+type ResultRefReader interface {
+	Open(ref *messages.ResultRef) (io.ReadCloser, error)
+}
+
+// SaveResult saves the result inlined into the message or the result passed by the reference
+func SaveResult(saver ResultSaver, refReader ResultRefReader, msg *messages.ResultMessage) error {
+	if msg.Ref == nil {
+		return saver.Save(msg.ID, msg.Result)
+	}
+	if refReader == nil {
+		return errors.New("Result reference reader not provided")
+	}
+	r, err := refReader.Open(msg.Ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return saver.SaveStream(msg.ID, r)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/progress"
 	"github.com/airenas/listgo/internal/pkg/result"
 	"github.com/airenas/listgo/internal/pkg/status"
 	"github.com/airenas/listgo/internal/pkg/utils"
@@ -22,6 +23,7 @@ type ServiceData struct {
 	Publisher           messages.Publisher
 	StatusSaver         status.Saver
 	ResultSaver         ResultSaver
+	ResultRefReader     ResultRefReader
 	DecodeCh            <-chan amqp.Delivery
 	SplitChannelsCh     <-chan amqp.Delivery
 	AudioConvertCh      <-chan amqp.Delivery
@@ -29,8 +31,12 @@ type ServiceData struct {
 	TranscriptionCh     <-chan amqp.Delivery
 	RescoreCh           <-chan amqp.Delivery
 	ResultMakeCh        <-chan amqp.Delivery
+	ProgressCh          <-chan amqp.Delivery
 	fc                  *utils.MultiCloseChannel
 	speechIndicator     SpeechIndicator
+	// durationReader and durationSaver are optional, if set the converted audio duration is saved
+	durationReader DurationReader
+	durationSaver  DurationSaver
 }
 
 // SpeechIndicator looks if request audio has speech
@@ -68,6 +74,9 @@ func StartWorkerService(data *ServiceData) error {
 	go listenQueue(data.TranscriptionCh, transcriptionFinish, data)
 	go listenQueue(data.RescoreCh, rescoreFinish, data)
 	go listenQueue(data.ResultMakeCh, resultMakeFinish, data)
+	if data.ProgressCh != nil {
+		go listenQueue(data.ProgressCh, progressUpdate, data)
+	}
 
 	return nil
 }
@@ -142,7 +151,8 @@ func splitChannelsFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 
 // audioConvertFinish processes audio convert result messages
 // 1. logs status
-// 2. sends 'Diarization' message
+// 2. saves audio duration
+// 3. sends 'Diarization' message
 func audioConvertFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 	var message messages.QueueMessage
 	if err := json.Unmarshal(d.Body, &message); err != nil {
@@ -155,6 +165,7 @@ func audioConvertFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		}
 		return true, err
 	}
+	saveDuration(message.ID, data)
 	return true, data.MessageSender.Send(messages.NewQueueMessageFromM(&message),
 		messages.Diarization, messages.ResultQueueFor(messages.Diarization))
 }
@@ -232,7 +243,7 @@ func resultMakeFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		return false, errors.Wrap(err, "Can't unmarshal message "+string(d.Body))
 	}
 	if message.Error == "" {
-		err := data.ResultSaver.Save(message.ID, message.Result)
+		err := SaveResult(data.ResultSaver, data.ResultRefReader, &message)
 		if err != nil {
 			cmdapp.Log.Error(err)
 			return true, err
@@ -240,7 +251,8 @@ func resultMakeFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		err = data.StatusSaver.SaveF(message.ID, map[string]interface{}{
 			persistence.StAvailableResults: []string{result.Txt, result.TxtFinal,
 				result.Lat, result.LatGz,
-				result.LatRestored, result.LatRestoredGz, result.WebVTT}}, nil)
+				result.LatRestored, result.LatRestoredGz, result.WebVTT,
+				result.SRT, result.WordsJSON, result.TextGrid, result.Docx, result.Odt}}, nil)
 		if err != nil {
 			cmdapp.Log.Error(err)
 			return true, err
@@ -261,6 +273,29 @@ func resultMakeFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		messages.Inform, "")
 }
 
+// progressUpdate processes intermediate progress messages of the running step
+// 1. saves the live progress value
+// 2. publishes status change event
+func progressUpdate(d *amqp.Delivery, data *ServiceData) (bool, error) {
+	var message messages.ProgressMessage
+	if err := json.Unmarshal(d.Body, &message); err != nil {
+		return false, errors.Wrap(err, "Can't unmarshal message "+string(d.Body))
+	}
+	st := status.From(message.Step)
+	if st == 0 {
+		cmdapp.Log.Warnf("Unknown progress step '%s' for %s", message.Step, message.ID)
+		return false, nil
+	}
+	pr := progress.ConvertStep(st, message.Progress)
+	cmdapp.Log.Debugf("Got progress %s: %s %d%% -> %d%%", message.ID, message.Step, message.Progress, pr)
+	err := data.StatusSaver.SaveF(message.ID, map[string]interface{}{persistence.StProgress: pr}, nil)
+	if err != nil {
+		return false, err
+	}
+	publishStatusChange(&message.QueueMessage, data)
+	return false, nil
+}
+
 // processStatus analyzes message response and saves status
 // returns false if no futher processing is needed
 func processStatus(message *messages.QueueMessage, data *ServiceData, from string, to status.Status) (bool, error) {
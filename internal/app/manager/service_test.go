@@ -14,6 +14,7 @@ import (
 	"github.com/streadway/amqp"
 
 	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/persistence"
 	"github.com/airenas/listgo/internal/pkg/status"
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
 	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
@@ -86,6 +87,7 @@ type testdata struct {
 	tc     chan amqp.Delivery
 	rescCh chan amqp.Delivery
 	rc     chan amqp.Delivery
+	pc     chan amqp.Delivery
 	data   *ServiceData
 	fc     <-chan os.Signal
 }
@@ -113,6 +115,7 @@ func initTestData(t *testing.T) *testdata {
 	res.tc = make(chan amqp.Delivery)
 	res.rescCh = make(chan amqp.Delivery)
 	res.rc = make(chan amqp.Delivery)
+	res.pc = make(chan amqp.Delivery)
 
 	res.data.DecodeCh = res.dc
 	res.data.AudioConvertCh = res.ac
@@ -121,6 +124,7 @@ func initTestData(t *testing.T) *testdata {
 	res.data.TranscriptionCh = res.tc
 	res.data.RescoreCh = res.rescCh
 	res.data.ResultMakeCh = res.rc
+	res.data.ProgressCh = res.pc
 	res.data.fc = utils.NewMultiCloseChannel()
 
 	res.fc = res.data.fc.C
@@ -430,6 +434,48 @@ func TestHandlesMessagesResultMakeMsgWithTarget(t *testing.T) {
 	verifySendMessageOnce(t, "Q1")
 }
 
+func TestHandlesMessagesProgress(t *testing.T) {
+	td := initTestData(t)
+
+	msg := messages.ProgressMessage{QueueMessage: *newTestMsg(), Step: "Transcription", Progress: 50}
+	msgdata, _ := json.Marshal(msg)
+	td.pc <- amqp.Delivery{Body: msgdata}
+	close(td.pc)
+	<-td.fc
+	_, set, _ := statusSaverMock.VerifyWasCalled(pegomock.Once()).SaveF(pegomock.EqString("1"),
+		matchers.AnyMapOfStringToInterface(), matchers.AnyMapOfStringToInterface()).GetCapturedArguments()
+	assert.Equal(t, map[string]interface{}{persistence.StProgress: int32(60)}, set)
+	publisherMock.VerifyWasCalled(pegomock.Once()).Publish(pegomock.EqString("1"), pegomock.EqString(messages.TopicStatusChange))
+	statusSaverMock.VerifyWasCalled(pegomock.Never()).Save(pegomock.AnyString(), matchers.AnyStatusStatus())
+	msgSenderMock.VerifyWasCalled(pegomock.Never()).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(), pegomock.AnyString())
+}
+
+func TestHandlesMessagesProgress_UnknownStep(t *testing.T) {
+	td := initTestData(t)
+
+	msg := messages.ProgressMessage{QueueMessage: *newTestMsg(), Step: "olia", Progress: 50}
+	msgdata, _ := json.Marshal(msg)
+	td.pc <- amqp.Delivery{Body: msgdata}
+	close(td.pc)
+	<-td.fc
+	statusSaverMock.VerifyWasCalled(pegomock.Never()).SaveF(pegomock.AnyString(),
+		matchers.AnyMapOfStringToInterface(), matchers.AnyMapOfStringToInterface())
+	publisherMock.VerifyWasCalled(pegomock.Never()).Publish(pegomock.AnyString(), pegomock.AnyString())
+}
+
+func TestHandlesMessagesProgress_SaveFails(t *testing.T) {
+	td := initTestData(t)
+
+	pegomock.When(statusSaverMock.SaveF(pegomock.AnyString(), matchers.AnyMapOfStringToInterface(),
+		matchers.AnyMapOfStringToInterface())).ThenReturn(errors.New("fail"))
+	msg := messages.ProgressMessage{QueueMessage: *newTestMsg(), Step: "Diarization", Progress: 50}
+	msgdata, _ := json.Marshal(msg)
+	td.pc <- amqp.Delivery{Body: msgdata}
+	close(td.pc)
+	<-td.fc
+	publisherMock.VerifyWasCalled(pegomock.Never()).Publish(pegomock.AnyString(), pegomock.AnyString())
+}
+
 func newTestMsg() *messages.QueueMessage {
 	return &messages.QueueMessage{ID: "1", Recognizer: "rec"}
 }
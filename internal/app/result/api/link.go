@@ -0,0 +1,10 @@
+package api
+
+import "time"
+
+// Link is the signed query to append to the job links: /audio/{id}, /result/{id}/...
+type Link struct {
+	Scope   string    `json:"scope"`
+	Expires time.Time `json:"expires"`
+	Query   string    `json:"query"`
+}
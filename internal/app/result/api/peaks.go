@@ -0,0 +1,12 @@
+package api
+
+// Peaks is the min/max waveform in the audiowaveform JSON format, data keeps min and max of every pixel
+type Peaks struct {
+	Version         int     `json:"version"`
+	Channels        int     `json:"channels"`
+	SampleRate      int     `json:"sample_rate"`
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Bits            int     `json:"bits"`
+	Length          int     `json:"length"`
+	Data            []int16 `json:"data"`
+}
@@ -0,0 +1,16 @@
+package api
+
+// SpeakerTurn is a continuous speech segment of one speaker, times in seconds
+type SpeakerTurn struct {
+	Speaker string  `json:"speaker"`
+	Name    string  `json:"name,omitempty"`
+	From    float64 `json:"from"`
+	To      float64 `json:"to"`
+	Text    string  `json:"text"`
+}
+
+// Speakers is the speaker-turn transcript with the user's speaker names
+type Speakers struct {
+	Turns []SpeakerTurn     `json:"turns,omitempty"`
+	Names map[string]string `json:"names,omitempty"`
+}
@@ -0,0 +1,15 @@
+package api
+
+import "time"
+
+// Version is the corrected transcript version
+type Version struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+}
+
+// Versions lists the corrected transcript versions, the original transcript is version 0
+type Versions struct {
+	Latest   int       `json:"latest"`
+	Versions []Version `json:"versions"`
+}
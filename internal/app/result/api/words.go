@@ -0,0 +1,16 @@
+package api
+
+// Word is a recognized word with its time in seconds
+type Word struct {
+	Word       string  `json:"word"`
+	Punct      string  `json:"punct,omitempty"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Speaker    string  `json:"speaker,omitempty"`
+}
+
+// Words is the word level result
+type Words struct {
+	Words []Word `json:"words"`
+}
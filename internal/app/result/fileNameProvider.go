@@ -4,3 +4,9 @@ package result
 type FileNameProvider interface {
 	Get(ID string) (string, error)
 }
+
+// SourceIDProvider returns the ID of the transcription whose results are reused by the ID,
+// returns empty string if the results are not shared
+type SourceIDProvider interface {
+	GetSourceID(ID string) (string, error)
+}
@@ -0,0 +1,265 @@
+package result
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/result"
+	"github.com/pkg/errors"
+)
+
+// converter builds the result file from the restored lattice
+type converter struct {
+	contentType string
+	convert     func(parts []*lattice.Part, w io.Writer) error
+}
+
+var converters = map[string]converter{
+	result.SRT:       {contentType: "application/x-subrip; charset=utf-8", convert: writeSRT},
+	result.WordsJSON: {contentType: "application/json; charset=utf-8", convert: writeWordsJSON},
+	result.TextGrid:  {contentType: "text/plain; charset=utf-8", convert: writeTextGrid},
+	result.Docx: {contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		convert: writeDocx},
+	result.Odt: {contentType: "application/vnd.oasis.opendocument.text", convert: writeOdt},
+}
+
+// serveConverted converts the restored lattice of the ID to the requested format
+func serveConverted(w http.ResponseWriter, r *http.Request, loader FileLoader, id, fileName string, c converter,
+	names map[string]string) {
+	parts, modTime, err := loadLattice(loader, id)
+	if err != nil {
+		http.Error(w, "Cannot get file for ID: "+id, http.StatusNotFound)
+		cmdapp.Log.Errorf("Cannot get lattice for ID %s: %v", id, err)
+		return
+	}
+	renameParts(parts, names)
+	var buf bytes.Buffer
+	if err := c.convert(parts, &buf); err != nil {
+		http.Error(w, "Cannot convert file for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(errors.Wrapf(err, "Cannot convert %s for ID %s", fileName, id))
+		return
+	}
+	w.Header().Set("Content-Type", c.contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	http.ServeContent(w, r, fileName, modTime, bytes.NewReader(buf.Bytes()))
+}
+
+// loadLattice reads lat.restored.txt or the zipped one if the plain file is missing
+func loadLattice(loader FileLoader, id string) ([]*lattice.Part, time.Time, error) {
+	file, err := loader.Load(id + "/" + result.LatRestored)
+	gz := false
+	if err != nil {
+		file, err = loader.Load(id + "/" + result.LatRestoredGz)
+		if err != nil {
+			return nil, time.Time{}, errors.Wrap(err, "can't load lattice")
+		}
+		gz = true
+	}
+	defer file.Close()
+	var modTime time.Time
+	if fi, err := file.Stat(); err == nil {
+		modTime = fi.ModTime()
+	}
+	var rd io.Reader = file
+	if gz {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, time.Time{}, errors.Wrap(err, "can't unzip lattice")
+		}
+		defer zr.Close()
+		rd = zr
+	}
+	parts, err := lattice.Read(rd)
+	return parts, modTime, err
+}
+
+func writeSRT(parts []*lattice.Part, w io.Writer) error {
+	for i, c := range lattice.MakeCues(parts, lattice.MaxCueDuration, lattice.MaxCueWords) {
+		if _, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(c.From), srtTime(c.To), c.Text()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func srtTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func writeWordsJSON(parts []*lattice.Part, w io.Writer) error {
+	res := api.Words{Words: make([]api.Word, 0)}
+	for _, p := range parts {
+		for _, lw := range p.MainWords() {
+			res.Words = append(res.Words, api.Word{Word: lw.Word, Punct: lw.Punct, Start: lw.From.Seconds(),
+				End: lw.To.Seconds(), Confidence: lw.Confidence, Speaker: p.Speaker})
+		}
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+type interval struct {
+	from, to time.Duration
+	text     string
+}
+
+// writeTextGrid writes one interval tier per speaker, the gaps between words are empty intervals
+func writeTextGrid(parts []*lattice.Part, w io.Writer) error {
+	speakers := make([]string, 0)
+	words := make(map[string][]*lattice.Word)
+	var end time.Duration
+	for _, p := range parts {
+		if _, ok := words[p.Speaker]; !ok {
+			speakers = append(speakers, p.Speaker)
+		}
+		mw := p.MainWords()
+		words[p.Speaker] = append(words[p.Speaker], mw...)
+		for _, lw := range mw {
+			if lw.To > end {
+				end = lw.To
+			}
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "File type = \"ooTextFile\"\nObject class = \"TextGrid\"\n\nxmin = 0\nxmax = %s\ntiers? <exists>\nsize = %d\nitem []:\n",
+		tgTime(end), len(speakers))
+	for i, s := range speakers {
+		its := tierIntervals(words[s], end)
+		name := s
+		if name == "" {
+			name = "words"
+		}
+		fmt.Fprintf(&b, "    item [%d]:\n        class = \"IntervalTier\"\n        name = \"%s\"\n        xmin = 0\n        xmax = %s\n        intervals: size = %d\n",
+			i+1, tgEscape(name), tgTime(end), len(its))
+		for j, it := range its {
+			fmt.Fprintf(&b, "        intervals [%d]:\n            xmin = %s\n            xmax = %s\n            text = \"%s\"\n",
+				j+1, tgTime(it.from), tgTime(it.to), tgEscape(it.text))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func tierIntervals(words []*lattice.Word, end time.Duration) []interval {
+	res := make([]interval, 0)
+	var at time.Duration
+	for _, w := range words {
+		if w.From < at || w.To <= w.From {
+			continue
+		}
+		if w.From > at {
+			res = append(res, interval{from: at, to: w.From})
+		}
+		res = append(res, interval{from: w.From, to: w.To, text: w.Text()})
+		at = w.To
+	}
+	if at < end || len(res) == 0 {
+		res = append(res, interval{from: at, to: end})
+	}
+	return res
+}
+
+func tgTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func tgEscape(s string) string {
+	return strings.ReplaceAll(s, "\"", "\"\"")
+}
+
+// lineLabel returns the speaker and the time of the paragraph
+func lineLabel(l lattice.Line) string {
+	s := int(l.At.Seconds())
+	return fmt.Sprintf("%s [%02d:%02d:%02d]: ", l.Speaker, s/3600, s/60%60, s%60)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+func writeDocx(parts []*lattice.Part, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`)
+	for _, l := range lattice.Lines(parts) {
+		fmt.Fprintf(&b, `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">%s</w:t></w:r><w:r><w:t>%s</w:t></w:r></w:p>`,
+			xmlEscape(lineLabel(l)), xmlEscape(l.Text))
+	}
+	b.WriteString(`</w:body></w:document>`)
+	return writeZip(w, nil, []zipFile{{"[Content_Types].xml", docxContentTypes}, {"_rels/.rels", docxRels},
+		{"word/document.xml", b.String()}})
+}
+
+const odtMimeType = "application/vnd.oasis.opendocument.text"
+
+const odtManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+<manifest:file-entry manifest:full-path="/" manifest:media-type="application/vnd.oasis.opendocument.text"/>
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+
+func writeOdt(parts []*lattice.Part, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2"><office:body><office:text>`)
+	for _, l := range lattice.Lines(parts) {
+		fmt.Fprintf(&b, `<text:p>%s%s</text:p>`, xmlEscape(lineLabel(l)), xmlEscape(l.Text))
+	}
+	b.WriteString(`</office:text></office:body></office:document-content>`)
+	// mimetype must be the first and uncompressed entry
+	return writeZip(w, &zipFile{"mimetype", odtMimeType}, []zipFile{{"META-INF/manifest.xml", odtManifest},
+		{"content.xml", b.String()}})
+}
+
+type zipFile struct {
+	name, data string
+}
+
+func writeZip(w io.Writer, stored *zipFile, files []zipFile) error {
+	zw := zip.NewWriter(w)
+	if stored != nil {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: stored.name, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, stored.data); err != nil {
+			return err
+		}
+	}
+	for _, zf := range files {
+		f, err := zw.Create(zf.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, zf.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
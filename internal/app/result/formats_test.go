@@ -0,0 +1,169 @@
+package result
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/stretchr/testify/assert"
+)
+
+const testLat = "# 1 S0000\n1 0 0.5 <eps>\n1 0.5 1.2 labas , 0.9\n1 1.2 2 vakaras .\n# 2 S0001\n1 3 3.5 \"ačiū\"\n"
+
+func testParts(t *testing.T) []*lattice.Part {
+	res, err := lattice.Read(strings.NewReader(testLat))
+	assert.Nil(t, err)
+	return res
+}
+
+func TestWriteSRT(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeSRT(testParts(t), &b))
+
+	assert.Equal(t, "1\n00:00:00,500 --> 00:00:02,000\nlabas, vakaras.\n\n2\n00:00:03,000 --> 00:00:03,500\n\"ačiū\"\n\n",
+		b.String())
+}
+
+func TestSrtTime(t *testing.T) {
+	assert.Equal(t, "01:02:03,040", srtTime(time.Hour+2*time.Minute+3*time.Second+40*time.Millisecond))
+}
+
+func TestWriteWordsJSON(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeWordsJSON(testParts(t), &b))
+
+	assert.Equal(t, `{"words":[{"word":"labas","punct":",","start":0.5,"end":1.2,"confidence":0.9,"speaker":"S0000"},`+
+		`{"word":"vakaras","punct":".","start":1.2,"end":2,"speaker":"S0000"},`+
+		`{"word":"\"ačiū\"","start":3,"end":3.5,"speaker":"S0001"}]}`+"\n", b.String())
+}
+
+func TestWriteWordsJSON_Empty(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeWordsJSON(nil, &b))
+
+	assert.Equal(t, `{"words":[]}`+"\n", b.String())
+}
+
+func TestWriteTextGrid(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeTextGrid(testParts(t), &b))
+
+	s := b.String()
+	assert.True(t, strings.HasPrefix(s, "File type = \"ooTextFile\"\nObject class = \"TextGrid\"\n\nxmin = 0\nxmax = 3.500\n"))
+	assert.Contains(t, s, "size = 2\n")
+	assert.Contains(t, s, "name = \"S0000\"\n        xmin = 0\n        xmax = 3.500\n        intervals: size = 4\n")
+	assert.Contains(t, s, "xmin = 0.500\n            xmax = 1.200\n            text = \"labas,\"\n")
+	assert.Contains(t, s, "text = \"\"\"ačiū\"\"\"\n")
+}
+
+func TestTierIntervals(t *testing.T) {
+	res := tierIntervals([]*lattice.Word{{From: time.Second, To: 2 * time.Second, Word: "a"},
+		{From: 1500 * time.Millisecond, To: 3 * time.Second, Word: "b"}}, 4*time.Second)
+
+	assert.Equal(t, []interval{{from: 0, to: time.Second}, {from: time.Second, to: 2 * time.Second, text: "a"},
+		{from: 2 * time.Second, to: 4 * time.Second}}, res)
+	assert.Equal(t, []interval{{}}, tierIntervals(nil, 0))
+}
+
+func TestWriteDocx(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeDocx(testParts(t), &b))
+
+	doc := readZipFile(t, b.Bytes(), "word/document.xml")
+	assert.Contains(t, doc, `<w:t xml:space="preserve">S0000 [00:00:00]: </w:t></w:r><w:r><w:t>labas, vakaras.</w:t>`)
+	assert.Contains(t, doc, `<w:t>&#34;ačiū&#34;</w:t>`)
+	assert.NotEmpty(t, readZipFile(t, b.Bytes(), "[Content_Types].xml"))
+}
+
+func TestWriteOdt(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, writeOdt(testParts(t), &b))
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, "mimetype", zr.File[0].Name)
+	assert.Equal(t, zip.Store, zr.File[0].Method)
+	assert.Equal(t, odtMimeType, readZipFile(t, b.Bytes(), "mimetype"))
+	assert.Contains(t, readZipFile(t, b.Bytes(), "content.xml"), `<text:p>S0001 [00:00:03]: &#34;ačiū&#34;</text:p>`)
+}
+
+func readZipFile(t *testing.T, data []byte, name string) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	f, err := zr.Open(name)
+	assert.Nil(t, err)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	res, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	return string(res)
+}
+
+func TestResult_Converted(t *testing.T) {
+	initTest()
+	initFilesMock(t, resultFileLoaderMock, map[string][]byte{"id/lat.restored.txt": []byte(testLat)})
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/result.srt", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "application/x-subrip; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(resp.Body.String(), "1\n00:00:00,500 --> 00:00:02,000\n"))
+}
+
+func TestResult_ConvertedFromGz(t *testing.T) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(testLat))
+	zw.Close()
+	initTest()
+	initFilesMock(t, resultFileLoaderMock, map[string][]byte{"id/lat.restored.gz": b.Bytes()})
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/words.json", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, 3, len(decodeWords(t, resp.Body.Bytes()).Words))
+}
+
+func TestResult_ConvertedNoLattice(t *testing.T) {
+	initTest()
+	initFilesMock(t, resultFileLoaderMock, nil)
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/result.docx", nil))
+
+	assert.Equal(t, 404, resp.Code)
+}
+
+func TestResult_ConvertedWrongLattice(t *testing.T) {
+	initTest()
+	initFilesMock(t, resultFileLoaderMock, map[string][]byte{"id/lat.restored.txt": []byte("olia")})
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/result.TextGrid", nil))
+
+	assert.Equal(t, 404, resp.Code)
+}
+
+func decodeWords(t *testing.T, b []byte) *api.Words {
+	var res api.Words
+	assert.Nil(t, json.Unmarshal(b, &res))
+	return &res
+}
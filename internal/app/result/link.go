@@ -0,0 +1,39 @@
+package result
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
+	"github.com/gorilla/mux"
+)
+
+// linkHandler issues the signed links, the edit scope links are given only here as the status links are read-only
+type linkHandler struct {
+	data *ServiceData
+}
+
+func (h linkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" || strings.Contains(id, "..") {
+		http.Error(w, "Wrong ID", http.StatusBadRequest)
+		cmdapp.Log.Errorf("Wrong ID '%s'", id)
+		return
+	}
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = urlsign.ScopeEdit
+	}
+	if scope != urlsign.ScopeEdit && scope != urlsign.ScopeRead {
+		http.Error(w, "Wrong scope", http.StatusBadRequest)
+		cmdapp.Log.Errorf("Wrong scope '%s'", scope)
+		return
+	}
+	exp := time.Now().Add(h.data.linkTTL)
+	cmdapp.Log.Infof("Issuing %s link for %s", scope, id)
+	writeJSON(w, &api.Link{Scope: scope, Expires: exp.Truncate(time.Second),
+		Query: h.data.urlSigner.Sign(id, scope, exp).Encode()})
+}
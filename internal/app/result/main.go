@@ -3,12 +3,14 @@ package result
 import (
 	"time"
 
-	"github.com/airenas/listgo/internal/pkg/loader"
 	"github.com/airenas/listgo/internal/pkg/metrics"
 	"github.com/airenas/listgo/internal/pkg/mongo"
+	"github.com/airenas/listgo/internal/pkg/storage"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/heptiolabs/healthcheck"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +28,8 @@ func init() {
 	cmdapp.Config.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
 	cmdapp.Config.SetDefault("port", 8080)
 	cmdapp.Config.SetDefault("fileStorage.audio", "/data/audio.in/")
+	cmdapp.Config.SetDefault("edits.enabled", true)
+	cmdapp.Config.SetDefault("urlSign.linkTTL", "1h")
 }
 
 // Execute starts the server
@@ -46,20 +50,75 @@ func run(cmd *cobra.Command, args []string) {
 	defer mongoSessionProvider.Close()
 	data.health.AddLivenessCheck("mongo", healthcheck.Async(mongoSessionProvider.Healthy, 10*time.Second))
 
-	data.fileNameProvider, err = mongo.NewFileNameProvider(mongoSessionProvider)
+	fnp, err := mongo.NewFileNameProvider(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "Can't init fileName provider")
-
-	data.audioFileLoader, err = loader.NewLocalFileLoader(cmdapp.Config.GetString("fileStorage.audio"))
+	data.fileNameProvider = fnp
+	data.sourceIDProvider = fnp
+	data.speakerNames, err = mongo.NewSpeakerNames(mongoSessionProvider)
+	cmdapp.CheckOrPanic(err, "Can't init speaker names")
+
+	audioStorage, err := storage.New(cmdapp.Config.GetString("fileStorage.audio"))
+	cmdapp.CheckOrPanic(err, "Can't init audio storage")
+	data.audioFileLoader, err = storage.NewLoader(audioStorage)
 	cmdapp.CheckOrPanic(err, "Can't init audioFileLoader provider")
+	if dir := cmdapp.Config.GetString("fileStorage.preview"); dir != "" {
+		previewStorage, err := storage.New(dir)
+		cmdapp.CheckOrPanic(err, "Can't init preview storage")
+		data.previewLoader, err = storage.NewLoader(previewStorage)
+		cmdapp.CheckOrPanic(err, "Can't init previewLoader")
+		data.previewSaver, err = storage.NewSaver(previewStorage)
+		cmdapp.CheckOrPanic(err, "Can't init previewSaver")
+		data.previewWav = true
+	} else {
+		cmdapp.Log.Warn("No fileStorage.preview, audio peaks and clips are disabled")
+	}
 
-	data.resultFileLoader, err = loader.NewLocalFileLoader(cmdapp.Config.GetString("fileStorage.results"))
+	resultStorage, err := storage.New(cmdapp.Config.GetString("fileStorage.results"))
+	cmdapp.CheckOrPanic(err, "Can't init result storage")
+	data.resultFileLoader, err = storage.NewLoader(resultStorage)
 	cmdapp.CheckOrPanic(err, "Can't init resultFileLoader provider")
+	if cmdapp.Config.GetBool("edits.enabled") {
+		data.resultFileSaver, err = storage.NewSaver(resultStorage)
+		cmdapp.CheckOrPanic(err, "Can't init resultFileSaver")
+		data.resultFileRemover = resultStorage
+		data.versions, err = mongo.NewTranscriptVersions(mongoSessionProvider)
+		cmdapp.CheckOrPanic(err, "Can't init transcript versions")
+	}
+	data.adminKey = cmdapp.Config.GetString("admin.key")
+	if dir := cmdapp.Config.GetString("fileStorage.logs"); dir != "" {
+		if data.adminKey == "" {
+			cmdapp.CheckOrPanic(errors.New("No admin.key"), "Can't init logs endpoint")
+		}
+		data.logFileLoader, err = newStorageLoader(dir)
+		cmdapp.CheckOrPanic(err, "Can't init logFileLoader provider")
+	}
+	if key := cmdapp.Config.GetString("urlSign.key"); key != "" {
+		data.urlSigner, err = urlsign.NewSigner(key)
+		cmdapp.CheckOrPanic(err, "Can't init URL signer")
+		data.signRequired = cmdapp.Config.GetBool("urlSign.required")
+		cmdapp.Log.Infof("Signed links required: %t", data.signRequired)
+		data.linkTTL = cmdapp.Config.GetDuration("urlSign.linkTTL")
+		if data.linkTTL <= 0 {
+			cmdapp.CheckOrPanic(errors.New("Wrong urlSign.linkTTL"), "Can't init links endpoint")
+		}
+		if data.adminKey == "" {
+			cmdapp.Log.Warn("No admin.key, issuing the edit links is disabled")
+		}
+	}
 	data.port = cmdapp.Config.GetInt("port")
 
 	err = StartWebServer(data)
 	cmdapp.CheckOrPanic(err, "Can't start web server")
 }
 
+func newStorageLoader(location string) (*storage.Loader, error) {
+	st, err := storage.New(location)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewLoader(st)
+}
+
 func initMetrics(data *ServiceData) error {
 	namespace := "result_service"
 	data.metrics.audioResponseDur = prometheus.NewHistogramVec(
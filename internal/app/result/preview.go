@@ -0,0 +1,257 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/wav"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPeaksResolution = 100
+	maxPeaksResolution     = 1000
+	maxClipDuration        = 10 * time.Minute
+)
+
+// cachedPeaksResolutions are saved next to the audio, the other resolutions are calculated on every request
+var cachedPeaksResolutions = map[int]bool{10: true, 50: true, defaultPeaksResolution: true}
+
+// previewName returns the converted wav name of the audio file
+func previewName(data *ServiceData, fileName string) string {
+	if !data.previewWav {
+		return fileName
+	}
+	return strings.TrimSuffix(fileName, path.Ext(fileName)) + ".wav"
+}
+
+// previewFile returns the converted audio name of the ID, the returned status code is set on failure
+func previewFile(data *ServiceData, id string) (string, int, error) {
+	if id == "" || strings.Contains(id, "..") {
+		return "", http.StatusBadRequest, errors.Errorf("Wrong ID '%s'", id)
+	}
+	fileName, err := data.fileNameProvider.Get(id)
+	if err != nil {
+		return "", http.StatusNotFound, errors.Wrapf(err, "Cannot get file name for ID: %s", id)
+	}
+	if fileName == "" {
+		return "", http.StatusNotFound, errors.Errorf("No file name for ID: %s", id)
+	}
+	return previewName(data, fileName), 0, nil
+}
+
+// openPreview opens the converted audio, the returned status code is set on failure
+func openPreview(data *ServiceData, name string) (api.File, *wav.Header, int, error) {
+	file, err := data.previewLoader.Load(name)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, errors.Wrapf(err, "Cannot load %s", name)
+	}
+	h, err := wav.ReadHeader(file)
+	if err != nil {
+		file.Close()
+		if errors.Cause(err) == wav.ErrNotPCM {
+			return nil, nil, http.StatusUnsupportedMediaType, errors.Wrapf(err, "Wrong audio %s", name)
+		}
+		return nil, nil, http.StatusInternalServerError, errors.Wrapf(err, "Cannot read %s", name)
+	}
+	return file, h, 0, nil
+}
+
+type peaksHandler struct {
+	data *ServiceData
+}
+
+func (h peaksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("Peaks request from %s", r.Host)
+	id := mux.Vars(r)["id"]
+	res := defaultPeaksResolution
+	if s := r.URL.Query().Get("resolution"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > maxPeaksResolution {
+			http.Error(w, fmt.Sprintf("Wrong resolution, expected 1-%d", maxPeaksResolution), http.StatusBadRequest)
+			cmdapp.Log.Errorf("Wrong resolution '%s'", s)
+			return
+		}
+		res = v
+	}
+	name, code, err := previewFile(h.data, id)
+	if err != nil {
+		http.Error(w, "Cannot get peaks for ID: "+id, code)
+		cmdapp.Log.Error(err)
+		return
+	}
+	cache := cachedPeaksResolutions[res]
+	if cache {
+		if cached, err := h.data.previewLoader.Load(peaksName(name, res)); err == nil {
+			defer cached.Close()
+			servePeaks(w, r, cached)
+			return
+		}
+	}
+	file, header, code, err := openPreview(h.data, name)
+	if err != nil {
+		http.Error(w, "Cannot get peaks for ID: "+id, code)
+		cmdapp.Log.Error(err)
+		return
+	}
+	defer file.Close()
+	peaks, err := makePeaks(file, header, res)
+	if err != nil {
+		http.Error(w, "Cannot get peaks for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(errors.Wrapf(err, "Cannot make peaks for ID: %s", id))
+		return
+	}
+	b, _ := json.Marshal(peaks)
+	if cache && h.data.previewSaver != nil {
+		cmdapp.LogIf(errors.Wrap(h.data.previewSaver.Save(peaksName(name, res), bytes.NewReader(b)), "Can't cache peaks"))
+	}
+	servePeaks(w, r, bytes.NewReader(b))
+}
+
+func peaksName(audioName string, resolution int) string {
+	return fmt.Sprintf("%s.peaks.%d.json", audioName, resolution)
+}
+
+func servePeaks(w http.ResponseWriter, r *http.Request, rs io.ReadSeeker) {
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeContent(w, r, "peaks.json", time.Time{}, rs)
+}
+
+// makePeaks calculates min and max of every sampleRate/resolution mono samples
+func makePeaks(file api.File, h *wav.Header, resolution int) (*api.Peaks, error) {
+	spp := h.SampleRate / resolution
+	if spp < 1 {
+		spp = 1
+	}
+	res := &api.Peaks{Version: 2, Channels: 1, SampleRate: h.SampleRate, SamplesPerPixel: spp, Bits: 16,
+		Data: make([]int16, 0, 2*(h.Samples()/int64(spp)+1))}
+	if _, err := file.Seek(h.DataOffset, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "can't seek")
+	}
+	mr := wav.NewMonoReader(io.LimitReader(file, h.DataSize), h)
+	var min, max int16
+	n := 0
+	for {
+		s, err := mr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "can't read audio")
+		}
+		if n == 0 || s < min {
+			min = s
+		}
+		if n == 0 || s > max {
+			max = s
+		}
+		n++
+		if n == spp {
+			res.Data = append(res.Data, min, max)
+			n = 0
+		}
+	}
+	if n > 0 {
+		res.Data = append(res.Data, min, max)
+	}
+	res.Length = len(res.Data) / 2
+	return res, nil
+}
+
+type clipHandler struct {
+	data *ServiceData
+}
+
+func (h clipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("Clip request from %s", r.Host)
+	id := mux.Vars(r)["id"]
+	from, to, err := parseClip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		cmdapp.Log.Errorf("Wrong clip: %v", err)
+		return
+	}
+	name, code, err := previewFile(h.data, id)
+	if err != nil {
+		http.Error(w, "Cannot get audio for ID: "+id, code)
+		cmdapp.Log.Error(err)
+		return
+	}
+	file, header, code, err := openPreview(h.data, name)
+	if err != nil {
+		http.Error(w, "Cannot get audio for ID: "+id, code)
+		cmdapp.Log.Error(err)
+		return
+	}
+	defer file.Close()
+	b, err := makeClip(file, header, from, to)
+	if err != nil {
+		http.Error(w, "Cannot clip audio for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(errors.Wrapf(err, "Cannot clip audio for ID: %s", id))
+		return
+	}
+	var modTime time.Time
+	if fi, err := file.Stat(); err == nil {
+		modTime = fi.ModTime()
+	}
+	clipName := fmt.Sprintf("%s_%d-%d.wav", id, from.Milliseconds(), to.Milliseconds())
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", "attachment; filename="+clipName)
+	http.ServeContent(w, r, clipName, modTime, bytes.NewReader(b))
+}
+
+func parseClip(r *http.Request) (time.Duration, time.Duration, error) {
+	from, err := parseSeconds(r.URL.Query().Get("from"))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Wrong from")
+	}
+	to, err := parseSeconds(r.URL.Query().Get("to"))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Wrong to")
+	}
+	if to <= from {
+		return 0, 0, errors.New("Wrong clip, to <= from")
+	}
+	if to-from > maxClipDuration {
+		return 0, 0, errors.Errorf("Too long clip, max %s", maxClipDuration.String())
+	}
+	return from, to, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 {
+		return 0, errors.New("negative time")
+	}
+	return toDuration(f), nil
+}
+
+// makeClip copies the samples to the new wav, the clip is limited to the audio length
+func makeClip(file api.File, h *wav.Header, from, to time.Duration) ([]byte, error) {
+	ba := int64(h.BlockAlign())
+	start, end := h.SampleAt(from)*ba, h.SampleAt(to)*ba
+	var b bytes.Buffer
+	if err := wav.WriteHeader(&b, h, end-start); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(h.DataOffset+start, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "can't seek")
+	}
+	if _, err := io.CopyN(&b, file, end-start); err != nil {
+		return nil, errors.Wrap(err, "can't read audio")
+	}
+	return b.Bytes(), nil
+}
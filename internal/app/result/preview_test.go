@@ -0,0 +1,175 @@
+package result
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/wav"
+	"github.com/gorilla/mux"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWav(t *testing.T, sampleRate int, samples ...int16) []byte {
+	var b bytes.Buffer
+	assert.Nil(t, wav.WriteHeader(&b, &wav.Header{Channels: 1, SampleRate: sampleRate, BitsPerSample: 16},
+		int64(len(samples)*2)))
+	for _, s := range samples {
+		binary.Write(&b, binary.LittleEndian, s)
+	}
+	return b.Bytes()
+}
+
+// initPreviewMock returns the file name for every ID, the preview files are loaded and saved in the returned map
+func initPreviewMock(t *testing.T, name string) map[string][]byte {
+	files := map[string][]byte{"a.wav": testWav(t, 10, 1, -2, 3, 4, 5, -6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20),
+		"b.mp3": []byte("ID3 olia olia olia olia olia olia olia olia olia olia olia")}
+	pegomock.When(fileNameProviderMock.Get(pegomock.AnyString())).ThenReturn(name, nil)
+	initFilesMock(t, previewLoaderMock, files)
+	initSaverMock(fileSaverMock, files)
+	return files
+}
+
+func newTestPreviewRouter(previewWav bool) *mux.Router {
+	data := newTestData()
+	data.previewLoader = previewLoaderMock
+	data.previewSaver = fileSaverMock
+	data.previewWav = previewWav
+	return NewRouter(data)
+}
+
+func TestPeaks(t *testing.T) {
+	initTest()
+	files := initPreviewMock(t, "a.mp3")
+	files["a.wav.peaks.2.json"] = []byte(`{"cached":1}`)
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/peaks?resolution=2", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	var p api.Peaks
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &p))
+	assert.Equal(t, api.Peaks{Version: 2, Channels: 1, SampleRate: 10, SamplesPerPixel: 5, Bits: 16, Length: 4,
+		Data: []int16{-2, 5, -6, 10, 11, 15, 16, 20}}, p)
+	assert.Equal(t, `{"cached":1}`, string(files["a.wav.peaks.2.json"]))
+}
+
+func TestPeaks_Cached(t *testing.T) {
+	initTest()
+	files := initPreviewMock(t, "a.mp3")
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/peaks?resolution=10", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	var p api.Peaks
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &p))
+	assert.Equal(t, 1, p.SamplesPerPixel)
+	assert.Equal(t, 20, p.Length)
+	assert.Equal(t, resp.Body.Bytes(), files["a.wav.peaks.10.json"])
+}
+
+func TestPeaks_FromCache(t *testing.T) {
+	initTest()
+	files := initPreviewMock(t, "a.mp3")
+	files["a.wav.peaks.100.json"] = []byte(`{"cached":1}`)
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/peaks", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"cached":1}`, resp.Body.String())
+}
+
+func TestPeaks_Fails(t *testing.T) {
+	for _, tc := range []struct {
+		url, name string
+		err       error
+		code      int
+	}{
+		{"/audio/id/peaks?resolution=0", "a.mp3", nil, 400},
+		{"/audio/id/peaks?resolution=1001", "a.mp3", nil, 400},
+		{"/audio/id/peaks?resolution=a", "a.mp3", nil, 400},
+		{"/audio/id/peaks", "", nil, 404},
+		{"/audio/id/peaks", "a.mp3", errors.New("olia"), 404},
+		{"/audio/id/peaks", "c.mp3", nil, 404},
+		{"/audio/id/peaks", "b.mp3", nil, 415},
+	} {
+		initTest()
+		initPreviewMock(t, tc.name)
+		pegomock.When(fileNameProviderMock.Get(pegomock.AnyString())).ThenReturn(tc.name, tc.err)
+		resp := httptest.NewRecorder()
+
+		newTestPreviewRouter(tc.name == "a.mp3").ServeHTTP(resp, httptest.NewRequest("GET", tc.url, nil))
+
+		assert.Equal(t, tc.code, resp.Code, tc.url+" "+tc.name)
+	}
+}
+
+func TestClip(t *testing.T) {
+	initTest()
+	initPreviewMock(t, "a.mp3")
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/clip?from=0.3&to=0.5", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "audio/wav", resp.Header().Get("Content-Type"))
+	assert.Equal(t, testWav(t, 10, 4, 5), resp.Body.Bytes())
+}
+
+func TestClip_LimitedToAudio(t *testing.T) {
+	initTest()
+	initPreviewMock(t, "a.mp3")
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/clip?from=1.8&to=5", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, testWav(t, 10, 19, 20), resp.Body.Bytes())
+}
+
+func TestClip_Wrong(t *testing.T) {
+	for _, q := range []string{"", "?from=1", "?to=1", "?from=a&to=1", "?from=1&to=1", "?from=-1&to=1",
+		"?from=0&to=601"} {
+		initTest()
+		initPreviewMock(t, "a.mp3")
+		resp := httptest.NewRecorder()
+
+		newTestPreviewRouter(true).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/clip"+q, nil))
+
+		assert.Equal(t, 400, resp.Code, q)
+	}
+}
+
+func TestClip_NotWav(t *testing.T) {
+	initTest()
+	initPreviewMock(t, "b.mp3")
+	resp := httptest.NewRecorder()
+
+	newTestPreviewRouter(false).ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/clip?from=0&to=1", nil))
+
+	assert.Equal(t, 415, resp.Code)
+}
+
+func TestPreview_Disabled(t *testing.T) {
+	initTest()
+	initPreviewMock(t, "a.mp3")
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/audio/id/peaks", nil))
+
+	assert.NotEqual(t, 200, resp.Code)
+}
+
+func TestPreviewName(t *testing.T) {
+	data := &ServiceData{}
+	assert.Equal(t, "a/b.mp3", previewName(data, "a/b.mp3"))
+	data.previewWav = true
+	assert.Equal(t, "a/b.wav", previewName(data, "a/b.mp3"))
+}
@@ -1,11 +1,14 @@
 package result
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
 	"github.com/gorilla/mux"
 	"github.com/heptiolabs/healthcheck"
 	"github.com/pkg/errors"
@@ -25,8 +28,33 @@ type ServiceData struct {
 	audioFileLoader  FileLoader
 	resultFileLoader FileLoader
 	fileNameProvider FileNameProvider
-	port             int
-	health           healthcheck.Handler
+	// sourceIDProvider is optional, it resolves the deduplicated transcriptions to the source ID
+	sourceIDProvider SourceIDProvider
+	// previewLoader loads the converted wav for /audio/{id}/peaks and /audio/{id}/clip, disabled if nil
+	previewLoader FileLoader
+	// previewSaver caches the peaks next to the audio, optional
+	previewSaver FileSaver
+	// previewWav indicates the preview audio is named as the uploaded one with the .wav extension
+	previewWav bool
+	// speakerNames keeps the user's speaker names, /result/{id}/speakers is disabled if nil
+	speakerNames SpeakerNameStore
+	// versions keeps the corrected transcripts, editing is disabled if nil
+	versions        VersionStore
+	resultFileSaver FileSaver
+	// resultFileRemover deletes the edit file of the rejected version
+	resultFileRemover FileRemover
+	// logFileLoader loads worker logs, /logs endpoint is disabled if nil
+	logFileLoader FileLoader
+	// urlSigner verifies the signed links, nil - the links are not checked
+	urlSigner *urlsign.Signer
+	// signRequired rejects the unsigned links, otherwise only the signed ones are verified
+	signRequired bool
+	// linkTTL is the validity of the links issued by /links/{id}
+	linkTTL time.Duration
+	// adminKey is a bearer token required for the admin endpoints
+	adminKey string
+	port     int
+	health   healthcheck.Handler
 
 	metrics serviceMetric
 }
@@ -53,14 +81,34 @@ func StartWebServer(data *ServiceData) error {
 // NewRouter creates the router for HTTP service
 func NewRouter(data *ServiceData) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
-	rh := promhttp.InstrumentHandlerDuration(data.metrics.resultResponseDur,
-		promhttp.InstrumentHandlerResponseSize(data.metrics.resultResponseSize, resultHandler{data: data}))
-	ah := promhttp.InstrumentHandlerDuration(data.metrics.audioResponseDur,
-		promhttp.InstrumentHandlerResponseSize(data.metrics.audioResponseSize, audioHandler{data: data}))
+	rh := signedOnly(data.urlSigner, data.signRequired, promhttp.InstrumentHandlerDuration(data.metrics.resultResponseDur,
+		promhttp.InstrumentHandlerResponseSize(data.metrics.resultResponseSize, resultHandler{data: data})))
+	ah := signedOnly(data.urlSigner, data.signRequired, promhttp.InstrumentHandlerDuration(data.metrics.audioResponseDur,
+		promhttp.InstrumentHandlerResponseSize(data.metrics.audioResponseSize, audioHandler{data: data})))
 	router.Methods("GET").Path("/audio/{id}").Handler(ah)
+	if data.previewLoader != nil {
+		router.Methods("GET").Path("/audio/{id}/peaks").
+			Handler(signedOnly(data.urlSigner, data.signRequired, peaksHandler{data: data}))
+		router.Methods("GET").Path("/audio/{id}/clip").
+			Handler(signedOnly(data.urlSigner, data.signRequired, clipHandler{data: data}))
+	}
+	if data.speakerNames != nil {
+		router.Methods("GET", "PUT").Path("/result/{id}/speakers").
+			Handler(signedOnly(data.urlSigner, data.signRequired, speakersHandler{data: data}))
+	}
+	if data.versions != nil {
+		router.Methods("GET", "POST").Path("/result/{id}/versions").
+			Handler(signedOnly(data.urlSigner, data.signRequired, versionsHandler{data: data}))
+	}
 	router.Methods("GET").Path("/result/{id}/{file}").Handler(rh)
 	router.Methods("HEAD").Path("/audio/{id}").Handler(ah)
 	router.Methods("HEAD").Path("/result/{id}/{file}").Handler(rh)
+	if data.logFileLoader != nil {
+		router.Methods("GET").Path("/logs/{id}/{step}").Handler(adminOnly(data.adminKey, logHandler{data: data}))
+	}
+	if data.urlSigner != nil && data.adminKey != "" {
+		router.Methods("POST").Path("/links/{id}").Handler(adminOnly(data.adminKey, linkHandler{data: data}))
+	}
 	router.Methods("GET").Path("/metrics").Handler(promhttp.Handler())
 	if data.health != nil {
 		router.Methods("GET").Path("/live").HandlerFunc(data.health.LiveEndpoint)
@@ -133,7 +181,40 @@ func (h resultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, err := h.data.resultFileLoader.Load(id + "/" + fileName)
+	rID, err := resolveID(h.data.sourceIDProvider, id)
+	if err != nil {
+		http.Error(w, "Cannot get file for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	c, convert := converters[fileName]
+	rc, renamed := renamedConverters[fileName]
+	var names map[string]string
+	if convert || renamed {
+		if names, err = getSpeakerNames(h.data, id); err != nil {
+			http.Error(w, "Cannot get file for ID: "+id, http.StatusInternalServerError)
+			cmdapp.Log.Error(err)
+			return
+		}
+	}
+	version, code, err := selectVersion(h.data, r, id, fileName)
+	if err != nil {
+		http.Error(w, "Cannot get file for ID: "+id, code)
+		cmdapp.Log.Error(err)
+		return
+	}
+	if version != nil {
+		serveVersion(w, r, h.data.resultFileLoader, version, fileName, names)
+		return
+	}
+	if renamed && len(names) > 0 {
+		c, convert = rc, true
+	}
+	if convert {
+		serveConverted(w, r, h.data.resultFileLoader, rID, fileName, c, names)
+		return
+	}
+	file, err := h.data.resultFileLoader.Load(rID + "/" + fileName)
 	if err != nil {
 		http.Error(w, "Cannot get file for ID: "+id, http.StatusNotFound)
 		cmdapp.Log.Errorf("Cannot get file %s for ID: %s", fileName, id)
@@ -151,3 +232,89 @@ func (h resultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", "attachment; filename="+fileInfo.Name())
 	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
 }
+
+// resolveID returns the source ID if the results of the ID are shared
+func resolveID(p SourceIDProvider, id string) (string, error) {
+	if p == nil {
+		return id, nil
+	}
+	res, err := p.GetSourceID(id)
+	if err != nil {
+		return "", errors.Wrapf(err, "Can't get source ID for %s", id)
+	}
+	if res == "" {
+		return id, nil
+	}
+	return res, nil
+}
+
+// adminOnly allows the request only with the 'Authorization: Bearer <key>' header
+func adminOnly(key string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if key == "" || subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			cmdapp.Log.Warnf("Unauthorized admin request from %s", r.RemoteAddr)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// signedOnly verifies the signed link of the job routes, the unsigned links pass if the signature is not required.
+// The changing requests need the link of the edit scope
+func signedOnly(signer *urlsign.Signer, required bool, h http.Handler) http.Handler {
+	if signer == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := urlsign.ScopeRead
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			scope = urlsign.ScopeEdit
+		}
+		err := signer.Verify(mux.Vars(r)["id"], scope, r.URL.Query(), time.Now())
+		if err == urlsign.ErrNoSignature && !required {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			cmdapp.Log.Warnf("Wrong link from %s: %v", r.RemoteAddr, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type logHandler struct {
+	data *ServiceData
+}
+
+func (h logHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("Log load request from %s", r.Host)
+	id := mux.Vars(r)["id"]
+	step := mux.Vars(r)["step"]
+	if strings.Contains(step, "..") || strings.Contains(id, "..") {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		cmdapp.Log.Errorf("invalid URL path %s/%s", id, step)
+		return
+	}
+
+	file, err := h.data.logFileLoader.Load(id + "/" + step + ".log")
+	if err != nil {
+		http.Error(w, "Cannot get log for ID: "+id, http.StatusNotFound)
+		cmdapp.Log.Errorf("Cannot get log %s for ID: %s", step, id)
+		return
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Cannot get log for ID: "+id, http.StatusNotFound)
+		cmdapp.Log.Errorf("Cannot get log info for ID: " + id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+}
@@ -2,8 +2,11 @@ package result
 
 import (
 	"errors"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -13,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
 	"github.com/gorilla/mux"
 	"github.com/petergtz/pegomock"
 )
@@ -21,12 +25,26 @@ var audioFileLoaderMock *mocks.MockFileLoader
 var resultFileLoaderMock *mocks.MockFileLoader
 var fileMock *mocks.MockFile
 var fileNameProviderMock *mocks.MockFileNameProvider
+var logFileLoaderMock *mocks.MockFileLoader
+var speakerNamesMock *mocks.MockSpeakerNameStore
+var versionsMock *mocks.MockVersionStore
+var fileSaverMock *mocks.MockFileSaver
+var fileRemoverMock *mocks.MockFileRemover
+var previewLoaderMock *mocks.MockFileLoader
+var sourceIDProviderMock *mocks.MockSourceIDProvider
 
 func initTest() {
 	audioFileLoaderMock = mocks.NewMockFileLoader()
 	resultFileLoaderMock = mocks.NewMockFileLoader()
 	fileMock = mocks.NewMockFile()
 	fileNameProviderMock = mocks.NewMockFileNameProvider()
+	logFileLoaderMock = mocks.NewMockFileLoader()
+	speakerNamesMock = mocks.NewMockSpeakerNameStore()
+	versionsMock = mocks.NewMockVersionStore()
+	fileSaverMock = mocks.NewMockFileSaver()
+	fileRemoverMock = mocks.NewMockFileRemover()
+	previewLoaderMock = mocks.NewMockFileLoader()
+	sourceIDProviderMock = mocks.NewMockSourceIDProvider()
 }
 
 func TestWrongPath(t *testing.T) {
@@ -74,6 +92,37 @@ func initAudioMock() {
 		})
 }
 
+// initFilesMock makes the loader return the files, the other names are not found
+func initFilesMock(t *testing.T, loader *mocks.MockFileLoader, files map[string][]byte) {
+	pegomock.When(loader.Load(pegomock.AnyString())).Then(
+		func(params []pegomock.Param) pegomock.ReturnValues {
+			d, ok := files[params[0].(string)]
+			if !ok {
+				return []pegomock.ReturnValue{nil, errors.New("no file")}
+			}
+			return []pegomock.ReturnValue{newTestFile(t, d), nil}
+		})
+}
+
+// initSaverMock makes the saver keep the files in the map
+func initSaverMock(saver *mocks.MockFileSaver, files map[string][]byte) {
+	pegomock.When(saver.Save(pegomock.AnyString(), matchers.AnyIoReader())).Then(
+		func(params []pegomock.Param) pegomock.ReturnValues {
+			b, err := io.ReadAll(params[1].(io.Reader))
+			files[params[0].(string)] = b
+			return []pegomock.ReturnValue{err}
+		})
+}
+
+func newTestFile(t *testing.T, d []byte) *os.File {
+	name := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(name, d, 0644))
+	res, err := os.Open(name)
+	assert.Nil(t, err)
+	t.Cleanup(func() { res.Close() })
+	return res
+}
+
 func newTestData() *ServiceData {
 	data := &ServiceData{}
 	data.audioFileLoader = audioFileLoaderMock
@@ -243,6 +292,80 @@ func TestAudio_AddMetrics(t *testing.T) {
 	assert.Equal(t, 1, testutil.CollectAndCount(data.metrics.audioResponseSize))
 }
 
+func newTestLogData() *ServiceData {
+	data := newTestData()
+	data.logFileLoader = logFileLoaderMock
+	data.adminKey = "key"
+	return data
+}
+
+func newTestLogRequest(path, key string) *http.Request {
+	req := httptest.NewRequest("GET", path, nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return req
+}
+
+func TestLogs(t *testing.T) {
+	initTest()
+	mocks.AttachMockToTest(t)
+	pegomock.When(logFileLoaderMock.Load(pegomock.AnyString())).ThenReturn(fileMock, nil)
+	pegomock.When(fileMock.Stat()).ThenReturn(mockedFileInfo{}, nil)
+	pegomock.When(fileMock.Seek(pegomock.AnyInt64(), pegomock.AnyInt())).ThenReturn(int64(2), nil)
+	pegomock.When(fileMock.Read(anyByteArray())).Then(
+		func(params []pegomock.Param) pegomock.ReturnValues {
+			return []pegomock.ReturnValue{2, nil}
+		})
+	resp := httptest.NewRecorder()
+	NewRouter(newTestLogData()).ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", "key"))
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "id/Diarization.log", logFileLoaderMock.VerifyWasCalledOnce().Load(pegomock.AnyString()).GetCapturedArguments())
+}
+
+func TestLogs_Forbidden(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+	NewRouter(newTestLogData()).ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", ""))
+	assert.Equal(t, 403, resp.Code)
+	resp = httptest.NewRecorder()
+	NewRouter(newTestLogData()).ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", "olia"))
+	assert.Equal(t, 403, resp.Code)
+	logFileLoaderMock.VerifyWasCalled(pegomock.Never()).Load(pegomock.AnyString())
+}
+
+func TestLogs_NoAdminKey(t *testing.T) {
+	initTest()
+	data := newTestLogData()
+	data.adminKey = ""
+	resp := httptest.NewRecorder()
+	NewRouter(data).ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", ""))
+	assert.Equal(t, 403, resp.Code)
+}
+
+func TestLogs_Disabled(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", "key"))
+	assert.Equal(t, 404, resp.Code)
+}
+
+func TestLogs_WrongPath(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+	NewRouter(newTestLogData()).ServeHTTP(resp, newTestLogRequest("/logs/id/..olia", "key"))
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestLogs_NotFound(t *testing.T) {
+	initTest()
+	pegomock.When(logFileLoaderMock.Load(pegomock.AnyString())).ThenReturn(nil, errors.New("Can not get"))
+	resp := httptest.NewRecorder()
+	NewRouter(newTestLogData()).ServeHTTP(resp, newTestLogRequest("/logs/id/Diarization", "key"))
+	assert.Equal(t, 404, resp.Code)
+}
+
 type mockedFileInfo struct {
 	os.FileInfo
 }
@@ -259,3 +382,37 @@ func anyByteArray() []byte {
 	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf([]byte{})))
 	return []byte{}
 }
+
+func TestResult_SourceID(t *testing.T) {
+	initTest()
+	initResultMock()
+	data := newTestData()
+	pegomock.When(sourceIDProviderMock.GetSourceID(pegomock.AnyString())).ThenReturn("src", nil)
+	data.sourceIDProvider = sourceIDProviderMock
+	resp := httptest.NewRecorder()
+	NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/file", nil))
+	assert.Equal(t, 200, resp.Code)
+	resultFileLoaderMock.VerifyWasCalledOnce().Load("src/file")
+}
+
+func TestResult_SourceIDFails(t *testing.T) {
+	initTest()
+	initResultMock()
+	data := newTestData()
+	pegomock.When(sourceIDProviderMock.GetSourceID(pegomock.AnyString())).ThenReturn("", errors.New("olia"))
+	data.sourceIDProvider = sourceIDProviderMock
+	resp := httptest.NewRecorder()
+	NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/file", nil))
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestResolveID(t *testing.T) {
+	initTest()
+	id, err := resolveID(nil, "id")
+	assert.Nil(t, err)
+	assert.Equal(t, "id", id)
+	pegomock.When(sourceIDProviderMock.GetSourceID(pegomock.AnyString())).ThenReturn("", nil)
+	id, err = resolveID(sourceIDProviderMock, "id")
+	assert.Nil(t, err)
+	assert.Equal(t, "id", id)
+}
@@ -0,0 +1,185 @@
+package result
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSignedData(t *testing.T, required bool) (*ServiceData, *urlsign.Signer) {
+	initTest()
+	initFilesMock(t, resultFileLoaderMock, map[string][]byte{"id/result.txt": []byte("olia"),
+		"id/lat.restored.txt": []byte(testLat)})
+	data := newTestData()
+	data.urlSigner, _ = urlsign.NewSigner("0123456789abcdef")
+	data.signRequired = required
+	return data, data.urlSigner
+}
+
+func TestSigned(t *testing.T) {
+	data, signer := newTestSignedData(t, true)
+	q := signer.Sign("id", urlsign.ScopeRead, time.Now().Add(time.Minute)).Encode()
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/result/id/result.txt?" + q, 200},
+		{"/result/id/result.txt", 403},
+		{"/result/id/result.txt?" + signer.Sign("id", urlsign.ScopeRead, time.Now().Add(-time.Minute)).Encode(), 403},
+		{"/result/id/result.txt?" + signer.Sign("id2", urlsign.ScopeRead, time.Now().Add(time.Minute)).Encode(), 403},
+		{"/audio/id", 403},
+		{"/metrics", 200},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", tc.url, nil))
+
+		assert.Equal(t, tc.code, resp.Code, tc.url)
+	}
+}
+
+func TestSigned_NotRequired(t *testing.T) {
+	data, signer := newTestSignedData(t, false)
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/result/id/result.txt?" + signer.Sign("id", urlsign.ScopeRead, time.Now().Add(time.Minute)).Encode(), 200},
+		{"/result/id/result.txt", 200},
+		{"/result/id/result.txt?" + signer.Sign("id", urlsign.ScopeRead, time.Now().Add(-time.Minute)).Encode(), 403},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", tc.url, nil))
+
+		assert.Equal(t, tc.code, resp.Code, tc.url)
+	}
+}
+
+func TestSigned_Edits(t *testing.T) {
+	data, signer := newTestSignedData(t, true)
+	data.speakerNames = speakerNamesMock
+	read := signer.Sign("id", urlsign.ScopeRead, time.Now().Add(time.Minute)).Encode()
+	edit := signer.Sign("id", urlsign.ScopeEdit, time.Now().Add(time.Minute)).Encode()
+
+	for _, tc := range []struct {
+		method, url string
+		code        int
+	}{
+		{"GET", "/result/id/speakers", 403},
+		{"GET", "/result/id/speakers?" + read, 200},
+		{"GET", "/result/id/speakers?" + edit, 200},
+		{"PUT", "/result/id/speakers?" + read, 403},
+		{"PUT", "/result/id/speakers?" + edit, 200},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest(tc.method, tc.url, strings.NewReader(`{"names":{}}`)))
+
+		assert.Equal(t, tc.code, resp.Code, tc.method+" "+tc.url)
+	}
+}
+
+func newTestLinkData(t *testing.T) *ServiceData {
+	data, _ := newTestSignedData(t, true)
+	initVersionsMock(t, testVersion)
+	data.speakerNames = speakerNamesMock
+	data.versions = versionsMock
+	data.resultFileSaver = fileSaverMock
+	data.resultFileRemover = fileRemoverMock
+	data.adminKey = "admin"
+	data.linkTTL = time.Minute
+	return data
+}
+
+func issueLink(t *testing.T, data *ServiceData, url, key string) (*api.Link, int) {
+	req := httptest.NewRequest("POST", url, nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	if resp.Code != 200 {
+		return nil, resp.Code
+	}
+	var res api.Link
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&res))
+	return &res, resp.Code
+}
+
+func TestLinks_Edit(t *testing.T) {
+	data := newTestLinkData(t)
+
+	l, code := issueLink(t, data, "/links/id", "admin")
+
+	assert.Equal(t, 200, code)
+	assert.Equal(t, urlsign.ScopeEdit, l.Scope)
+	assert.True(t, l.Expires.After(time.Now()))
+	for _, tc := range []struct {
+		method, url, body string
+		code              int
+	}{
+		{"GET", "/result/id/result.txt?" + l.Query, "", 200},
+		{"PUT", "/result/id/speakers?" + l.Query, `{"names":{"S0000":"Jonas"}}`, 200},
+		{"POST", "/result/id/versions?" + l.Query, testEdit, 201},
+		{"PUT", "/result/id2/speakers?" + l.Query, `{"names":{}}`, 403},
+		{"PUT", "/result/id/speakers", `{"names":{}}`, 403},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+
+		assert.Equal(t, tc.code, resp.Code, tc.method+" "+tc.url)
+	}
+}
+
+func TestLinks_Read(t *testing.T) {
+	data := newTestLinkData(t)
+
+	l, code := issueLink(t, data, "/links/id?scope=read", "admin")
+
+	assert.Equal(t, 200, code)
+	assert.Equal(t, urlsign.ScopeRead, l.Scope)
+	for _, tc := range []struct {
+		method, url, body string
+		code              int
+	}{
+		{"GET", "/result/id/speakers?" + l.Query, "", 200},
+		{"PUT", "/result/id/speakers?" + l.Query, `{"names":{}}`, 403},
+		{"POST", "/result/id/versions?" + l.Query, testEdit, 403},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+
+		assert.Equal(t, tc.code, resp.Code, tc.method+" "+tc.url)
+	}
+}
+
+func TestLinks_Fails(t *testing.T) {
+	data := newTestLinkData(t)
+
+	_, code := issueLink(t, data, "/links/id", "olia")
+	assert.Equal(t, 403, code)
+	_, code = issueLink(t, data, "/links/id", "")
+	assert.Equal(t, 403, code)
+	_, code = issueLink(t, data, "/links/id?scope=admin", "admin")
+	assert.Equal(t, 400, code)
+}
+
+func TestLinks_Disabled(t *testing.T) {
+	data := newTestLinkData(t)
+	data.adminKey = ""
+
+	_, code := issueLink(t, data, "/links/id", "")
+
+	assert.Equal(t, 404, code)
+}
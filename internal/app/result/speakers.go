@@ -0,0 +1,161 @@
+package result
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/result"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxSpeakerNames  = 100
+	maxSpeakerName   = 100
+	maxSpeakersBytes = 64 * 1024
+)
+
+// SpeakerNameStore keeps the user's names for the diarization speaker labels
+type SpeakerNameStore interface {
+	Get(ID string) (map[string]string, error)
+	Save(ID string, names map[string]string) error
+}
+
+// renamedConverters render the stored text results from the lattice when the speakers are renamed
+var renamedConverters = map[string]converter{
+	result.Txt:      {contentType: "text/plain; charset=utf-8", convert: lattice.WriteTxt},
+	result.TxtFinal: {contentType: "text/plain; charset=utf-8", convert: lattice.WriteTxtFinal},
+	result.WebVTT:   {contentType: "text/vtt; charset=utf-8", convert: lattice.WriteVTT},
+}
+
+type speakersHandler struct {
+	data *ServiceData
+}
+
+func (h speakersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("Speakers request from %s", r.Host)
+	id := mux.Vars(r)["id"]
+	if strings.Contains(id, "..") {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		cmdapp.Log.Errorf("invalid URL path %s", id)
+		return
+	}
+	if r.Method == http.MethodPut {
+		h.save(w, r, id)
+		return
+	}
+	rID, err := resolveID(h.data.sourceIDProvider, id)
+	if err != nil {
+		http.Error(w, "Cannot get speakers for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	parts, err := loadTranscript(h.data, id, rID)
+	if err != nil {
+		http.Error(w, "Cannot get speakers for ID: "+id, http.StatusNotFound)
+		cmdapp.Log.Errorf("Cannot get transcript for ID %s: %v", id, err)
+		return
+	}
+	names, err := h.data.speakerNames.Get(id)
+	if err != nil {
+		http.Error(w, "Cannot get speakers for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	writeJSON(w, &api.Speakers{Turns: speakerTurns(parts, names), Names: names})
+}
+
+// loadTranscript returns the latest corrected transcript or the original lattice
+func loadTranscript(data *ServiceData, id, rID string) ([]*lattice.Part, error) {
+	if data.versions != nil {
+		v, err := data.versions.Latest(id)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			return loadVersion(data.resultFileLoader, v)
+		}
+	}
+	parts, _, err := loadLattice(data.resultFileLoader, rID)
+	return parts, err
+}
+
+func (h speakersHandler) save(w http.ResponseWriter, r *http.Request, id string) {
+	var req api.Speakers
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSpeakersBytes)).Decode(&req); err != nil {
+		http.Error(w, "Wrong body", http.StatusBadRequest)
+		cmdapp.Log.Errorf("Can't decode speakers: %v", err)
+		return
+	}
+	if err := validateNames(req.Names); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		cmdapp.Log.Errorf("Wrong speaker names: %v", err)
+		return
+	}
+	if err := h.data.speakerNames.Save(id, req.Names); err != nil {
+		http.Error(w, "Cannot save speakers for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	writeJSON(w, &api.Speakers{Names: req.Names})
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		cmdapp.Log.Error(errors.Wrap(err, "Can't write response"))
+	}
+}
+
+func validateNames(names map[string]string) error {
+	if len(names) > maxSpeakerNames {
+		return errors.Errorf("Too many names, max %d", maxSpeakerNames)
+	}
+	for k, v := range names {
+		if k == "" || strings.ContainsAny(k, ".$ ") {
+			return errors.Errorf("Wrong speaker '%s'", k)
+		}
+		if len(v) > maxSpeakerName || strings.ContainsAny(v, "\n\r") {
+			return errors.Errorf("Wrong name for speaker '%s'", k)
+		}
+	}
+	return nil
+}
+
+// speakerTurns joins the consecutive parts of the same speaker
+func speakerTurns(parts []*lattice.Part, names map[string]string) []api.SpeakerTurn {
+	res := make([]api.SpeakerTurn, 0)
+	for _, l := range lattice.Lines(parts) {
+		mw := l.Words
+		if n := len(res); n > 0 && res[n-1].Speaker == l.Speaker {
+			res[n-1].To = mw[len(mw)-1].To.Seconds()
+			res[n-1].Text += " " + l.Text
+			continue
+		}
+		res = append(res, api.SpeakerTurn{Speaker: l.Speaker, Name: names[l.Speaker], From: l.At.Seconds(),
+			To: mw[len(mw)-1].To.Seconds(), Text: l.Text})
+	}
+	return res
+}
+
+// getSpeakerNames returns nil if the names are not supported
+func getSpeakerNames(data *ServiceData, id string) (map[string]string, error) {
+	if data.speakerNames == nil {
+		return nil, nil
+	}
+	res, err := data.speakerNames.Get(id)
+	return res, errors.Wrapf(err, "Can't get speaker names for %s", id)
+}
+
+// renameParts sets the user's names as the speakers of the lattice
+func renameParts(parts []*lattice.Part, names map[string]string) {
+	for _, p := range parts {
+		if n, ok := names[p.Speaker]; ok && n != "" {
+			p.Speaker = n
+		}
+	}
+}
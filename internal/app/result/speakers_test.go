@@ -0,0 +1,156 @@
+package result
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/gorilla/mux"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSpeakersLat = testLat + "# 3 S0001\n1 4 5 labas\n"
+
+func newTestSpeakersRouter(t *testing.T) *mux.Router {
+	initFilesMock(t, resultFileLoaderMock, map[string][]byte{"id/lat.restored.txt": []byte(testSpeakersLat),
+		"id/webvtt.txt": []byte("WEBVTT\n\n00:00.500 --> 00:02.000\n<v S0000>labas, vakaras.\n")})
+	data := newTestData()
+	data.speakerNames = speakerNamesMock
+	return NewRouter(data)
+}
+
+func TestSpeakers_GET(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(map[string]string{"S0001": "Jonas"}, nil)
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/speakers", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"turns":[{"speaker":"S0000","from":0.5,"to":2,"text":"labas, vakaras."},`+
+		`{"speaker":"S0001","name":"Jonas","from":3,"to":5,"text":"\"ačiū\" labas"}],"names":{"S0001":"Jonas"}}`+"\n",
+		resp.Body.String())
+}
+
+func TestSpeakers_GETNoLattice(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id2/speakers", nil))
+
+	assert.Equal(t, 404, resp.Code)
+}
+
+func TestSpeakers_GETFails(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/speakers", nil))
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestSpeakers_Disabled(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("PUT", "/result/id/speakers", strings.NewReader(`{}`)))
+
+	assert.Equal(t, 405, resp.Code)
+}
+
+func TestSpeakers_PUT(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("PUT", "/result/id/speakers",
+		strings.NewReader(`{"names":{"S0000":"Ona"}}`)))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"names":{"S0000":"Ona"}}`+"\n", resp.Body.String())
+	speakerNamesMock.VerifyWasCalledOnce().Save(pegomock.EqString("id"),
+		matchers.EqMapOfStringToString(map[string]string{"S0000": "Ona"}))
+}
+
+func TestSpeakers_PUTWrong(t *testing.T) {
+	for _, b := range []string{`olia`, `{"names":{"":"Ona"}}`, `{"names":{"a.b":"Ona"}}`, `{"names":{"S0":"O\nna"}}`,
+		`{"names":{"S0":"` + strings.Repeat("a", 101) + `"}}`} {
+		initTest()
+		resp := httptest.NewRecorder()
+
+		newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("PUT", "/result/id/speakers",
+			strings.NewReader(b)))
+
+		assert.Equal(t, 400, resp.Code, b)
+		speakerNamesMock.VerifyWasCalled(pegomock.Never()).Save(pegomock.AnyString(), matchers.AnyMapOfStringToString())
+	}
+}
+
+func TestSpeakers_PUTFails(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Save(pegomock.AnyString(), matchers.AnyMapOfStringToString())).
+		ThenReturn(errors.New("olia"))
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("PUT", "/result/id/speakers",
+		strings.NewReader(`{"names":{"S0000":"Ona"}}`)))
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestResult_RenamesVTT(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(map[string]string{"S0000": "Ona"}, nil)
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/webvtt.txt", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "text/vtt; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "WEBVTT\n\n00:00:00.500 --> 00:00:02.000\n<v Ona>labas, vakaras.\n\n"+
+		"00:00:03.000 --> 00:00:03.500\n<v S0001>\"ačiū\"\n\n00:00:04.000 --> 00:00:05.000\n<v S0001>labas\n\n",
+		resp.Body.String())
+}
+
+func TestResult_NotRenamed(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/webvtt.txt", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "WEBVTT\n\n00:00.500 --> 00:02.000\n<v S0000>labas, vakaras.\n", resp.Body.String())
+}
+
+func TestResult_RenamesConverted(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(map[string]string{"S0000": "Ona"}, nil)
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/words.json", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "Ona", decodeWords(t, resp.Body.Bytes()).Words[0].Speaker)
+}
+
+func TestResult_RenameFails(t *testing.T) {
+	initTest()
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	resp := httptest.NewRecorder()
+
+	newTestSpeakersRouter(t).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/webvtt.txt", nil))
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestSpeakerTurns(t *testing.T) {
+	parts := []*lattice.Part{{Speaker: "S0"}, {Speaker: "S1", Words: []*lattice.Word{{Main: true, Word: "a"}}}}
+
+	assert.Equal(t, []api.SpeakerTurn{{Speaker: "S1", Text: "a"}}, speakerTurns(parts, nil))
+}
@@ -0,0 +1,240 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const maxEditBytes = 50 * 1024 * 1024
+
+// VersionStore keeps the versions of the corrected transcripts
+type VersionStore interface {
+	// Latest returns nil if the transcript is not edited
+	Latest(ID string) (*persistence.TranscriptVersion, error)
+	// Get returns nil if there is no such version
+	Get(ID string, version int) (*persistence.TranscriptVersion, error)
+	List(ID string) ([]*persistence.TranscriptVersion, error)
+	// Add returns false if the version number is already taken
+	Add(v *persistence.TranscriptVersion) (bool, error)
+}
+
+// FileSaver saves the file
+type FileSaver interface {
+	Save(name string, reader io.Reader) error
+}
+
+// FileRemover deletes the file
+type FileRemover interface {
+	Delete(name string) error
+}
+
+// editedConverters render the corrected transcript, the lattice files have no corrected versions
+var editedConverters = newEditedConverters()
+
+func newEditedConverters() map[string]converter {
+	res := map[string]converter{}
+	for k, v := range renamedConverters {
+		res[k] = v
+	}
+	for k, v := range converters {
+		res[k] = v
+	}
+	return res
+}
+
+type versionsHandler struct {
+	data *ServiceData
+}
+
+func (h versionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("Versions request from %s", r.Host)
+	id := mux.Vars(r)["id"]
+	if strings.Contains(id, "..") || strings.Contains(id, "/") {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		cmdapp.Log.Errorf("invalid URL path %s", id)
+		return
+	}
+	if r.Method == http.MethodPost {
+		h.save(w, r, id)
+		return
+	}
+	vs, err := h.data.versions.List(id)
+	if err != nil {
+		http.Error(w, "Cannot get versions for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	res := &api.Versions{Versions: make([]api.Version, 0, len(vs))}
+	for _, v := range vs {
+		res.Versions = append(res.Versions, api.Version{Version: v.Version, Created: v.Created})
+		if v.Version > res.Latest {
+			res.Latest = v.Version
+		}
+	}
+	writeJSON(w, res)
+}
+
+func (h versionsHandler) save(w http.ResponseWriter, r *http.Request, id string) {
+	var words api.Words
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEditBytes)).Decode(&words); err != nil {
+		http.Error(w, "Wrong body", http.StatusBadRequest)
+		cmdapp.Log.Errorf("Can't decode transcript: %v", err)
+		return
+	}
+	if err := validateWords(words.Words); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		cmdapp.Log.Errorf("Wrong transcript: %v", err)
+		return
+	}
+	latest, err := h.data.versions.Latest(id)
+	if err != nil {
+		http.Error(w, "Cannot save version for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	v := &persistence.TranscriptVersion{ID: id, Version: 1, Created: time.Now()}
+	if latest != nil {
+		v.Version = latest.Version + 1
+	}
+	// the file name is unique, so a concurrent edit of the same version does not overwrite it
+	v.File = fmt.Sprintf("%s/edits/%d-%d.json", id, v.Version, v.Created.UnixNano())
+	b, _ := json.Marshal(words)
+	if err := h.data.resultFileSaver.Save(v.File, bytes.NewReader(b)); err != nil {
+		http.Error(w, "Cannot save version for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(errors.Wrapf(err, "Can't save %s", v.File))
+		return
+	}
+	added, err := h.data.versions.Add(v)
+	if err != nil {
+		http.Error(w, "Cannot save version for ID: "+id, http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+	if !added {
+		http.Error(w, "Transcript was changed, retry", http.StatusConflict)
+		cmdapp.Log.Warnf("Version %d for %s exists", v.Version, id)
+		cmdapp.LogIf(errors.Wrapf(h.data.resultFileRemover.Delete(v.File), "Can't delete %s", v.File))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, &api.Version{Version: v.Version, Created: v.Created})
+}
+
+func validateWords(words []api.Word) error {
+	var prev float64
+	for i, w := range words {
+		if strings.TrimSpace(w.Word) == "" || strings.ContainsAny(w.Word, "\n\r") {
+			return errors.Errorf("Wrong word %d", i)
+		}
+		if strings.ContainsAny(w.Punct+w.Speaker, "\n\r") {
+			return errors.Errorf("Wrong punctuation or speaker of word %d", i)
+		}
+		if w.Start < 0 || w.End < w.Start || w.Start < prev {
+			return errors.Errorf("Wrong time of word %d", i)
+		}
+		prev = w.Start
+	}
+	return nil
+}
+
+// selectVersion returns the version to serve, nil - the original transcript.
+// The latest version is selected if no ?version is provided
+func selectVersion(data *ServiceData, r *http.Request, id, fileName string) (*persistence.TranscriptVersion, int, error) {
+	q := r.URL.Query().Get("version")
+	_, editable := editedConverters[fileName]
+	if data.versions == nil || !editable {
+		if q != "" && q != "0" {
+			return nil, http.StatusBadRequest, errors.Errorf("No versions for %s", fileName)
+		}
+		return nil, 0, nil
+	}
+	if q == "" {
+		v, err := data.versions.Latest(id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return v, 0, nil
+	}
+	n, err := strconv.Atoi(q)
+	if err != nil || n < 0 {
+		return nil, http.StatusBadRequest, errors.Errorf("Wrong version '%s'", q)
+	}
+	if n == 0 {
+		return nil, 0, nil
+	}
+	v, err := data.versions.Get(id, n)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if v == nil {
+		return nil, http.StatusNotFound, errors.Errorf("No version %d for %s", n, id)
+	}
+	return v, 0, nil
+}
+
+// serveVersion renders the file from the corrected transcript
+func serveVersion(w http.ResponseWriter, r *http.Request, loader FileLoader, v *persistence.TranscriptVersion,
+	fileName string, names map[string]string) {
+	parts, err := loadVersion(loader, v)
+	if err != nil {
+		http.Error(w, "Cannot get file for ID: "+v.ID, http.StatusNotFound)
+		cmdapp.Log.Error(err)
+		return
+	}
+	renameParts(parts, names)
+	c := editedConverters[fileName]
+	var buf bytes.Buffer
+	if err := c.convert(parts, &buf); err != nil {
+		http.Error(w, "Cannot convert file for ID: "+v.ID, http.StatusInternalServerError)
+		cmdapp.Log.Error(errors.Wrapf(err, "Cannot convert %s for ID %s", fileName, v.ID))
+		return
+	}
+	w.Header().Set("Content-Type", c.contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	http.ServeContent(w, r, fileName, v.Created, bytes.NewReader(buf.Bytes()))
+}
+
+func loadVersion(loader FileLoader, v *persistence.TranscriptVersion) ([]*lattice.Part, error) {
+	file, err := loader.Load(v.File)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't load %s", v.File)
+	}
+	defer file.Close()
+	var words api.Words
+	if err := json.NewDecoder(file).Decode(&words); err != nil {
+		return nil, errors.Wrapf(err, "Can't decode %s", v.File)
+	}
+	return toParts(words.Words), nil
+}
+
+// toParts makes a lattice part of the consecutive words of the same speaker
+func toParts(words []api.Word) []*lattice.Part {
+	res := make([]*lattice.Part, 0)
+	var p *lattice.Part
+	for _, w := range words {
+		if p == nil || p.Speaker != w.Speaker {
+			p = &lattice.Part{Num: len(res) + 1, Speaker: w.Speaker}
+			res = append(res, p)
+		}
+		p.Words = append(p.Words, &lattice.Word{Main: true, From: toDuration(w.Start), To: toDuration(w.End),
+			Word: w.Word, Punct: w.Punct, Confidence: w.Confidence})
+	}
+	return res
+}
+
+func toDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,216 @@
+package result
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/gorilla/mux"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testEdit = `{"words":[{"word":"labas","punct":",","start":0.5,"end":1.2,"speaker":"S0000"},` +
+	`{"word":"rytas","punct":".","start":1.2,"end":2,"speaker":"S0000"},{"word":"ačiū","start":3,"end":3.5,"speaker":"S0001"}]}`
+
+var testVersion = &persistence.TranscriptVersion{ID: "id", Version: 1, File: "id/edits/1.json"}
+
+// initVersionsMock makes the store keep the versions and the loader return the edits and the original files
+func initVersionsMock(t *testing.T, vs ...*persistence.TranscriptVersion) map[string][]byte {
+	files := map[string][]byte{"id/lat.restored.txt": []byte(testLat), "id/lat.txt": []byte("lat"),
+		"id/webvtt.txt": []byte("original"), "id/edits/1.json": []byte(testEdit)}
+	initFilesMock(t, resultFileLoaderMock, files)
+	initSaverMock(fileSaverMock, files)
+	var latest *persistence.TranscriptVersion
+	if len(vs) > 0 {
+		latest = vs[len(vs)-1]
+	}
+	pegomock.When(versionsMock.Latest(pegomock.AnyString())).ThenReturn(latest, nil)
+	pegomock.When(versionsMock.List(pegomock.AnyString())).ThenReturn(vs, nil)
+	for _, v := range vs {
+		pegomock.When(versionsMock.Get(pegomock.AnyString(), pegomock.EqInt(v.Version))).ThenReturn(v, nil)
+	}
+	pegomock.When(versionsMock.Add(matchers.AnyPtrToPersistenceTranscriptVersion())).ThenReturn(true, nil)
+	return files
+}
+
+func newTestVersionsRouter() *mux.Router {
+	data := newTestData()
+	data.versions = versionsMock
+	data.resultFileSaver = fileSaverMock
+	data.resultFileRemover = fileRemoverMock
+	return NewRouter(data)
+}
+
+func TestVersions_POST(t *testing.T) {
+	initTest()
+	files := initVersionsMock(t, testVersion)
+	resp := httptest.NewRecorder()
+
+	newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("POST", "/result/id/versions", strings.NewReader(testEdit)))
+
+	assert.Equal(t, 201, resp.Code)
+	assert.True(t, strings.HasPrefix(resp.Body.String(), `{"version":2,"created":`))
+	v := versionsMock.VerifyWasCalledOnce().Add(matchers.AnyPtrToPersistenceTranscriptVersion()).GetCapturedArguments()
+	assert.Equal(t, 2, v.Version)
+	assert.True(t, strings.HasPrefix(v.File, "id/edits/2-"))
+	assert.Equal(t, 3, len(decodeWords(t, files[v.File]).Words))
+	fileRemoverMock.VerifyWasCalled(pegomock.Never()).Delete(pegomock.AnyString())
+}
+
+func TestVersions_POSTConflict(t *testing.T) {
+	initTest()
+	initVersionsMock(t)
+	pegomock.When(versionsMock.Add(matchers.AnyPtrToPersistenceTranscriptVersion())).ThenReturn(false, nil)
+	resp := httptest.NewRecorder()
+
+	newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("POST", "/result/id/versions", strings.NewReader(testEdit)))
+
+	assert.Equal(t, 409, resp.Code)
+	name, _ := fileSaverMock.VerifyWasCalledOnce().Save(pegomock.AnyString(), matchers.AnyIoReader()).
+		GetCapturedArguments()
+	fileRemoverMock.VerifyWasCalledOnce().Delete(pegomock.EqString(name))
+}
+
+func TestVersions_POSTFails(t *testing.T) {
+	initTest()
+	initVersionsMock(t)
+	pegomock.When(versionsMock.Latest(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	resp := httptest.NewRecorder()
+
+	newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("POST", "/result/id/versions", strings.NewReader(testEdit)))
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestVersions_POSTWrong(t *testing.T) {
+	for _, b := range []string{`olia`, `{"words":[{"word":"","start":0,"end":1}]}`,
+		`{"words":[{"word":"a","start":1,"end":0.5}]}`, `{"words":[{"word":"a","start":-1,"end":0.5}]}`,
+		`{"words":[{"word":"a","start":1,"end":2},{"word":"b","start":0.5,"end":2}]}`,
+		`{"words":[{"word":"a","start":1,"end":2,"speaker":"S\n"}]}`} {
+		initTest()
+		initVersionsMock(t)
+		resp := httptest.NewRecorder()
+
+		newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("POST", "/result/id/versions", strings.NewReader(b)))
+
+		assert.Equal(t, 400, resp.Code, b)
+		versionsMock.VerifyWasCalled(pegomock.Never()).Add(matchers.AnyPtrToPersistenceTranscriptVersion())
+	}
+}
+
+func TestVersions_GET(t *testing.T) {
+	tm := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	initTest()
+	initVersionsMock(t, &persistence.TranscriptVersion{Version: 1, Created: tm},
+		&persistence.TranscriptVersion{Version: 2, Created: tm})
+	resp := httptest.NewRecorder()
+
+	newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/versions", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"latest":2,"versions":[{"version":1,"created":"2021-01-01T00:00:00Z"},`+
+		`{"version":2,"created":"2021-01-01T00:00:00Z"}]}`+"\n", resp.Body.String())
+}
+
+func TestVersions_Disabled(t *testing.T) {
+	initTest()
+	resp := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(resp, httptest.NewRequest("POST", "/result/id/versions", strings.NewReader(testEdit)))
+
+	assert.Equal(t, 405, resp.Code)
+}
+
+func TestResult_ServesLatestVersion(t *testing.T) {
+	initTest()
+	initVersionsMock(t, testVersion)
+	pegomock.When(speakerNamesMock.Get(pegomock.AnyString())).ThenReturn(map[string]string{"S0001": "Jonas"}, nil)
+	data := newTestData()
+	data.versions = versionsMock
+	data.speakerNames = speakerNamesMock
+
+	for _, tc := range []struct {
+		url, expected string
+	}{
+		{"/result/id/webvtt.txt", "WEBVTT\n\n00:00:00.500 --> 00:00:02.000\n<v S0000>labas, rytas.\n\n" +
+			"00:00:03.000 --> 00:00:03.500\n<v Jonas>ačiū\n\n"},
+		{"/result/id/resultFinal.txt", "labas, rytas.\načiū\n"},
+		{"/result/id/result.txt", "labas rytas\načiū\n"},
+		{"/result/id/result.srt?version=1", "1\n00:00:00,500 --> 00:00:02,000\nlabas, rytas.\n\n" +
+			"2\n00:00:03,000 --> 00:00:03,500\načiū\n\n"},
+		{"/result/id/webvtt.txt?version=0", "WEBVTT\n\n00:00:00.500 --> 00:00:02.000\n<v S0000>labas, vakaras.\n\n" +
+			"00:00:03.000 --> 00:00:03.500\n<v Jonas>\"ačiū\"\n\n"},
+		{"/result/id/lat.txt", "lat"},
+	} {
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", tc.url, nil))
+
+		assert.Equal(t, 200, resp.Code, tc.url)
+		assert.Equal(t, tc.expected, resp.Body.String(), tc.url)
+	}
+}
+
+func TestResult_WrongVersion(t *testing.T) {
+	initTest()
+	initVersionsMock(t, testVersion)
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/result/id/webvtt.txt?version=a", 400},
+		{"/result/id/webvtt.txt?version=-1", 400},
+		{"/result/id/lat.txt?version=1", 400},
+		{"/result/id/webvtt.txt?version=2", 404},
+	} {
+		resp := httptest.NewRecorder()
+
+		newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("GET", tc.url, nil))
+
+		assert.Equal(t, tc.code, resp.Code, tc.url)
+	}
+}
+
+func TestResult_VersionStoreFails(t *testing.T) {
+	initTest()
+	initVersionsMock(t)
+	pegomock.When(versionsMock.Latest(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	resp := httptest.NewRecorder()
+
+	newTestVersionsRouter().ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/webvtt.txt", nil))
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestSpeakers_FromLatestVersion(t *testing.T) {
+	initTest()
+	initVersionsMock(t, testVersion)
+	data := newTestData()
+	data.versions = versionsMock
+	data.speakerNames = speakerNamesMock
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, httptest.NewRequest("GET", "/result/id/speakers", nil))
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"text":"labas, rytas."`)
+}
+
+func TestToParts(t *testing.T) {
+	res := toParts([]api.Word{{Word: "a", Start: 0.5, End: 1, Speaker: "S1"}, {Word: "b", Speaker: "S1"},
+		{Word: "c", Confidence: 0.5}})
+
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, &lattice.Word{Main: true, From: 500 * time.Millisecond, To: time.Second, Word: "a"}, res[0].Words[0])
+	assert.Equal(t, 2, len(res[0].Words))
+	assert.Equal(t, 2, res[1].Num)
+	assert.Equal(t, 0.5, res[1].Words[0].Confidence)
+}
@@ -0,0 +1,92 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/strategy"
+	"github.com/airenas/listgo/internal/pkg/strategy/sim"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var appName = "LiST Strategy Simulator"
+
+var rootCmd = &cobra.Command{
+	Use:   "strategySimulator [trace file]",
+	Short: appName,
+	Long:  `Replays recorded dispatcher task/worker trace through the task selection strategies and reports waiting time, model reloads and makespan`,
+	Args:  cobra.ExactArgs(1),
+	Run:   run,
+}
+
+func init() {
+	cmdapp.InitApplication(rootCmd)
+	rootCmd.PersistentFlags().StringP("strategy", "s", "all", "Comma separated strategy names or 'all'")
+	cmdapp.Config.BindPFlag("strategy.names", rootCmd.PersistentFlags().Lookup("strategy"))
+	rootCmd.PersistentFlags().BoolP("fairShare", "", false, "Wrap strategies with the fair share tenant selection")
+	cmdapp.Config.BindPFlag("strategy.fairShare.enabled", rootCmd.PersistentFlags().Lookup("fairShare"))
+	rootCmd.PersistentFlags().DurationP("modelLoadDuration", "", time.Minute, "Model load duration")
+	cmdapp.Config.BindPFlag("strategy.modelLoadDuration", rootCmd.PersistentFlags().Lookup("modelLoadDuration"))
+	rootCmd.PersistentFlags().Float64P("realTimeFactor", "", 1, "Real time factor of the transcription")
+	cmdapp.Config.BindPFlag("strategy.realTimeFactor", rootCmd.PersistentFlags().Lookup("realTimeFactor"))
+	rootCmd.PersistentFlags().Float64P("delayCostPerSecond", "", 1, "Delay cost per second for the cost strategy")
+	cmdapp.Config.BindPFlag("strategy.delayCostPerSecond", rootCmd.PersistentFlags().Lookup("delayCostPerSecond"))
+}
+
+// Execute starts the simulator
+func Execute() {
+	cmdapp.Execute(rootCmd)
+}
+
+func run(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	cmdapp.CheckOrPanic(err, "Can't open trace file")
+	defer f.Close()
+	evs, err := sim.ReadTrace(f)
+	cmdapp.CheckOrPanic(err, "Can't read trace")
+
+	p := sim.Params{ModelLoadDuration: cmdapp.Config.GetDuration("strategy.modelLoadDuration"),
+		RTFactor: cmdapp.Config.GetFloat64("strategy.realTimeFactor")}
+	names := strategyNames(cmdapp.Config.GetString("strategy.names"))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "strategy\ttasks\tavg wait\tmax wait\tmodel reloads\tmakespan")
+	for _, n := range names {
+		r, err := simulate(n, evs, p)
+		cmdapp.CheckOrPanic(err, "Can't simulate "+n)
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%d\t%v\n", n, r.Tasks, r.AvgWait.Round(time.Second),
+			r.MaxWait.Round(time.Second), r.ModelReloads, r.Makespan.Round(time.Second))
+	}
+	w.Flush()
+}
+
+func simulate(name string, evs []*sim.Event, p sim.Params) (*sim.Result, error) {
+	s, err := strategy.New(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't init strategy")
+	}
+	if cmdapp.Config.GetBool("strategy.fairShare.enabled") {
+		s, err = strategy.NewFairShare(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't init fair share strategy")
+		}
+	}
+	return sim.Run(s, evs, p)
+}
+
+func strategyNames(s string) []string {
+	if strings.TrimSpace(s) == "" || strings.TrimSpace(s) == "all" {
+		return strategy.Names()
+	}
+	res := make([]string, 0)
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			res = append(res, n)
+		}
+	}
+	return res
+}
@@ -0,0 +1,37 @@
+package api
+
+import "time"
+
+// StateError selects the jobs failed at any step
+const StateError = "ERROR"
+
+// ListFilter - status list query parameters
+type ListFilter struct {
+	// State is a status name or StateError
+	State      string
+	Recognizer string
+	ExternalID string
+	From       time.Time
+	To         time.Time
+	// Cursor is the NextCursor value of the previous page
+	Cursor string
+	Limit  int
+}
+
+// ListItem - job info in the status list
+type ListItem struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status,omitempty"`
+	ErrorCode    string    `json:"errorCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Recognizer   string    `json:"recognizer,omitempty"`
+	RecognizerID string    `json:"recognizerID,omitempty"`
+	ExternalID   string    `json:"externalID,omitempty"`
+	Created      time.Time `json:"created"`
+}
+
+// List - status list method response in JSON, items are sorted from the newest
+type List struct {
+	Items      []ListItem `json:"items"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // TranscriptionResult - status method response in JSON
 type TranscriptionResult struct {
 	ID               string   `json:"id"`
@@ -10,4 +12,12 @@ type TranscriptionResult struct {
 	Progress         int32    `json:"progress,omitempty"`
 	AudioReady       bool     `json:"audioReady,omitempty"`
 	AvailableResults []string `json:"avResults,omitempty"`
+	// QueuePosition is the place in the transcription queue, 0 - not waiting
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// ETA is the estimated end of the transcription
+	ETA *time.Time `json:"eta,omitempty"`
+	// AudioURL is the signed audio link, set if the links are signed
+	AudioURL string `json:"audioURL,omitempty"`
+	// ResultURLs are the signed links of avResults
+	ResultURLs map[string]string `json:"resultURLs,omitempty"`
 }
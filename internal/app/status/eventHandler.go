@@ -54,25 +54,29 @@ func registerQueue(data *ServiceData, quitChan <-chan bool, initialWait time.Dur
 func processMsg(d *amqp.Delivery, data *ServiceData) error {
 	id := string(d.Body)
 	cmdapp.Log.Infof("processMsg event " + id)
-	conns, found := getConnections(id)
-	if found {
-		result, err := data.StatusProvider.Get(id)
-		if err != nil {
-			return errors.Wrap(err, "Cannot get status for ID: "+id)
-		}
-		for c := range conns {
-			err = sendMsg(c, result)
-			cmdapp.LogIf(err)
-		}
-	} else {
+	start := time.Now()
+	conns := data.hub.connections(id)
+	if len(conns) == 0 {
 		cmdapp.Log.Infof("No connections found for " + id)
+		return nil
+	}
+	result, err := data.StatusProvider.Get(id)
+	if err != nil {
+		return errors.Wrap(err, "Cannot get status for ID: "+id)
+	}
+	for _, c := range conns {
+		err = sendMsg(c, result)
+		cmdapp.LogIf(err)
+	}
+	if data.metrics.fanoutDur != nil {
+		data.metrics.fanoutDur.Observe(time.Since(start).Seconds())
 	}
 	return nil
 }
 
-func sendMsg(c WsConn, result *api.TranscriptionResult) error {
+func sendMsg(c *hubConn, result *api.TranscriptionResult) error {
 	cmdapp.Log.Debugf("Sending result for %s to websockket", result.ID)
-	err := c.WriteJSON(result)
+	err := c.send(result)
 	if err != nil {
 		return errors.Wrap(err, "Cannot write to websockket")
 	}
@@ -27,7 +27,7 @@ func initTest(t *testing.T) {
 
 func Test_ListenQueue_MsgSent(t *testing.T) {
 	initTest(t)
-	data := &ServiceData{}
+	data := &ServiceData{hub: NewHub()}
 	data.StatusProvider = statusProviderMock
 	c := make(chan amqp.Delivery)
 	fc := make(chan bool)
@@ -57,7 +57,7 @@ func initTestData(t *testing.T) *testdata {
 	initTest(t)
 	res := testdata{}
 	res.c = make(chan amqp.Delivery)
-	res.data = &ServiceData{}
+	res.data = &ServiceData{hub: NewHub()}
 	res.data.StatusProvider = statusProviderMock
 	res.fc = make(chan bool)
 	res.waitc = make(chan bool)
@@ -71,46 +71,46 @@ func initTestData(t *testing.T) *testdata {
 func Test_ListenQueue_MsgSentWithID(t *testing.T) {
 	td := initTestData(t)
 
-	saveConnection(connMock, "id")
+	td.data.hub.replace(connMock, "id")
 	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(&api.TranscriptionResult{}, nil)
 	go td.f()
 	d := amqp.Delivery{Body: []byte("id")}
 	td.c <- d
 	close(td.c)
 	<-td.waitc
-	deleteConnection(connMock)
+	td.data.hub.unregister(connMock)
 }
 
 func Test_ListenQueue_MsgSentWithExistingID(t *testing.T) {
 	td := initTestData(t)
 
-	saveConnection(connMock, "id")
+	td.data.hub.replace(connMock, "id")
 	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(&api.TranscriptionResult{}, nil)
 	go td.f()
 	d := amqp.Delivery{Body: []byte("id")}
 	td.c <- d
 	close(td.c)
 	<-td.waitc
-	deleteConnection(connMock)
+	td.data.hub.unregister(connMock)
 }
 
 func Test_ListenQueue_WithFailingProvider(t *testing.T) {
 	td := initTestData(t)
 
-	saveConnection(connMock, "id")
+	td.data.hub.replace(connMock, "id")
 	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(nil, errors.New("error"))
 	go td.f()
 	d := amqp.Delivery{Body: []byte("id")}
 	td.c <- d
 	close(td.c)
 	<-td.waitc
-	deleteConnection(connMock)
+	td.data.hub.unregister(connMock)
 }
 
 func Test_ListenQueue_WithFailingConnection(t *testing.T) {
 	td := initTestData(t)
 
-	saveConnection(connMock, "id")
+	td.data.hub.replace(connMock, "id")
 	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(&api.TranscriptionResult{}, nil)
 	pegomock.When(connMock.WriteJSON(matchers.AnyPtrToApiTranscriptionResult())).ThenReturn(errors.New("error"))
 
@@ -119,20 +119,20 @@ func Test_ListenQueue_WithFailingConnection(t *testing.T) {
 	td.c <- d
 	close(td.c)
 	<-td.waitc
-	deleteConnection(connMock)
+	td.data.hub.unregister(connMock)
 }
 
 func Test_ListenQueue_NultipleConnections(t *testing.T) {
 	td := initTestData(t)
 
-	saveConnection(connMock, "id1")
-	defer deleteConnection(connMock)
+	td.data.hub.replace(connMock, "id1")
+	defer td.data.hub.unregister(connMock)
 	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(&api.TranscriptionResult{}, nil)
 	pegomock.When(connMock.WriteJSON(matchers.AnyPtrToApiTranscriptionResult())).ThenReturn(nil)
 	connMock1 := mocks.NewMockWsConn()
-	defer deleteConnection(connMock1)
+	defer td.data.hub.unregister(connMock1)
 	pegomock.When(connMock1.WriteJSON(matchers.AnyPtrToApiTranscriptionResult())).ThenReturn(nil)
-	saveConnection(connMock1, "id1")
+	td.data.hub.replace(connMock1, "id1")
 	go td.f()
 	d := amqp.Delivery{Body: []byte("id1")}
 	td.c <- d
@@ -146,7 +146,7 @@ func initTestDataRegisterQueue(t *testing.T) *testdata {
 	t.Helper()
 	res := initTestData(t)
 	res.c = make(chan amqp.Delivery)
-	res.data = &ServiceData{}
+	res.data = &ServiceData{hub: NewHub()}
 	res.data.StatusProvider = statusProviderMock
 	res.fc = make(chan bool)
 	res.waitc = make(chan bool)
@@ -201,3 +201,23 @@ func Test_RegisteringQueue_NoFailure(t *testing.T) {
 	<-td.waitc
 	assert.Equal(t, int64(1), td.i)
 }
+
+func Test_ListenQueue_SendsByWriter(t *testing.T) {
+	td := initTestData(t)
+	writeCh := make(chan interface{}, 1)
+	conn := &wsConnMock{writeCh: writeCh}
+	w := newWsWriter(conn)
+	td.data.hub.register(conn, w)
+	go w.run()
+	defer w.close()
+	td.data.hub.replace(conn, "id1")
+	defer td.data.hub.unregister(conn)
+	pegomock.When(statusProviderMock.Get(pegomock.AnyString())).ThenReturn(&api.TranscriptionResult{ID: "id1"}, nil)
+
+	go td.f()
+	td.c <- amqp.Delivery{Body: []byte("id1")}
+	close(td.c)
+	<-td.waitc
+
+	assert.Equal(t, &api.TranscriptionResult{ID: "id1"}, <-writeCh)
+}
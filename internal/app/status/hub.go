@@ -0,0 +1,320 @@
+package status
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+)
+
+const hubShards = 32
+
+// Binder is notified when the hub starts or stops watching an ID,
+// it is used to receive the events only for the watched IDs
+type Binder interface {
+	Bind(ID string) error
+	Unbind(ID string) error
+}
+
+// Hub keeps the websocket and SSE connections subscribed to job IDs.
+// IDs are split into shards by hash, each shard has its own lock
+type Hub struct {
+	shards []*hubShard
+
+	connLock sync.RWMutex
+	conns    map[WsConn]*hubConn
+
+	binderLock sync.RWMutex
+	binder     Binder
+}
+
+// hubShard keeps the connections by ID under lock. The binder is called under bindLock only,
+// so the events for the other IDs of the shard are not delayed by the queue binding
+type hubShard struct {
+	lock sync.Mutex
+	ids  map[string]map[WsConn]*hubConn
+
+	bindLock sync.Mutex
+	bound    map[string]bool
+}
+
+type hubConn struct {
+	conn   WsConn
+	writer *wsWriter
+
+	lock sync.Mutex
+	ids  map[string]bool
+}
+
+// NewHub creates Hub instance
+func NewHub() *Hub {
+	res := &Hub{conns: map[WsConn]*hubConn{}}
+	for i := 0; i < hubShards; i++ {
+		res.shards = append(res.shards, &hubShard{ids: map[string]map[WsConn]*hubConn{}, bound: map[string]bool{}})
+	}
+	return res
+}
+
+// send writes the message through the connection's writer if there is one
+func (c *hubConn) send(v interface{}) error {
+	if c.writer == nil {
+		return c.conn.WriteJSON(v)
+	}
+	if !c.writer.send(v) {
+		return errors.New("Cannot queue msg to websocket")
+	}
+	return nil
+}
+
+func (h *Hub) shard(id string) *hubShard {
+	f := fnv.New32a()
+	f.Write([]byte(id))
+	return h.shards[f.Sum32()%hubShards]
+}
+
+// register adds the connection served by the writer
+func (h *Hub) register(conn WsConn, w *wsWriter) {
+	h.connLock.Lock()
+	defer h.connLock.Unlock()
+	h.conns[conn] = &hubConn{conn: conn, writer: w, ids: map[string]bool{}}
+}
+
+func (h *Hub) getConn(conn WsConn) *hubConn {
+	h.connLock.RLock()
+	res := h.conns[conn]
+	h.connLock.RUnlock()
+	if res != nil {
+		return res
+	}
+	h.connLock.Lock()
+	defer h.connLock.Unlock()
+	res = h.conns[conn]
+	if res == nil {
+		res = &hubConn{conn: conn, ids: map[string]bool{}}
+		h.conns[conn] = res
+	}
+	return res
+}
+
+// unregister drops the connection and all its subscriptions
+func (h *Hub) unregister(conn WsConn) {
+	h.connLock.Lock()
+	c, found := h.conns[conn]
+	delete(h.conns, conn)
+	h.connLock.Unlock()
+	if !found {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for id := range c.ids {
+		h.removeID(c, id)
+	}
+	c.ids = map[string]bool{}
+	cmdapp.Log.Infof("Connection removed, left: %d", h.connectionCount())
+}
+
+// replace subscribes the connection to one ID dropping the previous subscriptions
+func (h *Hub) replace(conn WsConn, id string) {
+	c := h.getConn(conn)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for old := range c.ids {
+		if old != id {
+			h.removeID(c, old)
+			delete(c.ids, old)
+		}
+	}
+	if !c.ids[id] {
+		c.ids[id] = true
+		h.addID(c, id)
+	}
+}
+
+// subscribe adds IDs to the connection's subscriptions
+func (h *Hub) subscribe(conn WsConn, ids []string) error {
+	c := h.getConn(conn)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	count := len(c.ids)
+	for _, id := range ids {
+		if !c.ids[id] {
+			count++
+		}
+	}
+	if count > maxSubscriptions {
+		return errors.Errorf("Too many subscriptions, max %d", maxSubscriptions)
+	}
+	for _, id := range ids {
+		if !c.ids[id] {
+			c.ids[id] = true
+			h.addID(c, id)
+		}
+	}
+	cmdapp.Log.Infof("Subscribed to %d IDs, total %d", len(ids), len(c.ids))
+	return nil
+}
+
+// unsubscribe removes IDs from the connection's subscriptions, all of them if ids is empty.
+// Returns the removed IDs
+func (h *Hub) unsubscribe(conn WsConn, ids []string) []string {
+	c := h.getConn(conn)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(ids) == 0 {
+		for id := range c.ids {
+			ids = append(ids, id)
+		}
+	}
+	res := make([]string, 0)
+	for _, id := range ids {
+		if c.ids[id] {
+			h.removeID(c, id)
+			delete(c.ids, id)
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func (h *Hub) addID(c *hubConn, id string) {
+	s := h.shard(id)
+	s.lock.Lock()
+	conns, found := s.ids[id]
+	if !found {
+		conns = map[WsConn]*hubConn{}
+		s.ids[id] = conns
+	}
+	conns[c.conn] = c
+	s.lock.Unlock()
+	if !found {
+		h.syncBinding(s, id)
+	}
+}
+
+func (h *Hub) removeID(c *hubConn, id string) {
+	s := h.shard(id)
+	s.lock.Lock()
+	conns, found := s.ids[id]
+	if !found {
+		s.lock.Unlock()
+		return
+	}
+	delete(conns, c.conn)
+	removed := len(conns) == 0
+	if removed {
+		delete(s.ids, id)
+	}
+	s.lock.Unlock()
+	if removed {
+		h.syncBinding(s, id)
+	}
+}
+
+// syncBinding binds or unbinds the ID to match the current watch state.
+// The state is rechecked under bindLock, so concurrent add and remove calls can not leave
+// the binding out of date
+func (h *Hub) syncBinding(s *hubShard, id string) {
+	s.bindLock.Lock()
+	defer s.bindLock.Unlock()
+	b := h.getBinder()
+	if b == nil {
+		return
+	}
+	s.lock.Lock()
+	_, watched := s.ids[id]
+	s.lock.Unlock()
+	if watched == s.bound[id] {
+		return
+	}
+	if watched {
+		err := b.Bind(id)
+		cmdapp.LogIf(errors.Wrapf(err, "Can't bind %s", id))
+		if err == nil {
+			s.bound[id] = true
+		}
+		return
+	}
+	cmdapp.LogIf(errors.Wrapf(b.Unbind(id), "Can't unbind %s", id))
+	delete(s.bound, id)
+}
+
+// connections returns a copy of the connections subscribed to the ID
+func (h *Hub) connections(id string) []*hubConn {
+	s := h.shard(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	conns := s.ids[id]
+	res := make([]*hubConn, 0, len(conns))
+	for _, c := range conns {
+		res = append(res, c)
+	}
+	return res
+}
+
+// subscriptions returns the IDs the connection is subscribed to
+func (h *Hub) subscriptions(conn WsConn) []string {
+	h.connLock.RLock()
+	c := h.conns[conn]
+	h.connLock.RUnlock()
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	res := make([]string, 0, len(c.ids))
+	for id := range c.ids {
+		res = append(res, id)
+	}
+	return res
+}
+
+func (h *Hub) connectionCount() int {
+	h.connLock.RLock()
+	defer h.connLock.RUnlock()
+	return len(h.conns)
+}
+
+// idCount returns the number of the watched IDs
+func (h *Hub) idCount() int {
+	res := 0
+	for _, s := range h.shards {
+		s.lock.Lock()
+		res += len(s.ids)
+		s.lock.Unlock()
+	}
+	return res
+}
+
+func (h *Hub) getBinder() Binder {
+	h.binderLock.RLock()
+	defer h.binderLock.RUnlock()
+	return h.binder
+}
+
+// setBinder replaces the binder and binds all the watched IDs to it
+func (h *Hub) setBinder(b Binder) {
+	for _, s := range h.shards {
+		s.bindLock.Lock()
+	}
+	h.binderLock.Lock()
+	h.binder = b
+	h.binderLock.Unlock()
+	var ids []string
+	for _, s := range h.shards {
+		s.bound = map[string]bool{}
+		s.bindLock.Unlock()
+		s.lock.Lock()
+		for id := range s.ids {
+			ids = append(ids, id)
+		}
+		s.lock.Unlock()
+	}
+	if b == nil {
+		return
+	}
+	for _, id := range ids {
+		h.syncBinding(h.shard(id), id)
+	}
+}
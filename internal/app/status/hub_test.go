@@ -0,0 +1,215 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_SubscribeSeveralConnections(t *testing.T) {
+	h := NewHub()
+	c1, c2 := &wsConnMock{}, &wsConnMock{}
+
+	assert.Nil(t, h.subscribe(c1, []string{"id1", "id2"}))
+	assert.Nil(t, h.subscribe(c2, []string{"id2"}))
+
+	assert.Equal(t, 2, h.connectionCount())
+	assert.Equal(t, 2, h.idCount())
+	assert.Equal(t, 1, len(h.connections("id1")))
+	assert.Equal(t, 2, len(h.connections("id2")))
+
+	h.unregister(c1)
+	assert.Equal(t, 1, h.connectionCount())
+	assert.Equal(t, 1, h.idCount())
+	assert.Empty(t, h.connections("id1"))
+	h.unregister(c2)
+	assert.Equal(t, 0, h.connectionCount())
+	assert.Equal(t, 0, h.idCount())
+}
+
+func TestHub_Replace(t *testing.T) {
+	h := NewHub()
+	c := &wsConnMock{}
+	h.subscribe(c, []string{"id1", "id2"})
+
+	h.replace(c, "id2")
+
+	assert.Equal(t, []string{"id2"}, h.subscriptions(c))
+	assert.Equal(t, 1, h.idCount())
+}
+
+func TestHub_TooManySubscriptions(t *testing.T) {
+	h := NewHub()
+	c := &wsConnMock{}
+	ids := make([]string, 0)
+	for i := 0; i <= maxSubscriptions; i++ {
+		ids = append(ids, fmt.Sprintf("id%d", i))
+	}
+
+	assert.NotNil(t, h.subscribe(c, ids))
+	assert.Nil(t, h.subscribe(c, ids[1:]))
+	assert.NotNil(t, h.subscribe(c, ids[:1]))
+	assert.Equal(t, maxSubscriptions, h.idCount())
+}
+
+func TestHub_Binder(t *testing.T) {
+	h := NewHub()
+	b := &testBinder{}
+	h.setBinder(b)
+	c1, c2 := &wsConnMock{}, &wsConnMock{}
+
+	h.subscribe(c1, []string{"id1", "id2"})
+	h.subscribe(c2, []string{"id2"})
+	assert.Equal(t, []string{"id1", "id2"}, b.sortedBound())
+
+	h.unsubscribe(c1, []string{"id2"})
+	assert.Equal(t, []string{"id1", "id2"}, b.sortedBound())
+	h.unregister(c2)
+	assert.Equal(t, []string{"id1"}, b.sortedBound())
+	assert.Equal(t, []string{"id2"}, b.unbound)
+}
+
+func TestHub_SetBinderBindsWatched(t *testing.T) {
+	h := NewHub()
+	h.subscribe(&wsConnMock{}, []string{"id1", "id2"})
+	b := &testBinder{}
+
+	h.setBinder(b)
+
+	assert.Equal(t, []string{"id1", "id2"}, b.sortedBound())
+}
+
+func TestHub_BinderFails_KeepsSubscription(t *testing.T) {
+	h := NewHub()
+	h.setBinder(&testBinder{err: errors.New("olia")})
+	c := &wsConnMock{}
+
+	assert.Nil(t, h.subscribe(c, []string{"id1"}))
+
+	assert.Equal(t, 1, len(h.connections("id1")))
+}
+
+func TestHub_BindsOutsideShardLock(t *testing.T) {
+	h := NewHub()
+	b := &lockCheckBinder{hub: h}
+	h.setBinder(b)
+	c := &wsConnMock{}
+
+	h.subscribe(c, []string{"id1"})
+	h.unsubscribe(c, []string{"id1"})
+	h.subscribe(c, []string{"id2"})
+	h.setBinder(b)
+	h.unregister(c)
+
+	assert.Equal(t, 5, b.calls)
+	assert.Equal(t, 0, b.locked)
+}
+
+func TestHub_ConcurrentBinding(t *testing.T) {
+	h := NewHub()
+	b := &testBinder{}
+	h.setBinder(b)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := &wsConnMock{}
+			for j := 0; j < 50; j++ {
+				h.subscribe(c, []string{"id1"})
+				h.unsubscribe(c, []string{"id1"})
+			}
+		}()
+	}
+	wg.Wait()
+	h.subscribe(&wsConnMock{}, []string{"id2"})
+
+	assert.Equal(t, []string{"id2"}, b.sortedBound())
+}
+
+func TestHub_Concurrent(t *testing.T) {
+	h := NewHub()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := &wsConnMock{}
+			for j := 0; j < 50; j++ {
+				h.subscribe(c, []string{fmt.Sprintf("id%d", j), fmt.Sprintf("c%d-%d", i, j)})
+				h.connections(fmt.Sprintf("id%d", j))
+			}
+			h.unsubscribe(c, []string{"id1"})
+			h.unregister(c)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, h.connectionCount())
+	assert.Equal(t, 0, h.idCount())
+}
+
+type testBinder struct {
+	lock    sync.Mutex
+	bound   map[string]bool
+	unbound []string
+	err     error
+}
+
+func (b *testBinder) Bind(ID string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.bound == nil {
+		b.bound = map[string]bool{}
+	}
+	b.bound[ID] = true
+	return b.err
+}
+
+func (b *testBinder) Unbind(ID string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.bound, ID)
+	b.unbound = append(b.unbound, ID)
+	return b.err
+}
+
+func (b *testBinder) sortedBound() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	res := make([]string, 0)
+	for id := range b.bound {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res
+}
+
+type lockCheckBinder struct {
+	hub    *Hub
+	calls  int
+	locked int
+}
+
+func (b *lockCheckBinder) check(ID string) error {
+	b.calls++
+	s := b.hub.shard(ID)
+	if !s.lock.TryLock() {
+		b.locked++
+		return nil
+	}
+	s.lock.Unlock()
+	return nil
+}
+
+func (b *lockCheckBinder) Bind(ID string) error {
+	return b.check(ID)
+}
+
+func (b *lockCheckBinder) Unbind(ID string) error {
+	return b.check(ID)
+}
@@ -0,0 +1,48 @@
+package status
+
+import (
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
+	"github.com/airenas/listgo/internal/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// linkProvider adds the signed resultService links to the status.
+// The links are given to anyone who knows the ID as the status itself, so they are signed for reading only
+type linkProvider struct {
+	provider  Provider
+	signer    *urlsign.Signer
+	resultURL string
+	ttl       time.Duration
+}
+
+func newLinkProvider(provider Provider, signer *urlsign.Signer, resultURL string, ttl time.Duration) (*linkProvider, error) {
+	if resultURL == "" {
+		return nil, errors.New("No result URL")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("Wrong link TTL")
+	}
+	return &linkProvider{provider: provider, signer: signer, resultURL: resultURL, ttl: ttl}, nil
+}
+
+// Get returns the status with the links valid for ttl
+func (p *linkProvider) Get(ID string) (*api.TranscriptionResult, error) {
+	res, err := p.provider.Get(ID)
+	if err != nil || res == nil {
+		return res, err
+	}
+	q := "?" + p.signer.Sign(ID, urlsign.ScopeRead, time.Now().Add(p.ttl)).Encode()
+	if res.AudioReady {
+		res.AudioURL = utils.URLJoin(p.resultURL, "audio", ID) + q
+	}
+	if len(res.AvailableResults) > 0 {
+		res.ResultURLs = make(map[string]string, len(res.AvailableResults))
+		for _, r := range res.AvailableResults {
+			res.ResultURLs[r] = utils.URLJoin(p.resultURL, "result", ID, r) + q
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package status
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProviderFunc func(ID string) (*api.TranscriptionResult, error)
+
+func (f testProviderFunc) Get(ID string) (*api.TranscriptionResult, error) {
+	return f(ID)
+}
+
+func newTestLinkProvider(t *testing.T, res *api.TranscriptionResult, err error) (*linkProvider, *urlsign.Signer) {
+	signer, _ := urlsign.NewSigner("0123456789abcdef")
+	p, perr := newLinkProvider(testProviderFunc(func(ID string) (*api.TranscriptionResult, error) {
+		return res, err
+	}), signer, "http://host/result.service", time.Hour)
+	assert.Nil(t, perr)
+	return p, signer
+}
+
+func TestLinkProvider(t *testing.T) {
+	p, signer := newTestLinkProvider(t, &api.TranscriptionResult{ID: "1", AudioReady: true,
+		AvailableResults: []string{"result.txt"}}, nil)
+
+	res, err := p.Get("1")
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(res.AudioURL, "http://host/result.service/audio/1?expires="))
+	l := res.ResultURLs["result.txt"]
+	assert.True(t, strings.HasPrefix(l, "http://host/result.service/result/1/result.txt?"))
+	u, _ := url.Parse(l)
+	assert.Nil(t, signer.Verify("1", urlsign.ScopeRead, u.Query(), time.Now()))
+	assert.NotNil(t, signer.Verify("1", urlsign.ScopeRead, u.Query(), time.Now().Add(time.Hour+time.Minute)))
+}
+
+func TestLinkProvider_NoResults(t *testing.T) {
+	p, _ := newTestLinkProvider(t, &api.TranscriptionResult{ID: "1"}, nil)
+
+	res, err := p.Get("1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", res.AudioURL)
+	assert.Nil(t, res.ResultURLs)
+}
+
+func TestLinkProvider_Fails(t *testing.T) {
+	p, _ := newTestLinkProvider(t, nil, errors.New("olia"))
+
+	_, err := p.Get("1")
+
+	assert.NotNil(t, err)
+}
+
+func TestNewLinkProvider_Fails(t *testing.T) {
+	signer, _ := urlsign.NewSigner("0123456789abcdef")
+	_, err := newLinkProvider(nil, signer, "", time.Hour)
+	assert.NotNil(t, err)
+	_, err = newLinkProvider(nil, signer, "http://host", 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/status"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type listHandler struct {
+	data *ServiceData
+}
+
+func (h listHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("List request from %s", r.Host)
+
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		cmdapp.Log.Error(err)
+		return
+	}
+	if h.data.StatusLister == nil {
+		http.Error(w, "Listing is not supported", http.StatusBadRequest)
+		cmdapp.Log.Errorf("No lister")
+		return
+	}
+
+	result, err := h.data.StatusLister.List(filter)
+	if err != nil {
+		http.Error(w, "Cannot list status", http.StatusInternalServerError)
+		cmdapp.Log.Error(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(result)
+	if err != nil {
+		http.Error(w, "Can not prepare result", http.StatusBadRequest)
+		cmdapp.Log.Error(err)
+		return
+	}
+}
+
+// parseListFilter requires at least one filter or the cursor, a bare /status is treated as a missing ID
+func parseListFilter(q url.Values) (*api.ListFilter, error) {
+	res := &api.ListFilter{State: q.Get("state"), Recognizer: q.Get("recognizer"),
+		ExternalID: q.Get("externalID"), Cursor: q.Get("cursor"), Limit: defaultListLimit}
+	if res.State != "" && res.State != api.StateError && status.From(res.State) == 0 {
+		return nil, errors.New("Wrong state: " + res.State)
+	}
+	var err error
+	if res.From, err = parseListTime(q.Get("from")); err != nil {
+		return nil, errors.Wrap(err, "Wrong from")
+	}
+	if res.To, err = parseListTime(q.Get("to")); err != nil {
+		return nil, errors.Wrap(err, "Wrong to")
+	}
+	if l := q.Get("limit"); l != "" {
+		res.Limit, err = strconv.Atoi(l)
+		if err != nil || res.Limit < 1 || res.Limit > maxListLimit {
+			return nil, errors.Errorf("Wrong limit, expected 1..%d", maxListLimit)
+		}
+	}
+	if res.State == "" && res.Recognizer == "" && res.ExternalID == "" && res.From.IsZero() &&
+		res.To.IsZero() && res.Cursor == "" {
+		return nil, errors.New("No ID")
+	}
+	return res, nil
+}
+
+// parseListTime accepts RFC3339 time or a date
+func parseListTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
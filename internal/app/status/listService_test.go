@@ -0,0 +1,106 @@
+package status
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	var got *api.ListFilter
+	data := newTestListData()
+	data.StatusLister = testListFunc(func(f *api.ListFilter) (*api.List, error) {
+		got = f
+		return &api.List{Items: []api.ListItem{{ID: "1", Status: "UPLOADED", Recognizer: "ben",
+			Created: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}}, NextCursor: "c1"}, nil
+	})
+	req := newTestListRequest("/status?state=UPLOADED&recognizer=ben&from=2021-01-01&limit=5")
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"items":[{"id":"1","status":"UPLOADED","recognizer":"ben","created":"2021-01-01T00:00:00Z"}],"nextCursor":"c1"}`+"\n",
+		resp.Body.String())
+	assert.Equal(t, &api.ListFilter{State: "UPLOADED", Recognizer: "ben", From: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit: 5}, got)
+}
+
+func TestList_Fails(t *testing.T) {
+	data := newTestListData()
+	data.StatusLister = testListFunc(func(f *api.ListFilter) (*api.List, error) {
+		return nil, errors.New("olia")
+	})
+	req := newTestListRequest("/status?state=ERROR")
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestList_WrongParams(t *testing.T) {
+	data := newTestListData()
+	data.StatusLister = testListFunc(func(f *api.ListFilter) (*api.List, error) {
+		return &api.List{}, nil
+	})
+	for _, q := range []string{"", "?limit=10", "?state=olia", "?from=olia", "?to=2021-13-01",
+		"?state=ERROR&limit=0", "?state=ERROR&limit=1001", "?state=ERROR&limit=a"} {
+		req := newTestListRequest("/status" + q)
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, req)
+
+		assert.Equal(t, 400, resp.Code, q)
+	}
+}
+
+func TestList_Unauthorized(t *testing.T) {
+	data := newTestListData()
+	data.StatusLister = testListFunc(func(f *api.ListFilter) (*api.List, error) {
+		return &api.List{}, nil
+	})
+	for _, key := range []string{"", "Bearer k2"} {
+		req := httptest.NewRequest("GET", "/status?state=ERROR", nil)
+		if key != "" {
+			req.Header.Set("Authorization", key)
+		}
+		resp := httptest.NewRecorder()
+
+		NewRouter(data).ServeHTTP(resp, req)
+
+		assert.Equal(t, 403, resp.Code, key)
+	}
+}
+
+func TestParseListFilter(t *testing.T) {
+	f, err := parseListFilter(url.Values{"externalID": {"e1"}, "to": {"2021-01-01T10:00:00Z"}, "cursor": {"c"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &api.ListFilter{ExternalID: "e1", To: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), Cursor: "c",
+		Limit: defaultListLimit}, f)
+}
+
+type testListFunc func(f *api.ListFilter) (*api.List, error)
+
+func (f testListFunc) List(filter *api.ListFilter) (*api.List, error) {
+	return f(filter)
+}
+
+func newTestListData() *ServiceData {
+	data := newTestData()
+	data.adminKey = "k1"
+	return data
+}
+
+func newTestListRequest(url string) *http.Request {
+	res := httptest.NewRequest("GET", url, nil)
+	res.Header.Set("Authorization", "Bearer k1")
+	return res
+}
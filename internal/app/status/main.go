@@ -7,6 +7,7 @@ import (
 	"github.com/airenas/listgo/internal/pkg/metrics"
 	"github.com/airenas/listgo/internal/pkg/mongo"
 	"github.com/airenas/listgo/internal/pkg/rabbit"
+	"github.com/airenas/listgo/internal/pkg/urlsign"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/heptiolabs/healthcheck"
@@ -30,6 +31,8 @@ func init() {
 	rootCmd.PersistentFlags().Int32P("port", "", 8000, "Default service port")
 	cmdapp.Config.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
 	cmdapp.Config.SetDefault("port", 8080)
+	cmdapp.Config.SetDefault("events.routed", false)
+	cmdapp.Config.SetDefault("urlSign.ttl", "24h")
 }
 
 // Execute starts the server
@@ -43,13 +46,29 @@ func run(cmd *cobra.Command, args []string) {
 	cmdapp.CheckOrPanic(err, "")
 	defer mongoSessionProvider.Close()
 
-	data := &ServiceData{}
+	data := &ServiceData{hub: NewHub()}
 	err = initMetrics(data)
 	cmdapp.CheckOrPanic(err, "Can't init metrics")
 
 	data.health = healthcheck.NewHandler()
 	data.StatusProvider, err = mongo.NewStatusProvider(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "")
+	if key := cmdapp.Config.GetString("urlSign.key"); key != "" {
+		signer, err := urlsign.NewSigner(key)
+		cmdapp.CheckOrPanic(err, "Can't init URL signer")
+		data.StatusProvider, err = newLinkProvider(data.StatusProvider, signer,
+			cmdapp.Config.GetString("urlSign.resultURL"), cmdapp.Config.GetDuration("urlSign.ttl"))
+		cmdapp.CheckOrPanic(err, "Can't init signed links")
+		cmdapp.Log.Infof("Signing result links for %s", cmdapp.Config.GetString("urlSign.resultURL"))
+	}
+	data.StatusLister, err = mongo.NewStatusLister(mongoSessionProvider)
+	cmdapp.CheckOrPanic(err, "")
+	data.SubscriptionResolver, err = mongo.NewSubscriptionResolver(mongoSessionProvider)
+	cmdapp.CheckOrPanic(err, "")
+	data.adminKey = cmdapp.Config.GetString("admin.key")
+	if data.adminKey == "" {
+		cmdapp.Log.Warn("No admin.key, status listing and subscriptions by externalID or batch are disabled")
+	}
 	data.health.AddLivenessCheck("mongo", healthcheck.Async(mongoSessionProvider.Healthy, 10*time.Second))
 
 	msgChannelProvider, err := rabbit.NewChannelProvider()
@@ -57,7 +76,12 @@ func run(cmd *cobra.Command, args []string) {
 	defer msgChannelProvider.Close()
 	data.health.AddLivenessCheck("rabbit", healthcheck.Async(msgChannelProvider.Healthy, 10*time.Second))
 
+	routed := cmdapp.Config.GetBool("events.routed")
+	cmdapp.Log.Infof("Events routed by ID: %t", routed)
 	data.EventChannelFunc = func() (<-chan amqp.Delivery, error) {
+		if routed {
+			return initRoutedEventChannel(msgChannelProvider, data.hub)
+		}
 		return initEventChannel(msgChannelProvider)
 	}
 
@@ -73,8 +97,56 @@ func initEventChannel(provider *rabbit.ChannelProvider) (<-chan amqp.Delivery, e
 	if err != nil {
 		return nil, errors.Wrap(err, "Can't open channel")
 	}
+	q, err := declareEventQueue(provider, ch)
+	if err != nil {
+		return nil, err
+	}
+	err = ch.QueueBind(q.Name, "", provider.QueueName(messages.TopicStatusChange), false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't bing to topic queue")
+	}
+	return consumeEvents(ch, q)
+}
+
+// initRoutedEventChannel binds the queue to the routed exchange by the IDs watched in the hub,
+// so the instance receives only the events it has connections for
+func initRoutedEventChannel(provider *rabbit.ChannelProvider, hub *Hub) (<-chan amqp.Delivery, error) {
+	cmdapp.Log.Info("Init routed event channel")
+	ch, err := provider.Channel()
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't open channel")
+	}
+	q, err := declareEventQueue(provider, ch)
+	if err != nil {
+		return nil, err
+	}
+	topic := provider.QueueName(messages.TopicStatusChange)
+	err = rabbit.DeclareRoutedExchange(ch, topic)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't init routed exchange")
+	}
+	hub.setBinder(&queueBinder{ch: ch, queue: q.Name, exchange: rabbit.RoutedExchange(topic)})
+	return consumeEvents(ch, q)
+}
 
+// queueBinder binds the event queue to the routed exchange by ID
+type queueBinder struct {
+	ch       *amqp.Channel
+	queue    string
+	exchange string
+}
+
+func (b *queueBinder) Bind(ID string) error {
+	return b.ch.QueueBind(b.queue, ID, b.exchange, false, nil)
+}
+
+func (b *queueBinder) Unbind(ID string) error {
+	return b.ch.QueueUnbind(b.queue, ID, b.exchange, nil)
+}
+
+func declareEventQueue(provider *rabbit.ChannelProvider, ch *amqp.Channel) (amqp.Queue, error) {
 	var q amqp.Queue
+	var err error
 	provider.RunOnChannelWithRetry(func(*amqp.Channel) error {
 		q, err = ch.QueueDeclare(
 			"",    // name
@@ -86,14 +158,10 @@ func initEventChannel(provider *rabbit.ChannelProvider) (<-chan amqp.Delivery, e
 		)
 		return err
 	})
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't init queue")
-	}
-	err = ch.QueueBind(q.Name, "", provider.QueueName(messages.TopicStatusChange), false, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't bing to topic queue")
-	}
+	return q, errors.Wrap(err, "Can't init queue")
+}
 
+func consumeEvents(ch *amqp.Channel, q amqp.Queue) (<-chan amqp.Delivery, error) {
 	msgs, err := ch.Consume(
 		q.Name, // queue
 		"",     // consumer
@@ -128,5 +196,35 @@ func initMetrics(data *ServiceData) error {
 			Namespace: namespace,
 			Name:      "request_response_size_bytes",
 			Help:      "Response size in bytes."}, nil)
-	return metrics.Register(data.metrics.responseSize)
+	err = metrics.Register(data.metrics.responseSize)
+	if err != nil {
+		return err
+	}
+	fanoutDur := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fanout_durations_seconds",
+			Help:      "Duration of status event delivery to the subscribed connections.",
+		})
+	err = metrics.Register(fanoutDur)
+	if err != nil {
+		return err
+	}
+	data.metrics.fanoutDur = fanoutDur
+	hub := data.hub
+	err = metrics.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "connections",
+			Help:      "Number of the open websocket and SSE connections.",
+		}, func() float64 { return float64(hub.connectionCount()) }))
+	if err != nil {
+		return err
+	}
+	return metrics.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watched_ids",
+			Help:      "Number of the IDs with at least one subscribed connection.",
+		}, func() float64 { return float64(hub.idCount()) }))
 }
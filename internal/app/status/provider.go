@@ -6,3 +6,8 @@ import "github.com/airenas/listgo/internal/app/status/api"
 type Provider interface {
 	Get(ID string) (*api.TranscriptionResult, error)
 }
+
+// Lister provides the page of jobs matching the filter
+type Lister interface {
+	List(filter *api.ListFilter) (*api.List, error)
+}
@@ -1,9 +1,11 @@
 package status
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
@@ -18,14 +20,20 @@ import (
 type serviceMetric struct {
 	responseDur  prometheus.ObserverVec
 	responseSize prometheus.ObserverVec
+	fanoutDur    prometheus.Observer
 }
 
 // ServiceData keeps data required for service work
 type ServiceData struct {
-	StatusProvider   Provider
-	Port             int
-	EventChannelFunc eventChannelFunc
-	health           healthcheck.Handler
+	StatusProvider       Provider
+	StatusLister         Lister
+	SubscriptionResolver SubscriptionResolver
+	Port                 int
+	EventChannelFunc     eventChannelFunc
+	health               healthcheck.Handler
+	hub                  *Hub
+	// adminKey is a bearer token required for the subscriptions by externalID or batch
+	adminKey string
 
 	metrics serviceMetric
 }
@@ -55,9 +63,12 @@ func NewRouter(data *ServiceData) *mux.Router {
 	router := mux.NewRouter()
 	sh := promhttp.InstrumentHandlerDuration(data.metrics.responseDur,
 		promhttp.InstrumentHandlerResponseSize(data.metrics.responseSize, statusHandler{data: data}))
+	router.Methods("GET").Path("/status/{id}/events").Handler(sseHandler{data: data})
 	router.Methods("GET").Path("/status/{id}").Handler(sh)
-	router.Methods("GET").Path("/status").Handler(sh)
-	router.Methods("GET").Path("/status/").Handler(sh)
+	lh := promhttp.InstrumentHandlerDuration(data.metrics.responseDur,
+		promhttp.InstrumentHandlerResponseSize(data.metrics.responseSize, adminOnly(data.adminKey, listHandler{data: data})))
+	router.Methods("GET").Path("/status").Handler(lh)
+	router.Methods("GET").Path("/status/").Handler(lh)
 	router.Methods("GET").Path("/metrics").Handler(promhttp.Handler())
 	router.Handle("/subscribe", websocketHandler{data: data})
 	if data.health != nil {
@@ -116,5 +127,27 @@ func (h websocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		cmdapp.Log.Error(err)
 		return
 	}
-	go handleConnection(c)
+	resolver := h.data.SubscriptionResolver
+	if !authorized(h.data.adminKey, r) {
+		resolver = nil
+	}
+	go handleConnection(c, h.data.hub, resolver)
+}
+
+// adminOnly allows the request only with the 'Authorization: Bearer <key>' header
+func adminOnly(key string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(key, r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			cmdapp.Log.Warnf("Unauthorized admin request from %s", r.RemoteAddr)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the 'Authorization: Bearer <key>' header, no key configured means nobody is authorized
+func authorized(key string, r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1
 }
@@ -3,10 +3,13 @@ package status
 import (
 	"errors"
 	"log"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/heptiolabs/healthcheck"
 	"github.com/stretchr/testify/assert"
 
@@ -27,9 +30,9 @@ func TestNoID(t *testing.T) {
 }
 
 func test400(t *testing.T, path string) {
-	req := httptest.NewRequest("GET", path, nil)
+	req := newTestListRequest(path)
 	resp := httptest.NewRecorder()
-	data := newTestData()
+	data := newTestListData()
 	NewRouter(data).ServeHTTP(resp, req)
 	assert.Equal(t, resp.Code, 400)
 }
@@ -89,7 +92,7 @@ func testCode(t *testing.T, data *ServiceData, path string, code int) {
 }
 
 func newTestData() *ServiceData {
-	data := &ServiceData{}
+	data := &ServiceData{hub: NewHub()}
 	initMetrics(data)
 	data.health = healthcheck.NewHandler()
 	return data
@@ -102,3 +105,66 @@ func TestReady(t *testing.T) {
 func TestMetrics(t *testing.T) {
 	testCode(t, newTestData(), "/metrics", 200)
 }
+
+func Test_ReturnsQueueInfo(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status/x", nil)
+	resp := httptest.NewRecorder()
+	data := newTestData()
+	eta := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	data.StatusProvider = testStatusFunc(
+		func(ID string) (*api.TranscriptionResult, error) {
+			return &api.TranscriptionResult{ID: ID, Status: "UPLOADED", QueuePosition: 2, ETA: &eta}, nil
+		})
+	NewRouter(data).ServeHTTP(resp, req)
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, `{"id":"x","status":"UPLOADED","queuePosition":2,"eta":"2021-01-01T10:00:00Z"}`+"\n", resp.Body.String())
+}
+
+func TestWebsocket_ResolvesOnlyAuthorized(t *testing.T) {
+	data := &ServiceData{hub: NewHub(), adminKey: "k1",
+		SubscriptionResolver: testResolver{ext: map[string][]string{"e1": {"j1"}}}}
+	srv := httptest.NewServer(websocketHandler{data: data})
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, tc := range []struct {
+		auth string
+		exp  string
+	}{
+		{"", MsgTypeError},
+		{"Bearer k2", MsgTypeError},
+		{"Bearer k1", MsgTypeSubscribed},
+	} {
+		h := http.Header{}
+		if tc.auth != "" {
+			h.Set("Authorization", tc.auth)
+		}
+		c, _, err := websocket.DefaultDialer.Dial(url, h)
+		assert.Nil(t, err)
+		assert.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"action":"subscribe","externalIDs":["e1"]}`)))
+		var r SubscriptionResponse
+		assert.Nil(t, c.ReadJSON(&r))
+		assert.Equal(t, tc.exp, r.Type, tc.auth)
+		if tc.exp == MsgTypeError {
+			assert.Empty(t, r.IDs, tc.auth)
+		}
+		c.Close()
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	for _, tc := range []struct {
+		key, header string
+		exp         bool
+	}{
+		{"k1", "Bearer k1", true},
+		{"k1", "Bearer k2", false},
+		{"k1", "", false},
+		{"", "", false},
+		{"", "Bearer ", false},
+	} {
+		r := httptest.NewRequest("GET", "/subscribe", nil)
+		r.Header.Set("Authorization", tc.header)
+		assert.Equal(t, tc.exp, authorized(tc.key, r), tc.key+" "+tc.header)
+	}
+}
@@ -0,0 +1,145 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/status"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	sseBufferSize    = 16
+	sseHeartbeatWait = 30 * time.Second
+)
+
+type sseHandler struct {
+	data *ServiceData
+}
+
+// sseConn registers a Server-Sent Events stream as a connection so it is fed by processMsg
+// the same way as websockets
+type sseConn struct {
+	ch        chan interface{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSSEConn() *sseConn {
+	return &sseConn{ch: make(chan interface{}, sseBufferSize), done: make(chan struct{})}
+}
+
+func (c *sseConn) ReadMessage() (messageType int, p []byte, err error) {
+	<-c.done
+	return 0, nil, errors.New("closed")
+}
+
+func (c *sseConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+// WriteJSON queues the message, a client not reading fast enough is disconnected
+func (c *sseConn) WriteJSON(v interface{}) error {
+	select {
+	case <-c.done:
+		return errors.New("closed")
+	default:
+	}
+	select {
+	case c.ch <- v:
+		return nil
+	default:
+		c.Close()
+		return errors.New("SSE buffer is full")
+	}
+}
+
+func (h sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cmdapp.Log.Infof("SSE request from %s", r.Host)
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		http.Error(w, "No ID", http.StatusBadRequest)
+		cmdapp.Log.Errorf("No ID")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		cmdapp.Log.Errorf("Streaming is not supported")
+		return
+	}
+
+	conn := newSSEConn()
+	// subscribe before reading the current status to not miss a change in between
+	h.data.hub.subscribe(conn, []string{id})
+	defer h.data.hub.unregister(conn)
+	defer conn.Close()
+
+	result, err := h.data.StatusProvider.Get(id)
+	if err != nil {
+		http.Error(w, "Cannot get status for ID: "+id, http.StatusBadRequest)
+		cmdapp.Log.Errorf("Cannot get status for ID: " + id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(sseHeartbeatWait)
+	defer heartbeat.Stop()
+	for {
+		if result != nil {
+			if err := writeSSEEvent(w, result); err != nil {
+				cmdapp.Log.Error(err)
+				return
+			}
+			flusher.Flush()
+			if isFinal(result) {
+				cmdapp.Log.Infof("SSE stream finished for %s", id)
+				return
+			}
+		}
+		result = nil
+		select {
+		case <-r.Context().Done():
+			cmdapp.Log.Infof("SSE client disconnected")
+			return
+		case <-conn.done:
+			cmdapp.Log.Infof("SSE connection closed")
+			return
+		case v := <-conn.ch:
+			result, _ = v.(*api.TranscriptionResult)
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				cmdapp.Log.Error(err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, result *api.TranscriptionResult) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal status")
+	}
+	_, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", b)
+	return errors.Wrap(err, "Can't write event")
+}
+
+// isFinal returns true if no more status changes are expected
+func isFinal(result *api.TranscriptionResult) bool {
+	return status.From(result.Status) == status.Completed || result.ErrorCode != "" || result.Error != ""
+}
@@ -0,0 +1,125 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSE_Completed(t *testing.T) {
+	data := newTestData()
+	data.StatusProvider = testStatusFunc(
+		func(ID string) (*api.TranscriptionResult, error) {
+			return &api.TranscriptionResult{ID: ID, Status: "COMPLETED"}, nil
+		})
+	req := httptest.NewRequest("GET", "/status/x/events", nil)
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "text/event-stream", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "event: status\ndata: {\"id\":\"x\",\"status\":\"COMPLETED\"}\n\n", resp.Body.String())
+	assert.Empty(t, data.hub.connections("x"))
+}
+
+func TestSSE_ProviderFails(t *testing.T) {
+	data := newTestData()
+	data.StatusProvider = testStatusFunc(
+		func(ID string) (*api.TranscriptionResult, error) {
+			return nil, errors.New("olia")
+		})
+	req := httptest.NewRequest("GET", "/status/x/events", nil)
+	resp := httptest.NewRecorder()
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+	assert.Empty(t, data.hub.connections("x"))
+}
+
+func TestSSE_SendsChanges(t *testing.T) {
+	initTest(t)
+	var calls int64
+	data := newTestData()
+	data.StatusProvider = testStatusFunc(
+		func(ID string) (*api.TranscriptionResult, error) {
+			switch atomic.AddInt64(&calls, 1) {
+			case 1:
+				return &api.TranscriptionResult{ID: ID, Status: "UPLOADED"}, nil
+			case 2:
+				return &api.TranscriptionResult{ID: ID, Status: "Diarization"}, nil
+			}
+			return &api.TranscriptionResult{ID: ID, Error: "err"}, nil
+		})
+	req := httptest.NewRequest("GET", "/status/sse1/events", nil)
+	resp := httptest.NewRecorder()
+	fc := make(chan bool)
+	go func() {
+		NewRouter(data).ServeHTTP(resp, req)
+		close(fc)
+	}()
+
+	for i := 0; i < 2; i++ {
+		waitForCall(t, &calls, int64(i+1))
+		assert.Nil(t, processMsg(&amqp.Delivery{Body: []byte("sse1")}, data))
+	}
+	<-fc
+
+	events := strings.Split(strings.TrimSpace(resp.Body.String()), "\n\n")
+	assert.Equal(t, 3, len(events))
+	assert.Contains(t, events[0], `"status":"UPLOADED"`)
+	assert.Contains(t, events[1], `"status":"Diarization"`)
+	assert.Contains(t, events[2], `"error":"err"`)
+	assert.Empty(t, data.hub.connections("sse1"))
+}
+
+func TestSSE_ClientDisconnects(t *testing.T) {
+	data := newTestData()
+	data.StatusProvider = testStatusFunc(
+		func(ID string) (*api.TranscriptionResult, error) {
+			return &api.TranscriptionResult{ID: ID, Status: "UPLOADED"}, nil
+		})
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/status/sse2/events", nil).WithContext(ctx)
+	resp := httptest.NewRecorder()
+	fc := make(chan bool)
+	go func() {
+		NewRouter(data).ServeHTTP(resp, req)
+		close(fc)
+	}()
+	cancel()
+	<-fc
+
+	assert.Empty(t, data.hub.connections("sse2"))
+}
+
+func TestSSEConn_FullBufferCloses(t *testing.T) {
+	c := newSSEConn()
+	for i := 0; i < sseBufferSize; i++ {
+		assert.Nil(t, c.WriteJSON(i))
+	}
+
+	assert.NotNil(t, c.WriteJSON(sseBufferSize))
+	<-c.done
+	assert.NotNil(t, c.WriteJSON(1))
+}
+
+func waitForCall(t *testing.T, calls *int64, expected int64) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if atomic.LoadInt64(calls) >= expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Fail(t, "no call")
+}
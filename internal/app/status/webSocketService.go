@@ -1,14 +1,35 @@
 package status
 
 import (
+	"bytes"
+	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 )
 
-var idConnectionMap = make(map[string]map[WsConn]bool)
-var connectionIDMap = make(map[WsConn]string)
-var mapLock = sync.Mutex{}
+const (
+	// ActionSubscribe adds IDs to the connection's subscriptions
+	ActionSubscribe = "subscribe"
+	// ActionUnsubscribe removes IDs from the connection's subscriptions, all of them if the request has no IDs
+	ActionUnsubscribe = "unsubscribe"
+
+	// MsgTypeSubscribed is sent back after a successful subscribe request
+	MsgTypeSubscribed = "subscribed"
+	// MsgTypeUnsubscribed is sent back after a successful unsubscribe request
+	MsgTypeUnsubscribed = "unsubscribed"
+	// MsgTypeError is sent back if a request can not be processed
+	MsgTypeError = "error"
+
+	maxSubscriptions = 1000
+	writeBufferSize  = 64
+	writeWait        = 10 * time.Second
+	pongWait         = 60 * time.Second
+	pingPeriod       = pongWait * 9 / 10
+)
 
 // WsConn is interface for websocket handling in status service
 type WsConn interface {
@@ -17,60 +38,193 @@ type WsConn interface {
 	WriteJSON(v interface{}) error
 }
 
-func handleConnection(conn WsConn) {
-	defer deleteConnection(conn)
-	defer conn.Close()
+// keepAliveConn is implemented by connections supporting ping/pong keepalive, e.g. *websocket.Conn
+type keepAliveConn interface {
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+}
+
+// SubscriptionResolver expands batch and external ID subscriptions to job IDs
+type SubscriptionResolver interface {
+	IDsByExternalID(externalID string) ([]string, error)
+	IDsByBatch(batchID string) ([]string, error)
+}
+
+// SubscriptionRequest is a JSON message sent by a client over the websocket
+type SubscriptionRequest struct {
+	Action      string   `json:"action"`
+	IDs         []string `json:"ids,omitempty"`
+	ExternalIDs []string `json:"externalIDs,omitempty"`
+	Batches     []string `json:"batches,omitempty"`
+}
+
+// SubscriptionResponse is a JSON reply to SubscriptionRequest
+type SubscriptionResponse struct {
+	Type  string   `json:"type"`
+	IDs   []string `json:"ids,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// handleConnection reads client requests until the connection is closed.
+// A JSON frame is processed as SubscriptionRequest, any other frame is treated as
+// a single job ID replacing the previous subscriptions of the connection
+func handleConnection(conn WsConn, hub *Hub, resolver SubscriptionResolver) {
+	w := newWsWriter(conn)
+	hub.register(conn, w)
+	defer hub.unregister(conn)
+	defer w.close()
+	go w.run()
+	if kc, ok := conn.(keepAliveConn); ok {
+		kc.SetReadDeadline(time.Now().Add(pongWait))
+		kc.SetPongHandler(func(string) error { return kc.SetReadDeadline(time.Now().Add(pongWait)) })
+	}
 	for {
 		cmdapp.Log.Infof("handleConnection")
 		_, message, err := conn.ReadMessage()
 		if err != nil {
 			cmdapp.Log.Error(err)
 			break
+		}
+		if isJSON(message) {
+			w.send(processRequest(hub, conn, message, resolver))
 		} else {
-			saveConnection(conn, string(message))
+			hub.replace(conn, string(message))
 		}
 	}
 	cmdapp.Log.Infof("handleConnection finish")
 }
 
-func deleteConnection(conn WsConn) {
-	mapLock.Lock()
-	defer mapLock.Unlock()
-	deleteConnectionNoSync(conn)
+func isJSON(message []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(message), []byte("{"))
+}
+
+func processRequest(hub *Hub, conn WsConn, message []byte, resolver SubscriptionResolver) *SubscriptionResponse {
+	var req SubscriptionRequest
+	err := json.Unmarshal(message, &req)
+	if err != nil {
+		cmdapp.Log.Error(err)
+		return &SubscriptionResponse{Type: MsgTypeError, Error: "Can't parse request"}
+	}
+	ids, err := resolveIDs(&req, resolver)
+	if err != nil {
+		cmdapp.Log.Error(err)
+		return &SubscriptionResponse{Type: MsgTypeError, Error: err.Error()}
+	}
+	switch req.Action {
+	case ActionSubscribe:
+		if len(ids) == 0 {
+			return &SubscriptionResponse{Type: MsgTypeError, Error: "No IDs"}
+		}
+		err = hub.subscribe(conn, ids)
+		if err != nil {
+			return &SubscriptionResponse{Type: MsgTypeError, Error: err.Error()}
+		}
+		return &SubscriptionResponse{Type: MsgTypeSubscribed, IDs: ids}
+	case ActionUnsubscribe:
+		return &SubscriptionResponse{Type: MsgTypeUnsubscribed, IDs: hub.unsubscribe(conn, ids)}
+	}
+	return &SubscriptionResponse{Type: MsgTypeError, Error: "Unknown action: " + req.Action}
 }
 
-func deleteConnectionNoSync(conn WsConn) {
-	cmdapp.Log.Info("deleteConnection")
-	id, found := connectionIDMap[conn]
-	if found {
-		conns, found := idConnectionMap[id]
-		if found {
-			delete(conns, conn)
-			if len(conns) == 0 {
-				delete(idConnectionMap, id)
+func resolveIDs(req *SubscriptionRequest, resolver SubscriptionResolver) ([]string, error) {
+	res := make([]string, 0)
+	added := map[string]bool{}
+	add := func(ids []string) {
+		for _, id := range ids {
+			if id != "" && !added[id] {
+				added[id] = true
+				res = append(res, id)
 			}
 		}
 	}
-	delete(connectionIDMap, conn)
-	cmdapp.Log.Infof("deleteConnection finish: %d", len(connectionIDMap))
+	add(req.IDs)
+	if (len(req.ExternalIDs) > 0 || len(req.Batches) > 0) && resolver == nil {
+		return nil, errors.New("Subscriptions by externalID or batch are allowed only for the authorized connection")
+	}
+	for _, e := range req.ExternalIDs {
+		ids, err := resolver.IDsByExternalID(e)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't resolve externalID "+e)
+		}
+		add(ids)
+	}
+	for _, b := range req.Batches {
+		ids, err := resolver.IDsByBatch(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't resolve batch "+b)
+		}
+		add(ids)
+	}
+	return res, nil
+}
+
+// wsWriter is the only goroutine writing to the connection. Messages are queued
+// into a bounded buffer, a client not reading fast enough is disconnected
+// instead of blocking the event processing
+type wsWriter struct {
+	conn      WsConn
+	sendCh    chan interface{}
+	quitCh    chan struct{}
+	closeOnce sync.Once
+}
+
+func newWsWriter(conn WsConn) *wsWriter {
+	return &wsWriter{conn: conn, sendCh: make(chan interface{}, writeBufferSize), quitCh: make(chan struct{})}
 }
 
-func saveConnection(conn WsConn, id string) {
-	cmdapp.Log.Infof("saveConnection")
-	mapLock.Lock()
-	defer mapLock.Unlock()
-	deleteConnectionNoSync(conn)
-	connectionIDMap[conn] = id
-	conns, found := idConnectionMap[id]
-	if !found {
-		conns = map[WsConn]bool{}
-		idConnectionMap[id] = conns
+func (w *wsWriter) send(v interface{}) bool {
+	select {
+	case <-w.quitCh:
+		return false
+	default:
+	}
+	select {
+	case w.sendCh <- v:
+		return true
+	default:
+		cmdapp.Log.Warn("Websocket write buffer is full, closing connection")
+		w.close()
+		return false
+	}
+}
+
+func (w *wsWriter) run() {
+	kc, keepAlive := w.conn.(keepAliveConn)
+	var pingCh <-chan time.Time
+	if keepAlive {
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+		pingCh = ticker.C
+	}
+	for {
+		select {
+		case <-w.quitCh:
+			return
+		case v := <-w.sendCh:
+			if keepAlive {
+				kc.SetWriteDeadline(time.Now().Add(writeWait))
+			}
+			if err := w.conn.WriteJSON(v); err != nil {
+				cmdapp.Log.Error(errors.Wrap(err, "Cannot write to websocket"))
+				w.close()
+				return
+			}
+		case <-pingCh:
+			if err := kc.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				cmdapp.Log.Error(errors.Wrap(err, "Cannot ping websocket"))
+				w.close()
+				return
+			}
+		}
 	}
-	conns[conn] = true
-	cmdapp.Log.Infof("saveConnection finish: %d", len(connectionIDMap))
 }
 
-func getConnections(id string) (map[WsConn]bool, bool) {
-	r, found := idConnectionMap[id]
-	return r, found
+// close stops the writer and closes the connection, the reading loop fails then and drops subscriptions
+func (w *wsWriter) close() {
+	w.closeOnce.Do(func() {
+		close(w.quitCh)
+		cmdapp.LogIf(w.conn.Close())
+	})
 }
@@ -2,7 +2,6 @@ package status
 
 import (
 	"errors"
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +13,7 @@ type testdataWS struct {
 	fc     chan bool
 	f      func()
 	conn   *wsConnMock
+	hub    *Hub
 }
 
 func initTestDataWS(t *testing.T) *testdataWS {
@@ -23,8 +23,9 @@ func initTestDataWS(t *testing.T) *testdataWS {
 	res.fc = make(chan bool)
 	res.readCh = make(chan bool)
 	res.conn = &wsConnMock{valueCh: res.ch, sCh: res.readCh}
+	res.hub = NewHub()
 	res.f = func() {
-		handleConnection(res.conn)
+		handleConnection(res.conn, res.hub, nil)
 		res.fc <- true
 	}
 	return &res
@@ -48,8 +49,8 @@ func TestHandleConnection_ReadOK_Closed(t *testing.T) {
 	<-td.fc
 	assert.Equal(t, td.conn.closedCount, 1)
 
-	assert.Equal(t, len(idConnectionMap), 0)
-	assert.Equal(t, len(connectionIDMap), 0)
+	assert.Equal(t, td.hub.idCount(), 0)
+	assert.Equal(t, td.hub.connectionCount(), 0)
 }
 
 func TestHandleConnection_SeveralReadOK_Closed(t *testing.T) {
@@ -64,8 +65,8 @@ func TestHandleConnection_SeveralReadOK_Closed(t *testing.T) {
 
 	assert.Equal(t, td.conn.closedCount, 1)
 
-	assert.Equal(t, len(idConnectionMap), 0)
-	assert.Equal(t, len(connectionIDMap), 0)
+	assert.Equal(t, td.hub.idCount(), 0)
+	assert.Equal(t, td.hub.connectionCount(), 0)
 }
 
 func TestHandleConnection_SeveralReadOK_Waiting(t *testing.T) {
@@ -75,13 +76,13 @@ func TestHandleConnection_SeveralReadOK_Waiting(t *testing.T) {
 	td.ch <- "id3"
 	<-td.readCh
 	<-td.readCh // wait for next read
-	c, ok := getConnections("id3")
+	c, ok := getConnections(td.hub, "id3")
 
 	assert.True(t, ok)
 	assert.True(t, c[td.conn])
 
-	assert.Equal(t, len(idConnectionMap), 1)
-	assert.Equal(t, len(connectionIDMap), 1)
+	assert.Equal(t, td.hub.idCount(), 1)
+	assert.Equal(t, td.hub.connectionCount(), 1)
 
 	close(td.ch)
 	<-td.fc
@@ -98,20 +99,19 @@ func TestHandleConnection_SeveralConnections(t *testing.T) {
 	fc1 := make(chan bool)
 	conn1 := &wsConnMock{valueCh: ch1, sCh: readCh1}
 	go func() {
-		handleConnection(conn1)
+		handleConnection(conn1, td.hub, nil)
 		fc1 <- true
 	}()
 	ch1 <- "id4"
 	<-readCh1
 	<-readCh1 // wait for next read
-	c, ok := getConnections("id4")
+	c, ok := getConnections(td.hub, "id4")
 	assert.True(t, ok)
 	assert.True(t, c[td.conn])
 	assert.True(t, c[conn1])
 
-	assert.Equal(t, len(idConnectionMap), 1)
-	fmt.Println(connectionIDMap)
-	assert.Equal(t, len(connectionIDMap), 2)
+	assert.Equal(t, td.hub.idCount(), 1)
+	assert.Equal(t, td.hub.connectionCount(), 2)
 
 	assert.Equal(t, td.conn.closedCount, 0)
 	assert.Equal(t, conn1.closedCount, 0)
@@ -121,19 +121,196 @@ func TestHandleConnection_SeveralConnections(t *testing.T) {
 	<-td.fc
 	<-fc1
 
-	_, ok = getConnections("id4")
+	_, ok = getConnections(td.hub, "id4")
 	assert.False(t, ok)
-	assert.Equal(t, len(idConnectionMap), 0)
-	fmt.Println(connectionIDMap)
-	assert.Equal(t, len(connectionIDMap), 0)
+	assert.Equal(t, td.hub.idCount(), 0)
+	assert.Equal(t, td.hub.connectionCount(), 0)
 
 	assert.Equal(t, td.conn.closedCount, 1)
 	assert.Equal(t, conn1.closedCount, 1)
 }
 
+func initTestDataWSJSON(t *testing.T, resolver SubscriptionResolver) *testdataWS {
+	res := initTestDataWS(t)
+	res.conn.writeCh = make(chan interface{}, 10)
+	res.f = func() {
+		handleConnection(res.conn, res.hub, resolver)
+		res.fc <- true
+	}
+	return res
+}
+
+func (td *testdataWS) request(s string) *SubscriptionResponse {
+	td.ch <- s
+	return (<-td.conn.writeCh).(*SubscriptionResponse)
+}
+
+func (td *testdataWS) finish() {
+	close(td.ch)
+	<-td.fc
+}
+
+func TestHandleConnection_SubscribeSeveral(t *testing.T) {
+	td := initTestDataWSJSON(t, nil)
+	go td.f()
+	defer td.finish()
+
+	r := td.request(`{"action":"subscribe","ids":["j1","j2","j1"]}`)
+
+	assert.Equal(t, &SubscriptionResponse{Type: MsgTypeSubscribed, IDs: []string{"j1", "j2"}}, r)
+	for _, id := range []string{"j1", "j2"} {
+		c, ok := getConnections(td.hub, id)
+		assert.True(t, ok)
+		assert.True(t, c[td.conn])
+	}
+	r = td.request(`{"action":"subscribe","ids":["j3"]}`)
+	assert.Equal(t, MsgTypeSubscribed, r.Type)
+	assert.Equal(t, 3, len(td.hub.subscriptions(td.conn)))
+}
+
+func TestHandleConnection_Unsubscribe(t *testing.T) {
+	td := initTestDataWSJSON(t, nil)
+	go td.f()
+	defer td.finish()
+
+	td.request(`{"action":"subscribe","ids":["j1","j2"]}`)
+	r := td.request(`{"action":"unsubscribe","ids":["j1","j5"]}`)
+
+	assert.Equal(t, &SubscriptionResponse{Type: MsgTypeUnsubscribed, IDs: []string{"j1"}}, r)
+	_, ok := getConnections(td.hub, "j1")
+	assert.False(t, ok)
+	_, ok = getConnections(td.hub, "j2")
+	assert.True(t, ok)
+}
+
+func TestHandleConnection_UnsubscribeAll(t *testing.T) {
+	td := initTestDataWSJSON(t, nil)
+	go td.f()
+	defer td.finish()
+
+	td.request(`{"action":"subscribe","ids":["j1","j2"]}`)
+	r := td.request(`{"action":"unsubscribe"}`)
+
+	assert.Equal(t, MsgTypeUnsubscribed, r.Type)
+	assert.ElementsMatch(t, []string{"j1", "j2"}, r.IDs)
+	assert.Equal(t, 0, td.hub.idCount())
+	assert.Equal(t, 0, len(td.hub.subscriptions(td.conn)))
+}
+
+func TestHandleConnection_PlainIDReplaces(t *testing.T) {
+	td := initTestDataWSJSON(t, nil)
+	go td.f()
+
+	td.request(`{"action":"subscribe","ids":["j1","j2"]}`)
+	td.ch <- "j3"
+	td.request(`{"action":"subscribe","ids":["j4"]}`) // frames are processed in order
+
+	_, ok := getConnections(td.hub, "j1")
+	assert.False(t, ok)
+	_, ok = getConnections(td.hub, "j3")
+	assert.True(t, ok)
+	td.finish()
+	assert.Equal(t, 1, td.conn.closedCount)
+	assert.Equal(t, 0, td.hub.idCount())
+}
+
+func TestHandleConnection_SubscribeResolved(t *testing.T) {
+	td := initTestDataWSJSON(t, testResolver{ext: map[string][]string{"e1": {"j1", "j2"}},
+		batch: map[string][]string{"b1": {"j2", "j3"}}})
+	go td.f()
+	defer td.finish()
+
+	r := td.request(`{"action":"subscribe","ids":["j0"],"externalIDs":["e1"],"batches":["b1"]}`)
+
+	assert.Equal(t, &SubscriptionResponse{Type: MsgTypeSubscribed, IDs: []string{"j0", "j1", "j2", "j3"}}, r)
+	assert.Equal(t, 4, len(td.hub.subscriptions(td.conn)))
+}
+
+func TestHandleConnection_ResolveFails(t *testing.T) {
+	td := initTestDataWSJSON(t, testResolver{})
+	go td.f()
+	defer td.finish()
+
+	r := td.request(`{"action":"subscribe","batches":["b1"]}`)
+
+	assert.Equal(t, MsgTypeError, r.Type)
+	assert.Equal(t, 0, len(td.hub.subscriptions(td.conn)))
+}
+
+func TestHandleConnection_Errors(t *testing.T) {
+	td := initTestDataWSJSON(t, nil)
+	go td.f()
+	defer td.finish()
+
+	for _, s := range []string{`{"action":"subscribe"`, `{"action":"subscribe"}`, `{"action":"olia","ids":["j1"]}`,
+		`{"action":"subscribe","externalIDs":["e1"]}`} {
+		r := td.request(s)
+		assert.Equal(t, MsgTypeError, r.Type, s)
+		assert.NotEmpty(t, r.Error, s)
+	}
+	assert.Equal(t, 0, len(td.hub.subscriptions(td.conn)))
+}
+
+func TestWsWriter_FullBufferCloses(t *testing.T) {
+	conn := &wsConnMock{}
+	w := newWsWriter(conn)
+	for i := 0; i < writeBufferSize; i++ {
+		assert.True(t, w.send(i))
+	}
+
+	assert.False(t, w.send(writeBufferSize))
+	assert.False(t, w.send(writeBufferSize))
+	assert.Equal(t, 1, conn.closedCount)
+}
+
+func TestWsWriter_WriteFailCloses(t *testing.T) {
+	conn := &wsConnMock{writeErr: errors.New("olia")}
+	w := newWsWriter(conn)
+	fc := make(chan bool)
+	go func() {
+		w.run()
+		close(fc)
+	}()
+
+	w.send(1)
+	<-fc
+	assert.Equal(t, 1, conn.closedCount)
+	assert.False(t, w.send(1))
+}
+
+func getConnections(hub *Hub, id string) (map[WsConn]bool, bool) {
+	conns := hub.connections(id)
+	res := map[WsConn]bool{}
+	for _, c := range conns {
+		res[c.conn] = true
+	}
+	return res, len(conns) > 0
+}
+
+type testResolver struct {
+	ext   map[string][]string
+	batch map[string][]string
+}
+
+func (r testResolver) IDsByExternalID(externalID string) ([]string, error) {
+	if res, ok := r.ext[externalID]; ok {
+		return res, nil
+	}
+	return nil, errors.New("no externalID")
+}
+
+func (r testResolver) IDsByBatch(batchID string) ([]string, error) {
+	if res, ok := r.batch[batchID]; ok {
+		return res, nil
+	}
+	return nil, errors.New("no batch")
+}
+
 type wsConnMock struct {
 	sCh         chan<- bool   // start
 	valueCh     <-chan string // value
+	writeCh     chan interface{}
+	writeErr    error
 	closedCount int
 }
 
@@ -152,5 +329,8 @@ func (f *wsConnMock) Close() error {
 }
 
 func (f *wsConnMock) WriteJSON(v interface{}) error {
-	return nil
+	if f.writeCh != nil {
+		f.writeCh <- v
+	}
+	return f.writeErr
 }
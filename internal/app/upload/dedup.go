@@ -0,0 +1,79 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+)
+
+// HeaderAPIKey is the request header with the client API key
+const HeaderAPIKey = "X-Api-Key"
+
+// Dedup finds the completed transcription of the same audio and copies its results
+type Dedup interface {
+	// FindCompleted returns the original request of the completed transcription or nil
+	FindCompleted(key string) (*persistence.Request, error)
+	// Copy copies the status and the result to the new ID
+	Copy(srcID, ID string) error
+}
+
+// DedupConfig keeps the deduplication settings
+type DedupConfig struct {
+	Dedup Dedup
+	// APIKeys enables the deduplication only for the requests with the keys, empty - for all requests
+	APIKeys map[string]bool
+}
+
+func (c *DedupConfig) enabled(r *http.Request) bool {
+	if c == nil {
+		return false
+	}
+	if len(c.APIKeys) == 0 {
+		return true
+	}
+	return c.APIKeys[r.Header.Get(HeaderAPIKey)]
+}
+
+// findDuplicate returns the completed transcription or nil, the failure is only logged
+func findDuplicate(d Dedup, key string) *persistence.Request {
+	res, err := d.FindCompleted(key)
+	if err != nil {
+		cmdapp.Log.Warn(errors.Wrap(err, "Can't check for duplicates"))
+		return nil
+	}
+	return res
+}
+
+// dedupKey hashes the audio files and the parameters affecting the transcription.
+// The files are rewound after reading
+func dedupKey(recID string, params []string, files []multipart.File, fHeaders []*multipart.FileHeader) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "rec:%s\n", recID)
+	for _, p := range params {
+		fmt.Fprintf(h, "prm:%s\n", p)
+	}
+	for i, f := range files {
+		fh := sha256.New()
+		if _, err := io.Copy(fh, f); err != nil {
+			return "", errors.Wrap(err, "can't read file")
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", errors.Wrap(err, "can't rewind file")
+		}
+		name := strings.ToLower(filepath.Ext(fHeaders[i].Filename))
+		if len(files) > 1 {
+			name = toLowerExt(sanitizeName(fHeaders[i].Filename))
+		}
+		fmt.Fprintf(h, "file:%s:%s\n", name, hex.EncodeToString(fh.Sum(nil)))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,138 @@
+package upload
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/airenas/listgo/internal/pkg/test/mocks/matchers"
+	"github.com/petergtz/pegomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDedupTestData(dedupMock *mocks.MockDedup) *ServiceData {
+	res := newTestData()
+	res.Dedup = &DedupConfig{Dedup: dedupMock}
+	return res
+}
+
+func TestPOST_Dedup_Found(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	pegomock.When(dedupMock.FindCompleted(pegomock.AnyString())).
+		ThenReturn(&persistence.Request{ID: "src", File: "src.wav", Duration: 10}, nil)
+	resp := httptest.NewRecorder()
+	NewRouter(newDedupTestData(dedupMock)).ServeHTTP(resp, newReq("file.wav", "", "ext"))
+
+	assert.Equal(t, 200, resp.Code)
+	srcID, id := dedupMock.VerifyWasCalledOnce().Copy(pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, "src", srcID)
+	assert.Contains(t, resp.Body.String(), id)
+	r := requestSaverMock.VerifyWasCalledOnce().Save(matchers.AnyPtrToPersistenceRequest()).GetCapturedArguments()
+	assert.Equal(t, "src", r.SourceID)
+	assert.Equal(t, "src.wav", r.File)
+	assert.Equal(t, "ext", r.ExternalID)
+	assert.Equal(t, 10.0, r.Duration)
+	assert.NotEmpty(t, r.DedupKey)
+	fileSaverMock.VerifyWasCalled(pegomock.Never()).Save(pegomock.AnyString(), matchers.AnyIoReader())
+	msgSenderMock.VerifyWasCalled(pegomock.Never()).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString())
+}
+
+func TestPOST_Dedup_NotFound(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	resp := httptest.NewRecorder()
+	NewRouter(newDedupTestData(dedupMock)).ServeHTTP(resp, newReq("file.wav", "", ""))
+
+	assert.Equal(t, 200, resp.Code)
+	key := dedupMock.VerifyWasCalledOnce().FindCompleted(pegomock.AnyString()).GetCapturedArguments()
+	r := requestSaverMock.VerifyWasCalledOnce().Save(matchers.AnyPtrToPersistenceRequest()).GetCapturedArguments()
+	assert.Equal(t, key, r.DedupKey)
+	assert.Empty(t, r.SourceID)
+	fileSaverMock.VerifyWasCalledOnce().Save(pegomock.AnyString(), matchers.AnyIoReader())
+}
+
+func TestPOST_Dedup_FindFails(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	pegomock.When(dedupMock.FindCompleted(pegomock.AnyString())).ThenReturn(nil, errors.New("olia"))
+	resp := httptest.NewRecorder()
+	NewRouter(newDedupTestData(dedupMock)).ServeHTTP(resp, newReq("file.wav", "", ""))
+
+	assert.Equal(t, 200, resp.Code)
+	fileSaverMock.VerifyWasCalledOnce().Save(pegomock.AnyString(), matchers.AnyIoReader())
+}
+
+func TestPOST_Dedup_CopyFails(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	pegomock.When(dedupMock.FindCompleted(pegomock.AnyString())).
+		ThenReturn(&persistence.Request{ID: "src", File: "src.wav"}, nil)
+	pegomock.When(dedupMock.Copy(pegomock.AnyString(), pegomock.AnyString())).ThenReturn(errors.New("olia"))
+	resp := httptest.NewRecorder()
+	NewRouter(newDedupTestData(dedupMock)).ServeHTTP(resp, newReq("file.wav", "", ""))
+
+	assert.Equal(t, 500, resp.Code)
+	requestSaverMock.VerifyWasCalled(pegomock.Never()).Save(matchers.AnyPtrToPersistenceRequest())
+}
+
+func TestPOST_Dedup_SkipsEmail(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	resp := httptest.NewRecorder()
+	NewRouter(newDedupTestData(dedupMock)).ServeHTTP(resp, newReq("file.wav", "a@a.a", ""))
+
+	assert.Equal(t, 200, resp.Code)
+	dedupMock.VerifyWasCalled(pegomock.Never()).FindCompleted(pegomock.AnyString())
+}
+
+func TestPOST_Dedup_APIKey(t *testing.T) {
+	initTest(t)
+	dedupMock := mocks.NewMockDedup()
+	data := newDedupTestData(dedupMock)
+	data.Dedup.APIKeys = map[string]bool{"k1": true}
+	resp := httptest.NewRecorder()
+	NewRouter(data).ServeHTTP(resp, newReq("file.wav", "", ""))
+	assert.Equal(t, 200, resp.Code)
+	dedupMock.VerifyWasCalled(pegomock.Never()).FindCompleted(pegomock.AnyString())
+
+	req := newReq("file.wav", "", "")
+	req.Header.Set(HeaderAPIKey, "k1")
+	NewRouter(data).ServeHTTP(httptest.NewRecorder(), req)
+	dedupMock.VerifyWasCalledOnce().FindCompleted(pegomock.AnyString())
+}
+
+func TestDedupKey(t *testing.T) {
+	k1 := testDedupKey(t, "rec", []string{"1"}, "a.wav", "olia")
+	assert.Len(t, k1, 64)
+	assert.Equal(t, k1, testDedupKey(t, "rec", []string{"1"}, "b.WAV", "olia"))
+	assert.NotEqual(t, k1, testDedupKey(t, "rec2", []string{"1"}, "a.wav", "olia"))
+	assert.NotEqual(t, k1, testDedupKey(t, "rec", []string{"2"}, "a.wav", "olia"))
+	assert.NotEqual(t, k1, testDedupKey(t, "rec", []string{"1"}, "a.mp3", "olia"))
+	assert.NotEqual(t, k1, testDedupKey(t, "rec", []string{"1"}, "a.wav", "olia2"))
+}
+
+func testDedupKey(t *testing.T, rec string, prms []string, file, content string) string {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", file)
+	part.Write([]byte(content))
+	writer.Close()
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	assert.Nil(t, req.ParseMultipartForm(1<<20))
+	files, fHeaders, err := takeFiles(req, "file")
+	assert.Nil(t, err)
+	res, err := dedupKey(rec, prms, files, fHeaders)
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(files[0])
+	assert.Equal(t, content, string(b), "file must be rewound")
+	return res
+}
@@ -2,9 +2,15 @@ package upload
 
 import (
 	"io"
+	"time"
 )
 
 // FileSaver saves the file with the provided name
 type FileSaver interface {
 	Save(name string, reader io.Reader) error
 }
+
+// AudioDuration provides audio duration for the file
+type AudioDuration interface {
+	Get(name string, reader io.Reader) (time.Duration, error)
+}
@@ -1,11 +1,13 @@
 package upload
 
 import (
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/streadway/amqp"
 
+	"github.com/airenas/listgo/internal/pkg/audio"
 	"github.com/airenas/listgo/internal/pkg/config"
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/metrics"
@@ -14,7 +16,7 @@ import (
 	"github.com/airenas/listgo/internal/pkg/rabbit"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
-	"github.com/airenas/listgo/internal/pkg/saver"
+	"github.com/airenas/listgo/internal/pkg/storage"
 	"github.com/spf13/cobra"
 
 	"github.com/heptiolabs/healthcheck"
@@ -47,10 +49,11 @@ func run(cmd *cobra.Command, args []string) {
 	cmdapp.CheckOrPanic(err, "Can't init metrics")
 
 	data.health = healthcheck.NewHandler()
-	fs, err := saver.NewLocalFileSaver(cmdapp.Config.GetString("fileStorage.path"))
+	st, err := storage.New(cmdapp.Config.GetString("fileStorage.path"))
 	cmdapp.CheckOrPanic(err, "Can't init file storage")
-	data.FileSaver = fs
-	data.health.AddLivenessCheck("fs", fs.HealthyFunc(50))
+	data.FileSaver, err = storage.NewSaver(st)
+	cmdapp.CheckOrPanic(err, "Can't init file saver")
+	data.health.AddLivenessCheck("fs", storage.HealthyFunc(st, 50))
 
 	recProvider, err := config.NewFileRecognizerMap(cmdapp.Config.GetString("recognizerConfig.path"))
 	cmdapp.CheckOrPanic(err, "Can't init recognizer config (Did you provide correct setting 'recognizerConfig.path'?)")
@@ -77,12 +80,48 @@ func run(cmd *cobra.Command, args []string) {
 
 	data.RequestSaver, err = mongo.NewRequestSaver(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "Can't init request saver")
+	if cmdapp.Config.GetBool("dedup.enabled") {
+		data.Dedup, err = initDedup(mongoSessionProvider)
+		cmdapp.CheckOrPanic(err, "Can't init dedup")
+	}
+	data.Tenants = initTenants()
+	if du := cmdapp.Config.GetString("audio.durationUrl"); du != "" {
+		data.AudioDuration, err = audio.NewDurationClient(du)
+		cmdapp.CheckOrPanic(err, "Can't init audio duration client")
+	}
 	data.Port = cmdapp.Config.GetInt("port")
 
 	err = StartWebServer(data)
 	cmdapp.CheckOrPanic(err, "Can't start web server")
 }
 
+func initDedup(sp *mongo.SessionProvider) (*DedupConfig, error) {
+	d, err := mongo.NewDedup(sp)
+	if err != nil {
+		return nil, err
+	}
+	res := &DedupConfig{Dedup: d, APIKeys: map[string]bool{}}
+	for _, k := range cmdapp.Config.GetStringSlice("dedup.apiKeys") {
+		if k = strings.TrimSpace(k); k != "" {
+			res.APIKeys[k] = true
+		}
+	}
+	cmdapp.Log.Infof("Dedup enabled, API keys: %d", len(res.APIKeys))
+	return res, nil
+}
+
+// initTenants reads tenant.apiKeys as tenant name: API key map
+func initTenants() Tenants {
+	res := Tenants{}
+	for n, k := range cmdapp.Config.GetStringMapString("tenant.apiKeys") {
+		if k = strings.TrimSpace(k); k != "" {
+			res[k] = n
+		}
+	}
+	cmdapp.Log.Infof("Configured tenants: %d", len(res))
+	return res
+}
+
 func initQueues(prv *rabbit.ChannelProvider) error {
 	cmdapp.Log.Info("Initializing queues")
 	return prv.RunOnChannelWithRetry(func(ch *amqp.Channel) error {
@@ -2,6 +2,7 @@ package upload
 
 import (
 	"encoding/json"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -44,6 +45,12 @@ type ServiceData struct {
 	RequestSaver       RequestSaver
 	RecognizerMap      RecognizerMap
 	RecognizerProvider RecognizerProvider
+	// AudioDuration is optional, if set the audio duration is saved to the request info
+	AudioDuration AudioDuration
+	// Dedup is optional, if set the completed transcription of the same audio is reused
+	Dedup *DedupConfig
+	// Tenants maps the API keys to the tenant names, not configured keys are passed as their hashes
+	Tenants Tenants
 
 	Port    int
 	health  healthcheck.Handler
@@ -178,8 +185,44 @@ func (h uploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		audioReady = true
 	}
 
+	dur := audioDuration(h.data.AudioDuration, files, fHeaders)
+	// the requests with email are not deduplicated as the notification is sent by the pipeline
+	key := ""
+	if email == "" && h.data.Dedup.enabled(r) {
+		key, err = dedupKey(recID, []string{numberOfSpeakers, skipNumJoin, sepSpOnCh}, files, fHeaders)
+		if err != nil {
+			cmdapp.Log.Warn(errors.Wrap(err, "Can't prepare dedup key"))
+			key = ""
+		}
+	}
+	if key != "" {
+		if src := findDuplicate(h.data.Dedup.Dedup, key); src != nil {
+			cmdapp.Log.Infof("Found completed duplicate %s for %s", src.ID, id)
+			if dur == 0 {
+				dur = time.Duration(src.Duration * float64(time.Second))
+			}
+			err = h.data.Dedup.Dedup.Copy(src.ID, id)
+			if err != nil {
+				http.Error(w, "Can not copy results", http.StatusInternalServerError)
+				cmdapp.Log.Error(err)
+				return
+			}
+			err = h.data.RequestSaver.Save(&persistence.Request{ID: id, File: src.File, ExternalID: externalID,
+				RecognizerKey: recognizer, RecognizerID: recID, FileSize: filesSize(fHeaders),
+				Duration: dur.Seconds(), DedupKey: key, SourceID: src.ID})
+			if err != nil {
+				http.Error(w, "Can not save request to DB", http.StatusInternalServerError)
+				cmdapp.Log.Error(err)
+				return
+			}
+			writeFileResult(w, id)
+			return
+		}
+	}
+
 	err = h.data.RequestSaver.Save(&persistence.Request{ID: id, Email: email, File: fileName, ExternalID: externalID,
-		RecognizerKey: recognizer, RecognizerID: recID})
+		RecognizerKey: recognizer, RecognizerID: recID, FileSize: filesSize(fHeaders),
+		Duration: dur.Seconds(), DedupKey: key})
 	if err != nil {
 		http.Error(w, "Can not save request to DB", http.StatusInternalServerError)
 		cmdapp.Log.Error(err)
@@ -210,6 +253,15 @@ func (h uploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if utils.ParamTrue(sepSpOnCh) {
 		tags = append(tags, messages.NewTag(messages.TagSepSpeakersOnChannel, "1"))
 	}
+	if externalID != "" {
+		tags = append(tags, messages.NewTag(messages.TagExternalID, externalID))
+	}
+	if tn := h.data.Tenants.tenant(r); tn != "" {
+		tags = append(tags, messages.NewTag(messages.TagTenant, tn))
+	}
+	if dur > 0 {
+		tags = append(tags, messages.NewTag(messages.TagAudioDuration, messages.DurationValue(dur)))
+	}
 
 	msg := messages.Decode
 	if len(files) > 1 {
@@ -223,10 +275,14 @@ func (h uploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeFileResult(w, id)
+}
+
+func writeFileResult(w http.ResponseWriter, id string) {
 	result := FileResult{id}
 	w.Header().Set("Content-Type", "application/json")
 	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&result)
+	err := encoder.Encode(&result)
 	if err != nil {
 		http.Error(w, "Can not prepare result", http.StatusInternalServerError)
 		cmdapp.Log.Error(err)
@@ -234,6 +290,30 @@ func (h uploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func filesSize(fHeaders []*multipart.FileHeader) int64 {
+	res := int64(0)
+	for _, h := range fHeaders {
+		res += h.Size
+	}
+	return res
+}
+
+// audioDuration returns 0 if duration is unknown
+func audioDuration(ad AudioDuration, files []multipart.File, fHeaders []*multipart.FileHeader) time.Duration {
+	if ad == nil || len(files) != 1 {
+		return 0
+	}
+	res, err := ad.Get(fHeaders[0].Filename, files[0])
+	if err != nil {
+		cmdapp.Log.Warn(errors.Wrap(err, "Can't get audio duration"))
+		res = 0
+	}
+	if _, err := files[0].Seek(0, io.SeekStart); err != nil {
+		cmdapp.Log.Error(errors.Wrap(err, "Can't rewind file"))
+	}
+	return res
+}
+
 func cleanFiles(f *multipart.Form) {
 	if f != nil {
 		f.RemoveAll()
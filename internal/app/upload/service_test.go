@@ -300,6 +300,44 @@ func TestPOST_RequestSaverCalled(t *testing.T) {
 	assert.Equal(t, "recID", rd.RecognizerID)
 	assert.True(t, strings.HasSuffix(rd.File, ".wav"))
 	assert.NotEmpty(t, rd.ID)
+	assert.Equal(t, int64(4), rd.FileSize)
+	assert.Equal(t, 0.0, rd.Duration)
+}
+
+func TestPOST_RequestSaverDuration(t *testing.T) {
+	initTest(t)
+	req := newReq("filename.wav", "a@a.a", "externalID")
+	resp := httptest.NewRecorder()
+	ad := mocks.NewMockAudioDuration()
+	pegomock.When(ad.Get(pegomock.AnyString(), matchers.AnyIoReader())).ThenReturn(time.Millisecond*1500, nil)
+	data := newTestData()
+	data.AudioDuration = ad
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	rd := requestSaverMock.VerifyWasCalled(pegomock.Once()).Save(matchers.AnyPtrToPersistenceRequest()).GetCapturedArguments()
+	assert.Equal(t, 1.5, rd.Duration)
+	fileSaverMock.VerifyWasCalled(pegomock.Once()).Save(pegomock.AnyString(), matchers.AnyIoReader())
+	msg, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetCapturedArguments()
+	assert.Equal(t, "1.50", getTag(msg.(*messages.QueueMessage).Tags, messages.TagAudioDuration))
+}
+
+func TestPOST_RequestSaverDurationFails(t *testing.T) {
+	initTest(t)
+	req := newReq("filename.wav", "a@a.a", "externalID")
+	resp := httptest.NewRecorder()
+	ad := mocks.NewMockAudioDuration()
+	pegomock.When(ad.Get(pegomock.AnyString(), matchers.AnyIoReader())).ThenReturn(time.Duration(0), errors.New("olia"))
+	data := newTestData()
+	data.AudioDuration = ad
+
+	NewRouter(data).ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	rd := requestSaverMock.VerifyWasCalled(pegomock.Once()).Save(matchers.AnyPtrToPersistenceRequest()).GetCapturedArguments()
+	assert.Equal(t, 0.0, rd.Duration)
 }
 
 func TestPOST_RequestSaverMultiFiles(t *testing.T) {
@@ -317,6 +355,7 @@ func TestPOST_RequestSaverMultiFiles(t *testing.T) {
 	assert.Equal(t, "recID", rd.RecognizerID)
 	assert.True(t, strings.HasSuffix(rd.File, ""))
 	assert.NotEmpty(t, rd.ID)
+	assert.Equal(t, int64(12), rd.FileSize)
 	files, _ := fileSaverMock.VerifyWasCalled(pegomock.Times(3)).Save(pegomock.AnyString(), matchers.AnyIoReader()).
 		GetAllCapturedArguments()
 	if assert.Equal(t, 3, len(files)) {
@@ -367,6 +406,21 @@ func TestPOST_SkipNumJoinPassed(t *testing.T) {
 	assert.Equal(t, "1", getTag(qmsg.Tags, messages.TagSkipNumJoin))
 }
 
+func TestPOST_ExternalIDPassed(t *testing.T) {
+	initTest(t)
+	req := newReqMap([]string{"file.wav"}, map[string]string{"email": "a@a.lt",
+		"recognizer": "rec", api.PrmExternalID: "cl-1"})
+	resp := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(resp, req)
+
+	msg, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+		pegomock.AnyString()).GetCapturedArguments()
+
+	qmsg, ok := msg.(*messages.QueueMessage)
+	assert.True(t, ok)
+	assert.Equal(t, "cl-1", getTag(qmsg.Tags, messages.TagExternalID))
+}
+
 func TestPOST_TimestampAdded(t *testing.T) {
 	initTest(t)
 	req := newReqMap([]string{"file.wav"}, map[string]string{"email": "a@a.lt",
@@ -603,3 +657,25 @@ func Test_sanitizeName(t *testing.T) {
 		})
 	}
 }
+
+func TestPOST_TenantPassed(t *testing.T) {
+	for _, tc := range []struct {
+		key, expected string
+	}{
+		{"k1", "big"},
+		{"k2", "key-015f7e6bc5ae"},
+		{"", ""},
+	} {
+		initTest(t)
+		req := newReqMap([]string{"file.wav"}, map[string]string{"recognizer": "rec"})
+		req.Header.Set(HeaderAPIKey, tc.key)
+		data := newTestData()
+		data.Tenants = Tenants{"k1": "big"}
+		resp := httptest.NewRecorder()
+		NewRouter(data).ServeHTTP(resp, req)
+
+		msg, _, _ := msgSenderMock.VerifyWasCalled(pegomock.Once()).Send(matchers.AnyMessagesMessage(), pegomock.AnyString(),
+			pegomock.AnyString()).GetCapturedArguments()
+		assert.Equal(t, tc.expected, getTag(msg.(*messages.QueueMessage).Tags, messages.TagTenant), tc.key)
+	}
+}
@@ -0,0 +1,25 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Tenants maps the client API keys to the tenant names
+type Tenants map[string]string
+
+// tenant returns the tenant of the request's API key. The key of not configured tenant is hashed,
+// so the raw key is not passed in the messages
+func (t Tenants) tenant(r *http.Request) string {
+	k := strings.TrimSpace(r.Header.Get(HeaderAPIKey))
+	if k == "" {
+		return ""
+	}
+	if n, ok := t[k]; ok {
+		return n
+	}
+	h := sha256.Sum256([]byte(k))
+	return "key-" + hex.EncodeToString(h[:6])
+}
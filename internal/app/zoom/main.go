@@ -3,11 +3,10 @@ package zoom
 import (
 	"github.com/airenas/listgo/internal/app/manager"
 	"github.com/airenas/listgo/internal/pkg/audio"
-	"github.com/airenas/listgo/internal/pkg/loader"
 	"github.com/airenas/listgo/internal/pkg/messages"
 	"github.com/airenas/listgo/internal/pkg/mongo"
 	"github.com/airenas/listgo/internal/pkg/rabbit"
-	"github.com/airenas/listgo/internal/pkg/saver"
+	"github.com/airenas/listgo/internal/pkg/storage"
 	"github.com/airenas/listgo/internal/pkg/utils"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
@@ -74,13 +73,21 @@ func run(cmd *cobra.Command, args []string) {
 	cmdapp.CheckOrPanic(err, "can't init status saver")
 	data.ResultSaver, err = mongo.NewResultSaver(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "can't init result saver")
-	data.FilesGetter, err = loader.NewLocalFileList(cmdapp.Config.GetString("audio.path"))
+	if root := cmdapp.Config.GetString("resultRef.root"); root != "" {
+		rst, err := storage.New(root)
+		cmdapp.CheckOrPanic(err, "can't init result reference storage")
+		data.ResultRefReader, err = manager.NewStorageResultRefReader(rst)
+		cmdapp.CheckOrPanic(err, "can't init result reference reader")
+	}
+	st, err := storage.New(cmdapp.Config.GetString("audio.path"))
+	cmdapp.CheckOrPanic(err, "can't init file storage")
+	data.FilesGetter, err = storage.NewAudioList(st)
 	cmdapp.CheckOrPanic(err, "can't init files loader")
-	data.Loader, err = loader.NewLocalFileLoader(cmdapp.Config.GetString("audio.path"))
+	data.Loader, err = storage.NewLoader(st)
 	cmdapp.CheckOrPanic(err, "can't init file loader")
 	data.AudioLen, err = audio.NewDurationClient(cmdapp.Config.GetString("audio.durationUrl"))
 	cmdapp.CheckOrPanic(err, "can't init file loader")
-	data.FileSaver, err = saver.NewLocalFileSaver(cmdapp.Config.GetString("audio.path"))
+	data.FileSaver, err = storage.NewSaver(st)
 	cmdapp.CheckOrPanic(err, "can't init file storage")
 	data.RequestSaver, err = mongo.NewRequestSaver(mongoSessionProvider)
 	cmdapp.CheckOrPanic(err, "can't init request saver")
@@ -51,6 +51,7 @@ type (
 		StatusSaver         status.Saver
 		StatusProvider      StatusProvider
 		ResultSaver         manager.ResultSaver
+		ResultRefReader     manager.ResultRefReader
 		FilesGetter         FilesGetter
 		Loader              result.FileLoader
 		AudioLen            AudioDuration
@@ -152,7 +153,7 @@ func decode(d *amqp.Delivery, data *ServiceData) (bool, error) {
 	if err != nil {
 		return true, err
 	}
-	ok, err := validateLen(data, files)
+	ok, durs, err := validateLen(data, files)
 	if err != nil {
 		return true, err
 	}
@@ -185,7 +186,7 @@ func decode(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		}
 	}
 
-	ids, fNames, err := startTranscriptions(data, files, &message)
+	ids, fNames, err := startTranscriptions(data, files, durs, &message)
 	if err != nil {
 		if d.Redelivered {
 			if err := data.StatusSaver.SaveError(message.ID, "Can't start transcription. "+err.Error()); err != nil {
@@ -204,28 +205,30 @@ func decode(d *amqp.Delivery, data *ServiceData) (bool, error) {
 	return false, nil
 }
 
-// validateLen returns false if file len differs
-func validateLen(data *ServiceData, files []string) (bool, error) {
+// validateLen returns false if file len differs, returns files durations
+func validateLen(data *ServiceData, files []string) (bool, []time.Duration, error) {
 	var len time.Duration
+	res := make([]time.Duration, 0)
 	for i, f := range files {
 		bData, err := data.Loader.Load(f)
 		if err != nil {
-			return false, err
+			return false, nil, err
 		}
 		defer bData.Close()
 		fl, err := data.AudioLen.Get(f, bData)
 		if err != nil {
-			return false, err
+			return false, nil, err
 		}
 		if i == 0 {
 			len = fl
 		}
 		if !cmpDur(len, fl) {
 			cmdapp.Log.Infof("File len differs %s vs %s", len.String(), fl.String())
-			return false, nil
+			return false, nil, nil
 		}
+		res = append(res, fl)
 	}
-	return true, nil
+	return true, res, nil
 }
 
 func cmpDur(d1, d2 time.Duration) bool {
@@ -236,11 +239,12 @@ func cmpDur(d1, d2 time.Duration) bool {
 	return diff < time.Second
 }
 
-func startTranscriptions(data *ServiceData, files []string, message *messages.QueueMessage) ([]string, []string, error) {
+func startTranscriptions(data *ServiceData, files []string, durs []time.Duration,
+	message *messages.QueueMessage) ([]string, []string, error) {
 	res := make([]string, 0)
 	resF := make([]string, 0)
-	for _, f := range files {
-		id, err := startTranscription(data, f, message)
+	for i, f := range files {
+		id, err := startTranscription(data, f, durs[i], message)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -250,7 +254,7 @@ func startTranscriptions(data *ServiceData, files []string, message *messages.Qu
 	return res, resF, nil
 }
 
-func startTranscription(data *ServiceData, file string, message *messages.QueueMessage) (string, error) {
+func startTranscription(data *ServiceData, file string, dur time.Duration, message *messages.QueueMessage) (string, error) {
 	bData, err := data.Loader.Load(file)
 	if err != nil {
 		return "", err
@@ -261,7 +265,8 @@ func startTranscription(data *ServiceData, file string, message *messages.QueueM
 	ext := filepath.Ext(file)
 	fileName := id + ext
 
-	err = data.RequestSaver.Save(&persistence.Request{ID: id, File: fileName, RecognizerID: message.Recognizer})
+	err = data.RequestSaver.Save(&persistence.Request{ID: id, File: fileName, RecognizerID: message.Recognizer,
+		Duration: dur.Seconds()})
 	if err != nil {
 		return "", errors.Wrapf(err, "can't save request")
 	}
@@ -280,7 +285,7 @@ func startTranscription(data *ServiceData, file string, message *messages.QueueM
 	tags := make([]messages.Tag, 0)
 	for _, t := range message.Tags {
 		if t.Key == messages.TagNumberOfSpeakers || t.Key == messages.TagTimestamp ||
-			t.Key == messages.TagSepSpeakersOnChannel {
+			t.Key == messages.TagSepSpeakersOnChannel || t.Key == messages.TagAudioDuration {
 			continue
 		}
 		tags = append(tags, t)
@@ -291,6 +296,9 @@ func startTranscription(data *ServiceData, file string, message *messages.QueueM
 		messages.NewTag(messages.TagStatusQueue, messages.OneStatus),
 		messages.NewTag(messages.TagResultQueue, messages.OneCompleted),
 	)
+	if dur > 0 {
+		tags = append(tags, messages.NewTag(messages.TagAudioDuration, messages.DurationValue(dur)))
+	}
 
 	return id, data.MessageSender.Send(messages.NewQueueMessage(id, message.Recognizer, tags), messages.Decode, "")
 }
@@ -537,7 +545,7 @@ func joinResultsFinish(d *amqp.Delivery, data *ServiceData) (bool, error) {
 		return false, errors.Wrap(err, "Can't unmarshal message "+string(d.Body))
 	}
 	if message.Error == "" {
-		err := data.ResultSaver.Save(message.ID, message.Result)
+		err := manager.SaveResult(data.ResultSaver, data.ResultRefReader, &message)
 		if err != nil {
 			cmdapp.Log.Error(err)
 			return true, err
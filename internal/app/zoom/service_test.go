@@ -196,6 +196,12 @@ func TestHandlesMessagesDecodeMsg(t *testing.T) {
 	getterMock.VerifyWasCalled(pegomock.Once()).List(pegomock.AnyString())
 	loaderMock.VerifyWasCalled(pegomock.Times(4)).Load(pegomock.AnyString())
 	verifySendMessage(t, messages.Decode, 2)
+	rs := requestSaverMock.VerifyWasCalled(pegomock.Times(2)).Save(matchers.AnyPtrToPersistenceRequest()).
+		GetAllCapturedArguments()
+	if assert.Equal(t, 2, len(rs)) {
+		assert.Equal(t, 1.0, rs[0].Duration)
+		assert.Equal(t, 1.0, rs[1].Duration)
+	}
 }
 
 func TestHandlesMessagesDecodeMsg_SkipJoinAudion(t *testing.T) {
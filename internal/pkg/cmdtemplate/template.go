@@ -0,0 +1,271 @@
+package cmdtemplate
+
+import (
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// Params keeps values for the template placeholders
+type Params struct {
+	ID       string
+	Tags     []messages.Tag
+	Settings map[string]string
+}
+
+const (
+	kindLiteral = iota
+	kindID
+	kindParentID
+	kindTag
+	kindSetting
+)
+
+type part struct {
+	kind   int
+	value  string // literal value or key
+	def    string
+	hasDef bool
+}
+
+// Template is a parsed command or path template.
+//
+// Supported placeholders:
+//
+//	{ID} - message ID
+//	{PARENT_ID} - parent ID tag value or message ID if there is no parent
+//	{TAG:key} - message tag value
+//	{SETTING:key} - recognizer setting value
+//
+// A placeholder may have a default value used when the tag or setting is missing: {TAG:key|default}.
+// Use {{ and }} for literal braces.
+// Command templates are split into arguments with shell style quoting: '...', "..." and \ escapes.
+// Placeholder values are never split, so paths with spaces are passed as one argument.
+type Template struct {
+	words [][]part
+}
+
+// ParseCommand parses the command template into arguments
+func ParseCommand(str string) (*Template, error) {
+	res, err := parse(str, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Wrong command template '%s'", str)
+	}
+	if len(res.words) == 0 {
+		return nil, errors.Errorf("Empty command template '%s'", str)
+	}
+	return res, nil
+}
+
+// ParsePath parses the template of a single value (a file path), no quoting or splitting is applied
+func ParsePath(str string) (*Template, error) {
+	res, err := parse(str, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Wrong path template '%s'", str)
+	}
+	return res, nil
+}
+
+// Args returns the command arguments with the substituted placeholders
+func (t *Template) Args(p *Params) ([]string, error) {
+	res := make([]string, len(t.words))
+	for i, w := range t.words {
+		s, err := expand(w, p)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+// String returns the value with the substituted placeholders, command arguments are joined by space
+func (t *Template) String(p *Params) (string, error) {
+	args, err := t.Args(p)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(args, " "), nil
+}
+
+func expand(parts []part, p *Params) (string, error) {
+	var b strings.Builder
+	for _, pt := range parts {
+		v, err := value(pt, p)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+func value(pt part, p *Params) (string, error) {
+	switch pt.kind {
+	case kindLiteral:
+		return pt.value, nil
+	case kindID:
+		return p.ID, nil
+	case kindParentID:
+		if v, ok := messages.GetTag(p.Tags, messages.TagParentID); ok && v != "" {
+			return v, nil
+		}
+		return p.ID, nil
+	case kindTag:
+		if v, ok := messages.GetTag(p.Tags, pt.value); ok {
+			return v, nil
+		}
+		if pt.hasDef {
+			return pt.def, nil
+		}
+		return "", errors.Errorf("No tag '%s'", pt.value)
+	case kindSetting:
+		if v, ok := p.Settings[pt.value]; ok {
+			return v, nil
+		}
+		if pt.hasDef {
+			return pt.def, nil
+		}
+		return "", errors.Errorf("No recognizer setting '%s'", pt.value)
+	}
+	return "", errors.Errorf("Unknown placeholder type %d", pt.kind)
+}
+
+type parser struct {
+	words   [][]part
+	word    []part
+	lit     strings.Builder
+	started bool
+}
+
+func (p *parser) addRune(r rune) {
+	p.lit.WriteRune(r)
+	p.started = true
+}
+
+func (p *parser) addPart(pt part) {
+	p.flushLit()
+	p.word = append(p.word, pt)
+	p.started = true
+}
+
+func (p *parser) flushLit() {
+	if p.lit.Len() > 0 {
+		p.word = append(p.word, part{kind: kindLiteral, value: p.lit.String()})
+		p.lit.Reset()
+	}
+}
+
+func (p *parser) endWord() {
+	p.flushLit()
+	if p.started {
+		if len(p.word) == 0 {
+			p.word = []part{{kind: kindLiteral}}
+		}
+		p.words = append(p.words, p.word)
+	}
+	p.word = nil
+	p.started = false
+}
+
+func parse(str string, split bool) (*Template, error) {
+	p := &parser{}
+	rs := []rune(str)
+	var quote rune
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r == '{':
+			if i+1 < len(rs) && rs[i+1] == '{' {
+				p.addRune('{')
+				i++
+				continue
+			}
+			e := indexRune(rs, '}', i+1)
+			if e == -1 {
+				return nil, errors.Errorf("No closing '}' at %d", i)
+			}
+			pt, err := parsePlaceholder(string(rs[i+1 : e]))
+			if err != nil {
+				return nil, err
+			}
+			p.addPart(pt)
+			i = e
+		case r == '}' && i+1 < len(rs) && rs[i+1] == '}':
+			p.addRune('}')
+			i++
+		case !split:
+			p.addRune(r)
+		case quote == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			p.endWord()
+		case quote == 0 && (r == '\'' || r == '"'):
+			quote = r
+			p.started = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case r == '\\' && quote == 0:
+			if i+1 < len(rs) {
+				i++
+				p.addRune(rs[i])
+			}
+		case r == '\\' && quote == '"' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\'):
+			i++
+			p.addRune(rs[i])
+		default:
+			p.addRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("No closing quote %c", quote)
+	}
+	if split {
+		p.endWord()
+	} else {
+		p.flushLit()
+		p.words = [][]part{p.word}
+	}
+	return &Template{words: p.words}, nil
+}
+
+func parsePlaceholder(s string) (part, error) {
+	res := part{}
+	if i := strings.Index(s, "|"); i > -1 {
+		res.def = s[i+1:]
+		res.hasDef = true
+		s = s[:i]
+	}
+	k, key := s, ""
+	if i := strings.Index(s, ":"); i > -1 {
+		k, key = s[:i], strings.TrimSpace(s[i+1:])
+	}
+	switch strings.TrimSpace(k) {
+	case "ID":
+		res.kind = kindID
+	case "PARENT_ID":
+		res.kind = kindParentID
+	case "TAG":
+		res.kind = kindTag
+	case "SETTING":
+		res.kind = kindSetting
+	default:
+		return res, errors.Errorf("Unknown placeholder '{%s}'", s)
+	}
+	if (res.kind == kindTag || res.kind == kindSetting) && key == "" {
+		return res, errors.Errorf("No key in placeholder '{%s}'", s)
+	}
+	if (res.kind == kindID || res.kind == kindParentID) && (key != "" || res.hasDef) {
+		return res, errors.Errorf("Unexpected key or default in placeholder '{%s}'", s)
+	}
+	res.value = key
+	return res, nil
+}
+
+func indexRune(rs []rune, r rune, from int) int {
+	for i := from; i < len(rs); i++ {
+		if rs[i] == r {
+			return i
+		}
+	}
+	return -1
+}
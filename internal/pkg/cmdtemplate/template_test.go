@@ -0,0 +1,91 @@
+package cmdtemplate
+
+import (
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+var testParams = &Params{ID: "id1",
+	Tags:     []messages.Tag{messages.NewTag(messages.TagNumberOfSpeakers, "2"), messages.NewTag("path", "a b")},
+	Settings: map[string]string{"model": "m 1"}}
+
+func args(t *testing.T, s string, p *Params) []string {
+	t.Helper()
+	tmpl, err := ParseCommand(s)
+	assert.Nil(t, err)
+	res, err := tmpl.Args(p)
+	assert.Nil(t, err)
+	return res
+}
+
+func TestArgs(t *testing.T) {
+	assert.Equal(t, []string{"ls", "-la"}, args(t, "ls -la", testParams))
+	assert.Equal(t, []string{"ls", "-la"}, args(t, "  ls \t  -la  ", testParams))
+	assert.Equal(t, []string{"run.sh", "id1"}, args(t, "run.sh {ID}", testParams))
+	assert.Equal(t, []string{"run.sh", "/d/id1.txt"}, args(t, "run.sh /d/{ID}.txt", testParams))
+}
+
+func TestArgs_Quotes(t *testing.T) {
+	assert.Equal(t, []string{"sh", "-c", "echo a  b"}, args(t, "sh -c 'echo a  b'", testParams))
+	assert.Equal(t, []string{"sh", "-c", "echo a  b"}, args(t, `sh -c "echo a  b"`, testParams))
+	assert.Equal(t, []string{"a b", "c"}, args(t, `a\ b c`, testParams))
+	assert.Equal(t, []string{`a"b`, `c\d`, `e\f`}, args(t, `"a\"b" 'c\d' "e\\f"`, testParams))
+	assert.Equal(t, []string{"it's"}, args(t, `"it's"`, testParams))
+	assert.Equal(t, []string{"a", "", "b"}, args(t, `a '' b`, testParams))
+	assert.Equal(t, []string{"ab"}, args(t, `a'b'`, testParams))
+}
+
+func TestArgs_Placeholders(t *testing.T) {
+	assert.Equal(t, []string{"run", "2", "a b", "m 1"},
+		args(t, "run {TAG:number_of_speakers} {TAG:path} {SETTING:model}", testParams))
+	assert.Equal(t, []string{"run", "--p=a b"}, args(t, "run --p={TAG:path}", testParams))
+	assert.Equal(t, []string{"run", "x", "y"}, args(t, "run {TAG:olia|x} {SETTING:olia|y}", testParams))
+	assert.Equal(t, []string{"run", ""}, args(t, "run {TAG:olia|}", testParams))
+	assert.Equal(t, []string{"run", "{ID}", "}"}, args(t, "run {{ID}} }}", testParams))
+}
+
+func TestArgs_ParentID(t *testing.T) {
+	assert.Equal(t, []string{"run", "id1"}, args(t, "run {PARENT_ID}", testParams))
+	p := &Params{ID: "id1", Tags: []messages.Tag{messages.NewTag(messages.TagParentID, "pid")}}
+	assert.Equal(t, []string{"run", "pid"}, args(t, "run {PARENT_ID}", p))
+}
+
+func TestArgs_Missing(t *testing.T) {
+	tmpl, _ := ParseCommand("run {TAG:olia}")
+	_, err := tmpl.Args(testParams)
+	assert.NotNil(t, err)
+	tmpl, _ = ParseCommand("run {SETTING:olia}")
+	_, err = tmpl.Args(testParams)
+	assert.NotNil(t, err)
+}
+
+func TestParseCommand_Fail(t *testing.T) {
+	for _, s := range []string{"", "  ", "run 'aaa", `run "aaa`, "run {ID", "run {OLIA}", "run {TAG}",
+		"run {TAG:}", "run {ID:x}", "run {ID|x}", "run {SETTING: }"} {
+		_, err := ParseCommand(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tmpl, err := ParsePath("/data/{PARENT_ID}/a b/'{ID}'.txt")
+	assert.Nil(t, err)
+	s, err := tmpl.String(testParams)
+	assert.Nil(t, err)
+	assert.Equal(t, "/data/id1/a b/'id1'.txt", s)
+}
+
+func TestPath_Empty(t *testing.T) {
+	tmpl, err := ParsePath("")
+	assert.Nil(t, err)
+	s, err := tmpl.String(testParams)
+	assert.Nil(t, err)
+	assert.Equal(t, "", s)
+}
+
+func TestPath_Fail(t *testing.T) {
+	_, err := ParsePath("/data/{ID")
+	assert.NotNil(t, err)
+}
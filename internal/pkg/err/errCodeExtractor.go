@@ -8,7 +8,9 @@ const (
 	// DefaultCode is a default service error code
 	DefaultCode string = "SERVICE_ERROR"
 	// NotFoundCode is used for response when transcription ID is nof found
-	NotFoundCode   string = "NOT_FOUND"
+	NotFoundCode string = "NOT_FOUND"
+	// TimeoutCode is used when the task did not finish in time
+	TimeoutCode    string = "TIMEOUT"
 	errorCodeStart string = "[[[ErrorCode:"
 	errorCodeEnd   string = "]]]"
 )
@@ -32,3 +34,8 @@ func (ece CodeExtractor) Get(err string) string {
 	}
 	return DefaultCode
 }
+
+//Mark returns error code mark to be added to the error message
+func Mark(code string) string {
+	return errorCodeStart + code + errorCodeEnd
+}
@@ -26,3 +26,7 @@ func TestExtract(t *testing.T) {
 func TestTrims(t *testing.T) {
 	assert.Equal(t, "errorCode", ece.Get(errorCodeStart+"  errorCode \n\t"+errorCodeEnd))
 }
+
+func TestMark(t *testing.T) {
+	assert.Equal(t, TimeoutCode, ece.Get("error\n"+Mark(TimeoutCode)))
+}
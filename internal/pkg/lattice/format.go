@@ -0,0 +1,125 @@
+package lattice
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxCueDuration is the max duration of the subtitle cue
+	MaxCueDuration = 7 * time.Second
+	// MaxCueWords is the max word count of the subtitle cue
+	MaxCueWords = 12
+)
+
+// Cue is a subtitle size piece of the part
+type Cue struct {
+	From, To time.Duration
+	Speaker  string
+	Words    []*Word
+}
+
+// Text returns the cue words with the punctuation
+func (c *Cue) Text() string {
+	strs := make([]string, len(c.Words))
+	for i, w := range c.Words {
+		strs[i] = w.Text()
+	}
+	return strings.Join(strs, " ")
+}
+
+// MakeCues splits the parts into the subtitle size pieces
+func MakeCues(parts []*Part, maxDur time.Duration, maxWords int) []*Cue {
+	res := make([]*Cue, 0)
+	for _, p := range parts {
+		var c *Cue
+		for _, w := range p.MainWords() {
+			if c != nil && (len(c.Words) >= maxWords || w.To-c.From > maxDur) {
+				c = nil
+			}
+			if c == nil {
+				c = &Cue{From: w.From, Speaker: p.Speaker}
+				res = append(res, c)
+			}
+			c.Words = append(c.Words, w)
+			c.To = w.To
+		}
+	}
+	return res
+}
+
+// Line is a transcript paragraph of the part
+type Line struct {
+	Speaker string
+	At      time.Duration
+	Text    string
+	Words   []*Word
+}
+
+// Lines makes one paragraph per part, the parts without words are skipped
+func Lines(parts []*Part) []Line {
+	res := make([]Line, 0)
+	for _, p := range parts {
+		mw := p.MainWords()
+		if len(mw) == 0 {
+			continue
+		}
+		c := Cue{Words: mw}
+		res = append(res, Line{Speaker: p.Speaker, At: mw[0].From, Text: c.Text(), Words: mw})
+	}
+	return res
+}
+
+// WriteTxt writes one line per part without the punctuation
+func WriteTxt(parts []*Part, w io.Writer) error {
+	for _, l := range Lines(parts) {
+		strs := make([]string, len(l.Words))
+		for i, lw := range l.Words {
+			strs[i] = lw.Word
+		}
+		if _, err := io.WriteString(w, strings.Join(strs, " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTxtFinal writes one line per part
+func WriteTxtFinal(parts []*Part, w io.Writer) error {
+	for _, l := range Lines(parts) {
+		if _, err := io.WriteString(w, l.Text+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteVTT writes WebVTT with the speakers as voice spans
+func WriteVTT(parts []*Part, w io.Writer) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, c := range MakeCues(parts, MaxCueDuration, MaxCueWords) {
+		text := vttEscape(c.Text())
+		if c.Speaker != "" {
+			text = "<v " + vttEscape(c.Speaker) + ">" + text
+		}
+		if _, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n", vttTime(c.From), vttTime(c.To), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func vttTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+var vttReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func vttEscape(s string) string {
+	return vttReplacer.Replace(s)
+}
@@ -0,0 +1,70 @@
+package lattice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testParts(t *testing.T) []*Part {
+	t.Helper()
+	res, err := Read(strings.NewReader("# 1 S0000\n1 0 0.5 <eps>\n1 0.5 1.2 labas ,\n1 1.2 2 vakaras .\n" +
+		"# 2 S0001\n1 3 3.5 ačiū\n# 3 S0001\n1 4 5 <eps>\n"))
+	assert.Nil(t, err)
+	return res
+}
+
+func TestMakeCues_Splits(t *testing.T) {
+	p := &Part{Words: []*Word{{Main: true, From: 0, To: time.Second, Word: "a"},
+		{Main: true, From: time.Second, To: 2 * time.Second, Word: "b"},
+		{Main: true, From: 2 * time.Second, To: 9 * time.Second, Word: "c"}}}
+
+	res := MakeCues([]*Part{p}, 7*time.Second, 12)
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, "a b", res[0].Text())
+
+	res = MakeCues([]*Part{p}, time.Minute, 1)
+	assert.Equal(t, 3, len(res))
+}
+
+func TestLines(t *testing.T) {
+	res := Lines(testParts(t))
+
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, "S0000", res[0].Speaker)
+	assert.Equal(t, 500*time.Millisecond, res[0].At)
+	assert.Equal(t, "labas, vakaras.", res[0].Text)
+	assert.Equal(t, 2, len(res[0].Words))
+}
+
+func TestWriteTxt(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, WriteTxt(testParts(t), &b))
+
+	assert.Equal(t, "labas vakaras\načiū\n", b.String())
+}
+
+func TestWriteTxtFinal(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, WriteTxtFinal(testParts(t), &b))
+
+	assert.Equal(t, "labas, vakaras.\načiū\n", b.String())
+}
+
+func TestWriteVTT(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.Nil(t, WriteVTT([]*Part{{Speaker: "<S>", Words: []*Word{{Main: true, From: 500 * time.Millisecond,
+		To: 3723 * time.Second, Word: "a&b"}}}}, &b))
+
+	assert.Equal(t, "WEBVTT\n\n00:00:00.500 --> 01:02:03.000\n<v &lt;S&gt;>a&amp;b\n\n", b.String())
+}
+
+func TestVTTEscape(t *testing.T) {
+	assert.Equal(t, "a &lt;b&gt; &amp;", vttEscape("a <b> &"))
+}
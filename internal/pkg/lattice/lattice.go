@@ -0,0 +1,141 @@
+package lattice
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MainInd indicates the best path word line
+	MainInd = "1"
+	// EpsWord is a silence word
+	EpsWord = "<eps>"
+)
+
+// Part is a speaker segment of the lattice
+type Part struct {
+	Num     int
+	Speaker string
+	Words   []*Word
+}
+
+// Word is a lattice word line
+type Word struct {
+	Main  bool
+	From  time.Duration
+	To    time.Duration
+	Word  string
+	Punct string
+	// Confidence is in (0, 1], 0 - unknown
+	Confidence float64
+}
+
+// Read parses the restored lattice. The format is:
+//
+//	# <part num> <speaker>
+//	<main ind> <from sec> <to sec> <word> [<punctuation>] [<confidence>]
+//
+// Empty lines are ignored
+func Read(r io.Reader) ([]*Part, error) {
+	res := make([]*Part, 0)
+	var part *Part
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	ln := 0
+	for scanner.Scan() {
+		ln++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			p, err := readPart(line)
+			if err != nil {
+				return nil, errors.Wrapf(err, "wrong line %d", ln)
+			}
+			part = p
+			res = append(res, part)
+			continue
+		}
+		if part == nil {
+			return nil, errors.Errorf("no part header before line %d", ln)
+		}
+		w, err := readWord(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "wrong line %d", ln)
+		}
+		part.Words = append(part.Words, w)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "can't read lattice")
+	}
+	return res, nil
+}
+
+func readPart(line string) (*Part, error) {
+	strs := strings.Fields(strings.TrimPrefix(line, "#"))
+	if len(strs) < 1 {
+		return nil, errors.New("no part number")
+	}
+	n, err := strconv.Atoi(strs[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "wrong part number '%s'", strs[0])
+	}
+	res := &Part{Num: n}
+	if len(strs) > 1 {
+		res.Speaker = strs[1]
+	}
+	return res, nil
+}
+
+func readWord(line string) (*Word, error) {
+	strs := strings.Fields(line)
+	if len(strs) < 4 {
+		return nil, errors.Errorf("expected at least 4 fields, got %d", len(strs))
+	}
+	res := &Word{Main: strs[0] == MainInd, Word: strs[3]}
+	var err error
+	if res.From, err = toDuration(strs[1]); err != nil {
+		return nil, err
+	}
+	if res.To, err = toDuration(strs[2]); err != nil {
+		return nil, err
+	}
+	for _, s := range strs[4:] {
+		if c, err := strconv.ParseFloat(s, 64); err == nil {
+			res.Confidence = c
+		} else {
+			res.Punct = s
+		}
+	}
+	return res, nil
+}
+
+func toDuration(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "wrong time '%s'", s)
+	}
+	return time.Duration(f * float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// MainWords returns the best path words without silences
+func (p *Part) MainWords() []*Word {
+	res := make([]*Word, 0, len(p.Words))
+	for _, w := range p.Words {
+		if w.Main && w.Word != EpsWord {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// Text returns the word with the punctuation
+func (w *Word) Text() string {
+	return w.Word + w.Punct
+}
@@ -0,0 +1,42 @@
+package lattice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	lat := "# 1 S0000\n1 0 0.5 <eps>\n1 0.50 1.2 labas , 0.93\n0 0.5 1.2 lapas\n\n# 2 S0001\n1 1.5 2 vakaras .\n"
+
+	res, err := Read(strings.NewReader(lat))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, 1, res[0].Num)
+	assert.Equal(t, "S0000", res[0].Speaker)
+	assert.Equal(t, 3, len(res[0].Words))
+	assert.Equal(t, &Word{Main: true, From: 500 * time.Millisecond, To: 1200 * time.Millisecond, Word: "labas",
+		Punct: ",", Confidence: 0.93}, res[0].Words[1])
+	assert.False(t, res[0].Words[2].Main)
+	assert.Equal(t, "S0001", res[1].Speaker)
+	assert.Equal(t, "vakaras.", res[1].Words[0].Text())
+}
+
+func TestRead_Fails(t *testing.T) {
+	for _, s := range []string{"1 0 1 olia", "# a", "# 1\n1 0 1", "# 1\n1 a 1 olia", "# 1\n1 0 a olia"} {
+		_, err := Read(strings.NewReader(s))
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestMainWords(t *testing.T) {
+	p := &Part{Words: []*Word{{Main: true, Word: EpsWord}, {Main: true, Word: "a"}, {Word: "b"}}}
+
+	res := p.MainWords()
+
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "a", res[0].Word)
+}
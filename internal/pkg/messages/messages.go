@@ -1,6 +1,7 @@
 package messages
 
 import (
+	"strconv"
 	"time"
 )
 
@@ -29,6 +30,22 @@ const (
 	TagChildIDSFileNames = "ch_ids_fn"
 	//TagSepSpeakersOnChannel indicates separate speakers on separate audio channels
 	TagSepSpeakersOnChannel = "sep_speakers_on_channel"
+	//TagExternalID is the client's external ID of the transcription
+	TagExternalID = "external_id"
+	//TagTenant is the client's tenant resolved from the API key
+	TagTenant = "tenant"
+	//TagAudioDuration is the audio duration in seconds
+	TagAudioDuration = "audio_duration"
+	//TagChunkIndex is the index of the transcription chunk, starting from 0
+	TagChunkIndex = "chunk_index"
+	//TagChunkCount is the number of chunks the transcription is split into
+	TagChunkCount = "chunk_count"
+	//TagChunkFrom is the start of the chunk in 10ms frames
+	TagChunkFrom = "chunk_from"
+	//TagChunkTo is the end of the chunk in 10ms frames
+	TagChunkTo = "chunk_to"
+	//TagChunkJoin asks the worker to join results of all chunks, the value is the number of chunks
+	TagChunkJoin = "chunk_join"
 )
 
 //QueueMessage message going throuht broker
@@ -43,6 +60,15 @@ type QueueMessage struct {
 type ResultMessage struct {
 	QueueMessage
 	Result string `json:"result,omitempty"`
+	//Ref points to the result in the shared storage, the result is not inlined if set
+	Ref *ResultRef `json:"ref,omitempty"`
+}
+
+//ResultRef points to the result file in the shared storage
+type ResultRef struct {
+	Path   string `json:"path"`   // path relative to the shared result storage root
+	Size   int64  `json:"size"`   // size in bytes
+	SHA256 string `json:"sha256"` // hex encoded checksum of the content
 }
 
 //InformMessage message with inform information
@@ -52,12 +78,23 @@ type InformMessage struct {
 	At   time.Time `json:"at"`
 }
 
+//ProgressMessage message with the intermediate progress of the running step
+type ProgressMessage struct {
+	QueueMessage
+	Step     string `json:"step"`     // status name of the step
+	Progress int32  `json:"progress"` // step progress 0-100
+}
+
 //RegistrationMessage message for registering worker
 type RegistrationMessage struct {
 	Queue     string `json:"queue"`
 	Timestamp int64  `json:"timestamp"` //time.Unix in seconds
 	Working   bool   `json:"working"`
 	Type      string `json:"type"` // see RgrTypeXxx consts
+	//ModelTypes are the preloaded model types of the worker, the most recently used first
+	ModelTypes []string `json:"modelTypes,omitempty"`
+	//Draining is set in all the messages after the drain, so the restarted dispatcher knows the state
+	Draining bool `json:"draining,omitempty"`
 }
 
 const (
@@ -67,6 +104,8 @@ const (
 	RgrTypeExit = "Exit"
 	//RgrTypeBeat alive beat
 	RgrTypeBeat = "Beat"
+	//RgrTypeDrain indicates the worker finishes the current task and exits, no new tasks must be sent
+	RgrTypeDrain = "Drain"
 )
 
 //NewQueueMessageFromM copies message
@@ -89,6 +128,24 @@ func NewTag(key string, value string) Tag {
 	return Tag{Key: key, Value: value}
 }
 
+//DurationValue formats duration as tag value in seconds
+func DurationValue(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 2, 64)
+}
+
+//GetTagDuration retrieves duration from the tag value in seconds, returns 0 if the tag is missing or invalid
+func GetTagDuration(tags []Tag, key string) time.Duration {
+	v, ok := GetTag(tags, key)
+	if !ok {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
 //GetTag retrieves tag value from tag list
 func GetTag(tags []Tag, key string) (string, bool) {
 	for _, t := range tags {
@@ -28,6 +28,8 @@ const (
 	OneCompleted string = "OneCompleted"
 	// OneStatus queue
 	OneStatus string = "OneStatus"
+	// Progress queue for intermediate step progress events
+	Progress string = "Progress"
 )
 
 const (
@@ -1,9 +1,13 @@
 package mongo
 
 import (
+	"context"
+
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 )
 
 // CleanRecord deletes mongo table record
@@ -20,6 +24,8 @@ func NewCleanRecords(sessionProvider *SessionProvider) ([]*CleanRecord, error) {
 	result = append(result, newCleanRecord(sessionProvider, emailTable))
 	result = append(result, newCleanRecord(sessionProvider, requestTable))
 	result = append(result, newCleanRecord(sessionProvider, workTable))
+	result = append(result, newCleanRecord(sessionProvider, speakerTable))
+	result = append(result, newCleanRecord(sessionProvider, versionTable))
 	return result, nil
 }
 
@@ -46,3 +52,48 @@ func (fs *CleanRecord) Clean(ID string) error {
 	cmdapp.Log.Infof("Deleted %d", info.DeletedCount)
 	return nil
 }
+
+// CleanResultFiles deletes the result files saved in GridFS
+type CleanResultFiles struct {
+	SessionProvider *SessionProvider
+}
+
+// NewCleanResultFiles creates CleanResultFiles instance
+func NewCleanResultFiles(sessionProvider *SessionProvider) (*CleanResultFiles, error) {
+	cmdapp.Log.Infof("Init Mongo GridFS Clean for %s", resultBucket)
+	return &CleanResultFiles{SessionProvider: sessionProvider}, nil
+}
+
+// Clean deletes all result files by ID
+func (fs *CleanResultFiles) Clean(ID string) error {
+	cmdapp.Log.Infof("Cleaning result files for %s[ID=%s]", resultBucket, ID)
+
+	session, err := fs.SessionProvider.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "can't init new session")
+	}
+	defer session.EndSession(context.Background())
+	b, err := newResultBucket(session)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := mongoContext()
+	defer cancel()
+	cursor, err := b.Find(bson.M{"filename": sanitize(ID)})
+	if err != nil {
+		return errors.Wrap(err, "can't find result files")
+	}
+	var files []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return errors.Wrap(err, "can't read result files")
+	}
+	for _, f := range files {
+		if err := b.Delete(f.ID); err != nil && err != gridfs.ErrFileNotFound {
+			return errors.Wrapf(err, "can't delete result file %s", f.ID.Hex())
+		}
+	}
+	cmdapp.Log.Infof("Deleted %d", len(files))
+	return nil
+}
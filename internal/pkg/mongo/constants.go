@@ -7,12 +7,27 @@ const (
 	requestTable = "request"
 	workTable    = "work"
 	emailTable   = "emailLock"
+	queueTable   = "queue"
+	speakerTable = "speakers"
+	versionTable = "versions"
+	// resultBucket is GridFS bucket for the results passed by reference
+	resultBucket = "resultFiles"
 )
 
 var indexData = []IndexData{
 	newIndexData(statusTable, "ID", true),
+	newCompoundIndexData(statusTable, "status", "-_id"),
+	newCompoundIndexData(statusTable, "error", "-_id"),
 	newIndexData(resultTable, "ID", true),
 	newIndexData(requestTable, "ID", true),
+	newIndexData(requestTable, "dedupKey", false),
+	newIndexData(requestTable, "sourceID", false),
+	newIndexData(requestTable, "externalID", false),
+	newIndexData(requestTable, "recognizerKey", false),
+	newIndexData(requestTable, "recognizerID", false),
 	newIndexData(emailTable, "ID", false),
 	newIndexData(workTable, "ID", true),
+	newIndexData(queueTable, "ID", true),
+	newIndexData(speakerTable, "ID", true),
+	{Table: versionTable, Fields: []string{"ID", "version"}, Unique: true},
 }
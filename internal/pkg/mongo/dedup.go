@@ -0,0 +1,150 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/airenas/listgo/internal/pkg/status"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dedupCandidates is the max number of the latest requests checked for the completed transcription
+const dedupCandidates = 5
+
+// Dedup finds the completed transcriptions by the audio hash and copies them
+type Dedup struct {
+	SessionProvider *SessionProvider
+}
+
+// NewDedup creates Dedup instance
+func NewDedup(sessionProvider *SessionProvider) (*Dedup, error) {
+	f := Dedup{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// FindCompleted returns the original request of the latest successfully completed transcription by the key,
+// returns nil if there is no such transcription
+func (d *Dedup) FindCompleted(key string) (*persistence.Request, error) {
+	cmdapp.Log.Infof("Looking for dedup key %s", key)
+	session, err := d.SessionProvider.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "can't init new session")
+	}
+	defer session.EndSession(context.Background())
+	ctx, cancel := mongoContext()
+	defer cancel()
+
+	db := session.Client().Database(store)
+	cursor, err := db.Collection(requestTable).Find(ctx, bson.M{"dedupKey": sanitize(key)},
+		options.Find().SetSort(bson.M{"_id": -1}).SetLimit(dedupCandidates))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't find requests")
+	}
+	var reqs []persistence.Request
+	if err := cursor.All(ctx, &reqs); err != nil {
+		return nil, errors.Wrap(err, "can't read requests")
+	}
+	for _, r := range reqs {
+		var st persistence.Status
+		err := db.Collection(statusTable).FindOne(ctx, bson.M{"ID": r.ID}).Decode(&st)
+		if err == mgo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "can't load status")
+		}
+		if st.Status != status.Name(status.Completed) || st.Error != "" {
+			continue
+		}
+		if r.SourceID == "" {
+			return &r, nil
+		}
+		var src persistence.Request
+		err = db.Collection(requestTable).FindOne(ctx, bson.M{"ID": r.SourceID}).Decode(&src)
+		if err == nil {
+			return &src, nil
+		}
+		if err != mgo.ErrNoDocuments {
+			return nil, errors.Wrap(err, "can't load source request")
+		}
+	}
+	return nil, nil
+}
+
+// Copy copies the status and the result of the completed transcription to the new ID
+func (d *Dedup) Copy(srcID, ID string) error {
+	cmdapp.Log.Infof("Copying results %s -> %s", srcID, ID)
+	session, err := d.SessionProvider.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "can't init new session")
+	}
+	defer session.EndSession(context.Background())
+	ctx, cancel := mongoContext()
+	defer cancel()
+
+	db := session.Client().Database(store)
+	var res persistence.Result
+	if err := db.Collection(resultTable).FindOne(ctx, bson.M{"ID": srcID}).Decode(&res); err != nil {
+		return errors.Wrap(err, "can't load result")
+	}
+	upd := bson.M{"text": res.Text}
+	if res.FileID != "" {
+		fileID, err := copyResultFile(session, res.FileID, ID)
+		if err != nil {
+			return err
+		}
+		upd = bson.M{"fileID": fileID}
+	}
+	_, err = db.Collection(resultTable).UpdateOne(ctx, bson.M{"ID": sanitize(ID)}, bson.M{"$set": upd},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.Wrap(err, "can't save result")
+	}
+
+	var st persistence.Status
+	if err := db.Collection(statusTable).FindOne(ctx, bson.M{"ID": srcID}).Decode(&st); err != nil {
+		return errors.Wrap(err, "can't load status")
+	}
+	_, err = db.Collection(statusTable).UpdateOne(ctx, bson.M{"ID": sanitize(ID)},
+		bson.M{"$set": bson.M{"status": st.Status, persistence.StAudioReady: st.AudioReady,
+			persistence.StAvailableResults: st.AvailableResults}},
+		options.Update().SetUpsert(true))
+	return errors.Wrap(err, "can't save status")
+}
+
+// Refs returns the number of the transcriptions reusing the audio and results of the ID
+func (d *Dedup) Refs(ID string) (int64, error) {
+	c, ctx, cancel, err := newColl(d.SessionProvider, requestTable)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	res, err := c.CountDocuments(ctx, bson.M{"sourceID": sanitize(ID)})
+	return res, errors.Wrap(err, "can't count references")
+}
+
+func copyResultFile(session mgo.Session, fileID, ID string) (string, error) {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return "", errors.Wrapf(err, "wrong result file id %s", fileID)
+	}
+	b, err := newResultBucket(session)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if _, err := b.DownloadToStream(id, &buf); err != nil {
+		return "", errors.Wrapf(err, "can't load result file %s", fileID)
+	}
+	res, err := b.UploadFromStream(sanitize(ID), &buf)
+	if err != nil {
+		return "", errors.Wrap(err, "can't upload result")
+	}
+	return res.Hex(), nil
+}
@@ -43,3 +43,22 @@ func (ss *FileNameProvider) Get(id string) (string, error) {
 	}
 	return m.File, nil
 }
+
+// GetSourceID returns the ID of the transcription whose audio and results are reused by the ID
+func (ss *FileNameProvider) GetSourceID(id string) (string, error) {
+	c, ctx, cancel, err := newColl(ss.SessionProvider, requestTable)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	var m persistence.Request
+	err = c.FindOne(ctx, bson.M{"ID": id}).Decode(&m)
+	if err == mgo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "can't get request record")
+	}
+	return m.SourceID, nil
+}
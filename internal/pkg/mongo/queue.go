@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// queueInfoTTL is the max age of the queue estimate, older ones are left by a stopped dispatcher
+const queueInfoTTL = 2 * time.Minute
+
+// QueueSaver saves the dispatcher's queue estimates to mongo db
+type QueueSaver struct {
+	SessionProvider *SessionProvider
+}
+
+// NewQueueSaver creates QueueSaver instance
+func NewQueueSaver(sessionProvider *SessionProvider) (*QueueSaver, error) {
+	f := QueueSaver{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// Save replaces all the queue records with the items
+func (qs *QueueSaver) Save(items []*persistence.QueueInfo) error {
+	cmdapp.Log.Debugf("Saving %d queue estimates", len(items))
+	c, ctx, cancel, err := newColl(qs.SessionProvider, queueTable)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	ids := make([]string, 0, len(items))
+	if len(items) > 0 {
+		models := make([]mgo.WriteModel, 0, len(items))
+		for _, it := range items {
+			ids = append(ids, it.ID)
+			models = append(models, mgo.NewReplaceOneModel().SetFilter(bson.M{"ID": it.ID}).
+				SetReplacement(it).SetUpsert(true))
+		}
+		if _, err := c.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return errors.Wrap(err, "can't save queue estimates")
+		}
+	}
+	_, err = c.DeleteMany(ctx, bson.M{"ID": bson.M{"$nin": ids}})
+	return errors.Wrap(err, "can't delete old queue estimates")
+}
+
+// getQueueInfo returns the fresh queue estimate or nil
+func getQueueInfo(ctx context.Context, session mgo.Session, id string) (*persistence.QueueInfo, error) {
+	c := session.Client().Database(store).Collection(queueTable)
+	var res persistence.QueueInfo
+	err := c.FindOne(ctx, bson.M{"ID": id, "updated": bson.M{"$gt": time.Now().Add(-queueInfoTTL)}}).Decode(&res)
+	if err == mgo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load queue estimate")
+	}
+	return &res, nil
+}
@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+)
+
+// RequestProvider returns request info by transcription ID
+type RequestProvider struct {
+	SessionProvider *SessionProvider
+}
+
+// NewRequestProvider creates RequestProvider instance
+func NewRequestProvider(sessionProvider *SessionProvider) (*RequestProvider, error) {
+	f := RequestProvider{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// Get returns request by ID, returns nil if not found
+func (ss *RequestProvider) Get(id string) (*persistence.Request, error) {
+	cmdapp.Log.Debugf("Getting request by ID %s", id)
+
+	c, ctx, cancel, err := newColl(ss.SessionProvider, requestTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var m persistence.Request
+	err = c.FindOne(ctx, bson.M{"ID": sanitize(id)}).Decode(&m)
+	if err == mgo.ErrNoDocuments {
+		cmdapp.Log.Infof("ID not found %s", id)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't get request record")
+	}
+	return &m, nil
+}
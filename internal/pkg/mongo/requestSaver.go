@@ -28,8 +28,31 @@ func (ss *RequestSaver) Save(data *persistence.Request) error {
 	}
 	defer cancel()
 
+	upd := bson.M{"email": data.Email, "file": data.File,
+		"externalID": data.ExternalID, "recognizerKey": data.RecognizerKey, "recognizerID": data.RecognizerID}
+	if data.Duration > 0 {
+		upd["duration"] = data.Duration
+	}
+	if data.FileSize > 0 {
+		upd["fileSize"] = data.FileSize
+	}
+	if data.DedupKey != "" {
+		upd["dedupKey"] = data.DedupKey
+	}
+	if data.SourceID != "" {
+		upd["sourceID"] = data.SourceID
+	}
 	return skipNoDocErr(c.FindOneAndUpdate(ctx, bson.M{"ID": sanitize(data.ID)},
-		bson.M{"$set": bson.M{"email": data.Email, "file": data.File,
-			"externalID": data.ExternalID, "recognizerKey": data.RecognizerKey, "recognizerID": data.RecognizerID}},
-		options.FindOneAndUpdate().SetUpsert(true)).Err())
+		bson.M{"$set": upd}, options.FindOneAndUpdate().SetUpsert(true)).Err())
+}
+
+// SaveDuration updates the audio duration of the request
+func (ss *RequestSaver) SaveDuration(ID string, duration float64) error {
+	c, ctx, cancel, err := newColl(ss.SessionProvider, requestTable)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return skipNoDocErr(c.FindOneAndUpdate(ctx, bson.M{"ID": sanitize(ID)},
+		bson.M{"$set": bson.M{"duration": duration}}).Err())
 }
@@ -1,8 +1,16 @@
 package mongo
 
 import (
+	"context"
+	"io"
+
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -21,13 +29,73 @@ func NewResultSaver(sessionProvider *SessionProvider) (*ResultSaver, error) {
 func (fs *ResultSaver) Save(ID string, result string) error {
 	cmdapp.Log.Infof("Saving result for %s", ID)
 
+	return fs.update(ID, bson.M{"$set": bson.M{"text": result}, "$unset": bson.M{"fileID": ""}})
+}
+
+// SaveStream saves result to GridFS, the result table keeps the file id
+func (fs *ResultSaver) SaveStream(ID string, reader io.Reader) error {
+	cmdapp.Log.Infof("Saving result stream for %s", ID)
+
+	session, err := fs.SessionProvider.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "can't init new session")
+	}
+	defer session.EndSession(context.Background())
+	b, err := newResultBucket(session)
+	if err != nil {
+		return err
+	}
+	fileID, err := b.UploadFromStream(sanitize(ID), reader)
+	if err != nil {
+		return errors.Wrap(err, "can't upload result")
+	}
+	cmdapp.Log.Infof("Saved result file %s", fileID.Hex())
+	return fs.update(ID, bson.M{"$set": bson.M{"fileID": fileID.Hex()}, "$unset": bson.M{"text": ""}})
+}
+
+// update upserts the result record and drops the previous result file if any
+func (fs *ResultSaver) update(ID string, upd bson.M) error {
 	c, ctx, cancel, err := newColl(fs.SessionProvider, resultTable)
 	if err != nil {
 		return err
 	}
 	defer cancel()
 
-	return skipNoDocErr(c.FindOneAndUpdate(ctx, bson.M{"ID": sanitize(ID)},
-		bson.M{"$set": bson.M{"text": result}},
-		options.FindOneAndUpdate().SetUpsert(true)).Err())
+	var old persistence.Result
+	err = c.FindOneAndUpdate(ctx, bson.M{"ID": sanitize(ID)}, upd,
+		options.FindOneAndUpdate().SetUpsert(true)).Decode(&old)
+	if err = skipNoDocErr(err); err != nil {
+		return err
+	}
+	if old.FileID != "" {
+		cmdapp.LogIf(deleteResultFile(fs.SessionProvider, old.FileID))
+	}
+	return nil
+}
+
+func newResultBucket(session mgo.Session) (*gridfs.Bucket, error) {
+	b, err := gridfs.NewBucket(session.Client().Database(store), options.GridFSBucket().SetName(resultBucket))
+	return b, errors.Wrap(err, "can't init result bucket")
+}
+
+func deleteResultFile(sessionProvider *SessionProvider, fileID string) error {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return errors.Wrapf(err, "wrong result file id %s", fileID)
+	}
+	session, err := sessionProvider.NewSession()
+	if err != nil {
+		return errors.Wrap(err, "can't init new session")
+	}
+	defer session.EndSession(context.Background())
+	b, err := newResultBucket(session)
+	if err != nil {
+		return err
+	}
+	cmdapp.Log.Infof("Deleting result file %s", fileID)
+	err = b.Delete(id)
+	if err == gridfs.ErrFileNotFound {
+		return nil
+	}
+	return errors.Wrapf(err, "can't delete result file %s", fileID)
 }
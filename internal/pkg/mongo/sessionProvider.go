@@ -15,7 +15,7 @@ import (
 	"go.mongodb.org/mongo-driver/x/bsonx"
 )
 
-// IndexData keeps index creation data
+// IndexData keeps index creation data, the field with the '-' prefix is indexed in descending order
 type IndexData struct {
 	Table  string
 	Fields []string
@@ -27,6 +27,10 @@ func newIndexData(table string, field string, unique bool) IndexData {
 	return IndexData{Table: table, Fields: []string{field}, Unique: unique}
 }
 
+func newCompoundIndexData(table string, fields ...string) IndexData {
+	return IndexData{Table: table, Fields: fields}
+}
+
 // SessionProvider connects and provides session for mongo DB
 type SessionProvider struct {
 	client  *mgo.Client
@@ -96,7 +100,11 @@ func checkIndex(s mgo.Session, indexData IndexData) error {
 	c := s.Client().Database(store).Collection(indexData.Table)
 	keys := bsonx.Doc{}
 	for _, f := range indexData.Fields {
-		keys = keys.Append(f, bsonx.Int32(int32(1)))
+		if strings.HasPrefix(f, "-") {
+			keys = keys.Append(f[1:], bsonx.Int32(int32(-1)))
+		} else {
+			keys = keys.Append(f, bsonx.Int32(int32(1)))
+		}
 	}
 	index := mongo.IndexModel{
 		Keys:    keys,
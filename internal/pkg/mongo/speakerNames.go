@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SpeakerNames keeps the user's speaker names of the transcription
+type SpeakerNames struct {
+	SessionProvider *SessionProvider
+}
+
+// NewSpeakerNames creates SpeakerNames instance
+func NewSpeakerNames(sessionProvider *SessionProvider) (*SpeakerNames, error) {
+	f := SpeakerNames{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// Get returns the speaker label to name map, nil if no names are set
+func (sn *SpeakerNames) Get(id string) (map[string]string, error) {
+	c, ctx, cancel, err := newColl(sn.SessionProvider, speakerTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var m persistence.SpeakerNames
+	err = c.FindOne(ctx, bson.M{"ID": sanitize(id)}).Decode(&m)
+	if err == mgo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't get speaker names")
+	}
+	return m.Names, nil
+}
+
+// Save replaces the speaker names of the transcription
+func (sn *SpeakerNames) Save(id string, names map[string]string) error {
+	cmdapp.Log.Infof("Saving %d speaker names for %s", len(names), id)
+	c, ctx, cancel, err := newColl(sn.SessionProvider, speakerTable)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	id = sanitize(id)
+	_, err = c.ReplaceOne(ctx, bson.M{"ID": id},
+		&persistence.SpeakerNames{ID: id, Names: names, Updated: time.Now()}, options.Replace().SetUpsert(true))
+	return errors.Wrap(err, "can't save speaker names")
+}
@@ -0,0 +1,202 @@
+package mongo
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StatusLister selects jobs by status and request fields
+type StatusLister struct {
+	SessionProvider *SessionProvider
+}
+
+// NewStatusLister creates StatusLister instance
+func NewStatusLister(sessionProvider *SessionProvider) (*StatusLister, error) {
+	f := StatusLister{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// listRecord is the common shape of the list pipeline output
+type listRecord struct {
+	Cursor        primitive.ObjectID `bson:"cursor"`
+	RequestOID    primitive.ObjectID `bson:"requestOID,omitempty"`
+	ID            string             `bson:"ID"`
+	Status        string             `bson:"status,omitempty"`
+	Error         string             `bson:"error,omitempty"`
+	ErrorCode     string             `bson:"errorCode,omitempty"`
+	RecognizerKey string             `bson:"recognizerKey,omitempty"`
+	RecognizerID  string             `bson:"recognizerID,omitempty"`
+	ExternalID    string             `bson:"externalID,omitempty"`
+}
+
+// List returns one page of the jobs matching the filter
+func (l *StatusLister) List(filter *api.ListFilter) (*api.List, error) {
+	cmdapp.Log.Infof("Listing status %+v", *filter)
+	table, pipeline, err := listPipeline(filter)
+	if err != nil {
+		return nil, err
+	}
+	c, ctx, cancel, err := newColl(l.SessionProvider, table)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	cursor, err := c.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't list "+table)
+	}
+	defer cursor.Close(context.Background())
+	var recs []listRecord
+	if err := cursor.All(ctx, &recs); err != nil {
+		return nil, errors.Wrap(err, "can't read list")
+	}
+	return toList(recs, filter.Limit), nil
+}
+
+// listPipeline selects the collection to drive the query: the status collection if only a state is
+// requested, otherwise the request collection, as its filters are indexed and selective. The status is
+// joined after the limit unless it is filtered by the state.
+// The page is sorted by _id of the driving collection, the cursor is the last _id of the page
+func listPipeline(filter *api.ListFilter) (string, []bson.M, error) {
+	if filter.Limit <= 0 {
+		return "", nil, errors.New("no limit")
+	}
+	idCond, err := idRange(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	reqMatch := requestMatch(filter)
+	if filter.State != "" && len(reqMatch) == 0 {
+		return statusTable, statusPipeline(filter.State, idCond, filter.Limit), nil
+	}
+	if len(idCond) > 0 {
+		reqMatch["_id"] = idCond
+	}
+	res := []bson.M{
+		{"$match": reqMatch},
+		{"$sort": bson.M{"_id": -1}},
+	}
+	lookup := bson.M{"$lookup": bson.M{"from": statusTable, "localField": "ID", "foreignField": "ID", "as": "st"}}
+	if filter.State == "" {
+		res = append(res, bson.M{"$limit": filter.Limit + 1}, lookup,
+			bson.M{"$unwind": bson.M{"path": "$st", "preserveNullAndEmptyArrays": true}})
+	} else {
+		res = append(res, lookup, bson.M{"$unwind": "$st"},
+			bson.M{"$match": prefixFields([]bson.M{stateMatch(filter.State)}, "st.")[0]},
+			bson.M{"$limit": filter.Limit + 1})
+	}
+	res = append(res, bson.M{"$project": bson.M{"_id": 0, "cursor": "$_id", "requestOID": "$_id", "ID": 1,
+		"status": "$st.status", "error": "$st.error", "errorCode": "$st.errorCode",
+		"recognizerKey": 1, "recognizerID": 1, "externalID": 1}})
+	return requestTable, res, nil
+}
+
+// statusPipeline selects the page by the state only, the request is joined after the limit
+func statusPipeline(state string, idCond bson.M, limit int) []bson.M {
+	stMatch := stateMatch(state)
+	if len(idCond) > 0 {
+		stMatch["_id"] = idCond
+	}
+	return []bson.M{
+		{"$match": stMatch},
+		{"$sort": bson.M{"_id": -1}},
+		{"$limit": limit + 1},
+		{"$lookup": bson.M{"from": requestTable, "localField": "ID", "foreignField": "ID", "as": "req"}},
+		{"$unwind": bson.M{"path": "$req", "preserveNullAndEmptyArrays": true}},
+		{"$project": bson.M{"_id": 0, "cursor": "$_id", "requestOID": "$req._id", "ID": 1,
+			"status": 1, "error": 1, "errorCode": 1,
+			"recognizerKey": "$req.recognizerKey", "recognizerID": "$req.recognizerID", "externalID": "$req.externalID"}},
+	}
+}
+
+func requestMatch(filter *api.ListFilter) bson.M {
+	res := bson.M{}
+	if filter.ExternalID != "" {
+		res["externalID"] = sanitize(filter.ExternalID)
+	}
+	if filter.Recognizer != "" {
+		r := sanitize(filter.Recognizer)
+		res["$or"] = []bson.M{{"recognizerKey": r}, {"recognizerID": r}}
+	}
+	return res
+}
+
+func stateMatch(state string) bson.M {
+	if state == api.StateError {
+		return bson.M{"error": bson.M{"$exists": true, "$ne": ""}}
+	}
+	return bson.M{"status": sanitize(state), "error": bson.M{"$in": bson.A{nil, ""}}}
+}
+
+func prefixFields(ms []bson.M, prefix string) []bson.M {
+	res := make([]bson.M, 0, len(ms))
+	for _, m := range ms {
+		pm := bson.M{}
+		for k, v := range m {
+			pm[prefix+k] = v
+		}
+		res = append(res, pm)
+	}
+	return res
+}
+
+// idRange makes the _id condition by the time range and the cursor, _id holds the creation time
+func idRange(filter *api.ListFilter) (bson.M, error) {
+	res := bson.M{}
+	if !filter.From.IsZero() {
+		res["$gte"] = timeObjectID(filter.From)
+	}
+	var to primitive.ObjectID
+	if !filter.To.IsZero() {
+		to = timeObjectID(filter.To)
+	}
+	if filter.Cursor != "" {
+		c, err := primitive.ObjectIDFromHex(filter.Cursor)
+		if err != nil {
+			return nil, errors.Wrap(err, "wrong cursor")
+		}
+		if to == primitive.NilObjectID || c.Hex() < to.Hex() {
+			to = c
+		}
+	}
+	if to != primitive.NilObjectID {
+		res["$lt"] = to
+	}
+	return res, nil
+}
+
+// timeObjectID returns the lowest ObjectID of the second
+func timeObjectID(t time.Time) primitive.ObjectID {
+	var res primitive.ObjectID
+	binary.BigEndian.PutUint32(res[0:4], uint32(t.Unix()))
+	return res
+}
+
+func toList(recs []listRecord, limit int) *api.List {
+	res := &api.List{Items: make([]api.ListItem, 0, len(recs))}
+	for i, r := range recs {
+		if i == limit {
+			res.NextCursor = recs[i-1].Cursor.Hex()
+			break
+		}
+		res.Items = append(res.Items, api.ListItem{ID: r.ID, Status: r.Status, Error: r.Error, ErrorCode: r.ErrorCode,
+			Recognizer: r.RecognizerKey, RecognizerID: r.RecognizerID, ExternalID: r.ExternalID,
+			Created: created(&r)})
+	}
+	return res
+}
+
+func created(r *listRecord) time.Time {
+	if !r.RequestOID.IsZero() {
+		return r.RequestOID.Timestamp().UTC()
+	}
+	return r.Cursor.Timestamp().UTC()
+}
@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/app/status/api"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListPipeline_ByRequest(t *testing.T) {
+	table, p, err := listPipeline(&api.ListFilter{ExternalID: "e1", Limit: 10})
+
+	assert.Nil(t, err)
+	assert.Equal(t, requestTable, table)
+	assert.Equal(t, bson.M{"$match": bson.M{"externalID": "e1"}}, p[0])
+	assert.Equal(t, bson.M{"$limit": 11}, p[2])
+}
+
+func TestListPipeline_ByState(t *testing.T) {
+	table, p, err := listPipeline(&api.ListFilter{State: "UPLOADED", Limit: 10})
+
+	assert.Nil(t, err)
+	assert.Equal(t, statusTable, table)
+	assert.Equal(t, bson.M{"$match": bson.M{"status": "UPLOADED", "error": bson.M{"$in": bson.A{nil, ""}}}}, p[0])
+	assert.Equal(t, bson.M{"$limit": 11}, p[2])
+}
+
+func TestListPipeline_ByStateAndRecognizer(t *testing.T) {
+	table, p, err := listPipeline(&api.ListFilter{State: api.StateError, Recognizer: "ben", Limit: 10})
+
+	assert.Nil(t, err)
+	assert.Equal(t, requestTable, table)
+	assert.Equal(t, bson.M{"$match": bson.M{"$or": []bson.M{{"recognizerKey": "ben"}, {"recognizerID": "ben"}}}}, p[0])
+	assert.Equal(t, bson.M{"$unwind": "$st"}, p[3])
+	assert.Equal(t, bson.M{"$match": bson.M{"st.error": bson.M{"$exists": true, "$ne": ""}}}, p[4])
+	assert.Equal(t, bson.M{"$limit": 11}, p[5])
+}
+
+func TestListPipeline_ByStateAndExternalID(t *testing.T) {
+	c := primitive.NewObjectID()
+	table, p, err := listPipeline(&api.ListFilter{State: "UPLOADED", ExternalID: "e1", Cursor: c.Hex(), Limit: 10})
+
+	assert.Nil(t, err)
+	assert.Equal(t, requestTable, table)
+	assert.Equal(t, bson.M{"$match": bson.M{"externalID": "e1", "_id": bson.M{"$lt": c}}}, p[0])
+	assert.Equal(t, bson.M{"$match": bson.M{"st.status": "UPLOADED", "st.error": bson.M{"$in": bson.A{nil, ""}}}}, p[4])
+	assert.Equal(t, bson.M{"$limit": 11}, p[5])
+}
+
+func TestListPipeline_WrongCursor(t *testing.T) {
+	_, _, err := listPipeline(&api.ListFilter{Cursor: "olia", Limit: 10})
+
+	assert.NotNil(t, err)
+}
+
+func TestIDRange(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	c := primitive.NewObjectIDFromTimestamp(from.Add(time.Minute))
+
+	r, err := idRange(&api.ListFilter{From: from, To: to})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"$gte": timeObjectID(from), "$lt": timeObjectID(to)}, r)
+
+	r, err = idRange(&api.ListFilter{To: to, Cursor: c.Hex()})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"$lt": c}, r)
+
+	r, err = idRange(&api.ListFilter{To: to, Cursor: timeObjectID(to.Add(time.Second)).Hex()})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"$lt": timeObjectID(to)}, r)
+
+	r, err = idRange(&api.ListFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{}, r)
+}
+
+func TestTimeObjectID(t *testing.T) {
+	tm := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := timeObjectID(tm)
+
+	assert.Equal(t, tm, id.Timestamp().UTC())
+	assert.True(t, id.Hex() < primitive.NewObjectIDFromTimestamp(tm).Hex())
+	assert.Equal(t, "5fee66000000000000000000", id.Hex())
+}
+
+func TestToList(t *testing.T) {
+	tm := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	recs := []listRecord{{Cursor: primitive.NewObjectIDFromTimestamp(tm), ID: "1", RecognizerKey: "ben"},
+		{Cursor: primitive.NewObjectIDFromTimestamp(tm), RequestOID: primitive.NewObjectIDFromTimestamp(tm.Add(time.Second)), ID: "2"},
+		{Cursor: primitive.NewObjectIDFromTimestamp(tm), ID: "3"}}
+
+	l := toList(recs, 2)
+
+	assert.Equal(t, 2, len(l.Items))
+	assert.Equal(t, api.ListItem{ID: "1", Recognizer: "ben", Created: tm}, l.Items[0])
+	assert.Equal(t, tm.Add(time.Second), l.Items[1].Created)
+	assert.Equal(t, recs[1].Cursor.Hex(), l.NextCursor)
+
+	l = toList(recs, 3)
+	assert.Equal(t, 3, len(l.Items))
+	assert.Equal(t, "", l.NextCursor)
+}
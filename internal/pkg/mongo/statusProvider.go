@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"strings"
 
 	"github.com/airenas/listgo/internal/app/status/api"
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
@@ -11,6 +12,7 @@ import (
 	"github.com/airenas/listgo/internal/pkg/status"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	mgo "go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -58,11 +60,22 @@ func (fs StatusProvider) Get(id string) (*api.TranscriptionResult, error) {
 	result.Error = m.Error
 	stv := status.From(result.Status)
 	result.Progress = progress.Convert(stv)
+	if m.Progress > result.Progress && stv != status.Completed && m.Error == "" {
+		result.Progress = m.Progress
+	}
 	if stv == status.Completed {
 		result.RecognizedText, err = getResultText(ctx, session, id)
 	}
 	result.AudioReady = m.AudioReady
 	result.AvailableResults = m.AvailableResults
+	if err == nil && stv != status.Completed && m.Error == "" {
+		var qi *persistence.QueueInfo
+		qi, err = getQueueInfo(ctx, session, id)
+		if qi != nil {
+			result.QueuePosition = qi.Position
+			result.ETA = qi.ETA
+		}
+	}
 
 	return &result, err
 }
@@ -83,9 +96,28 @@ func getResultText(ctx context.Context, session mgo.Session, id string) (string,
 	if err != nil {
 		return "", errors.Wrap(err, "can't load results")
 	}
+	if m.FileID != "" {
+		return readResultFile(session, m.FileID)
+	}
 	return m.Text, nil
 }
 
+func readResultFile(session mgo.Session, fileID string) (string, error) {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return "", errors.Wrapf(err, "wrong result file id %s", fileID)
+	}
+	b, err := newResultBucket(session)
+	if err != nil {
+		return "", err
+	}
+	var res strings.Builder
+	if _, err := b.DownloadToStream(id, &res); err != nil {
+		return "", errors.Wrapf(err, "can't load result file %s", fileID)
+	}
+	return res.String(), nil
+}
+
 func newNotFoundResult(ID string) *api.TranscriptionResult {
 	result := api.TranscriptionResult{ID: ID}
 	result.Status = "NOT_FOUND"
@@ -25,7 +25,7 @@ func NewStatusSaver(sessionProvider *SessionProvider) (*StatusSaver, error) {
 	return &f, nil
 }
 
-// Save saves status to DB
+// Save saves status to DB, the error and the live progress of the previous step are dropped
 func (ss *StatusSaver) Save(ID string, st status.Status) error {
 	cmdapp.Log.Infof("Saving status %s: %s", ID, status.Name(st))
 
@@ -38,7 +38,8 @@ func (ss *StatusSaver) Save(ID string, st status.Status) error {
 	return skipNoDocErr(c.FindOneAndUpdate(ctx, bson.M{"ID": sanitize(ID)},
 		bson.M{"$set": bson.M{"status": status.Name(st)}, "$unset": bson.M{
 			persistence.StError:     1,
-			persistence.StErrorCode: 1}},
+			persistence.StErrorCode: 1,
+			persistence.StProgress:  1}},
 		options.FindOneAndUpdate().SetUpsert(true)).Err())
 }
 
@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxSubscriptionIDs limits the number of IDs returned for one external ID
+const maxSubscriptionIDs = 1000
+
+// SubscriptionResolver finds job IDs by external ID or batch
+type SubscriptionResolver struct {
+	SessionProvider *SessionProvider
+}
+
+// NewSubscriptionResolver creates SubscriptionResolver instance
+func NewSubscriptionResolver(sessionProvider *SessionProvider) (*SubscriptionResolver, error) {
+	f := SubscriptionResolver{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// IDsByExternalID returns IDs of the latest requests with the external ID
+func (r *SubscriptionResolver) IDsByExternalID(externalID string) ([]string, error) {
+	c, ctx, cancel, err := newColl(r.SessionProvider, requestTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	cursor, err := c.Find(ctx, bson.M{"externalID": sanitize(externalID)},
+		options.Find().SetSort(bson.M{"_id": -1}).SetLimit(maxSubscriptionIDs).SetProjection(bson.M{"ID": 1}))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't find requests")
+	}
+	defer cursor.Close(context.Background())
+	res := make([]string, 0)
+	for cursor.Next(ctx) {
+		var req persistence.Request
+		if err := cursor.Decode(&req); err != nil {
+			return nil, errors.Wrap(err, "can't decode request")
+		}
+		res = append(res, req.ID)
+	}
+	return res, errors.Wrap(cursor.Err(), "can't read requests")
+}
+
+// IDsByBatch returns IDs of the jobs related to the batch work record
+func (r *SubscriptionResolver) IDsByBatch(batchID string) ([]string, error) {
+	c, ctx, cancel, err := newColl(r.SessionProvider, workTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var res persistence.WorkData
+	err = c.FindOne(ctx, bson.M{"ID": sanitize(batchID)}).Decode(&res)
+	if err == mgo.ErrNoDocuments {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't get batch by ID = %s", batchID)
+	}
+	return res.Related, nil
+}
@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/persistence"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	mgo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TranscriptVersions keeps the versions of the corrected transcripts
+type TranscriptVersions struct {
+	SessionProvider *SessionProvider
+}
+
+// NewTranscriptVersions creates TranscriptVersions instance
+func NewTranscriptVersions(sessionProvider *SessionProvider) (*TranscriptVersions, error) {
+	f := TranscriptVersions{SessionProvider: sessionProvider}
+	return &f, nil
+}
+
+// Latest returns the last version, nil if the transcript is not edited
+func (tv *TranscriptVersions) Latest(id string) (*persistence.TranscriptVersion, error) {
+	return tv.find(bson.M{"ID": sanitize(id)}, options.FindOne().SetSort(bson.M{"version": -1}))
+}
+
+// Get returns the version, nil if it does not exist
+func (tv *TranscriptVersions) Get(id string, version int) (*persistence.TranscriptVersion, error) {
+	return tv.find(bson.M{"ID": sanitize(id), "version": version})
+}
+
+func (tv *TranscriptVersions) find(filter bson.M, opts ...*options.FindOneOptions) (*persistence.TranscriptVersion, error) {
+	c, ctx, cancel, err := newColl(tv.SessionProvider, versionTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var res persistence.TranscriptVersion
+	err = c.FindOne(ctx, filter, opts...).Decode(&res)
+	if err == mgo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "can't get version")
+	}
+	return &res, nil
+}
+
+// List returns all the versions sorted by number
+func (tv *TranscriptVersions) List(id string) ([]*persistence.TranscriptVersion, error) {
+	c, ctx, cancel, err := newColl(tv.SessionProvider, versionTable)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	cursor, err := c.Find(ctx, bson.M{"ID": sanitize(id)}, options.Find().SetSort(bson.M{"version": 1}))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't list versions")
+	}
+	defer cursor.Close(context.Background())
+	res := make([]*persistence.TranscriptVersion, 0)
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, errors.Wrap(err, "can't read versions")
+	}
+	return res, nil
+}
+
+// Add registers the new version, returns false if the number is already taken by a concurrent edit
+func (tv *TranscriptVersions) Add(v *persistence.TranscriptVersion) (bool, error) {
+	cmdapp.Log.Infof("Adding transcript version %d for %s", v.Version, v.ID)
+	c, ctx, cancel, err := newColl(tv.SessionProvider, versionTable)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	_, err = c.InsertOne(ctx, v)
+	if mgo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "can't add version")
+	}
+	return true, nil
+}
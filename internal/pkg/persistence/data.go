@@ -1,5 +1,7 @@
 package persistence
 
+import "time"
+
 const (
 	// StAudioReady status table field for audioReady
 	StAudioReady = "audioReady"
@@ -9,6 +11,8 @@ const (
 	StErrorCode = "errorCode"
 	// StAvailableResults status table field for available Results
 	StAvailableResults = "avResults"
+	// StProgress status table field for the live progress of the running step
+	StProgress = "progress"
 )
 
 type (
@@ -27,20 +31,53 @@ type (
 		ErrorCode        string   `bson:"errorCode,omitempty"`
 		AudioReady       bool     `bson:"audioReady,omitempty"`
 		AvailableResults []string `bson:"avResults,omitempty"`
+		Progress         int32    `bson:"progress,omitempty"`
 	}
 
 	// Result is table for the final text
 	Result struct {
 		ID   string `json:"ID"`
 		Text string `json:"text,omitempty"`
+		// FileID is the id of the result stored in chunks, Text is empty then
+		FileID string `bson:"fileID,omitempty"`
 	}
 	// Request is table for initial request info
 	Request struct {
-		ID            string `json:"ID"`
-		Email         string `json:"email,omitempty"`
-		File          string `json:"file,omitempty"`
-		ExternalID    string `json:"externalID,omitempty"`
-		RecognizerKey string `json:"recognizerKey,omitempty"`
-		RecognizerID  string `json:"recognizerID,omitempty"`
+		ID            string  `json:"ID" bson:"ID"`
+		Email         string  `json:"email,omitempty"`
+		File          string  `json:"file,omitempty"`
+		ExternalID    string  `json:"externalID,omitempty" bson:"externalID,omitempty"`
+		RecognizerKey string  `json:"recognizerKey,omitempty" bson:"recognizerKey,omitempty"`
+		RecognizerID  string  `json:"recognizerID,omitempty" bson:"recognizerID,omitempty"`
+		Duration      float64 `json:"duration,omitempty" bson:"duration,omitempty"` // audio duration in seconds
+		FileSize      int64   `json:"fileSize,omitempty" bson:"fileSize,omitempty"`
+		// DedupKey is the hash of the audio and the recognition parameters
+		DedupKey string `json:"dedupKey,omitempty" bson:"dedupKey,omitempty"`
+		// SourceID is the ID of the transcription whose audio and results are reused
+		SourceID string `json:"sourceID,omitempty" bson:"sourceID,omitempty"`
+	}
+	// QueueInfo is the dispatcher's estimate for the waiting job
+	QueueInfo struct {
+		ID string `bson:"ID"`
+		// Position is 1-based place in the queue, 0 - the job is running
+		Position int        `bson:"position"`
+		ETA      *time.Time `bson:"eta,omitempty"`
+		Updated  time.Time  `bson:"updated"`
+	}
+
+	// SpeakerNames maps the diarization speaker labels to the names set by the user
+	SpeakerNames struct {
+		ID      string            `bson:"ID"`
+		Names   map[string]string `bson:"names"`
+		Updated time.Time         `bson:"updated"`
+	}
+
+	// TranscriptVersion is the user's corrected transcript, the original is version 0
+	TranscriptVersion struct {
+		ID      string `bson:"ID"`
+		Version int    `bson:"version"`
+		// File is the word level transcript in the results storage
+		File    string    `bson:"file"`
+		Created time.Time `bson:"created"`
 	}
 )
@@ -26,3 +26,24 @@ func Convert(status status.Status) int32 {
 	}
 	return 0
 }
+
+// ConvertStep returns percentage value of a progress inside the running step.
+// stepProgress is 0-100 of the step, the result is between the step's and the next status values
+func ConvertStep(st status.Status, stepProgress int32) int32 {
+	from := Convert(st)
+	to := Convert(st + 1)
+	if from == 0 || to <= from {
+		return from
+	}
+	if stepProgress < 0 {
+		stepProgress = 0
+	}
+	if stepProgress > 100 {
+		stepProgress = 100
+	}
+	res := from + (to-from)*stepProgress/100
+	if res >= to { // do not show the next status progress until the step is finished
+		res = to - 1
+	}
+	return res
+}
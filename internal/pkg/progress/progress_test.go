@@ -28,3 +28,27 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertStep(t *testing.T) {
+	tests := []struct {
+		name string
+		st   status.Status
+		p    int32
+		want int32
+	}{
+		{name: "any", st: status.From("olia"), p: 50, want: 0},
+		{name: "start", st: status.Transcription, p: 0, want: 50},
+		{name: "middle", st: status.Transcription, p: 50, want: 60},
+		{name: "end", st: status.Transcription, p: 100, want: 69},
+		{name: "over", st: status.Transcription, p: 200, want: 69},
+		{name: "negative", st: status.Diarization, p: -1, want: 35},
+		{name: "completed", st: status.Completed, p: 50, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progress.ConvertStep(tt.st, tt.p); got != tt.want {
+				t.Errorf("ConvertStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
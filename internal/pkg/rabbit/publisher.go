@@ -25,8 +25,8 @@ func (sender *Publisher) Publish(id string, topic string) error {
 	err := sender.ChannelProvider.RunOnChannelWithRetry(func(ch *amqp.Channel) error {
 		return ch.Publish(
 			realTopic, // exchange
-			"",
-			false, // mandatory
+			id,        // routing key, used by the routed exchange
+			false,     // mandatory
 			false,
 			amqp.Publishing{
 				ContentType: "text/plain",
@@ -16,14 +16,19 @@ func DeclareQueue(ch *amqp.Channel, qName string) (amqp.Queue, error) {
 
 //NewChannel creates channel to listen from rabbit with auto ack = false
 func NewChannel(ch *amqp.Channel, qName string) (<-chan amqp.Delivery, error) {
+	return NewConsumerChannel(ch, qName, "")
+}
+
+//NewConsumerChannel creates channel for listening with the consumer tag, the tag allows to cancel the consumer
+func NewConsumerChannel(ch *amqp.Channel, qName string, consumer string) (<-chan amqp.Delivery, error) {
 	return ch.Consume(
-		qName, // queue
-		"",    // consumer
-		false, // auto-ack
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,   // args
+		qName,    // queue
+		consumer, // consumer
+		false,    // auto-ack
+		false,    // exclusive
+		false,    // no-local
+		false,    // no-wait
+		nil,      // args
 	)
 }
 
@@ -39,3 +44,27 @@ func DeclareExchange(ch *amqp.Channel, topic string) error {
 		nil,      // arguments
 	)
 }
+
+//RoutedExchange returns the name of the direct exchange routing the events of the topic by ID
+func RoutedExchange(topic string) string {
+	return topic + ".routed"
+}
+
+//DeclareRoutedExchange creates direct exchange bound to the fanout exchange of the topic.
+//Events are published with ID as routing key, so a queue bound to the routed exchange by ID
+//receives only the events of that ID
+func DeclareRoutedExchange(ch *amqp.Channel, topic string) error {
+	err := ch.ExchangeDeclare(
+		RoutedExchange(topic), // name
+		"direct",              // type
+		true,                  // durable
+		false,                 // auto-deleted
+		false,                 // internal
+		false,                 // no-wait
+		nil,                   // arguments
+	)
+	if err != nil {
+		return err
+	}
+	return ch.ExchangeBind(RoutedExchange(topic), "", topic, false, nil)
+}
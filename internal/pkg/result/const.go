@@ -15,4 +15,14 @@ const (
 	LatRestoredGz = "lat.restored.gz"
 	// WebVTT indicates webvtt result file
 	WebVTT = "webvtt.txt"
+	// SRT indicates subtitles file, converted from the restored lattice on request
+	SRT = "result.srt"
+	// WordsJSON indicates word level JSON file, converted from the restored lattice on request
+	WordsJSON = "words.json"
+	// TextGrid indicates Praat TextGrid file, converted from the restored lattice on request
+	TextGrid = "result.TextGrid"
+	// Docx indicates MS Word transcript, converted from the restored lattice on request
+	Docx = "result.docx"
+	// Odt indicates OpenDocument transcript, converted from the restored lattice on request
+	Odt = "result.odt"
 )
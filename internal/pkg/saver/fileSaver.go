@@ -73,7 +73,7 @@ func openFile(fileName string) (WriterCloser, error) {
 			return nil, errors.Wrapf(err, "can't create dir '%s'", dir)
 		}
 	}
-	return os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE, 0666)
+	return os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 }
 
 // HealthyFunc returns func for health check
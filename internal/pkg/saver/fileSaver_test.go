@@ -3,6 +3,8 @@ package saver
 import (
 	"bytes"
 	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -44,6 +46,19 @@ func TestFails_WrongPath(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestSaves_TruncatesOnOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	fileSaver, err := NewLocalFileSaver(dir)
+	assert.Nil(t, err)
+
+	assert.Nil(t, fileSaver.Save("a/file", strings.NewReader("long body")))
+	assert.Nil(t, fileSaver.Save("a/file", strings.NewReader("short")))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "a", "file"))
+	assert.Nil(t, err)
+	assert.Equal(t, "short", string(b))
+}
+
 func TestChecksDirOnInit(t *testing.T) {
 	_, err := NewLocalFileSaver("./")
 	assert.Nil(t, err)
@@ -0,0 +1,16 @@
+package step
+
+import (
+	"context"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+)
+
+// Noop step does nothing, it replies with the same message.
+// It may be used to skip the pipeline step
+type Noop struct{}
+
+// Process returns the message copy
+func (s *Noop) Process(ctx context.Context, msg *messages.QueueMessage) (*messages.ResultMessage, error) {
+	return &messages.ResultMessage{QueueMessage: *messages.NewQueueMessageFromM(msg)}, nil
+}
@@ -0,0 +1,17 @@
+package step
+
+import (
+	"context"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoop(t *testing.T) {
+	msg := messages.NewQueueMessage("id", "rec", []messages.Tag{messages.NewTag("a", "b")})
+	res, err := (&Noop{}).Process(context.Background(), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, *msg, res.QueueMessage)
+	assert.Equal(t, "", res.Result)
+}
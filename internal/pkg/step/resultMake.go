@@ -0,0 +1,76 @@
+package step
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/lattice"
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/result"
+	"github.com/pkg/errors"
+)
+
+// ResultMake step makes the text and WebVTT result files from the restored lattice.
+// The files are read and written in the ID dir of the results root,
+// the final text is returned as the result
+type ResultMake struct {
+	root string
+}
+
+// NewResultMake creates the step, root is the results dir
+func NewResultMake(root string) (*ResultMake, error) {
+	if root == "" {
+		return nil, errors.New("No results root")
+	}
+	return &ResultMake{root: root}, nil
+}
+
+func newResultMakeFromConfig() (Step, error) {
+	return NewResultMake(cmdapp.Config.GetString("step.resultMake.root"))
+}
+
+type resultFile struct {
+	name  string
+	write func(parts []*lattice.Part, w io.Writer) error
+}
+
+var resultMakeFiles = []resultFile{{result.Txt, lattice.WriteTxt}, {result.TxtFinal, lattice.WriteTxtFinal},
+	{result.WebVTT, lattice.WriteVTT}}
+
+// Process writes the result files, returns the final text
+func (s *ResultMake) Process(ctx context.Context, msg *messages.QueueMessage) (*messages.ResultMessage, error) {
+	dir := filepath.Join(s.root, msg.ID)
+	f, err := os.Open(filepath.Join(dir, result.LatRestored))
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't open lattice")
+	}
+	defer f.Close()
+	parts, err := lattice.Read(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't read lattice")
+	}
+	var b bytes.Buffer
+	res := ""
+	for i, rf := range resultMakeFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b.Reset()
+		if err := rf.write(parts, &b); err != nil {
+			return nil, errors.Wrapf(err, "Can't make %s", rf.name)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, rf.name), b.Bytes(), 0666); err != nil {
+			return nil, errors.Wrapf(err, "Can't save %s", rf.name)
+		}
+		if rf.name == result.TxtFinal {
+			res = b.String()
+		}
+		ReportProgress(ctx, int32((i+1)*100/len(resultMakeFiles)))
+	}
+	return &messages.ResultMessage{QueueMessage: *messages.NewQueueMessageFromM(msg), Result: res}, nil
+}
@@ -0,0 +1,58 @@
+package step
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/airenas/listgo/internal/pkg/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestResultMake(t *testing.T, lat string) (*ResultMake, string) {
+	t.Helper()
+	root := t.TempDir()
+	assert.Nil(t, os.Mkdir(filepath.Join(root, "id"), 0755))
+	if lat != "" {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "id", result.LatRestored), []byte(lat), 0644))
+	}
+	s, err := NewResultMake(root)
+	assert.Nil(t, err)
+	return s, filepath.Join(root, "id")
+}
+
+func TestResultMake(t *testing.T) {
+	s, dir := newTestResultMake(t, "# 1 S0000\n1 0 0.5 <eps>\n1 0.5 1.2 labas ,\n1 1.2 2 vakaras .\n")
+	var pr int32
+	ctx := WithProgress(context.Background(), func(p int32) { pr = p })
+	msg := messages.NewQueueMessage("id", "rec", []messages.Tag{messages.NewTag("a", "b")})
+
+	res, err := s.Process(ctx, msg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, *msg, res.QueueMessage)
+	assert.Equal(t, "labas, vakaras.\n", res.Result)
+	assert.Equal(t, int32(100), pr)
+	for f, exp := range map[string]string{result.Txt: "labas vakaras\n", result.TxtFinal: "labas, vakaras.\n",
+		result.WebVTT: "WEBVTT\n\n00:00:00.500 --> 00:00:02.000\n<v S0000>labas, vakaras.\n\n"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, f))
+		assert.Nil(t, err, f)
+		assert.Equal(t, exp, string(b), f)
+	}
+}
+
+func TestResultMake_Fails(t *testing.T) {
+	for _, lat := range []string{"", "1 0 1 olia"} {
+		s, _ := newTestResultMake(t, lat)
+		_, err := s.Process(context.Background(), messages.NewQueueMessage("id", "rec", nil))
+		assert.NotNil(t, err, lat)
+	}
+}
+
+func TestNewResultMake_NoRoot(t *testing.T) {
+	_, err := NewResultMake("")
+	assert.NotNil(t, err)
+}
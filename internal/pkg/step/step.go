@@ -0,0 +1,77 @@
+package step
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/messages"
+	"github.com/pkg/errors"
+)
+
+// Step processes the pipeline step message in-process, as an alternative to the external command.
+// The returned message is sent as a reply, the error is reported in the reply's Error field
+type Step interface {
+	Process(ctx context.Context, msg *messages.QueueMessage) (*messages.ResultMessage, error)
+}
+
+// Factory creates a new step
+type Factory func() (Step, error)
+
+var registry = map[string]Factory{
+	"noop":       func() (Step, error) { return &Noop{}, nil },
+	"resultmake": newResultMakeFromConfig,
+}
+
+// New creates the step by name
+func New(name string) (Step, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	f, ok := registry[n]
+	if !ok {
+		return nil, errors.Errorf("Unknown step '%s'. Available: %s", name, strings.Join(Names(), ", "))
+	}
+	return f()
+}
+
+// Register adds the step factory to the registry
+func Register(name string, f Factory) error {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "" {
+		return errors.New("No step name")
+	}
+	if f == nil {
+		return errors.New("No step factory")
+	}
+	if _, ok := registry[n]; ok {
+		return errors.Errorf("Step '%s' already registered", n)
+	}
+	registry[n] = f
+	return nil
+}
+
+// Names returns sorted names of the registered steps
+func Names() []string {
+	res := make([]string, 0, len(registry))
+	for k := range registry {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+type progressKey struct{}
+
+// ProgressFunc receives the step progress 0-100
+type ProgressFunc func(progress int32)
+
+// WithProgress returns the context with the progress callback for the step
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// ReportProgress reports the step progress 0-100, does nothing if the context has no progress callback
+func ReportProgress(ctx context.Context, progress int32) {
+	if f, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && f != nil {
+		f(progress)
+	}
+}
@@ -0,0 +1,50 @@
+package step
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	for _, n := range []string{"noop", " NOOP "} {
+		s, err := New(n)
+		assert.Nil(t, err, n)
+		assert.NotNil(t, s, n)
+	}
+}
+
+func TestNew_Fails(t *testing.T) {
+	_, err := New("olia")
+	assert.NotNil(t, err)
+	_, err = New("")
+	assert.NotNil(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	err := Register("test-step", func() (Step, error) { return &Noop{}, nil })
+	assert.Nil(t, err)
+	defer delete(registry, "test-step")
+	s, err := New("test-step")
+	assert.Nil(t, err)
+	assert.IsType(t, &Noop{}, s)
+	assert.Contains(t, Names(), "test-step")
+}
+
+func TestRegister_Fails(t *testing.T) {
+	assert.NotNil(t, Register("", func() (Step, error) { return &Noop{}, nil }))
+	assert.NotNil(t, Register("noop", func() (Step, error) { return &Noop{}, nil }))
+	assert.NotNil(t, Register("olia", nil))
+}
+
+func TestReportProgress(t *testing.T) {
+	var got int32
+	ctx := WithProgress(context.Background(), func(p int32) { got = p })
+	ReportProgress(ctx, 42)
+	assert.Equal(t, int32(42), got)
+}
+
+func TestReportProgress_NoFunc(t *testing.T) {
+	ReportProgress(context.Background(), 42)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"io"
+	"path"
+	"strings"
+
+	"github.com/airenas/listgo/internal/app/result/api"
+	"github.com/airenas/listgo/internal/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Saver adapts Storage to the file saver interface
+type Saver struct {
+	Storage Storage
+}
+
+// NewSaver creates Saver instance
+func NewSaver(st Storage) (*Saver, error) {
+	if st == nil {
+		return nil, errors.New("No storage provided")
+	}
+	return &Saver{Storage: st}, nil
+}
+
+// Save saves the file
+func (s *Saver) Save(name string, reader io.Reader) error {
+	return s.Storage.Put(name, reader)
+}
+
+// Loader adapts Storage to the file loader interface
+type Loader struct {
+	Storage Storage
+}
+
+// NewLoader creates Loader instance
+func NewLoader(st Storage) (*Loader, error) {
+	if st == nil {
+		return nil, errors.New("No storage provided")
+	}
+	return &Loader{Storage: st}, nil
+}
+
+// Load opens the file
+func (l *Loader) Load(name string) (api.File, error) {
+	return l.Storage.Get(name)
+}
+
+// AudioList lists the audio files in the storage dir
+type AudioList struct {
+	Storage Storage
+}
+
+// NewAudioList creates AudioList instance
+func NewAudioList(st Storage) (*AudioList, error) {
+	if st == nil {
+		return nil, errors.New("No storage provided")
+	}
+	return &AudioList{Storage: st}, nil
+}
+
+// List returns the audio files directly in dir
+func (l *AudioList) List(dir string) ([]string, error) {
+	dir = strings.Trim(dir, "/")
+	objs, err := l.Storage.List(dir + "/")
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, errors.Wrap(ErrNotFound, dir)
+	}
+	var res []string
+	for _, o := range objs {
+		if path.Dir(o.Name) == dir && utils.SupportAudioExt(path.Ext(o.Name)) {
+			res = append(res, o.Name)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaverLoader(t *testing.T) {
+	l, cf := newTestLocal(t)
+	defer cf()
+	s, err := NewSaver(l)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Save("1/a.txt", strings.NewReader("olia")))
+	ld, err := NewLoader(l)
+	assert.Nil(t, err)
+	f, err := ld.Load("1/a.txt")
+	assert.Nil(t, err)
+	defer f.Close()
+	b, _ := ioutil.ReadAll(f)
+	assert.Equal(t, "olia", string(b))
+	_, err = ld.Load("1/b.txt")
+	assert.NotNil(t, err)
+}
+
+func TestAudioList(t *testing.T) {
+	l, cf := newTestLocal(t)
+	defer cf()
+	for _, n := range []string{"1/a.wav", "1/b.mp3", "1/c.txt", "1/d/e.wav", "12/f.wav"} {
+		assert.Nil(t, l.Put(n, strings.NewReader("olia")))
+	}
+	al, err := NewAudioList(l)
+	assert.Nil(t, err)
+	res, err := al.List("1")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"1/a.wav", "1/b.mp3"}, res)
+	_, err = al.List("2")
+	assert.NotNil(t, err)
+}
+
+func TestNewAdapters_Fail(t *testing.T) {
+	_, err := NewSaver(nil)
+	assert.NotNil(t, err)
+	_, err = NewLoader(nil)
+	assert.NotNil(t, err)
+	_, err = NewAudioList(nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+)
+
+// Local keeps objects as files in the local (or mounted) directory
+type Local struct {
+	root string
+}
+
+// NewLocal creates Local storage instance, the directory is created if missing
+func NewLocal(root string) (*Local, error) {
+	cmdapp.Log.Infof("Init Local File Storage at: %s", root)
+	if root == "" {
+		return nil, errors.New("No storage path provided")
+	}
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, errors.Wrapf(err, "can't create dir '%s'", root)
+	}
+	return &Local{root: root}, nil
+}
+
+// Put saves the object, the file is replaced atomically
+func (l *Local) Put(name string, reader io.Reader) error {
+	fn, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(fn)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrapf(err, "can't create dir '%s'", dir)
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-"+filepath.Base(fn))
+	if err != nil {
+		return errors.Wrapf(err, "can't create file in '%s'", dir)
+	}
+	defer os.Remove(f.Name())
+	n, err := io.Copy(f, reader)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "can't save file %s", fn)
+	}
+	if err := os.Chmod(f.Name(), 0666); err != nil {
+		return errors.Wrapf(err, "can't chmod file %s", fn)
+	}
+	if err := os.Rename(f.Name(), fn); err != nil {
+		return errors.Wrapf(err, "can't save file %s", fn)
+	}
+	cmdapp.Log.Infof("Saved file %s. Size = %d b", fn, n)
+	return nil
+}
+
+// Get opens the object
+func (l *Local) Get(name string) (File, error) {
+	fn, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		return nil, errors.Wrap(ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't open file %s", fn)
+	}
+	return f, nil
+}
+
+// List returns the files with names starting with prefix
+func (l *Local) List(prefix string) ([]ObjectInfo, error) {
+	if strings.Contains(prefix, "..") {
+		return nil, errors.Errorf("wrong prefix '%s'", prefix)
+	}
+	dir := prefix
+	if i := strings.LastIndex(dir, "/"); i > -1 {
+		dir = dir[:i]
+	} else {
+		dir = ""
+	}
+	start := filepath.Join(l.root, filepath.FromSlash(dir))
+	var res []ObjectInfo
+	err := filepath.Walk(start, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, ObjectInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	return res, errors.Wrapf(err, "can't list '%s'", prefix)
+}
+
+// Delete removes the object, missing object is not an error
+func (l *Local) Delete(name string) error {
+	fn, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "can't remove %s", fn)
+	}
+	return nil
+}
+
+// Stat returns the object info
+func (l *Local) Stat(name string) (*ObjectInfo, error) {
+	fn, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fn)
+	if os.IsNotExist(err) {
+		return nil, errors.Wrap(ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't stat %s", fn)
+	}
+	if info.IsDir() {
+		return nil, errors.Wrap(ErrNotFound, name)
+	}
+	return &ObjectInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// HealthyFunc returns func for health check, it fails if free space is less than sizeInMb
+func (l *Local) HealthyFunc(sizeInMb uint64) func() error {
+	return func() error {
+		var info syscall.Statfs_t
+		err := syscall.Statfs(l.root, &info)
+		if err != nil {
+			return errors.Errorf("Can't get info for dir: %s", l.root)
+		}
+		mb := info.Bavail * uint64(info.Bsize) / 1024 / 1024
+		if mb < sizeInMb {
+			return errors.Errorf("Disk space is %d mb, threshold: %d mb, location: %s", mb, sizeInMb, l.root)
+		}
+		return nil
+	}
+}
+
+func (l *Local) path(name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(name)), nil
+}
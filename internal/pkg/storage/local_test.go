@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLocal(t *testing.T) (*Local, func()) {
+	dir, err := ioutil.TempDir("", "storage")
+	assert.Nil(t, err)
+	l, err := NewLocal(dir)
+	assert.Nil(t, err)
+	return l, func() { os.RemoveAll(dir) }
+}
+
+func TestNewLocal_Fails(t *testing.T) {
+	_, err := NewLocal("")
+	assert.NotNil(t, err)
+}
+
+func TestLocal(t *testing.T) {
+	l, cf := newTestLocal(t)
+	defer cf()
+	testStorage(t, l)
+}
+
+func TestLocal_WrongNames(t *testing.T) {
+	l, cf := newTestLocal(t)
+	defer cf()
+	for _, n := range []string{"", "/a", "../a", "a/../../b"} {
+		assert.NotNil(t, l.Put(n, strings.NewReader("olia")), n)
+		_, err := l.Get(n)
+		assert.NotNil(t, err, n)
+	}
+	_, err := l.List("../")
+	assert.NotNil(t, err)
+}
+
+// testStorage checks the storage contract, it is shared by the implementations
+func testStorage(t *testing.T, st Storage) {
+	t.Helper()
+	assert.Nil(t, st.Put("1/a.txt", strings.NewReader("olia")))
+	assert.Nil(t, st.Put("1/b/c.wav", strings.NewReader("olia2")))
+	assert.Nil(t, st.Put("12/a.txt", strings.NewReader("x")))
+	assert.Nil(t, st.Put("1/a.txt", strings.NewReader("olia1")))
+
+	f, err := st.Get("1/a.txt")
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(f)
+	assert.Nil(t, err)
+	assert.Equal(t, "olia1", string(b))
+	_, err = f.Seek(2, 0)
+	assert.Nil(t, err)
+	b, _ = ioutil.ReadAll(f)
+	assert.Equal(t, "ia1", string(b))
+	fi, err := f.Stat()
+	assert.Nil(t, err)
+	assert.Equal(t, "a.txt", fi.Name())
+	assert.Equal(t, int64(5), fi.Size())
+	assert.Nil(t, f.Close())
+
+	info, err := st.Stat("1/b/c.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), info.Size)
+	_, err = st.Stat("1/b")
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+	_, err = st.Get("1/x.txt")
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+
+	objs, err := st.List("1/")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"1/a.txt", "1/b/c.wav"}, names(objs))
+	objs, err = st.List("1")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"1/a.txt", "1/b/c.wav", "12/a.txt"}, names(objs))
+	objs, err = st.List("2/")
+	assert.Nil(t, err)
+	assert.Empty(t, objs)
+
+	assert.Nil(t, st.Delete("1/a.txt"))
+	assert.Nil(t, st.Delete("1/a.txt"))
+	_, err = st.Stat("1/a.txt")
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+}
+
+func names(objs []ObjectInfo) []string {
+	res := make([]string, 0, len(objs))
+	for _, o := range objs {
+		res = append(res, o.Name)
+	}
+	return res
+}
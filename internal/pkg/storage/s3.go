@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+)
+
+// S3Config keeps the connection settings of S3 compatible storage
+type S3Config struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 keeps objects in S3 compatible storage, path-style requests are used
+type S3 struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	signer   *signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 creates S3 storage instance for the location s3://bucket/prefix
+func NewS3(location string, cfg S3Config) (*S3, error) {
+	cmdapp.Log.Infof("Init S3 Storage at: %s, endpoint: %s", location, cfg.Endpoint)
+	if !IsS3(location) {
+		return nil, errors.Errorf("wrong S3 location '%s'", location)
+	}
+	bp := strings.SplitN(strings.TrimPrefix(location, s3Scheme), "/", 2)
+	if bp[0] == "" {
+		return nil, errors.Errorf("no bucket in '%s'", location)
+	}
+	if cfg.Endpoint == "" {
+		return nil, errors.New("no S3 endpoint")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("wrong S3 endpoint '%s'", cfg.Endpoint)
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("no S3 credentials")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	res := &S3{endpoint: u, bucket: bp[0], client: &http.Client{}, now: time.Now,
+		signer: &signer{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: cfg.Region}}
+	if len(bp) > 1 {
+		res.prefix = strings.Trim(bp[1], "/")
+		if res.prefix != "" {
+			res.prefix += "/"
+		}
+	}
+	return res, nil
+}
+
+// Put uploads the object. The content is spooled into a temp file as S3 needs the length in advance
+func (s *S3) Put(name string, reader io.Reader) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile("", "s3-put-")
+	if err != nil {
+		return errors.Wrap(err, "can't create temp file")
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), reader)
+	if err != nil {
+		return errors.Wrapf(err, "can't read %s", name)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "can't rewind temp file")
+	}
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(name), ioutil.NopCloser(f))
+	if err != nil {
+		return errors.Wrapf(err, "can't prepare request for %s", name)
+	}
+	req.ContentLength = n
+	resp, err := s.do(req, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return errors.Wrapf(err, "can't put %s", name)
+	}
+	resp.Body.Close()
+	cmdapp.Log.Infof("Saved object %s. Size = %d b", name, n)
+	return nil
+}
+
+// Get opens the object, the content is read lazily with range requests
+func (s *S3) Get(name string) (File, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &s3File{s: s, info: *info}, nil
+}
+
+// List returns the objects with names starting with prefix
+func (s *S3) List(prefix string) ([]ObjectInfo, error) {
+	var res []ObjectInfo
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.prefix + prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest(http.MethodGet, s.bucketURL(q), nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't prepare list request")
+		}
+		resp, err := s.do(req, emptySHA256)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't list '%s'", prefix)
+		}
+		var lr listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&lr)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "can't decode list response")
+		}
+		for _, c := range lr.Contents {
+			res = append(res, ObjectInfo{Name: strings.TrimPrefix(c.Key, s.prefix), Size: c.Size,
+				ModTime: c.LastModified})
+		}
+		if !lr.IsTruncated || lr.NextContinuationToken == "" {
+			return res, nil
+		}
+		token = lr.NextContinuationToken
+	}
+}
+
+// Delete removes the object, missing object is not an error
+func (s *S3) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(name), nil)
+	if err != nil {
+		return errors.Wrapf(err, "can't prepare request for %s", name)
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil && errors.Cause(err) != ErrNotFound {
+		return errors.Wrapf(err, "can't delete %s", name)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Stat returns the object info
+func (s *S3) Stat(name string) (*ObjectInfo, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodHead, s.objectURL(name), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't prepare request for %s", name)
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	resp.Body.Close()
+	res := &ObjectInfo{Name: name, Size: resp.ContentLength}
+	res.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return res, nil
+}
+
+// HealthyFunc returns func for health check, it fails if the bucket is not accessible
+func (s *S3) HealthyFunc() func() error {
+	return func() error {
+		req, err := http.NewRequest(http.MethodHead, s.bucketURL(nil), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req, emptySHA256)
+		if err != nil {
+			return errors.Wrapf(err, "bucket %s is not accessible", s.bucket)
+		}
+		resp.Body.Close()
+		return nil
+	}
+}
+
+// openRange returns the object content from the offset
+func (s *S3) openRange(name string, from int64) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(name), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't prepare request for %s", name)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", from))
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't get %s", name)
+	}
+	return resp.Body, nil
+}
+
+// do signs and executes the request, the response with non 2xx code is returned as error
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.sign(req, payloadHash, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, errors.Errorf("S3 returned %d: %s", resp.StatusCode, string(b))
+	}
+	return resp, nil
+}
+
+func (s *S3) bucketURL(q url.Values) string {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket
+	u.RawQuery = strings.Replace(q.Encode(), "+", "%20", -1)
+	return u.String()
+}
+
+func (s *S3) objectURL(name string) string {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + s.prefix + name
+	u.RawPath = encodePath(u.Path)
+	return u.String()
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// s3File reads the object with range requests, the request is reopened after Seek
+type s3File struct {
+	s    *S3
+	info ObjectInfo
+	off  int64
+	body io.ReadCloser
+}
+
+func (f *s3File) Read(p []byte) (int, error) {
+	if f.off >= f.info.Size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		b, err := f.s.openRange(f.info.Name, f.off)
+		if err != nil {
+			return 0, err
+		}
+		f.body = b
+	}
+	n, err := f.body.Read(p)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	var n int64
+	switch whence {
+	case io.SeekStart:
+		n = offset
+	case io.SeekCurrent:
+		n = f.off + offset
+	case io.SeekEnd:
+		n = f.info.Size + offset
+	default:
+		return 0, errors.New("wrong whence " + strconv.Itoa(whence))
+	}
+	if n < 0 {
+		return 0, errors.New("negative position")
+	}
+	if n != f.off && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.off = n
+	return n, nil
+}
+
+func (f *s3File) Stat() (os.FileInfo, error) {
+	return fileInfo{info: f.info}, nil
+}
+
+func (f *s3File) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
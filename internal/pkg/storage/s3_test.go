@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal in-memory S3 stand-in, it supports path-style object requests and ListObjectsV2
+type fakeS3 struct {
+	lock    sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	// pageSize of the list response
+	pageSize int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), signAlgorithm+" Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	p := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if p[0] != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if len(p) == 1 {
+		if r.Method == http.MethodGet {
+			f.list(w, r)
+		}
+		return
+	}
+	key := p[1]
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("x-amz-content-sha256") != hexSHA256(b) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = b
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead, http.MethodGet:
+		b, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if rg := r.Header.Get("Range"); rg != "" {
+			from, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rg, "bytes="), "-"))
+			b = b[from:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("continuation-token") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var res listBucketResult
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		res.IsTruncated = true
+		res.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		res.Contents = append(res.Contents, struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		}{Key: k, Size: int64(len(f.objects[k])), LastModified: time.Now()})
+	}
+	xml.NewEncoder(w).Encode(&res)
+}
+
+func newTestS3(t *testing.T, location string) (*S3, *fakeS3, func()) {
+	fake := &fakeS3{bucket: "bucket", objects: map[string][]byte{}, pageSize: 2}
+	server := httptest.NewServer(fake)
+	s, err := NewS3(location, S3Config{Endpoint: server.URL, AccessKey: "key", SecretKey: "secret"})
+	assert.Nil(t, err)
+	return s, fake, server.Close
+}
+
+func TestNewS3_Fails(t *testing.T) {
+	cfg := S3Config{Endpoint: "http://localhost:9000", AccessKey: "key", SecretKey: "secret"}
+	tests := []struct {
+		location string
+		cfg      S3Config
+	}{
+		{location: "/data", cfg: cfg},
+		{location: "s3://", cfg: cfg},
+		{location: "s3://bucket", cfg: S3Config{AccessKey: "key", SecretKey: "secret"}},
+		{location: "s3://bucket", cfg: S3Config{Endpoint: "http://localhost:9000"}},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			_, err := NewS3(tt.location, tt.cfg)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestS3(t *testing.T) {
+	s, _, cf := newTestS3(t, "s3://bucket")
+	defer cf()
+	testStorage(t, s)
+}
+
+func TestS3_Prefix(t *testing.T) {
+	s, fake, cf := newTestS3(t, "s3://bucket/data/")
+	defer cf()
+	assert.Nil(t, s.Put("1/a b.txt", strings.NewReader("olia")))
+	assert.Equal(t, []byte("olia"), fake.objects["data/1/a b.txt"])
+	objs, err := s.List("1/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1/a b.txt"}, names(objs))
+}
+
+func TestS3_Healthy(t *testing.T) {
+	s, _, cf := newTestS3(t, "s3://bucket")
+	defer cf()
+	assert.Nil(t, s.HealthyFunc()())
+	s.bucket = "other"
+	assert.NotNil(t, s.HealthyFunc()())
+}
+
+func TestS3_File_ServeContent(t *testing.T) {
+	s, _, cf := newTestS3(t, "s3://bucket")
+	defer cf()
+	assert.Nil(t, s.Put("a.txt", strings.NewReader("0123456789")))
+	f, err := s.Get("a.txt")
+	assert.Nil(t, err)
+	defer f.Close()
+	fi, _ := f.Stat()
+	req := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	req.Header.Set("Range", "bytes=3-5")
+	resp := httptest.NewRecorder()
+	http.ServeContent(resp, req, fi.Name(), fi.ModTime(), f)
+	assert.Equal(t, http.StatusPartialContent, resp.Code)
+	assert.Equal(t, "345", resp.Body.String())
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned if the object does not exist
+var ErrNotFound = errors.New("not found")
+
+// ObjectInfo describes the stored object
+type ObjectInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// File is the opened object, it can be served with http.ServeContent
+type File interface {
+	io.ReadCloser
+	io.Seeker
+	Stat() (os.FileInfo, error)
+}
+
+// Storage keeps objects by name. The names are slash separated paths relative to the storage root
+type Storage interface {
+	Put(name string, reader io.Reader) error
+	Get(name string) (File, error)
+	// List returns all objects with the names starting with prefix, recursively
+	List(prefix string) ([]ObjectInfo, error)
+	Delete(name string) error
+	Stat(name string) (*ObjectInfo, error)
+}
+
+// s3Scheme is the prefix of the S3 storage location: s3://bucket/prefix
+const s3Scheme = "s3://"
+
+// New creates the storage by the location. Location s3://bucket/prefix selects S3 compatible storage
+// configured by s3.* settings, any other location is a local directory
+func New(location string) (Storage, error) {
+	if IsS3(location) {
+		return NewS3(location, S3Config{Endpoint: cmdapp.Config.GetString("s3.endpoint"),
+			Region:    cmdapp.Config.GetString("s3.region"),
+			AccessKey: cmdapp.Config.GetString("s3.accessKey"),
+			SecretKey: cmdapp.Config.GetString("s3.secretKey")})
+	}
+	return NewLocal(location)
+}
+
+// IsS3 returns true if the location points to S3 storage
+func IsS3(location string) bool {
+	return strings.HasPrefix(location, s3Scheme)
+}
+
+// HealthyFunc returns func for the storage health check,
+// sizeInMb is the free space threshold for the local storage, 0 - only the dir is checked
+func HealthyFunc(st Storage, sizeInMb uint64) func() error {
+	switch s := st.(type) {
+	case *Local:
+		return s.HealthyFunc(sizeInMb)
+	case *S3:
+		return s.HealthyFunc()
+	}
+	return func() error { return nil }
+}
+
+// checkName validates the object name
+func checkName(name string) error {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return errors.Errorf("wrong name '%s'", name)
+	}
+	for _, p := range strings.Split(name, "/") {
+		if p == ".." {
+			return errors.Errorf("wrong name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// fileInfo adapts ObjectInfo to os.FileInfo
+type fileInfo struct {
+	info ObjectInfo
+}
+
+func (fi fileInfo) Name() string       { return path.Base(fi.info.Name) }
+func (fi fileInfo) Size() int64        { return fi.info.Size }
+func (fi fileInfo) Mode() os.FileMode  { return 0444 }
+func (fi fileInfo) ModTime() time.Time { return fi.info.ModTime }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() interface{}   { return nil }
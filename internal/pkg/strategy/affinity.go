@@ -0,0 +1,37 @@
+package strategy
+
+import (
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+)
+
+// Affinity is a greedy model affinity strategy.
+// It selects the oldest task requiring the model already loaded (active or preloaded) by the worker.
+// If there is no such task, the oldest task is selected
+type Affinity struct {
+}
+
+// NewAffinity init new model affinity task selection strategy
+func NewAffinity() (*Affinity, error) {
+	return &Affinity{}, nil
+}
+
+// FindBest returns the best task for worker ws[workerIndex]
+func (s *Affinity) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	if err := validateWorker(ws, workerIndex); err != nil {
+		return nil, err
+	}
+	var res, resSame *api.Task
+	w := ws[workerIndex]
+	for _, t := range ts {
+		if res == nil || t.ArrivedAt.Before(res.ArrivedAt) {
+			res = t
+		}
+		if w.HasTaskType(t.TaskType) && (resSame == nil || t.ArrivedAt.Before(resSame.ArrivedAt)) {
+			resSame = t
+		}
+	}
+	if resSame != nil {
+		return resSame, nil
+	}
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAffinity_Fails(t *testing.T) {
+	testInit(t)
+	s, _ := NewAffinity()
+	_, err := s.FindBest(nil, nil, 0)
+	assert.NotNil(t, err)
+	_, err = s.FindBest(testWrks(testW("", 0)), nil, 1)
+	assert.NotNil(t, err)
+}
+
+func TestAffinity_SameType(t *testing.T) {
+	testInit(t)
+	s, _ := NewAffinity()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 200)
+	t3 := testT("1", 20, 20)
+
+	bt, err := s.FindBest(testWrks(testW("1", 0), testW("2", 0)), testTsks(t1, t2, t3), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, t3, bt)
+	bt, err = s.FindBest(testWrks(testW("1", 0), testW("2", 0)), testTsks(t1, t2, t3), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, t2, bt)
+}
+
+func TestAffinity_Oldest(t *testing.T) {
+	testInit(t)
+	s, _ := NewAffinity()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 200)
+	t3 := testT("1", 20, 20)
+
+	bt, err := s.FindBest(testWrks(testW("3", 0)), testTsks(t1, t2, t3), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, t2, bt)
+}
+
+func TestAffinity_Loaded(t *testing.T) {
+	testInit(t)
+	s, _ := NewAffinity()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 200)
+
+	w := testW("3", 0)
+	w.Loaded = []string{"3", "1"}
+	bt, err := s.FindBest(testWrks(w), testTsks(t1, t2), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, t1, bt)
+}
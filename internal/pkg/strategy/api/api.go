@@ -8,6 +8,25 @@ import (
 type Worker struct {
 	TaskType string
 	EndAt    time.Time
+	Working  bool
+	Tenant   string // tenant of the running task
+	// Draining worker takes no new tasks, it is passed only to account its running task
+	Draining bool
+	// Loaded are all the preloaded task types of the worker, TaskType is the active one
+	Loaded []string
+}
+
+//HasTaskType returns true if the task type is active or preloaded in the worker
+func (w *Worker) HasTaskType(tt string) bool {
+	if w.TaskType == tt {
+		return true
+	}
+	for _, l := range w.Loaded {
+		if l == tt {
+			return true
+		}
+	}
+	return false
 }
 
 //Task object wrapper
@@ -15,6 +34,7 @@ type Task struct {
 	TaskType  string
 	ArrivedAt time.Time
 	Duration  time.Duration
+	Tenant    string
 
 	RealObject interface{}
 }
@@ -23,3 +43,9 @@ type Task struct {
 type TaskSelector interface {
 	FindBest(ws []*Worker, ts []*Task, workerIndex int) (*Task, error)
 }
+
+//Cloner is implemented by the selector that keeps the state between the selections.
+//The clone is used to simulate the selections without changing the original state
+type Cloner interface {
+	Clone() TaskSelector
+}
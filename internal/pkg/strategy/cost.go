@@ -45,6 +45,10 @@ func newCost(modelLoadTime time.Duration, rtFactor float64, delayCostPerSec floa
 // FindBest is the main selection method
 // select task for worker ws[workerIndex]
 func (c *Cost) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	ws, workerIndex, err := activeWorkers(ws, workerIndex)
+	if err != nil {
+		return nil, err
+	}
 	ctx := newContext(time.Now())
 	ctx.modelLoadTime = c.modelLoadTime
 	ctx.delayCostPerSec = c.delayCostPerSec
@@ -92,7 +96,7 @@ func calcCost(w *api.Worker, t []*api.Task, ctx *context) float64 {
 	if len(t) == 0 {
 		return ctx.max
 	}
-	if w.TaskType != t[0].TaskType {
+	if !w.HasTaskType(t[0].TaskType) {
 		res += float64(ctx.modelLoadTime.Seconds())
 	}
 	d := ctx.now.Sub(t[0].ArrivedAt)
@@ -146,7 +150,7 @@ func getTaskByV2(ws []*api.Worker, tg *taskGroups, wi int, ctx *context) *api.Ta
 			if arr[i] < 0 {
 				arr[i] = 0
 			}
-			if tk != w.TaskType {
+			if !w.HasTaskType(tk) {
 				arr[i] += float64(ctx.modelLoadTime.Seconds())
 			}
 		}
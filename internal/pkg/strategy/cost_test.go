@@ -63,6 +63,30 @@ func TestFind(t *testing.T) {
 	assert.Equal(t, "2", bt.TaskType)
 }
 
+func TestCalcCost_Loaded(t *testing.T) {
+	testInit(t)
+	ctx := newContext(now)
+	ctx.modelLoadTime = 100 * time.Second
+	w := testW("2", 0)
+	tsks := testTsks(testT("1", 0, 20))
+	assert.Equal(t, 100.0, calcCost(w, tsks, ctx))
+	w.Loaded = []string{"2", "1"}
+	assert.Equal(t, 0.0, calcCost(w, tsks, ctx))
+}
+
+func TestFind_Loaded(t *testing.T) {
+	testInit(t)
+	s, _ := newCost(time.Second*100, 2, 3)
+	w2 := testW("2", 0)
+	w2.Loaded = []string{"2", "1"}
+	bt, err := s.FindBest(testWrks(testW("3", 0), w2), testTsks(testT("1", 0, 20)), 1)
+	assert.Nil(t, err)
+	assert.NotNil(t, bt)
+	bt, err = s.FindBest(testWrks(testW("3", 0), w2), testTsks(testT("1", 0, 20)), 0)
+	assert.Nil(t, err)
+	assert.Nil(t, bt)
+}
+
 func TestFind_SelectLatest(t *testing.T) {
 	testInit(t)
 	s, _ := newCost(time.Second*100, 2, 3)
@@ -0,0 +1,196 @@
+package strategy
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/pkg/errors"
+)
+
+// minCharge is the minimal service charged for one task, so short tasks are not free
+const minCharge = time.Second
+
+// FairShare strategy shares workers among tenants.
+// It selects a tenant using start-time fair queuing (a deficit scheduling variant weighted by
+// the tenant's weight and the task duration) and delegates the task selection
+// among the tenant's tasks to the wrapped strategy
+type FairShare struct {
+	inner        api.TaskSelector
+	weights      map[string]float64
+	defaultLimit int
+	limits       map[string]int
+
+	lock   sync.Mutex
+	served map[string]float64
+	vtime  float64
+}
+
+// NewFairShare wraps strategy with fair share tenant selection. Params are read from config
+func NewFairShare(inner api.TaskSelector) (*FairShare, error) {
+	weights, err := toFloatMap(cmdapp.Config.GetStringMapString("strategy.fairShare.weights"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Wrong strategy.fairShare.weights")
+	}
+	limits, err := toIntMap(cmdapp.Config.GetStringMapString("strategy.fairShare.limits"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Wrong strategy.fairShare.limits")
+	}
+	return newFairShare(inner, weights, cmdapp.Config.GetInt("strategy.fairShare.maxRunning"), limits)
+}
+
+func newFairShare(inner api.TaskSelector, weights map[string]float64, defaultLimit int,
+	limits map[string]int) (*FairShare, error) {
+	if inner == nil {
+		return nil, errors.New("No wrapped strategy")
+	}
+	for k, v := range weights {
+		if v <= 0 {
+			return nil, errors.Errorf("Wrong weight %f for '%s'", v, k)
+		}
+	}
+	if defaultLimit < 0 {
+		return nil, errors.Errorf("Wrong running task limit %d", defaultLimit)
+	}
+	res := &FairShare{inner: inner, weights: weights, defaultLimit: defaultLimit, limits: limits}
+	if res.weights == nil {
+		res.weights = make(map[string]float64)
+	}
+	if res.limits == nil {
+		res.limits = make(map[string]int)
+	}
+	res.served = make(map[string]float64)
+	return res, nil
+}
+
+// FindBest selects the tenant and returns its best task for worker ws[workerIndex].
+// Tasks running on draining workers are counted against the tenant limits
+func (s *FairShare) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	if err := validateWorker(ws, workerIndex); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	running := make(map[string]int)
+	for _, w := range ws {
+		if w.Working {
+			running[w.Tenant]++
+		}
+	}
+	ws, workerIndex, err := activeWorkers(ws, workerIndex)
+	if err != nil {
+		return nil, err
+	}
+	byTenant := make(map[string][]*api.Task)
+	for _, t := range ts {
+		byTenant[t.Tenant] = append(byTenant[t.Tenant], t)
+	}
+	s.forgetIdle(byTenant)
+
+	for _, tn := range s.candidates(byTenant, running) {
+		t, err := s.inner.FindBest(ws, byTenant[tn], workerIndex)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			continue
+		}
+		st := s.startTag(tn)
+		s.served[tn] = st + math.Max(t.Duration.Seconds(), minCharge.Seconds())/s.weight(tn)
+		s.vtime = st
+		return t, nil
+	}
+	return nil, nil
+}
+
+// Clone returns a copy with the same served state, the copy can be used for the queue estimation
+func (s *FairShare) Clone() api.TaskSelector {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := &FairShare{inner: s.inner, weights: s.weights, defaultLimit: s.defaultLimit, limits: s.limits,
+		served: make(map[string]float64, len(s.served)), vtime: s.vtime}
+	if c, ok := s.inner.(api.Cloner); ok {
+		res.inner = c.Clone()
+	}
+	for k, v := range s.served {
+		res.served[k] = v
+	}
+	return res
+}
+
+// candidates returns tenants with pending tasks and free running slots ordered by their start tags
+func (s *FairShare) candidates(byTenant map[string][]*api.Task, running map[string]int) []string {
+	res := make([]string, 0, len(byTenant))
+	for tn := range byTenant {
+		if l := s.limit(tn); l > 0 && running[tn] >= l {
+			continue
+		}
+		res = append(res, tn)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		si, sj := s.startTag(res[i]), s.startTag(res[j])
+		if si != sj {
+			return si < sj
+		}
+		return res[i] < res[j]
+	})
+	return res
+}
+
+// startTag does not let the idle tenant to collect the unused share
+func (s *FairShare) startTag(tn string) float64 {
+	return math.Max(s.served[tn], s.vtime)
+}
+
+func (s *FairShare) forgetIdle(byTenant map[string][]*api.Task) {
+	for tn, v := range s.served {
+		if _, ok := byTenant[tn]; !ok && v <= s.vtime {
+			delete(s.served, tn)
+		}
+	}
+}
+
+func (s *FairShare) weight(tn string) float64 {
+	if w, ok := s.weights[strings.ToLower(tn)]; ok {
+		return w
+	}
+	return 1
+}
+
+func (s *FairShare) limit(tn string) int {
+	if l, ok := s.limits[strings.ToLower(tn)]; ok {
+		return l
+	}
+	return s.defaultLimit
+}
+
+// config keys are case insensitive, so tenants are matched in lower case
+func toFloatMap(m map[string]string) (map[string]float64, error) {
+	res := make(map[string]float64)
+	for k, v := range m {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Wrong value for '%s'", k)
+		}
+		res[strings.ToLower(k)] = f
+	}
+	return res, nil
+}
+
+func toIntMap(m map[string]string) (map[string]int, error) {
+	res := make(map[string]int)
+	for k, v := range m {
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Wrong value for '%s'", k)
+		}
+		res[strings.ToLower(k)] = i
+	}
+	return res, nil
+}
@@ -0,0 +1,206 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFairShare(t *testing.T, weights map[string]float64, defLimit int, limits map[string]int) *FairShare {
+	t.Helper()
+	inner, _ := NewFIFO()
+	res, err := newFairShare(inner, weights, defLimit, limits)
+	assert.Nil(t, err)
+	return res
+}
+
+func TestFairShare_Init(t *testing.T) {
+	inner, _ := NewFIFO()
+	_, err := newFairShare(nil, nil, 0, nil)
+	assert.NotNil(t, err)
+	_, err = newFairShare(inner, map[string]float64{"a": 0}, 0, nil)
+	assert.NotNil(t, err)
+	_, err = newFairShare(inner, nil, -1, nil)
+	assert.NotNil(t, err)
+}
+
+func TestFairShare_InitFromConfig(t *testing.T) {
+	inner, _ := NewFIFO()
+	cmdapp.Config.Set("strategy.fairShare.weights", map[string]string{"Big": "2.5"})
+	cmdapp.Config.Set("strategy.fairShare.limits", map[string]string{"big": "3"})
+	cmdapp.Config.Set("strategy.fairShare.maxRunning", 1)
+	defer func() {
+		cmdapp.Config.Set("strategy.fairShare.weights", nil)
+		cmdapp.Config.Set("strategy.fairShare.limits", nil)
+		cmdapp.Config.Set("strategy.fairShare.maxRunning", nil)
+	}()
+	s, err := NewFairShare(inner)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, s.weight("BIG"))
+	assert.Equal(t, 3, s.limit("Big"))
+	assert.Equal(t, 1, s.limit("other"))
+}
+
+func TestFairShare_Fails(t *testing.T) {
+	s := newTestFairShare(t, nil, 0, nil)
+	_, err := s.FindBest(nil, nil, 0)
+	assert.NotNil(t, err)
+}
+
+func TestFairShare_Alternates(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 0, nil)
+	a1, a2, a3 := testTT("a", 30), testTT("a", 29), testTT("a", 28)
+	b1 := testTT("b", 10)
+	ts := testTsks(a1, a2, a3, b1)
+	ws := testWrks(testW("1", 0))
+
+	var res []*api.Task
+	for i := 0; i < 4; i++ {
+		bt, err := s.FindBest(ws, ts, 0)
+		assert.Nil(t, err)
+		res = append(res, bt)
+		ts = removeTask(ts, bt)
+	}
+	assert.Equal(t, []*api.Task{a1, b1, a2, a3}, res)
+}
+
+func TestFairShare_Clone(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 0, nil)
+	a1, a2 := testTT("a", 30), testTT("a", 29)
+	ws := testWrks(testW("1", 0))
+	_, err := s.FindBest(ws, testTsks(a1, a2), 0)
+	assert.Nil(t, err)
+	served, vtime := s.served["a"], s.vtime
+
+	c := s.Clone()
+	_, err = c.FindBest(ws, testTsks(a2), 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, served, s.served["a"])
+	assert.Equal(t, vtime, s.vtime)
+	assert.NotEqual(t, served, c.(*FairShare).served["a"])
+}
+
+func TestFairShare_Weights(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, map[string]float64{"a": 2}, 0, nil)
+	a1, a2, a3 := testTT("a", 30), testTT("a", 29), testTT("a", 28)
+	b1, b2 := testTT("b", 10), testTT("b", 9)
+	ts := testTsks(a1, a2, a3, b1, b2)
+	ws := testWrks(testW("1", 0))
+
+	var res []*api.Task
+	for i := 0; i < 5; i++ {
+		bt, _ := s.FindBest(ws, ts, 0)
+		res = append(res, bt)
+		ts = removeTask(ts, bt)
+	}
+	assert.Equal(t, []*api.Task{a1, b1, a2, a3, b2}, res)
+}
+
+func TestFairShare_Limit(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 1, map[string]int{"b": 2})
+	a1 := testTT("a", 30)
+	b1 := testTT("b", 10)
+	w := testW("1", 10)
+	w.Working = true
+	w.Tenant = "a"
+	ws := testWrks(w, testW("1", 0))
+
+	bt, _ := s.FindBest(ws, testTsks(a1), 1)
+	assert.Nil(t, bt)
+	bt, _ = s.FindBest(ws, testTsks(a1, b1), 1)
+	assert.Equal(t, b1, bt)
+	w.Tenant = "b"
+	bt, _ = s.FindBest(ws, testTsks(b1), 1)
+	assert.Equal(t, b1, bt)
+}
+
+func TestFairShare_IdleTenantNoBurst(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 0, nil)
+	ws := testWrks(testW("1", 0))
+	for i := 0; i < 5; i++ {
+		s.FindBest(ws, testTsks(testTT("a", 30)), 0)
+	}
+	a, b1, b2 := testTT("a", 30), testTT("b", 10), testTT("b", 9)
+	bt, _ := s.FindBest(ws, testTsks(a, b1, b2), 0)
+	assert.Equal(t, b1, bt)
+	bt, _ = s.FindBest(ws, testTsks(a, b2), 0)
+	assert.Equal(t, a, bt)
+}
+
+func testTT(tenant string, arrivedBefore int) *api.Task {
+	res := testT("1", arrivedBefore, 20)
+	res.Tenant = tenant
+	return res
+}
+
+func removeTask(ts []*api.Task, t *api.Task) []*api.Task {
+	res := make([]*api.Task, 0)
+	for _, v := range ts {
+		if v != t {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func TestFairShare_MinCharge(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 0, nil)
+	tsk := testTT("a", 1)
+	tsk.Duration = 0
+	s.FindBest(testWrks(testW("1", 0)), testTsks(tsk), 0)
+	assert.Equal(t, minCharge.Seconds(), s.served["a"])
+}
+
+func TestFairShare_LimitCountsDraining(t *testing.T) {
+	testInit(t)
+	s := newTestFairShare(t, nil, 1, nil)
+	a1 := testTT("a", 30)
+	w := testW("1", 10)
+	w.Working, w.Draining, w.Tenant = true, true, "a"
+	ws := testWrks(w, testW("1", 0))
+
+	bt, err := s.FindBest(ws, testTsks(a1), 1)
+	assert.Nil(t, err)
+	assert.Nil(t, bt)
+}
+
+type testSelector func(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error)
+
+func (f testSelector) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	return f(ws, ts, workerIndex)
+}
+
+func TestFairShare_TriesNextTenant(t *testing.T) {
+	testInit(t)
+	fifo, _ := NewFIFO()
+	var innerWs []*api.Worker
+	inner := testSelector(func(ws []*api.Worker, ts []*api.Task, wi int) (*api.Task, error) {
+		innerWs = ws
+		if ts[0].Tenant == "a" {
+			return nil, nil
+		}
+		return fifo.FindBest(ws, ts, wi)
+	})
+	s, err := newFairShare(inner, nil, 0, nil)
+	assert.Nil(t, err)
+	a1, b1 := testTT("a", 30), testTT("b", 10)
+	d := testW("1", 10)
+	d.Draining = true
+
+	bt, err := s.FindBest(testWrks(d, testW("1", 0)), testTsks(a1, b1), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, b1, bt)
+	assert.Equal(t, 1, len(innerWs))
+	_, charged := s.served["a"]
+	assert.False(t, charged)
+}
@@ -0,0 +1,39 @@
+package strategy
+
+import (
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/pkg/errors"
+)
+
+// FIFO strategy selects the task that arrived first
+type FIFO struct {
+}
+
+// NewFIFO init new FIFO task selection strategy
+func NewFIFO() (*FIFO, error) {
+	return &FIFO{}, nil
+}
+
+// FindBest returns the oldest task for worker ws[workerIndex]
+func (s *FIFO) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	if err := validateWorker(ws, workerIndex); err != nil {
+		return nil, err
+	}
+	var res *api.Task
+	for _, t := range ts {
+		if res == nil || t.ArrivedAt.Before(res.ArrivedAt) {
+			res = t
+		}
+	}
+	return res, nil
+}
+
+func validateWorker(ws []*api.Worker, wi int) error {
+	if len(ws) == 0 {
+		return errors.New("No workers")
+	}
+	if wi < 0 || wi >= len(ws) {
+		return errors.Errorf("Wrong worker index %d of %d", wi, len(ws))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFIFO_Fails(t *testing.T) {
+	testInit(t)
+	s, _ := NewFIFO()
+	_, err := s.FindBest(nil, nil, 0)
+	assert.NotNil(t, err)
+	_, err = s.FindBest(testWrks(testW("", 0)), nil, 1)
+	assert.NotNil(t, err)
+}
+
+func TestFIFO_NoTasks(t *testing.T) {
+	testInit(t)
+	s, _ := NewFIFO()
+	bt, err := s.FindBest(testWrks(testW("", 0)), nil, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, bt)
+}
+
+func TestFIFO(t *testing.T) {
+	testInit(t)
+	s, _ := NewFIFO()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 200)
+	t3 := testT("1", 20, 20)
+
+	bt, err := s.FindBest(testWrks(testW("1", 0), testW("2", 0)), testTsks(t1, t2, t3), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, t2, bt)
+}
@@ -0,0 +1,83 @@
+package strategy
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/pkg/errors"
+)
+
+// Factory creates a new task selection strategy
+type Factory func() (api.TaskSelector, error)
+
+// DefaultName is the name of the strategy used when no name is configured
+const DefaultName = "cost"
+
+var registry = map[string]Factory{
+	"cost":     func() (api.TaskSelector, error) { return NewCost() },
+	"fifo":     func() (api.TaskSelector, error) { return NewFIFO() },
+	"sjf":      func() (api.TaskSelector, error) { return NewSJF() },
+	"affinity": func() (api.TaskSelector, error) { return NewAffinity() },
+}
+
+// New creates the task selection strategy by name
+func New(name string) (api.TaskSelector, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "" {
+		n = DefaultName
+	}
+	f, ok := registry[n]
+	if !ok {
+		return nil, errors.Errorf("Unknown strategy '%s'. Available: %s", name, strings.Join(Names(), ", "))
+	}
+	return f()
+}
+
+// Register adds the strategy factory to the registry
+func Register(name string, f Factory) error {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "" {
+		return errors.New("No strategy name")
+	}
+	if f == nil {
+		return errors.New("No strategy factory")
+	}
+	if _, ok := registry[n]; ok {
+		return errors.Errorf("Strategy '%s' already registered", n)
+	}
+	registry[n] = f
+	return nil
+}
+
+// Names returns sorted names of the registered strategies
+func Names() []string {
+	res := make([]string, 0, len(registry))
+	for k := range registry {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// activeWorkers drops the draining workers, ws[wi] must not be draining.
+// It returns the new index of the worker
+func activeWorkers(ws []*api.Worker, wi int) ([]*api.Worker, int, error) {
+	if err := validateWorker(ws, wi); err != nil {
+		return nil, 0, err
+	}
+	if ws[wi].Draining {
+		return nil, 0, errors.Errorf("Worker %d is draining", wi)
+	}
+	res := make([]*api.Worker, 0, len(ws))
+	ri := 0
+	for i, w := range ws {
+		if i == wi {
+			ri = len(res)
+		}
+		if !w.Draining {
+			res = append(res, w)
+		}
+	}
+	return res, ri, nil
+}
@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	for _, n := range []string{"fifo", "sjf", "affinity", " FIFO "} {
+		s, err := New(n)
+		assert.Nil(t, err, n)
+		assert.NotNil(t, s, n)
+	}
+}
+
+func TestNew_Default(t *testing.T) {
+	cmdapp.Config.Set("strategy.modelLoadDuration", time.Minute)
+	cmdapp.Config.Set("strategy.realTimeFactor", 1)
+	cmdapp.Config.Set("strategy.delayCostPerSecond", 1)
+	defer cmdapp.Config.Set("strategy.modelLoadDuration", nil)
+	s, err := New("")
+	assert.Nil(t, err)
+	assert.IsType(t, &Cost{}, s)
+}
+
+func TestNew_Fails(t *testing.T) {
+	_, err := New("olia")
+	assert.NotNil(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	err := Register("test-strategy", func() (api.TaskSelector, error) { return NewFIFO() })
+	assert.Nil(t, err)
+	defer delete(registry, "test-strategy")
+	s, err := New("test-strategy")
+	assert.Nil(t, err)
+	assert.IsType(t, &FIFO{}, s)
+	assert.Contains(t, Names(), "test-strategy")
+}
+
+func TestRegister_Fails(t *testing.T) {
+	assert.NotNil(t, Register("", func() (api.TaskSelector, error) { return NewFIFO() }))
+	assert.NotNil(t, Register("fifo", func() (api.TaskSelector, error) { return NewFIFO() }))
+	assert.NotNil(t, Register("olia", nil))
+}
+
+func TestActiveWorkers(t *testing.T) {
+	d := testW("1", 0)
+	d.Draining = true
+	w1, w2 := testW("1", 0), testW("2", 0)
+
+	ws, wi, err := activeWorkers(testWrks(w1, d, w2), 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, testWrks(w1, w2), ws)
+	assert.Equal(t, 1, wi)
+	_, _, err = activeWorkers(testWrks(w1, d, w2), 1)
+	assert.NotNil(t, err)
+	_, _, err = activeWorkers(testWrks(w1), 1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,218 @@
+package sim
+
+import (
+	"math"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	idleStep      = time.Second
+	maxIterations = 10000000
+)
+
+// Params for the simulation
+type Params struct {
+	ModelLoadDuration time.Duration
+	RTFactor          float64
+}
+
+// Result of the simulation
+type Result struct {
+	Tasks        int
+	AvgWait      time.Duration
+	MaxWait      time.Duration
+	ModelReloads int
+	Makespan     time.Duration
+}
+
+type simWorker struct {
+	taskType string
+	tenant   string
+	working  bool
+	draining bool
+	endAt    time.Time
+}
+
+type simTask struct {
+	e *Event
+}
+
+// Run replays the trace through the strategy, the workers are identified by their queue names
+func Run(sel api.TaskSelector, events []*Event, p Params) (*Result, error) {
+	if sel == nil {
+		return nil, errors.New("No strategy")
+	}
+	if p.RTFactor <= 0 {
+		return nil, errors.Errorf("Wrong real time factor %f", p.RTFactor)
+	}
+	res := &Result{}
+	if len(events) == 0 {
+		return res, nil
+	}
+	var wrks []*simWorker
+	byQueue := make(map[string]*simWorker)
+	var pending []*simTask
+	var first, last time.Time
+	waitSum := time.Duration(0)
+	now := events[0].At
+	next := 0
+	for it := 0; ; it++ {
+		if it > maxIterations {
+			return nil, errors.New("Simulation does not finish")
+		}
+		for ; next < len(events) && !events[next].At.After(now); next++ {
+			e := events[next]
+			switch e.Type {
+			case EventWorker:
+				w, f := byQueue[e.ID]
+				if !f {
+					w = &simWorker{taskType: e.TaskType}
+					byQueue[e.ID] = w
+					wrks = append(wrks, w)
+				}
+				w.draining = false
+			case EventWorkerDrain:
+				if w, f := byQueue[e.ID]; f {
+					w.draining = true
+				}
+			case EventWorkerExit:
+				if w, f := byQueue[e.ID]; f {
+					delete(byQueue, e.ID)
+					wrks = removeWorker(wrks, w)
+				}
+			default:
+				if first.IsZero() {
+					first = e.At
+				}
+				pending = append(pending, &simTask{e: e})
+			}
+		}
+		for _, w := range wrks {
+			if w.working && !w.endAt.After(now) {
+				w.working = false
+			}
+		}
+		for i, w := range wrks {
+			if w.working || w.draining || len(pending) == 0 {
+				continue
+			}
+			realNow := time.Now()
+			t, err := sel.FindBest(toAPIWorkers(wrks, now, realNow), toAPITasks(pending, now, realNow), i)
+			if err != nil {
+				return nil, errors.Wrap(err, "Can't select task")
+			}
+			if t == nil {
+				continue
+			}
+			st := t.RealObject.(*simTask)
+			pending = remove(pending, st)
+			wait := now.Sub(st.e.At)
+			waitSum += wait
+			if wait > res.MaxWait {
+				res.MaxWait = wait
+			}
+			res.Tasks++
+			w.working = true
+			w.tenant = st.e.Tenant
+			w.endAt = now.Add(durTimes(toDuration(st.e.Duration), p.RTFactor))
+			if w.taskType != st.e.TaskType {
+				res.ModelReloads++
+				w.taskType = st.e.TaskType
+				w.endAt = w.endAt.Add(p.ModelLoadDuration)
+			}
+			if w.endAt.After(last) {
+				last = w.endAt
+			}
+		}
+		nt, ok := nextTime(events, next, wrks)
+		if !ok {
+			if len(pending) == 0 {
+				break
+			}
+			if !hasActive(wrks) {
+				return nil, errors.Errorf("%d tasks left, but no workers", len(pending))
+			}
+			nt = now.Add(idleStep)
+		}
+		now = nt
+	}
+	if res.Tasks > 0 {
+		res.AvgWait = waitSum / time.Duration(res.Tasks)
+		res.Makespan = last.Sub(first)
+	}
+	return res, nil
+}
+
+func nextTime(events []*Event, next int, wrks []*simWorker) (time.Time, bool) {
+	var res time.Time
+	ok := false
+	if next < len(events) {
+		res, ok = events[next].At, true
+	}
+	for _, w := range wrks {
+		if w.working && (!ok || w.endAt.Before(res)) {
+			res, ok = w.endAt, true
+		}
+	}
+	return res, ok
+}
+
+// strategies work with the wall clock, so all simulation times are shifted relative to it
+func toAPIWorkers(wrks []*simWorker, now, realNow time.Time) []*api.Worker {
+	res := make([]*api.Worker, len(wrks))
+	for i, w := range wrks {
+		res[i] = &api.Worker{TaskType: w.taskType, EndAt: realNow, Working: w.working, Draining: w.draining}
+		if w.working {
+			res[i].EndAt = realNow.Add(w.endAt.Sub(now))
+			res[i].Tenant = w.tenant
+		}
+	}
+	return res
+}
+
+func toAPITasks(tsks []*simTask, now, realNow time.Time) []*api.Task {
+	res := make([]*api.Task, len(tsks))
+	for i, t := range tsks {
+		res[i] = &api.Task{TaskType: t.e.TaskType, Duration: toDuration(t.e.Duration),
+			ArrivedAt: realNow.Add(t.e.At.Sub(now)), Tenant: t.e.Tenant, RealObject: t}
+	}
+	return res
+}
+
+func remove(tsks []*simTask, t *simTask) []*simTask {
+	for i, v := range tsks {
+		if v == t {
+			return append(tsks[:i], tsks[i+1:]...)
+		}
+	}
+	return tsks
+}
+
+func removeWorker(wrks []*simWorker, w *simWorker) []*simWorker {
+	for i, v := range wrks {
+		if v == w {
+			return append(wrks[:i], wrks[i+1:]...)
+		}
+	}
+	return wrks
+}
+
+func hasActive(wrks []*simWorker) bool {
+	for _, w := range wrks {
+		if !w.draining {
+			return true
+		}
+	}
+	return false
+}
+
+func toDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
+
+func durTimes(d time.Duration, times float64) time.Duration {
+	return time.Duration(math.Round(float64(d.Nanoseconds()) * times))
+}
@@ -0,0 +1,112 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/strategy"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func TestRun_Fails(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	_, err := Run(nil, nil, Params{RTFactor: 1})
+	assert.NotNil(t, err)
+	_, err = Run(s, nil, Params{RTFactor: 0})
+	assert.NotNil(t, err)
+	_, err = Run(s, []*Event{testTask("1", "a", 0, 10)}, Params{RTFactor: 1})
+	assert.NotNil(t, err)
+}
+
+func TestRun_Empty(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	r, err := Run(s, nil, Params{RTFactor: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, r.Tasks)
+}
+
+func TestRun_FIFO(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	r, err := Run(s, []*Event{testWorker("w", 0), testTask("1", "a", 0, 10), testTask("2", "b", 1, 10),
+		testTask("3", "a", 2, 10)}, Params{RTFactor: 1, ModelLoadDuration: 5 * time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Tasks)
+	assert.Equal(t, 3, r.ModelReloads)
+	assert.Equal(t, 45*time.Second, r.Makespan)
+	assert.Equal(t, 28*time.Second, r.MaxWait)
+	assert.Equal(t, (14+28)*time.Second/3, r.AvgWait)
+}
+
+func TestRun_Affinity(t *testing.T) {
+	s, _ := strategy.NewAffinity()
+	r, err := Run(s, []*Event{testWorker("w", 0), testTask("1", "a", 0, 10), testTask("2", "b", 1, 10),
+		testTask("3", "a", 2, 10)}, Params{RTFactor: 1, ModelLoadDuration: 5 * time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Tasks)
+	assert.Equal(t, 2, r.ModelReloads)
+	assert.Equal(t, 40*time.Second, r.Makespan)
+}
+
+func TestRun_SeveralWorkers(t *testing.T) {
+	s, _ := strategy.NewSJF()
+	r, err := Run(s, []*Event{testWorker("w", 0), testWorker("w1", 0), testTask("1", "a", 0, 10),
+		testTask("2", "a", 0, 10)}, Params{RTFactor: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.Tasks)
+	assert.Equal(t, time.Duration(0), r.MaxWait)
+	assert.Equal(t, 20*time.Second, r.Makespan)
+}
+
+func TestRun_ReRegisteredWorker(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	r, err := Run(s, []*Event{testWorker("w", 0), testWorker("w", 0), testTask("1", "a", 0, 10),
+		testTask("2", "a", 0, 10)}, Params{RTFactor: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.Tasks)
+	assert.Equal(t, 10*time.Second, r.MaxWait)
+}
+
+func TestRun_DrainingWorker(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	d := testWorker("w1", 0)
+	d.Type = EventWorkerDrain
+	r, err := Run(s, []*Event{testWorker("w", 0), testWorker("w1", 0), d, testTask("1", "a", 0, 10),
+		testTask("2", "a", 0, 10)}, Params{RTFactor: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.Tasks)
+	assert.Equal(t, 10*time.Second, r.MaxWait)
+	assert.Equal(t, 20*time.Second, r.Makespan)
+}
+
+func TestRun_ExitedWorker(t *testing.T) {
+	s, _ := strategy.NewFIFO()
+	e := testWorker("w", 1)
+	e.Type = EventWorkerExit
+	_, err := Run(s, []*Event{testWorker("w", 0), e, testTask("1", "a", 2, 10)}, Params{RTFactor: 1})
+	assert.NotNil(t, err)
+}
+
+func testWorker(id string, at int) *Event {
+	return &Event{Type: EventWorker, ID: id, At: start.Add(time.Duration(at) * time.Second)}
+}
+
+func testTask(id, tt string, at int, dur float64) *Event {
+	return &Event{Type: EventTask, ID: id, TaskType: tt, Duration: dur, At: start.Add(time.Duration(at) * time.Second)}
+}
+
+func TestRun_FairShare(t *testing.T) {
+	f, _ := strategy.NewFIFO()
+	s, _ := strategy.NewFairShare(f)
+	ta := testTask("1", "a", 0, 10)
+	ta.Tenant = "a"
+	ta1 := testTask("2", "a", 0, 10)
+	ta1.Tenant = "a"
+	tb := testTask("3", "a", 1, 10)
+	tb.Tenant = "b"
+	r, err := Run(s, []*Event{testWorker("w", 0), ta, ta1, tb}, Params{RTFactor: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Tasks)
+	assert.Equal(t, 20*time.Second, r.MaxWait)
+}
@@ -0,0 +1,89 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	//EventTask indicates task arrival
+	EventTask = "task"
+	//EventWorker indicates worker registration
+	EventWorker = "worker"
+	//EventWorkerDrain indicates the worker takes no new tasks
+	EventWorkerDrain = "workerDrain"
+	//EventWorkerExit indicates the worker exit
+	EventWorkerExit = "workerExit"
+)
+
+var eventTypes = map[string]bool{EventTask: true, EventWorker: true, EventWorkerDrain: true, EventWorkerExit: true}
+
+// Event is one record of the dispatcher trace
+type Event struct {
+	Type     string    `json:"type"` // see EventXxx consts
+	At       time.Time `json:"at"`
+	ID       string    `json:"id"`
+	TaskType string    `json:"taskType,omitempty"`
+	Duration float64   `json:"duration,omitempty"` // audio duration in seconds
+	Tenant   string    `json:"tenant,omitempty"`
+}
+
+// ReadTrace reads trace from JSON lines and sorts events by time
+func ReadTrace(r io.Reader) ([]*Event, error) {
+	res := make([]*Event, 0)
+	scanner := bufio.NewScanner(r)
+	ln := 0
+	for scanner.Scan() {
+		ln++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		e := &Event{}
+		if err := json.Unmarshal([]byte(line), e); err != nil {
+			return nil, errors.Wrapf(err, "Can't parse line %d", ln)
+		}
+		if !eventTypes[e.Type] {
+			return nil, errors.Errorf("Unknown event type '%s' at line %d", e.Type, ln)
+		}
+		res = append(res, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Can't read trace")
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].At.Before(res[j].At) })
+	return res, nil
+}
+
+// TraceWriter writes events as JSON lines
+type TraceWriter struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+// NewTraceWriter creates trace writer
+func NewTraceWriter(w io.Writer) (*TraceWriter, error) {
+	if w == nil {
+		return nil, errors.New("No writer")
+	}
+	return &TraceWriter{w: w}, nil
+}
+
+// Write writes the event
+func (tw *TraceWriter) Write(e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal event")
+	}
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	_, err = tw.w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,47 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTrace(t *testing.T) {
+	evs, err := ReadTrace(strings.NewReader(`{"type":"task","at":"2020-01-01T10:00:10Z","id":"1","taskType":"a","duration":20}
+
+{"type":"worker","at":"2020-01-01T10:00:00Z","id":"w1","taskType":"a"}
+{"type":"workerExit","at":"2020-01-01T10:00:20Z","id":"w1"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(evs))
+	assert.Equal(t, EventWorker, evs[0].Type)
+	assert.Equal(t, "a", evs[0].TaskType)
+	assert.Equal(t, EventWorkerExit, evs[2].Type)
+	assert.Equal(t, "a", evs[1].TaskType)
+	assert.Equal(t, 20.0, evs[1].Duration)
+}
+
+func TestReadTrace_Fails(t *testing.T) {
+	_, err := ReadTrace(strings.NewReader(`{"type":"task"`))
+	assert.NotNil(t, err)
+	_, err = ReadTrace(strings.NewReader(`{"type":"olia"}`))
+	assert.NotNil(t, err)
+}
+
+func TestWriteTrace(t *testing.T) {
+	b := &bytes.Buffer{}
+	tw, _ := NewTraceWriter(b)
+	err := tw.Write(&Event{Type: EventTask, At: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), ID: "1", Duration: 10})
+	assert.Nil(t, err)
+	evs, err := ReadTrace(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(evs))
+	assert.Equal(t, "1", evs[0].ID)
+}
+
+func TestNewTraceWriter_Fails(t *testing.T) {
+	_, err := NewTraceWriter(nil)
+	assert.NotNil(t, err)
+}
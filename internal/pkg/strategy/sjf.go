@@ -0,0 +1,30 @@
+package strategy
+
+import (
+	"github.com/airenas/listgo/internal/pkg/strategy/api"
+)
+
+// SJF (shortest job first) strategy selects the shortest task.
+// Tasks of the same duration are selected by arrival time
+type SJF struct {
+}
+
+// NewSJF init new shortest job first task selection strategy
+func NewSJF() (*SJF, error) {
+	return &SJF{}, nil
+}
+
+// FindBest returns the shortest task for worker ws[workerIndex]
+func (s *SJF) FindBest(ws []*api.Worker, ts []*api.Task, workerIndex int) (*api.Task, error) {
+	if err := validateWorker(ws, workerIndex); err != nil {
+		return nil, err
+	}
+	var res *api.Task
+	for _, t := range ts {
+		if res == nil || t.Duration < res.Duration ||
+			(t.Duration == res.Duration && t.ArrivedAt.Before(res.ArrivedAt)) {
+			res = t
+		}
+	}
+	return res, nil
+}
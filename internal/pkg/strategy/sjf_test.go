@@ -0,0 +1,40 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSJF_Fails(t *testing.T) {
+	testInit(t)
+	s, _ := NewSJF()
+	_, err := s.FindBest(nil, nil, 0)
+	assert.NotNil(t, err)
+	_, err = s.FindBest(testWrks(testW("", 0)), nil, -1)
+	assert.NotNil(t, err)
+}
+
+func TestSJF(t *testing.T) {
+	testInit(t)
+	s, _ := NewSJF()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 200)
+	t3 := testT("1", 20, 10)
+
+	bt, err := s.FindBest(testWrks(testW("1", 0), testW("2", 0)), testTsks(t1, t2, t3), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, t3, bt)
+}
+
+func TestSJF_SameDuration_Oldest(t *testing.T) {
+	testInit(t)
+	s, _ := NewSJF()
+	t1 := testT("1", 10, 20)
+	t2 := testT("2", 30, 20)
+	t3 := testT("1", 20, 20)
+
+	bt, err := s.FindBest(testWrks(testW("1", 0)), testTsks(t1, t2, t3), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, t2, bt)
+}
@@ -0,0 +1,13 @@
+package api
+
+import "time"
+
+// TaskHealth is a state of the preload task
+type TaskHealth struct {
+	Key       string    `json:"key"`
+	Running   bool      `json:"running"`
+	Ready     bool      `json:"ready"`
+	Probe     string    `json:"probe,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+}
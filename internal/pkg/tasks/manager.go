@@ -2,10 +2,13 @@ package tasks
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/cmdapp"
+	"github.com/airenas/listgo/internal/pkg/tasks/api"
 	"github.com/pkg/errors"
 )
 
@@ -16,40 +19,98 @@ type ProcessRunner interface {
 	Running() bool
 }
 
-// Manager manages tasks by key
+type runnerFactory func() (ProcessRunner, error)
+
+type warmRunner struct {
+	key      string
+	runner   ProcessRunner
+	memoryMB int64
+	probe    probe
+
+	// health state, guarded by Manager.hLock
+	ready     bool
+	err       string
+	checkedAt time.Time
+}
+
+// Manager manages tasks by key.
+// It keeps up to max warm processes, the least recently used process is closed
+// if there is no free slot or the memory budget is exceeded
 type Manager struct {
 	keyPrefix string
+	max       int
+	// memoryMB is a budget for all warm processes, 0 - no limit.
+	// The memory of a process is taken from the <prefix>_memoryMB setting
+	memoryMB  int64
+	newRunner runnerFactory
 
-	lock       *sync.Mutex
-	currentKey string
-	runner     ProcessRunner
+	lock    *sync.Mutex
+	runners []*warmRunner // the most recently used first, changed only holding both locks
+	// hLock guards the health state, it is not held while waiting for the task to start
+	hLock *sync.RWMutex
+	// done is closed on Close to stop the checks
+	done      chan struct{}
+	closeOnce sync.Once
+	// reapLock is read locked by the liveness cmd probes not to let the reaper take their process.
+	// EnsureRunning is expected to be called holding it, as the worker does
+	reapLock *sync.RWMutex
 }
 
-// NewManager creates LocalFileSaver instance
+// NewManager creates Manager instance with one warm process
 func NewManager(prefix string, workingDir string) (*Manager, error) {
-	r, err := NewRunner(workingDir)
+	return NewManagerWithLimits(prefix, workingDir, 1, 0, nil)
+}
+
+// NewManagerWithLimits creates Manager instance keeping up to max warm processes in memoryMB budget,
+// reapLock is the lock of the zombie reaper, it may be nil
+func NewManagerWithLimits(prefix string, workingDir string, max int, memoryMB int64,
+	reapLock *sync.RWMutex) (*Manager, error) {
+	f := func() (ProcessRunner, error) {
+		r, err := NewRunner(workingDir)
+		if err != nil {
+			return nil, err
+		}
+		logWriter := cmdapp.Log.Writer()
+		r.outWriter = logWriter
+		r.errWriter = logWriter
+		return r, nil
+	}
+	res, err := newManagerWithFactory(prefix, f, max, memoryMB)
 	if err != nil {
 		return nil, err
 	}
-	logWriter := cmdapp.Log.Writer()
-	r.outWriter = logWriter
-	r.errWriter = logWriter
-	return newManager(prefix, r)
+	res.reapLock = reapLock
+	return res, nil
 }
 
-// NewManager creates LocalFileSaver instance
 func newManager(prefix string, r ProcessRunner) (*Manager, error) {
+	if r == nil {
+		return nil, errors.New("No runner for task manager provided")
+	}
+	return newManagerWithFactory(prefix, func() (ProcessRunner, error) { return r, nil }, 1, 0)
+}
+
+func newManagerWithFactory(prefix string, f runnerFactory, max int, memoryMB int64) (*Manager, error) {
 	if prefix == "" {
 		return nil, errors.New("No prefix for task manager provided")
 	}
-	if r == nil {
-		return nil, errors.New("No runner for task manager provided")
+	if f == nil {
+		return nil, errors.New("No runner factory for task manager provided")
+	}
+	if max < 1 {
+		return nil, errors.Errorf("Wrong max warm task count %d", max)
 	}
-	m := Manager{keyPrefix: prefix, lock: &sync.Mutex{}, runner: r}
+	if memoryMB < 0 {
+		return nil, errors.Errorf("Wrong memory budget %d", memoryMB)
+	}
+	m := Manager{keyPrefix: prefix, lock: &sync.Mutex{}, hLock: &sync.RWMutex{}, newRunner: f, max: max,
+		memoryMB: memoryMB, done: make(chan struct{})}
 	return &m, nil
 }
 
-// EnsureRunning loads data by key and ensure it is running
+// EnsureRunning loads data by key and ensure it is running and ready.
+// The running task is restarted if its readiness probe fails.
+// Empty key closes all the warm processes
 func (m *Manager) EnsureRunning(in map[string]string) error {
 	key, f := in[m.keyPrefix+"_key"]
 	if !f {
@@ -59,53 +120,282 @@ func (m *Manager) EnsureRunning(in map[string]string) error {
 	defer m.lock.Unlock()
 
 	if key == "" {
-		err := m.runner.Close()
-		if err != nil {
-			cmdapp.Log.Warn(err)
+		for len(m.runners) > 0 {
+			if err := m.evict(); err != nil {
+				cmdapp.Log.Warn(err)
+			}
 		}
-		m.currentKey = ""
 		return nil
 	}
 
-	if key != m.currentKey {
-		if m.currentKey != "" {
-			cmdapp.Log.Infof("Closing preloader task for key %s...", m.currentKey)
+	if i := m.find(key); i > -1 {
+		wr := m.runners[i]
+		m.touch(i)
+		if !wr.runner.Running() {
+			cmdapp.Log.Infof("Preload task %s is not running. Trying to start...", key)
+			return m.restart(wr, in)
 		}
-		err := m.runner.Close()
-		if err != nil {
+		if wr.probe != nil {
+			if err := checkOnce(wr.probe); err != nil {
+				cmdapp.Log.Warn(errors.Wrapf(err, "Preload task %s liveness check failed. Restarting...", key))
+				m.setState(wr, false, err)
+				return m.restart(wr, in)
+			}
+			m.setState(wr, true, nil)
+		}
+		cmdapp.Log.Infof("Preload task %s is running.", key)
+		return nil
+	}
+
+	mem, err := m.memory(in)
+	if err != nil {
+		return err
+	}
+	if m.memoryMB > 0 && mem > m.memoryMB {
+		cmdapp.Log.Warnf("Preload task %s needs %d MB, budget is %d MB", key, mem, m.memoryMB)
+	}
+	for len(m.runners) > 0 && (len(m.runners) >= m.max || (m.memoryMB > 0 && m.usedMemory()+mem > m.memoryMB)) {
+		if err := m.evict(); err != nil {
 			return errors.Wrap(err, "Can't close the running task")
 		}
-		return m.start(key, in)
 	}
+	r, err := m.newRunner()
+	if err != nil {
+		return errors.Wrap(err, "Can't init runner")
+	}
+	wr := &warmRunner{key: key, runner: r, memoryMB: mem}
+	m.setRunners(append([]*warmRunner{wr}, m.runners...))
+	return m.start(wr, in)
+}
+
+// Health returns the state of the preload tasks, the most recently used first
+func (m *Manager) Health() []api.TaskHealth {
+	m.hLock.RLock()
+	defer m.hLock.RUnlock()
 
-	if !m.runner.Running() {
-		cmdapp.Log.Info("Preload task is not running. Trying to start...")
-		return m.start(key, in)
+	res := make([]api.TaskHealth, 0, len(m.runners))
+	for _, wr := range m.runners {
+		h := api.TaskHealth{Key: wr.key, Running: wr.runner.Running(), Ready: wr.ready, Error: wr.err,
+			CheckedAt: wr.checkedAt}
+		if wr.probe != nil {
+			h.Probe = wr.probe.String()
+		}
+		h.Ready = h.Ready && h.Running
+		res = append(res, h)
 	}
-	cmdapp.Log.Info("Preload task is running.")
-	return nil
+	return res
 }
 
-func (m *Manager) start(key string, in map[string]string) error {
+// Check runs liveness probes of the warm tasks and updates the health state.
+// Failed tasks are restarted on the next EnsureRunning call
+func (m *Manager) Check() {
+	type item struct {
+		wr     *warmRunner
+		probe  probe
+		runner ProcessRunner
+	}
+	m.hLock.RLock()
+	items := make([]item, 0, len(m.runners))
+	for _, wr := range m.runners {
+		if wr.probe != nil {
+			items = append(items, item{wr: wr, probe: wr.probe, runner: wr.runner})
+		}
+	}
+	m.hLock.RUnlock()
+
+	for _, it := range items {
+		if !it.runner.Running() {
+			continue
+		}
+		err := m.checkLive(it.probe)
+		if err != nil {
+			cmdapp.Log.Warn(errors.Wrapf(err, "Preload task %s liveness check failed", it.wr.key))
+		}
+		m.setState(it.wr, err == nil, err)
+	}
+}
+
+// checkLive runs the liveness probe, the cmd probe waits for its process holding the reap lock
+func (m *Manager) checkLive(p probe) error {
+	if _, ok := p.(*cmdProbe); ok && m.reapLock != nil {
+		m.reapLock.RLock()
+		defer m.reapLock.RUnlock()
+	}
+	return checkOnce(p)
+}
+
+// StartChecks runs liveness checks periodically until the manager is closed
+func (m *Manager) StartChecks(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-t.C:
+			m.Check()
+		}
+	}
+}
+
+// Warm returns keys of the running and ready preload tasks, the most recently used first
+func (m *Manager) Warm() []string {
+	m.hLock.RLock()
+	defer m.hLock.RUnlock()
+
+	res := make([]string, 0, len(m.runners))
+	for _, wr := range m.runners {
+		if wr.ready && wr.runner.Running() {
+			res = append(res, wr.key)
+		}
+	}
+	return res
+}
+
+func (m *Manager) start(wr *warmRunner, in map[string]string) error {
 	cmd, f := in[m.keyPrefix+"_cmd"]
 	if !f || cmd == "" {
+		m.remove(wr)
 		return errors.Errorf("No preload command '%s' found for the task", m.keyPrefix+"_cmd")
 	}
+	p, err := newProbe(m.keyPrefix, in)
+	if err != nil {
+		m.remove(wr)
+		return err
+	}
+	timeout, err := readyTimeout(m.keyPrefix, in)
+	if err != nil {
+		m.remove(wr)
+		return err
+	}
+	m.hLock.Lock()
+	wr.probe, wr.ready, wr.err = p, false, ""
+	m.hLock.Unlock()
 	cmdapp.Log.Info("Starting preload task " + cmd)
-	err := m.runner.Run(cmd, makeEnv(in))
+	err = wr.runner.Run(cmd, makeEnv(in))
 	if err != nil {
+		m.remove(wr)
 		return errors.Wrap(err, "Can't start the preload task")
 	}
-	m.currentKey = key
-	if !m.runner.Running() {
+	if !wr.runner.Running() {
+		m.remove(wr)
 		return errors.New("Preload task is not running or terminated")
 	}
+	if p != nil {
+		cmdapp.Log.Infof("Waiting for preload task %s to be ready: %s", wr.key, p.String())
+		if err := waitReady(p, wr.runner, timeout); err != nil {
+			m.remove(wr)
+			cmdapp.LogIf(wr.runner.Close())
+			return err
+		}
+		cmdapp.Log.Infof("Preload task %s is ready", wr.key)
+	}
+	m.setState(wr, true, nil)
 	return nil
 }
 
-// Close terminates runnig preocess if any
+// restart closes the task and starts it in a new runner
+func (m *Manager) restart(wr *warmRunner, in map[string]string) error {
+	cmdapp.LogIf(wr.runner.Close())
+	r, err := m.newRunner()
+	if err != nil {
+		m.remove(wr)
+		return errors.Wrap(err, "Can't init runner")
+	}
+	m.hLock.Lock()
+	wr.runner = r
+	m.hLock.Unlock()
+	return m.start(wr, in)
+}
+
+func (m *Manager) setState(wr *warmRunner, ready bool, err error) {
+	m.hLock.Lock()
+	defer m.hLock.Unlock()
+	wr.ready = ready
+	wr.err = ""
+	if err != nil {
+		wr.err = err.Error()
+	}
+	wr.checkedAt = time.Now()
+}
+
+func (m *Manager) setRunners(rs []*warmRunner) {
+	m.hLock.Lock()
+	defer m.hLock.Unlock()
+	m.runners = rs
+}
+
+// evict closes the least recently used task
+func (m *Manager) evict() error {
+	l := len(m.runners) - 1
+	wr := m.runners[l]
+	m.setRunners(m.runners[:l])
+	cmdapp.Log.Infof("Closing preloader task for key %s...", wr.key)
+	return wr.runner.Close()
+}
+
+func (m *Manager) remove(wr *warmRunner) {
+	for i, r := range m.runners {
+		if r == wr {
+			rs := append([]*warmRunner{}, m.runners[:i]...)
+			m.setRunners(append(rs, m.runners[i+1:]...))
+			return
+		}
+	}
+}
+
+func (m *Manager) find(key string) int {
+	for i, wr := range m.runners {
+		if wr.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// touch moves the runner to the front
+func (m *Manager) touch(i int) {
+	m.hLock.Lock()
+	defer m.hLock.Unlock()
+	wr := m.runners[i]
+	copy(m.runners[1:i+1], m.runners[:i])
+	m.runners[0] = wr
+}
+
+func (m *Manager) usedMemory() int64 {
+	var res int64
+	for _, wr := range m.runners {
+		res += wr.memoryMB
+	}
+	return res
+}
+
+func (m *Manager) memory(in map[string]string) (int64, error) {
+	s, f := in[m.keyPrefix+"_memoryMB"]
+	if !f || s == "" {
+		return 0, nil
+	}
+	res, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || res < 0 {
+		return 0, errors.Errorf("Wrong preload task memory '%s' = '%s'", m.keyPrefix+"_memoryMB", s)
+	}
+	return res, nil
+}
+
+// Close terminates running processes if any
 func (m *Manager) Close() error {
-	return m.runner.Close()
+	m.closeOnce.Do(func() { close(m.done) })
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var res error
+	for len(m.runners) > 0 {
+		if err := m.evict(); err != nil {
+			cmdapp.Log.Warn(err)
+			res = err
+		}
+	}
+	return res
 }
 
 func makeEnv(in map[string]string) []string {
@@ -1,7 +1,10 @@
 package tasks
 
 import (
+	"net"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/airenas/listgo/internal/pkg/test/mocks"
 	"github.com/petergtz/pegomock"
@@ -38,6 +41,7 @@ func TestInit_OK(t *testing.T) {
 
 func TestCloses(t *testing.T) {
 	m := newTestManager(t)
+	setWarm(m, "key")
 	m.Close()
 	runnerMock.VerifyWasCalledOnce().Close()
 }
@@ -50,7 +54,7 @@ func TestEnsure_NoKey(t *testing.T) {
 
 func TestEnsure_StartsCmd(t *testing.T) {
 	m := newTestManager(t)
-	m.currentKey = "key"
+	setWarm(m, "key")
 	err := m.EnsureRunning(newMap("key", "olia cmd"))
 	assert.Nil(t, err)
 	runnerMock.VerifyWasCalled(pegomock.Never()).Run(pegomock.AnyString(), pegomock.AnyStringSlice())
@@ -64,12 +68,12 @@ func TestEnsure_DoesNotStartOnRunning(t *testing.T) {
 	assert.Equal(t, "olia cmd", cmd)
 	assert.Contains(t, env, "PR_KEY=key")
 	assert.Contains(t, env, "PR_CMD=olia cmd")
-	assert.Equal(t, "key", m.currentKey)
+	assert.Equal(t, []string{"key"}, m.Warm())
 }
 
 func TestEnsure_StartOnNotRunning(t *testing.T) {
 	m := newTestManager(t)
-	m.currentKey = "key"
+	setWarm(m, "key")
 	pegomock.When(runnerMock.Running()).ThenReturn(false).ThenReturn(true)
 	err := m.EnsureRunning(newMap("key", "olia cmd"))
 	runnerMock.VerifyWasCalledOnce().Run(pegomock.AnyString(), pegomock.AnyStringSlice())
@@ -78,7 +82,7 @@ func TestEnsure_StartOnNotRunning(t *testing.T) {
 
 func TestEnsure_CloseOnRunning(t *testing.T) {
 	m := newTestManager(t)
-	m.currentKey = "key"
+	setWarm(m, "key")
 	pegomock.When(runnerMock.Running()).ThenReturn(false)
 	err := m.EnsureRunning(newMap("", "olia cmd"))
 	runnerMock.VerifyWasCalledOnce().Close()
@@ -88,7 +92,7 @@ func TestEnsure_CloseOnRunning(t *testing.T) {
 
 func TestEnsure_FailsOnClose(t *testing.T) {
 	m := newTestManager(t)
-	m.currentKey = "key"
+	setWarm(m, "key")
 	pegomock.When(runnerMock.Close()).ThenReturn(errors.New("error"))
 	pegomock.When(runnerMock.Running()).ThenReturn(true)
 	err := m.EnsureRunning(newMap("new", "olia cmd"))
@@ -119,6 +123,126 @@ func newTestManager(t *testing.T) *Manager {
 	return m
 }
 
+func setWarm(m *Manager, key string) {
+	m.runners = append(m.runners, &warmRunner{key: key, runner: runnerMock})
+}
+
+func TestFailsOnLimits(t *testing.T) {
+	initTest(t)
+	f := func() (ProcessRunner, error) { return runnerMock, nil }
+	_, err := newManagerWithFactory("pr", f, 0, 0)
+	assert.NotNil(t, err)
+	_, err = newManagerWithFactory("pr", f, 1, -1)
+	assert.NotNil(t, err)
+	_, err = newManagerWithFactory("pr", nil, 1, 0)
+	assert.NotNil(t, err)
+}
+
+type testRunners struct {
+	t       *testing.T
+	runners map[string]*mocks.MockProcessRunner
+	last    *mocks.MockProcessRunner
+}
+
+func newTestLRUManager(t *testing.T, max int, memoryMB int64) (*Manager, *testRunners) {
+	initTest(t)
+	tr := &testRunners{t: t, runners: make(map[string]*mocks.MockProcessRunner)}
+	f := func() (ProcessRunner, error) {
+		r := mocks.NewMockProcessRunner()
+		pegomock.When(r.Running()).ThenReturn(true)
+		tr.last = r
+		return r, nil
+	}
+	m, err := newManagerWithFactory("pr", f, max, memoryMB)
+	assert.Nil(t, err)
+	return m, tr
+}
+
+func (tr *testRunners) ensure(m *Manager, key string, mem string) {
+	in := newMap(key, "cmd "+key)
+	if mem != "" {
+		in["pr_memoryMB"] = mem
+	}
+	tr.last = nil
+	assert.Nil(tr.t, m.EnsureRunning(in))
+	if tr.last != nil {
+		tr.runners[key] = tr.last
+	}
+}
+
+func TestLRU_KeepsWarm(t *testing.T) {
+	m, tr := newTestLRUManager(t, 3, 0)
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "b", "")
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "c", "")
+	assert.Equal(t, []string{"c", "a", "b"}, m.Warm())
+	assert.Equal(t, 3, len(tr.runners))
+	for _, r := range tr.runners {
+		r.VerifyWasCalledOnce().Run(pegomock.AnyString(), pegomock.AnyStringSlice())
+		r.VerifyWasCalled(pegomock.Never()).Close()
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	m, tr := newTestLRUManager(t, 2, 0)
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "b", "")
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "c", "")
+	assert.Equal(t, []string{"c", "a"}, m.Warm())
+	tr.runners["b"].VerifyWasCalledOnce().Close()
+	tr.runners["a"].VerifyWasCalled(pegomock.Never()).Close()
+}
+
+func TestLRU_EvictsByMemory(t *testing.T) {
+	m, tr := newTestLRUManager(t, 3, 1000)
+	tr.ensure(m, "a", "400")
+	tr.ensure(m, "b", "400")
+	assert.Equal(t, []string{"b", "a"}, m.Warm())
+	tr.ensure(m, "c", "300")
+	assert.Equal(t, []string{"c", "b"}, m.Warm())
+	tr.runners["a"].VerifyWasCalledOnce().Close()
+	tr.ensure(m, "d", "2000")
+	assert.Equal(t, []string{"d"}, m.Warm())
+}
+
+func TestLRU_FailsOnWrongMemory(t *testing.T) {
+	m, _ := newTestLRUManager(t, 3, 1000)
+	in := newMap("a", "cmd")
+	in["pr_memoryMB"] = "olia"
+	assert.NotNil(t, m.EnsureRunning(in))
+	assert.Equal(t, []string{}, m.Warm())
+}
+
+func TestLRU_EmptyKeyClosesAll(t *testing.T) {
+	m, tr := newTestLRUManager(t, 3, 0)
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "b", "")
+	tr.ensure(m, "", "")
+	assert.Equal(t, []string{}, m.Warm())
+	tr.runners["a"].VerifyWasCalledOnce().Close()
+	tr.runners["b"].VerifyWasCalledOnce().Close()
+}
+
+func TestLRU_WarmSkipsNotRunning(t *testing.T) {
+	m, tr := newTestLRUManager(t, 3, 0)
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "b", "")
+	pegomock.When(tr.runners["a"].Running()).ThenReturn(false)
+	assert.Equal(t, []string{"b"}, m.Warm())
+}
+
+func TestLRU_CloseAll(t *testing.T) {
+	m, tr := newTestLRUManager(t, 3, 0)
+	tr.ensure(m, "a", "")
+	tr.ensure(m, "b", "")
+	assert.Nil(t, m.Close())
+	tr.runners["a"].VerifyWasCalledOnce().Close()
+	tr.runners["b"].VerifyWasCalledOnce().Close()
+	assert.Equal(t, []string{}, m.Warm())
+}
+
 func newMap(key, cmd string) map[string]string {
 	res := make(map[string]string)
 	if key != "<none>" {
@@ -129,3 +253,130 @@ func newMap(key, cmd string) map[string]string {
 	}
 	return res
 }
+
+func TestReady_WaitsForProbe(t *testing.T) {
+	m, tr := newTestLRUManager(t, 2, 0)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	in := newMap("a", "cmd")
+	in["pr_ready_tcp"] = l.Addr().String()
+	assert.Nil(t, m.EnsureRunning(in))
+	assert.Equal(t, []string{"a"}, m.Warm())
+	h := m.Health()
+	assert.Equal(t, 1, len(h))
+	assert.Equal(t, "a", h[0].Key)
+	assert.True(t, h[0].Ready)
+	assert.True(t, h[0].Running)
+	assert.Equal(t, "tcp "+l.Addr().String(), h[0].Probe)
+	tr.last.VerifyWasCalledOnce().Run(pegomock.AnyString(), pegomock.AnyStringSlice())
+}
+
+func TestReady_FailsOnTimeout(t *testing.T) {
+	m, tr := newTestLRUManager(t, 2, 0)
+	readyCheckInterval = time.Millisecond
+	in := newMap("a", "cmd")
+	in["pr_ready_cmd"] = "false"
+	in["pr_ready_timeout"] = "10ms"
+	assert.NotNil(t, m.EnsureRunning(in))
+	assert.Equal(t, []string{}, m.Warm())
+	assert.Equal(t, 0, len(m.Health()))
+	tr.last.VerifyWasCalledOnce().Close()
+}
+
+func TestLive_RestartsOnFailure(t *testing.T) {
+	m, tr := newTestLRUManager(t, 2, 0)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	in := newMap("a", "cmd")
+	in["pr_ready_tcp"] = l.Addr().String()
+	assert.Nil(t, m.EnsureRunning(in))
+	first := tr.last
+	l.Close()
+
+	m.Check()
+	h := m.Health()
+	assert.False(t, h[0].Ready)
+	assert.NotEmpty(t, h[0].Error)
+	assert.Equal(t, []string{}, m.Warm())
+
+	l, err = net.Listen("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer l.Close()
+	m.Check()
+	assert.Equal(t, []string{"a"}, m.Warm())
+	l.Close()
+
+	readyCheckInterval = time.Millisecond
+	in["pr_ready_timeout"] = "10ms"
+	assert.NotNil(t, m.EnsureRunning(in))
+	first.VerifyWasCalledOnce().Close()
+	assert.True(t, first != tr.last)
+}
+
+func TestLive_KeepsRunning(t *testing.T) {
+	m, tr := newTestLRUManager(t, 2, 0)
+	in := newMap("a", "cmd")
+	in["pr_ready_cmd"] = "true"
+	assert.Nil(t, m.EnsureRunning(in))
+	first := tr.last
+	assert.Nil(t, m.EnsureRunning(in))
+	first.VerifyWasCalledOnce().Run(pegomock.AnyString(), pegomock.AnyStringSlice())
+	first.VerifyWasCalled(pegomock.Never()).Close()
+}
+
+func TestLive_CmdProbeHoldsReapLock(t *testing.T) {
+	m, _ := newTestLRUManager(t, 2, 0)
+	m.reapLock = &sync.RWMutex{}
+	in := newMap("a", "cmd")
+	in["pr_ready_cmd"] = "true"
+	assert.Nil(t, m.EnsureRunning(in))
+
+	m.reapLock.Lock()
+	done := make(chan struct{})
+	go func() {
+		m.Check()
+		close(done)
+	}()
+	select {
+	case <-done:
+		assert.Fail(t, "probe run while the reap lock is taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.reapLock.Unlock()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "probe not run")
+	}
+	assert.Equal(t, []string{"a"}, m.Warm())
+}
+
+func TestStart_RemovesNotRunning(t *testing.T) {
+	initTest(t)
+	r := mocks.NewMockProcessRunner()
+	pegomock.When(r.Running()).ThenReturn(false)
+	m, err := newManagerWithFactory("pr", func() (ProcessRunner, error) { return r, nil }, 2, 0)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, m.EnsureRunning(newMap("a", "cmd")))
+
+	assert.Equal(t, 0, len(m.Health()))
+	assert.Equal(t, 0, len(m.runners))
+}
+
+func TestStartChecks_StopsOnClose(t *testing.T) {
+	m, _ := newTestLRUManager(t, 2, 0)
+	done := make(chan struct{})
+	go func() {
+		m.StartChecks(time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, m.Close())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "checks not stopped")
+	}
+}
@@ -0,0 +1,151 @@
+package tasks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// probe checks if the preload task is ready to serve
+type probe interface {
+	check(ctx context.Context) error
+	String() string
+}
+
+// probeTimeout is a timeout for one probe check
+var probeTimeout = 5 * time.Second
+
+// readyCheckInterval is the time between the readiness checks while waiting for the task to start
+var readyCheckInterval = 500 * time.Millisecond
+
+// defaultReadyTimeout is the default time to wait for the task to become ready
+const defaultReadyTimeout = 2 * time.Minute
+
+// newProbe creates the probe from the recognizer settings:
+//
+//	<prefix>_ready_http - URL, the task is ready if GET returns 2xx
+//	<prefix>_ready_tcp - host:port, the task is ready if the port accepts connections
+//	<prefix>_ready_cmd - command, the task is ready if the command exits with 0
+//
+// Returns nil if no probe is configured
+func newProbe(prefix string, in map[string]string) (probe, error) {
+	var res []probe
+	if v := in[prefix+"_ready_http"]; v != "" {
+		res = append(res, &httpProbe{url: v})
+	}
+	if v := in[prefix+"_ready_tcp"]; v != "" {
+		res = append(res, &tcpProbe{address: v})
+	}
+	if v := in[prefix+"_ready_cmd"]; v != "" {
+		res = append(res, &cmdProbe{cmd: v})
+	}
+	if len(res) > 1 {
+		return nil, errors.Errorf("Several readiness probes configured for '%s'", prefix)
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res[0], nil
+}
+
+// readyTimeout returns <prefix>_ready_timeout setting or the default value
+func readyTimeout(prefix string, in map[string]string) (time.Duration, error) {
+	v := in[prefix+"_ready_timeout"]
+	if v == "" {
+		return defaultReadyTimeout, nil
+	}
+	res, err := time.ParseDuration(v)
+	if err != nil || res <= 0 {
+		return 0, errors.Errorf("Wrong '%s' = '%s'", prefix+"_ready_timeout", v)
+	}
+	return res, nil
+}
+
+func checkOnce(p probe) error {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	return p.check(ctx)
+}
+
+// waitReady checks the probe until it succeeds, the runner exits or the timeout passes
+func waitReady(p probe, r ProcessRunner, timeout time.Duration) error {
+	end := time.Now().Add(timeout)
+	for {
+		err := checkOnce(p)
+		if err == nil {
+			return nil
+		}
+		if !r.Running() {
+			return errors.Wrap(err, "Preload task terminated before it got ready")
+		}
+		if time.Now().After(end) {
+			return errors.Wrapf(err, "Preload task is not ready after %v", timeout)
+		}
+		time.Sleep(readyCheckInterval)
+	}
+}
+
+type httpProbe struct {
+	url string
+}
+
+func (p *httpProbe) check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Wrong url %s", p.url)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Can't call %s", p.url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("%s returned %d", p.url, resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *httpProbe) String() string {
+	return "http " + p.url
+}
+
+type tcpProbe struct {
+	address string
+}
+
+func (p *tcpProbe) check(ctx context.Context) error {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return errors.Wrapf(err, "Can't connect %s", p.address)
+	}
+	return c.Close()
+}
+
+func (p *tcpProbe) String() string {
+	return "tcp " + p.address
+}
+
+type cmdProbe struct {
+	cmd string
+}
+
+func (p *cmdProbe) check(ctx context.Context) error {
+	args := stringToArgs(p.cmd)
+	if len(args) == 0 {
+		return errors.Errorf("Wrong probe command '%s'", p.cmd)
+	}
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "Probe command failed: %s", string(out))
+	}
+	return nil
+}
+
+func (p *cmdProbe) String() string {
+	return "cmd " + p.cmd
+}
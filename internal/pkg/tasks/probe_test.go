@@ -0,0 +1,117 @@
+package tasks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/airenas/listgo/internal/pkg/test/mocks"
+	"github.com/petergtz/pegomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProbe(t *testing.T) {
+	p, err := newProbe("pr", map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	p, err = newProbe("pr", map[string]string{"pr_ready_http": "http://localhost:8080/ready"})
+	assert.Nil(t, err)
+	assert.Equal(t, "http http://localhost:8080/ready", p.String())
+	p, err = newProbe("pr", map[string]string{"pr_ready_tcp": "localhost:8080"})
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp localhost:8080", p.String())
+	p, err = newProbe("pr", map[string]string{"pr_ready_cmd": "check.sh 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "cmd check.sh 1", p.String())
+	_, err = newProbe("pr", map[string]string{"pr_ready_cmd": "check.sh 1", "pr_ready_tcp": "localhost:8080"})
+	assert.NotNil(t, err)
+}
+
+func TestReadyTimeout(t *testing.T) {
+	d, err := readyTimeout("pr", map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, defaultReadyTimeout, d)
+	d, err = readyTimeout("pr", map[string]string{"pr_ready_timeout": "10s"})
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, d)
+	_, err = readyTimeout("pr", map[string]string{"pr_ready_timeout": "olia"})
+	assert.NotNil(t, err)
+	_, err = readyTimeout("pr", map[string]string{"pr_ready_timeout": "-1s"})
+	assert.NotNil(t, err)
+}
+
+func TestHTTPProbe(t *testing.T) {
+	code := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+	p := &httpProbe{url: srv.URL}
+	assert.Nil(t, p.check(context.Background()))
+	code = http.StatusServiceUnavailable
+	assert.NotNil(t, p.check(context.Background()))
+	srv.Close()
+	assert.NotNil(t, p.check(context.Background()))
+}
+
+func TestTCPProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	p := &tcpProbe{address: l.Addr().String()}
+	assert.Nil(t, p.check(context.Background()))
+	l.Close()
+	assert.NotNil(t, p.check(context.Background()))
+}
+
+func TestCmdProbe(t *testing.T) {
+	assert.Nil(t, (&cmdProbe{cmd: "true"}).check(context.Background()))
+	assert.NotNil(t, (&cmdProbe{cmd: "false"}).check(context.Background()))
+	assert.NotNil(t, (&cmdProbe{cmd: ""}).check(context.Background()))
+}
+
+type testProbe struct {
+	errs []error
+	i    int
+}
+
+func (p *testProbe) check(ctx context.Context) error {
+	if p.i >= len(p.errs) {
+		return nil
+	}
+	p.i++
+	return p.errs[p.i-1]
+}
+
+func (p *testProbe) String() string {
+	return "test"
+}
+
+func TestWaitReady(t *testing.T) {
+	initTest(t)
+	readyCheckInterval = time.Millisecond
+	p := &testProbe{errs: []error{context.DeadlineExceeded, context.DeadlineExceeded}}
+	assert.Nil(t, waitReady(p, runnerMock, time.Second))
+	assert.Equal(t, 2, p.i)
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	initTest(t)
+	readyCheckInterval = time.Millisecond
+	p := &testProbe{errs: make([]error, 1000)}
+	for i := range p.errs {
+		p.errs[i] = context.DeadlineExceeded
+	}
+	assert.NotNil(t, waitReady(p, runnerMock, 10*time.Millisecond))
+}
+
+func TestWaitReady_Terminated(t *testing.T) {
+	mocks.AttachMockToTest(t)
+	r := mocks.NewMockProcessRunner()
+	pegomock.When(r.Running()).ThenReturn(false)
+	readyCheckInterval = time.Millisecond
+	p := &testProbe{errs: []error{context.DeadlineExceeded}}
+	assert.NotNil(t, waitReady(p, r, time.Second))
+}
@@ -14,6 +14,10 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=speechIndicator.go -m bitbucket.org/airenas/listgo/internal/app/manager SpeechIndicator
 
+//go:generate pegomock generate --package=mocks --output=durationSaver.go -m bitbucket.org/airenas/listgo/internal/app/manager DurationSaver
+
+//go:generate pegomock generate --package=mocks --output=durationReader.go -m bitbucket.org/airenas/listgo/internal/app/manager DurationReader
+
 //go:generate pegomock generate --package=mocks --output=publisher.go -m bitbucket.org/airenas/listgo/internal/pkg/messages Publisher
 
 //go:generate pegomock generate --package=mocks --output=messageSender.go -m bitbucket.org/airenas/listgo/internal/pkg/messages Sender
@@ -32,6 +36,8 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=recognizerProvider.go -m bitbucket.org/airenas/listgo/internal/app/upload RecognizerProvider
 
+//go:generate pegomock generate --package=mocks --output=dedup.go -m bitbucket.org/airenas/listgo/internal/app/upload Dedup
+
 //go:generate pegomock generate --package=mocks --output=emailMaker.go -m bitbucket.org/airenas/listgo/internal/app/inform EmailMaker
 
 //go:generate pegomock generate --package=mocks --output=emailRetriever.go -m bitbucket.org/airenas/listgo/internal/app/inform EmailRetriever
@@ -44,6 +50,14 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=fileNameProvider.go -m bitbucket.org/airenas/listgo/internal/app/result FileNameProvider
 
+//go:generate pegomock generate --package=mocks --output=speakerNameStore.go -m bitbucket.org/airenas/listgo/internal/app/result SpeakerNameStore
+
+//go:generate pegomock generate --package=mocks --output=versionStore.go -m bitbucket.org/airenas/listgo/internal/app/result VersionStore
+
+//go:generate pegomock generate --package=mocks --output=fileRemover.go -m bitbucket.org/airenas/listgo/internal/app/result FileRemover
+
+//go:generate pegomock generate --package=mocks --output=sourceIDProvider.go -m bitbucket.org/airenas/listgo/internal/app/result SourceIDProvider
+
 //go:generate pegomock generate --package=mocks --output=kReader.go -m bitbucket.org/airenas/listgo/internal/app/kafkaintegration KafkaReader
 
 //go:generate pegomock generate --package=mocks --output=kWriter.go -m bitbucket.org/airenas/listgo/internal/app/kafkaintegration KafkaWriter
@@ -64,6 +78,8 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=oldIDsProvider.go -m bitbucket.org/airenas/listgo/internal/app/clean OldIDsProvider
 
+//go:generate pegomock generate --package=mocks --output=refCounter.go -m bitbucket.org/airenas/listgo/internal/app/clean RefCounter
+
 //go:generate pegomock generate --package=mocks --output=recInfoLoader.go -m bitbucket.org/airenas/listgo/internal/app/cmdworker RecInfoLoader
 
 //go:generate pegomock generate --package=mocks --output=preloadTaskManager.go -m bitbucket.org/airenas/listgo/internal/app/cmdworker PreloadTaskManager
@@ -78,6 +94,8 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=startTimeGetter.go -m bitbucket.org/airenas/listgo/internal/app/dispatcher StartTimeGetter
 
+//go:generate pegomock generate --package=mocks --output=requestGetter.go -m bitbucket.org/airenas/listgo/internal/app/dispatcher RequestGetter
+
 //go:generate pegomock generate --package=mocks --output=taskSelector.go -m bitbucket.org/airenas/listgo/internal/pkg/strategy/api TaskSelector
 
 //go:generate pegomock generate --package=mocks --output=filesGetter.go -m bitbucket.org/airenas/listgo/internal/app/zoom FilesGetter
@@ -86,6 +104,8 @@ import (
 
 //go:generate pegomock generate --package=mocks --output=workPersistence.go -m bitbucket.org/airenas/listgo/internal/app/zoom WorkPersistence
 
+//go:generate pegomock generate --package=mocks --output=step.go -m bitbucket.org/airenas/listgo/internal/pkg/step Step
+
 //AttachMockToTest register pegomock verification to be passed to testing engine
 func AttachMockToTest(t *testing.T) {
 	pegomock.RegisterMockFailHandler(handleByTest(t))
@@ -0,0 +1,77 @@
+package urlsign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ExpiresParam is the link expiry query parameter, unix seconds
+	ExpiresParam = "expires"
+	// SignatureParam is the link signature query parameter
+	SignatureParam = "signature"
+	// ScopeParam is the link scope query parameter
+	ScopeParam = "scope"
+
+	// ScopeRead allows to get the audio and the results
+	ScopeRead = "read"
+	// ScopeEdit allows to change the speakers and the transcript, it allows to read too
+	ScopeEdit = "edit"
+)
+
+// ErrNoSignature is returned if the link is not signed
+var ErrNoSignature = errors.New("no signature")
+
+// Signer makes and verifies HMAC-SHA256 signed links. The signature covers the job ID, the scope and the expiry time,
+// so one signature grants the scope's access to the audio and all the results of the job
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates Signer instance
+func NewSigner(key string) (*Signer, error) {
+	if len(key) < 16 {
+		return nil, errors.New("Signing key must be at least 16 symbols")
+	}
+	return &Signer{key: []byte(key)}, nil
+}
+
+// Sign returns the query parameters to add to the job links
+func (s *Signer) Sign(id, scope string, expires time.Time) url.Values {
+	e := strconv.FormatInt(expires.Unix(), 10)
+	return url.Values{ExpiresParam: {e}, ScopeParam: {scope}, SignatureParam: {s.signature(id, scope, e)}}
+}
+
+// Verify checks the signature, the scope and the expiry of the link
+func (s *Signer) Verify(id, scope string, q url.Values, now time.Time) error {
+	e, sig, ls := q.Get(ExpiresParam), q.Get(SignatureParam), q.Get(ScopeParam)
+	if e == "" && sig == "" {
+		return ErrNoSignature
+	}
+	if ls != scope && !(ls == ScopeEdit && scope == ScopeRead) {
+		return errors.Errorf("link scope '%s' does not allow '%s'", ls, scope)
+	}
+	exp, err := strconv.ParseInt(e, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "wrong expires '%s'", e)
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(id, ls, e))) {
+		return errors.New("wrong signature")
+	}
+	if now.Unix() > exp {
+		return errors.New("link expired")
+	}
+	return nil
+}
+
+func (s *Signer) signature(id, scope, expires string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(id + "\n" + scope + "\n" + expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
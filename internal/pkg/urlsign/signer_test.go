@@ -0,0 +1,61 @@
+package urlsign
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "0123456789abcdef"
+
+func TestNewSigner(t *testing.T) {
+	_, err := NewSigner("short")
+	assert.NotNil(t, err)
+	s, err := NewSigner(testKey)
+	assert.Nil(t, err)
+	assert.NotNil(t, s)
+}
+
+func TestSignVerify(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	now := time.Unix(1600000000, 0)
+
+	q := s.Sign("id", ScopeRead, now.Add(time.Hour))
+
+	assert.Equal(t, "1600003600", q.Get(ExpiresParam))
+	assert.Equal(t, ScopeRead, q.Get(ScopeParam))
+	assert.Equal(t, 64, len(q.Get(SignatureParam)))
+	assert.Nil(t, s.Verify("id", ScopeRead, q, now))
+	assert.Nil(t, s.Verify("id", ScopeRead, q, now.Add(time.Hour)))
+	assert.NotNil(t, s.Verify("id", ScopeRead, q, now.Add(time.Hour+time.Second)))
+	assert.NotNil(t, s.Verify("id2", ScopeRead, q, now))
+	s2, _ := NewSigner(testKey + "1")
+	assert.NotNil(t, s2.Verify("id", ScopeRead, q, now))
+}
+
+func TestVerify_Scope(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	now := time.Unix(1600000000, 0)
+	read, edit := s.Sign("id", ScopeRead, now.Add(time.Hour)), s.Sign("id", ScopeEdit, now.Add(time.Hour))
+
+	assert.NotNil(t, s.Verify("id", ScopeEdit, read, now))
+	assert.Nil(t, s.Verify("id", ScopeEdit, edit, now))
+	assert.Nil(t, s.Verify("id", ScopeRead, edit, now))
+	read.Set(ScopeParam, ScopeEdit)
+	assert.NotNil(t, s.Verify("id", ScopeEdit, read, now))
+	read.Del(ScopeParam)
+	assert.NotNil(t, s.Verify("id", ScopeRead, read, now))
+}
+
+func TestVerify_Wrong(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	now := time.Unix(1600000000, 0)
+	q := s.Sign("id", ScopeRead, now.Add(time.Hour))
+
+	assert.Equal(t, ErrNoSignature, s.Verify("id", ScopeRead, url.Values{}, now))
+	assert.NotNil(t, s.Verify("id", ScopeRead, url.Values{SignatureParam: q[SignatureParam]}, now))
+	assert.NotNil(t, s.Verify("id", ScopeRead, url.Values{ExpiresParam: {"1600007200"}, SignatureParam: q[SignatureParam]}, now))
+	assert.NotNil(t, s.Verify("id", ScopeRead, url.Values{ExpiresParam: q[ExpiresParam]}, now))
+}
@@ -0,0 +1,186 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotPCM is returned if the file is not a PCM WAV
+var ErrNotPCM = errors.New("not a PCM wav")
+
+const headerSize = 44
+
+// Header keeps the PCM WAV format and the position of the samples
+type Header struct {
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	// DataOffset is the offset of the first sample in the file
+	DataOffset int64
+	// DataSize is the size of the samples in bytes
+	DataSize int64
+}
+
+// ReadHeader parses the RIFF chunks up to the data chunk
+func ReadHeader(r io.ReadSeeker) (*Header, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "can't seek")
+	}
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, ErrNotPCM
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, ErrNotPCM
+	}
+	res := &Header{}
+	offset := int64(12)
+	fmtFound := false
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			return nil, errors.Wrap(ErrNotPCM, "no data chunk")
+		}
+		size := int64(binary.LittleEndian.Uint32(ch[4:8]))
+		offset += 8
+		switch string(ch[0:4]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.Wrap(ErrNotPCM, "wrong fmt chunk")
+			}
+			var f [16]byte
+			if _, err := io.ReadFull(r, f[:]); err != nil {
+				return nil, errors.Wrap(err, "can't read fmt chunk")
+			}
+			format := binary.LittleEndian.Uint16(f[0:2])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE, assumed to be PCM
+			if format != 1 && format != 0xFFFE {
+				return nil, errors.Wrapf(ErrNotPCM, "format %d", format)
+			}
+			res.Channels = int(binary.LittleEndian.Uint16(f[2:4]))
+			res.SampleRate = int(binary.LittleEndian.Uint32(f[4:8]))
+			res.BitsPerSample = int(binary.LittleEndian.Uint16(f[14:16]))
+			if res.Channels < 1 || res.SampleRate < 1 || res.BitsPerSample%8 != 0 || res.BitsPerSample < 8 ||
+				res.BitsPerSample > 32 {
+				return nil, errors.Wrap(ErrNotPCM, "wrong format")
+			}
+			fmtFound = true
+			if _, err := r.Seek(offset+size+size%2, io.SeekStart); err != nil {
+				return nil, errors.Wrap(err, "can't seek")
+			}
+		case "data":
+			if !fmtFound {
+				return nil, errors.Wrap(ErrNotPCM, "no fmt chunk")
+			}
+			res.DataOffset = offset
+			res.DataSize = size
+			if end, err := r.Seek(0, io.SeekEnd); err == nil && offset+size > end {
+				// streamed files have no final size in the header
+				res.DataSize = end - offset
+			}
+			res.DataSize -= res.DataSize % int64(res.BlockAlign())
+			return res, nil
+		default:
+			if _, err := r.Seek(offset+size+size%2, io.SeekStart); err != nil {
+				return nil, errors.Wrap(err, "can't seek")
+			}
+		}
+		offset += size + size%2
+	}
+}
+
+// BlockAlign returns the size of one sample of all channels
+func (h *Header) BlockAlign() int {
+	return h.Channels * h.BitsPerSample / 8
+}
+
+// Samples returns the number of the samples in one channel
+func (h *Header) Samples() int64 {
+	return h.DataSize / int64(h.BlockAlign())
+}
+
+// Duration returns the audio duration
+func (h *Header) Duration() time.Duration {
+	return time.Duration(h.Samples() * int64(time.Second) / int64(h.SampleRate))
+}
+
+// SampleAt returns the sample index at the time, the result is limited to the audio length
+func (h *Header) SampleAt(t time.Duration) int64 {
+	res := int64(t.Seconds() * float64(h.SampleRate))
+	if res < 0 {
+		return 0
+	}
+	if s := h.Samples(); res > s {
+		return s
+	}
+	return res
+}
+
+// MonoReader reads the samples mixed to one channel, scaled to the 16 bit range
+type MonoReader struct {
+	h   *Header
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewMonoReader creates the reader, r must be positioned at the first sample to read
+func NewMonoReader(r io.Reader, h *Header) *MonoReader {
+	return &MonoReader{h: h, r: bufio.NewReaderSize(r, 64*1024), buf: make([]byte, h.BlockAlign())}
+}
+
+// Read returns the next sample
+func (m *MonoReader) Read() (int16, error) {
+	if _, err := io.ReadFull(m.r, m.buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	bs := m.h.BitsPerSample / 8
+	var sum int64
+	for c := 0; c < m.h.Channels; c++ {
+		sum += int64(sample(m.buf[c*bs : (c+1)*bs]))
+	}
+	return int16(sum / int64(m.h.Channels)), nil
+}
+
+// sample converts the little endian sample to the 16 bit range
+func sample(b []byte) int16 {
+	switch len(b) {
+	case 1:
+		return int16((int(b[0]) - 128) << 8)
+	case 2:
+		return int16(binary.LittleEndian.Uint16(b))
+	case 3:
+		return int16(b[2])<<8 | int16(b[1])
+	default:
+		return int16(b[3])<<8 | int16(b[2])
+	}
+}
+
+// WriteHeader writes the canonical 44 bytes PCM WAV header
+func WriteHeader(w io.Writer, h *Header, dataSize int64) error {
+	if dataSize > math.MaxUint32-headerSize {
+		return errors.New("too long data")
+	}
+	var b [headerSize]byte
+	copy(b[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(b[4:8], uint32(headerSize-8+dataSize))
+	copy(b[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(b[16:20], 16)
+	binary.LittleEndian.PutUint16(b[20:22], 1)
+	binary.LittleEndian.PutUint16(b[22:24], uint16(h.Channels))
+	binary.LittleEndian.PutUint32(b[24:28], uint32(h.SampleRate))
+	binary.LittleEndian.PutUint32(b[28:32], uint32(h.SampleRate*h.BlockAlign()))
+	binary.LittleEndian.PutUint16(b[32:34], uint16(h.BlockAlign()))
+	binary.LittleEndian.PutUint16(b[34:36], uint16(h.BitsPerSample))
+	copy(b[36:40], "data")
+	binary.LittleEndian.PutUint32(b[40:44], uint32(dataSize))
+	_, err := w.Write(b[:])
+	return err
+}
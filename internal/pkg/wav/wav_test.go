@@ -0,0 +1,101 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testWav(t *testing.T, h *Header, samples ...int16) []byte {
+	var b bytes.Buffer
+	assert.Nil(t, WriteHeader(&b, h, int64(len(samples)*2)))
+	for _, s := range samples {
+		binary.Write(&b, binary.LittleEndian, s)
+	}
+	return b.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	d := testWav(t, &Header{Channels: 2, SampleRate: 8000, BitsPerSample: 16}, 1, 2, 3, 4, 5, 6)
+
+	h, err := ReadHeader(bytes.NewReader(d))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &Header{Channels: 2, SampleRate: 8000, BitsPerSample: 16, DataOffset: 44, DataSize: 12}, h)
+	assert.Equal(t, int64(3), h.Samples())
+	assert.Equal(t, 375*time.Microsecond, h.Duration())
+}
+
+func TestReadHeader_SkipsChunks(t *testing.T) {
+	d := testWav(t, &Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16}, 1, 2)
+	var b bytes.Buffer
+	b.Write(d[:12])
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.Write([]byte{1, 2, 3, 0})
+	b.Write(d[12:])
+
+	h, err := ReadHeader(bytes.NewReader(b.Bytes()))
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(56), h.DataOffset)
+	assert.Equal(t, int64(4), h.DataSize)
+}
+
+func TestReadHeader_StreamedSize(t *testing.T) {
+	d := testWav(t, &Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16}, 1, 2)
+	binary.LittleEndian.PutUint32(d[40:44], 0xFFFFFFFF)
+
+	h, err := ReadHeader(bytes.NewReader(d))
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), h.DataSize)
+}
+
+func TestReadHeader_Fails(t *testing.T) {
+	d := testWav(t, &Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16}, 1)
+	mp3 := []byte("ID3olia olia olia olia olia olia olia olia olia olia")
+	float := append([]byte{}, d...)
+	binary.LittleEndian.PutUint16(float[20:22], 3)
+	noData := append([]byte{}, d[:36]...)
+
+	for _, b := range [][]byte{mp3, float, noData, d[:10]} {
+		_, err := ReadHeader(bytes.NewReader(b))
+		assert.NotNil(t, err)
+	}
+}
+
+func TestSampleAt(t *testing.T) {
+	h := &Header{Channels: 1, SampleRate: 8000, BitsPerSample: 16, DataSize: 16000}
+
+	assert.Equal(t, int64(4000), h.SampleAt(500*time.Millisecond))
+	assert.Equal(t, int64(0), h.SampleAt(-time.Second))
+	assert.Equal(t, int64(8000), h.SampleAt(time.Hour))
+}
+
+func TestMonoReader(t *testing.T) {
+	h := &Header{Channels: 2, SampleRate: 8000, BitsPerSample: 16}
+	d := testWav(t, h, 100, 300, -100, -300)
+	r := NewMonoReader(bytes.NewReader(d[44:]), h)
+
+	s, err := r.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, int16(200), s)
+	s, err = r.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, int16(-200), s)
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSample(t *testing.T) {
+	assert.Equal(t, int16(0), sample([]byte{128}))
+	assert.Equal(t, int16(-32768), sample([]byte{0}))
+	assert.Equal(t, int16(-2), sample([]byte{0xFE, 0xFF}))
+	assert.Equal(t, int16(0x1234), sample([]byte{0x56, 0x34, 0x12}))
+	assert.Equal(t, int16(-1), sample([]byte{0, 0, 0xFF, 0xFF}))
+}